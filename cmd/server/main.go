@@ -7,8 +7,15 @@ import (
 	// "buildmychat-backend/internal/auth" // Not directly needed here
 	"buildmychat-backend/internal/config"
 	"buildmychat-backend/internal/crypto" // Import crypto package
+	"buildmychat-backend/internal/embedding"
 	"buildmychat-backend/internal/handlers"
 	"buildmychat-backend/internal/integrations" // Import integrations package
+	"buildmychat-backend/internal/integrations/gitrepo"
+	slack_sender "buildmychat-backend/internal/integrations/slack"
+	"buildmychat-backend/internal/integrations/website"
+	"buildmychat-backend/internal/llm"
+	"buildmychat-backend/internal/realtime"
+	"buildmychat-backend/internal/rerank"
 	"buildmychat-backend/internal/services"
 	"buildmychat-backend/internal/store/postgres"
 	"context" // Import cipher package
@@ -53,6 +60,8 @@ func main() {
 	// 3. Initialize Dependencies (Store, Services, Handlers)
 	pgStore := postgres.NewPostgresStore(dbpool)
 	log.Println("Postgres store initialized.")
+	vectorStore := postgres.NewPGVectorStore(dbpool)
+	log.Println("pgvector store initialized.")
 
 	// --- Create AEAD Cipher for Encryption ---
 	aead, err := crypto.NewAESGCM(cfg.EncryptionKey)
@@ -64,25 +73,98 @@ func main() {
 	// --- Initialize Integration Registry ---
 	intRegistry := integrations.NewRegistry()
 	notionIntegration := integrations.NewNotionIntegration()
-	slackIntegration := integrations.NewSlackIntegration()
+	slackIntegration := integrations.NewSlackIntegration(cfg.SlackSigningSecret)
+	crawler := website.NewCrawler(&http.Client{Timeout: 30 * time.Second}, cfg.CrawlerUserAgent)
+	websiteIntegration := integrations.NewWebsiteIntegration(crawler)
+	gitIntegration := integrations.NewGitIntegration(gitrepo.NewFetcher(cfg.GitCacheDir))
+	uploadIntegration := integrations.NewUploadIntegration()
 	intRegistry.Register(string(api_models.ServiceTypeNotion), notionIntegration)
 	intRegistry.Register(string(api_models.ServiceTypeSlack), slackIntegration)
+	intRegistry.Register(string(api_models.ServiceTypeWebsite), websiteIntegration)
+	intRegistry.Register(string(api_models.ServiceTypeGit), gitIntegration)
+	intRegistry.Register(string(api_models.ServiceTypeUpload), uploadIntegration)
 	log.Println("IntegrationRegistry initialized and populated.")
 
+	// --- Initialize LLM Provider Registry ---
+	llmRegistry := llm.NewRegistry(cfg.LLMProvider, cfg.LLMDefaultModel)
+	llmRegistry.Register(llm.NewOpenAIProvider("openai", cfg.OpenAIBaseURL, cfg.OpenAIAPIKey, nil))
+	llmRegistry.Register(llm.NewFakeProvider()) // Deterministic offline provider ("fake:<model>")
+	log.Println("LLM provider registry initialized.")
+
+	// --- Initialize Embedder ---
+	var embedder embedding.Embedder
+	switch cfg.EmbeddingProvider {
+	case "openai":
+		embedder = embedding.NewOpenAIEmbedder(cfg.EmbeddingBaseURL, cfg.OpenAIAPIKey, cfg.EmbeddingModel, cfg.EmbeddingDimensions, nil)
+	case "hashing":
+		embedder = embedding.NewHashingEmbedder(cfg.EmbeddingDimensions)
+	default:
+		log.Fatalf("FATAL: Unknown EMBEDDING_PROVIDER %q (expected \"openai\" or \"hashing\")", cfg.EmbeddingProvider)
+	}
+	log.Printf("Embedder initialized (%s, %d dimensions).", embedder.Model(), embedder.Dimensions())
+
+	// --- Initialize Reranker (optional) ---
+	var reranker rerank.Reranker
+	switch cfg.RerankerProvider {
+	case "":
+	case "lexical":
+		reranker = rerank.NewLexicalReranker()
+	case "http":
+		if cfg.RerankerBaseURL == "" {
+			log.Fatal("FATAL: RERANKER_BASE_URL must be set when RERANKER_PROVIDER is \"http\"")
+		}
+		reranker = rerank.NewHTTPReranker(cfg.RerankerBaseURL, cfg.RerankerAPIKey, cfg.RerankerModel, nil)
+	default:
+		log.Fatalf("FATAL: Unknown RERANKER_PROVIDER %q (expected \"\", \"lexical\" or \"http\")", cfg.RerankerProvider)
+	}
+	if reranker != nil {
+		log.Printf("Reranker initialized (%s).", reranker.Name())
+	}
+
+	// --- Initialize Real-time Broker ---
+	var broker realtime.Broker
+	switch cfg.RealtimeBroker {
+	case "postgres":
+		broker = realtime.NewPostgresBroker(dbpool)
+	case "memory":
+		broker = realtime.NewMemoryBroker()
+	default:
+		log.Fatalf("FATAL: Unknown REALTIME_BROKER %q (expected \"memory\" or \"postgres\")", cfg.RealtimeBroker)
+	}
+	defer broker.Close()
+	log.Printf("Real-time broker initialized (%s).", cfg.RealtimeBroker)
+
 	// --- Initialize Services ---
 	// Only Auth service for now
 	authService := services.NewAuthService(pgStore, cfg)
 	log.Println("AuthService initialized.")
 	credentialService := services.NewCredentialsService(pgStore, aead, intRegistry) // Inject registry
 	log.Println("CredentialsService initialized.")
-	kbService := services.NewKBService(pgStore)
+	kbIndexer := services.NewKBIndexer(vectorStore, embedder)
+	kbRetriever := services.NewKBRetriever(vectorStore, embedder, reranker)
+	kbService := services.NewKBService(pgStore, intRegistry, kbRetriever, kbIndexer)
 	log.Println("KBService initialized.")
-	interfaceService := services.NewInterfaceService(pgStore)
+	kbSyncService := services.NewKBSyncService(pgStore, credentialService, intRegistry, kbIndexer, cfg.KBSyncPollInterval)
+	defer kbSyncService.Close() // Stops running syncs before the pool closes
+	log.Println("KBSyncService initialized.")
+	interfaceService := services.NewInterfaceService(pgStore, credentialService, intRegistry)
 	log.Println("InterfaceService initialized.")
 	chatbotService := services.NewChatbotService(pgStore)
 	log.Println("ChatbotService initialized.")
-	chatService := services.NewChatService(pgStore, chatbotService, credentialService)
+	chatService := services.NewChatService(pgStore, chatbotService, credentialService, llmRegistry, intRegistry, broker, kbRetriever)
+	defer chatService.Close() // Stops replies to channel messages before the pool closes
 	log.Println("ChatService initialized with credential service.")
+	var slackInstallService *services.SlackInstallService
+	if cfg.SlackClientID != "" {
+		slackOAuth := slack_sender.NewOAuthClient(cfg.SlackBaseURL, cfg.SlackClientID, cfg.SlackClientSecret, nil)
+		slackInstallService = services.NewSlackInstallService(pgStore, credentialService, slackOAuth, services.SlackInstallConfig{
+			RedirectURL:   cfg.SlackOAuthRedirectURL,
+			SigningSecret: cfg.SlackSigningSecret,
+			StateSecret:   cfg.JWTSecret,
+			Scopes:        cfg.SlackBotScopes,
+		})
+		log.Println("SlackInstallService initialized.")
+	}
 	// ... Initialize other services here as they are created ...
 
 	// --- Initialize Handlers ---
@@ -90,18 +172,25 @@ func main() {
 	log.Println("AuthHandler initialized.")
 	credentialHandler := handlers.NewCredentialsHandler(credentialService)
 	log.Println("CredentialsHandler initialized.")
-	kbHandler := handlers.NewKBHandler(kbService)
+	kbHandler := handlers.NewKBHandler(kbService, kbSyncService)
 	log.Println("KBHandler initialized.")
 	interfaceHandler := handlers.NewInterfaceHandler(interfaceService)
 	log.Println("InterfaceHandler initialized.")
 	chatbotHandler := handlers.NewChatbotHandlers(chatbotService)
 	log.Println("ChatbotHandler initialized.")
-	chatHandler := handlers.NewChatHandlers(chatService)
+	chatHandler := handlers.NewChatHandlers(chatService, cfg.ChatStreamTimeout)
 	log.Println("ChatHandler initialized.")
 
 	// Initialize SlackWebhookHandler
 	slackWebhookHandler := handlers.NewSlackWebhookHandlers(chatService)
 	log.Println("SlackWebhookHandler initialized.")
+	var slackOAuthHandler *handlers.SlackOAuthHandlers
+	if slackInstallService != nil {
+		slackOAuthHandler = handlers.NewSlackOAuthHandlers(slackInstallService, cfg.SlackInstallReturnURL)
+		log.Println("SlackOAuthHandler initialized.")
+	} else {
+		log.Println("SLACK_CLIENT_ID is not set; \"Add to Slack\" is disabled.")
+	}
 	// ... Initialize other handlers here ...
 
 	// 4. Setup Router & Inject Dependencies
@@ -113,6 +202,7 @@ func main() {
 		ChatbotHandler:      chatbotHandler,      // Add Chatbot handler
 		ChatHandler:         chatHandler,         // Add Chat handler
 		SlackWebhookHandler: slackWebhookHandler, // Added SlackWebhookHandler
+		SlackOAuthHandler:   slackOAuthHandler,
 		Config:              cfg,
 	}
 	router := api.NewRouter(routerDeps) // Use the NewRouter function from internal/api
@@ -122,7 +212,9 @@ func main() {
 	server := &http.Server{
 		Addr:    ":" + cfg.HTTPPort,
 		Handler: router,
-		// Production hardening: Set timeouts to avoid Slowloris attacks
+		// Production hardening: Set timeouts to avoid Slowloris attacks.
+		// Streaming routes and requests waiting for a reply extend their own write deadline (see
+		// ChatHandlers.HandleStreamUserMessage and HandleAddUserMessage).
 		ReadTimeout:  5 * time.Second,
 		WriteTimeout: 10 * time.Second,
 		IdleTimeout:  120 * time.Second,
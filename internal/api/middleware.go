@@ -5,79 +5,85 @@ import (
 	"buildmychat-backend/pkg/httputil"
 	"context"
 	"errors"
-	"fmt"
 	"log"
 	"net/http"
 	"strings"
+	"time"
 
+	"github.com/go-chi/chi/v5/middleware"
 	"github.com/golang-jwt/jwt/v5"
-	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
 )
 
+// --- Request Timeout Middleware ---
+
+// streamRouteSuffixes identifies the long-lived chat streams (SSE replies and WebSocket connections),
+// which bound their own duration.
+var streamRouteSuffixes = []string{"/messages/stream", "/ws"}
+
+// TimeoutExceptStreams is middleware.Timeout for every request except the chat streams.
+func TimeoutExceptStreams(timeout time.Duration) func(http.Handler) http.Handler {
+	withTimeout := middleware.Timeout(timeout)
+	return func(next http.Handler) http.Handler {
+		timed := withTimeout(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for _, suffix := range streamRouteSuffixes {
+				if strings.HasSuffix(r.URL.Path, suffix) {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+			timed.ServeHTTP(w, r)
+		})
+	}
+}
+
 // --- JWT Middleware ---
 
 // JwtAuthMiddleware verifies the JWT token from the Authorization header.
+// WebSocket upgrade requests may pass it as the access_token query parameter instead,
+// since browsers cannot set headers on WebSocket connections.
 // If valid, it injects UserID and OrgID into the request context.
 func JwtAuthMiddleware(jwtSecret string) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			authHeader := r.Header.Get("Authorization")
-			if authHeader == "" {
+			var tokenString string
+			switch {
+			case authHeader != "":
+				parts := strings.Split(authHeader, " ")
+				if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
+					log.Printf("Auth Middleware: Malformed Authorization header: %s", authHeader)
+					httputil.RespondError(w, http.StatusUnauthorized, "Malformed Authorization header (Expected: Bearer <token>)")
+					return
+				}
+				tokenString = parts[1]
+			case websocket.IsWebSocketUpgrade(r) && r.URL.Query().Get("access_token") != "":
+				tokenString = r.URL.Query().Get("access_token")
+			default:
 				log.Println("Auth Middleware: Missing Authorization header")
 				httputil.RespondError(w, http.StatusUnauthorized, "Authorization header required")
 				return
 			}
 
-			parts := strings.Split(authHeader, " ")
-			if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
-				log.Printf("Auth Middleware: Malformed Authorization header: %s", authHeader)
-				httputil.RespondError(w, http.StatusUnauthorized, "Malformed Authorization header (Expected: Bearer <token>)")
-				return
-			}
-
-			tokenString := parts[1]
-			claims := &auth.CustomClaims{} // Use CustomClaims from the auth package
-
-			token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-				// Validate the signing algorithm
-				if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-					return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-				}
-				// Return the secret key for validation
-				return []byte(jwtSecret), nil
-			})
-
+			claims, err := auth.ParseAccessToken(tokenString, jwtSecret)
 			if err != nil {
 				log.Printf("Auth Middleware: Error parsing token: %v", err)
 				if errors.Is(err, jwt.ErrTokenExpired) {
 					httputil.RespondError(w, http.StatusUnauthorized, "Token has expired")
 				} else if errors.Is(err, jwt.ErrTokenMalformed) {
 					httputil.RespondError(w, http.StatusUnauthorized, "Malformed token")
+				} else if errors.Is(err, auth.ErrMissingClaims) {
+					httputil.RespondError(w, http.StatusUnauthorized, "Invalid token claims (missing IDs)")
 				} else {
 					httputil.RespondError(w, http.StatusUnauthorized, "Invalid token")
 				}
 				return
 			}
 
-			if !token.Valid {
-				log.Println("Auth Middleware: Token is present but invalid")
-				httputil.RespondError(w, http.StatusUnauthorized, "Invalid token")
-				return
-			}
-
-			// Token is valid, extract custom claims
-			userID := claims.UserID
-			orgID := claims.OrgID
-
-			if userID == uuid.Nil || orgID == uuid.Nil {
-				log.Printf("Auth Middleware: Missing UserID (%s) or OrgID (%s) in valid token claims", userID, orgID)
-				httputil.RespondError(w, http.StatusUnauthorized, "Invalid token claims (missing IDs)")
-				return
-			}
-
 			// Add user info to context
-			ctx := context.WithValue(r.Context(), auth.UserIDKey, userID)
-			ctx = context.WithValue(ctx, auth.OrgIDKey, orgID)
+			ctx := context.WithValue(r.Context(), auth.UserIDKey, claims.UserID)
+			ctx = context.WithValue(ctx, auth.OrgIDKey, claims.OrgID)
 
 			// Call the next handler in the chain with the enriched context
 			next.ServeHTTP(w, r.WithContext(ctx))
@@ -22,6 +22,7 @@ type RouterDependencies struct {
 	ChatbotHandler      *handlers.ChatbotHandlers
 	ChatHandler         *handlers.ChatHandlers
 	SlackWebhookHandler *handlers.SlackWebhookHandlers
+	SlackOAuthHandler   *handlers.SlackOAuthHandlers // nil unless the server's Slack app is configured
 	// OrgHandler        *handlers.OrgHandler
 	// NodeHandler       *handlers.NodeHandler
 	// WebhookHandler    *handlers.WebhookHandler
@@ -34,11 +35,11 @@ func NewRouter(deps RouterDependencies) *chi.Mux {
 	r := chi.NewRouter()
 
 	// --- Base Middleware Stack ---
-	r.Use(middleware.RequestID)                 // Inject request ID into context
-	r.Use(middleware.RealIP)                    // Use X-Forwarded-For or X-Real-IP
-	r.Use(middleware.Logger)                    // Log requests (consider a structured logger)
-	r.Use(middleware.Recoverer)                 // Recover from panics, return 500
-	r.Use(middleware.Timeout(60 * time.Second)) // Set a request timeout
+	r.Use(middleware.RequestID)                   // Inject request ID into context
+	r.Use(middleware.RealIP)                      // Use X-Forwarded-For or X-Real-IP
+	r.Use(middleware.Logger)                      // Log requests (consider a structured logger)
+	r.Use(middleware.Recoverer)                   // Recover from panics, return 500
+	r.Use(TimeoutExceptStreams(60 * time.Second)) // Set a request timeout; chat streams bound themselves
 
 	// --- CORS Configuration ---
 	// Adjust AllowedOrigins for your frontend deployment(s)
@@ -72,10 +73,21 @@ func NewRouter(deps RouterDependencies) *chi.Mux {
 		r.Route("/slack-events", func(r chi.Router) {
 			r.Post("/{chatbotID}", deps.SlackWebhookHandler.HandleSlackEvent)
 		})
+		// Shared by every workspace; events are routed by their team
+		r.Post("/slack/events", deps.SlackWebhookHandler.HandleSharedSlackEvents)
+		// Button clicks (feedback on replies) and slash commands of the server's app, routed the same way
+		r.Post("/slack/interactivity", deps.SlackWebhookHandler.HandleSlackInteractivity)
+		r.Post("/slack/commands", deps.SlackWebhookHandler.HandleSlackCommand)
 	} else {
 		log.Println("WARN: SlackWebhookHandler dependency is nil, skipping /v1/slack-events routes.")
 	}
 
+	// --- Public Slack OAuth Callback ---
+	// Slack redirects the installing user here; the signed state identifies their organization.
+	if deps.SlackOAuthHandler != nil {
+		r.Get("/slack/oauth/callback", deps.SlackOAuthHandler.HandleCallback)
+	}
+
 	// --- Authenticated Routes (JWT Required) ---
 	r.Route("/v1", func(r chi.Router) {
 		// Apply JWT Authentication Middleware
@@ -111,6 +123,13 @@ func NewRouter(deps RouterDependencies) *chi.Mux {
 				r.Get("/{kbID}", deps.KBHandler.HandleGetKnowledgeBase)
 				r.Put("/{kbID}", deps.KBHandler.HandleUpdateKnowledgeBase)
 				r.Delete("/{kbID}", deps.KBHandler.HandleDeleteKnowledgeBase)
+				r.Post("/{kbID}/sync", deps.KBHandler.HandleTriggerKnowledgeBaseSync)
+				r.Get("/{kbID}/sync/status", deps.KBHandler.HandleGetKnowledgeBaseSyncStatus)
+				r.Get("/{kbID}/documents", deps.KBHandler.HandleListKnowledgeBaseDocuments)
+				r.Post("/{kbID}/documents", deps.KBHandler.HandleUploadKnowledgeBaseDocuments) // UPLOAD knowledge bases only
+				r.Put("/{kbID}/documents/{documentID}", deps.KBHandler.HandleReplaceKnowledgeBaseDocument)
+				r.Delete("/{kbID}/documents/{documentID}", deps.KBHandler.HandleDeleteKnowledgeBaseDocument)
+				r.Get("/{kbID}/search", deps.KBHandler.HandleSearchKnowledgeBase)
 			})
 		} else {
 			log.Println("WARN: KBHandler dependency is nil, skipping /v1/knowledge-bases routes.")
@@ -129,6 +148,11 @@ func NewRouter(deps RouterDependencies) *chi.Mux {
 			log.Println("WARN: InterfaceHandler dependency is nil, skipping /v1/interfaces routes.")
 		}
 
+		// --- Mount Slack Installation Routes ---
+		if deps.SlackOAuthHandler != nil {
+			r.Get("/slack/install", deps.SlackOAuthHandler.HandleStartInstall)
+		}
+
 		// --- Mount Chatbot Routes ---
 		if deps.ChatbotHandler != nil {
 			r.Route("/chatbots", func(r chi.Router) {
@@ -143,6 +167,9 @@ func NewRouter(deps RouterDependencies) *chi.Mux {
 				r.Get("/{chatbotID}/mappings", deps.ChatbotHandler.GetChatbotMappings)
 				r.Post("/{chatbotID}/knowledge-bases", deps.ChatbotHandler.AddKnowledgeBase)
 				r.Delete("/{chatbotID}/knowledge-bases/{kbID}", deps.ChatbotHandler.RemoveKnowledgeBase)
+				if deps.KBHandler != nil {
+					r.Get("/{chatbotID}/knowledge-bases/search", deps.KBHandler.HandleSearchChatbotKnowledgeBases)
+				}
 				r.Post("/{chatbotID}/interfaces", deps.ChatbotHandler.AddInterface)
 				r.Delete("/{chatbotID}/interfaces/{interfaceID}", deps.ChatbotHandler.RemoveInterface)
 			})
@@ -160,6 +187,10 @@ func NewRouter(deps RouterDependencies) *chi.Mux {
 				// Message APIs
 				r.Post("/{chatID}/messages/user", deps.ChatHandler.HandleAddUserMessage)
 				r.Post("/{chatID}/messages/assistant", deps.ChatHandler.HandleAddAssistantMessage)
+				r.Post("/{chatID}/messages/stream", deps.ChatHandler.HandleStreamUserMessage) // Server-Sent Events
+				r.Get("/{chatID}/ws", deps.ChatHandler.HandleChatWebSocket)                   // WebSocket; JWT may be passed as ?access_token=
+
+				r.Put("/{chatID}/feedback", deps.ChatHandler.HandleUpdateChatFeedback)
 			})
 		} else {
 			log.Println("WARN: ChatHandler dependency is nil, skipping /v1/chats routes.")
@@ -1,6 +1,8 @@
 package auth
 
 import (
+	"errors"
+	"fmt"
 	"log"
 	"time"
 
@@ -57,4 +59,29 @@ func NewAccessToken(userID uuid.UUID, orgID uuid.UUID, jwtSecret string, expirat
 	return signedToken, nil
 }
 
-// TODO: Add function to ParseAndValidateToken if needed elsewhere besides middleware
+// ErrMissingClaims is returned for a validly signed token that lacks the user or organization ID.
+var ErrMissingClaims = errors.New("token is missing user or organization ID")
+
+// ParseAccessToken verifies a token created by NewAccessToken and returns its claims.
+// Errors wrap the jwt package sentinels (e.g. jwt.ErrTokenExpired) or ErrMissingClaims.
+func ParseAccessToken(tokenString string, jwtSecret string) (*CustomClaims, error) {
+	claims := &CustomClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		// Validate the signing algorithm
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		// Return the secret key for validation
+		return []byte(jwtSecret), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, jwt.ErrTokenInvalidClaims
+	}
+	if claims.UserID == uuid.Nil || claims.OrgID == uuid.Nil {
+		return nil, ErrMissingClaims
+	}
+	return claims, nil
+}
@@ -0,0 +1,97 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// ErrInvalidOAuthState is returned for an OAuth state that was not created by NewOAuthState for the
+// provider, or that expired.
+var ErrInvalidOAuthState = errors.New("invalid or expired OAuth state")
+
+// OAuthStateClaims tie an OAuth authorization to the organization and user that started it, so the
+// public callback knows whom the authorization belongs to, and to the browser it was started in.
+// The registered ID identifies the state, so that it can be used only once.
+type OAuthStateClaims struct {
+	UserID    uuid.UUID  `json:"user_id"`
+	OrgID     uuid.UUID  `json:"org_id"`
+	ChatbotID *uuid.UUID `json:"chatbot_id,omitempty"` // Chatbot to connect the installed app to, if any
+	NonceHash string     `json:"nonce_hash"`           // SHA-256 of the nonce kept by the browser
+	jwt.RegisteredClaims
+}
+
+// oauthStateKey derives the key states are signed with from the JWT secret. A different key keeps
+// states from being accepted as access tokens, which carry the same user and organization claims.
+func oauthStateKey(jwtSecret string) []byte {
+	mac := hmac.New(sha256.New, []byte(jwtSecret))
+	mac.Write([]byte("oauth-state"))
+	return mac.Sum(nil)
+}
+
+// hashOAuthNonce returns the hash of a nonce that is put in a state. The state is visible in URLs,
+// the nonce is not.
+func hashOAuthNonce(nonce string) string {
+	sum := sha256.Sum256([]byte(nonce))
+	return hex.EncodeToString(sum[:])
+}
+
+// NewOAuthNonce returns a random nonce for NewOAuthState, to be kept by the browser that starts the
+// authorization (e.g. in an HttpOnly cookie) and passed to ParseOAuthState by the callback.
+func NewOAuthNonce() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate OAuth nonce: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// NewOAuthState creates the state parameter of an authorization request to provider (e.g. "slack"),
+// bound to nonce. It expires after ttl.
+func NewOAuthState(provider string, userID, orgID uuid.UUID, chatbotID *uuid.UUID, nonce string, jwtSecret string, ttl time.Duration) (string, error) {
+	if nonce == "" {
+		return "", errors.New("OAuth state requires a nonce")
+	}
+	now := time.Now()
+	claims := OAuthStateClaims{
+		UserID:    userID,
+		OrgID:     orgID,
+		ChatbotID: chatbotID,
+		NonceHash: hashOAuthNonce(nonce),
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			Issuer:    "buildmychat-backend",
+			Audience:  jwt.ClaimStrings{"oauth:" + provider},
+			ID:        uuid.NewString(),
+		},
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(oauthStateKey(jwtSecret))
+}
+
+// ParseOAuthState verifies a state created by NewOAuthState for provider and nonce, and returns its
+// claims. Callers must still make sure the state is used only once, by its ID.
+func ParseOAuthState(state string, provider string, nonce string, jwtSecret string) (*OAuthStateClaims, error) {
+	claims := &OAuthStateClaims{}
+	_, err := jwt.ParseWithClaims(state, claims, func(token *jwt.Token) (interface{}, error) {
+		return oauthStateKey(jwtSecret), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithAudience("oauth:"+provider), jwt.WithExpirationRequired())
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidOAuthState, err)
+	}
+	if claims.UserID == uuid.Nil || claims.OrgID == uuid.Nil || claims.ID == "" {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidOAuthState, ErrMissingClaims)
+	}
+	if nonce == "" || !hmac.Equal([]byte(claims.NonceHash), []byte(hashOAuthNonce(nonce))) {
+		return nil, fmt.Errorf("%w: state was not started by this browser", ErrInvalidOAuthState)
+	}
+	return claims, nil
+}
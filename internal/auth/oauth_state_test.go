@@ -0,0 +1,65 @@
+package auth
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestOAuthState(t *testing.T) {
+	userID, orgID, chatbotID := uuid.New(), uuid.New(), uuid.New()
+
+	nonce, err := NewOAuthNonce()
+	if err != nil {
+		t.Fatal(err)
+	}
+	state, err := NewOAuthState("slack", userID, orgID, &chatbotID, nonce, "secret", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(state, nonce) {
+		t.Error("state contains the nonce")
+	}
+	claims, err := ParseOAuthState(state, "slack", nonce, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.UserID != userID || claims.OrgID != orgID || claims.ChatbotID == nil || *claims.ChatbotID != chatbotID || claims.ID == "" {
+		t.Errorf("claims = %+v", claims)
+	}
+
+	if _, err := ParseOAuthState(state, "notion", nonce, "secret"); !errors.Is(err, ErrInvalidOAuthState) {
+		t.Errorf("state for another provider: %v", err)
+	}
+	if _, err := ParseOAuthState(state, "slack", nonce, "other"); !errors.Is(err, ErrInvalidOAuthState) {
+		t.Errorf("state signed with another secret: %v", err)
+	}
+	otherNonce, _ := NewOAuthNonce()
+	for _, n := range []string{otherNonce, ""} {
+		if _, err := ParseOAuthState(state, "slack", n, "secret"); !errors.Is(err, ErrInvalidOAuthState) {
+			t.Errorf("state with nonce %q: %v", n, err)
+		}
+	}
+	if _, err := ParseAccessToken(state, "secret"); err == nil {
+		t.Error("state was accepted as an access token")
+	}
+
+	accessToken, err := NewAccessToken(userID, orgID, "secret", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseOAuthState(accessToken, "slack", nonce, "secret"); !errors.Is(err, ErrInvalidOAuthState) {
+		t.Errorf("access token was accepted as a state: %v", err)
+	}
+
+	expired, err := NewOAuthState("slack", userID, orgID, nil, nonce, "secret", -time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseOAuthState(expired, "slack", nonce, "secret"); !errors.Is(err, ErrInvalidOAuthState) {
+		t.Errorf("expired state: %v", err)
+	}
+}
@@ -0,0 +1,283 @@
+// Package chunking splits knowledge base documents into overlapping, retrieval-sized chunks.
+package chunking
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Defaults used when a knowledge base does not configure chunking.
+const (
+	DefaultChunkSize    = 400 // Tokens
+	DefaultChunkOverlap = 60  // Tokens
+	minChunkSize        = 50
+)
+
+// Options controls chunk sizes, in (estimated) tokens.
+type Options struct {
+	ChunkSize    int // Target maximum chunk length; <= 0 uses DefaultChunkSize
+	ChunkOverlap int // Tokens repeated from the end of the previous chunk of the same section; < 0 disables overlap
+}
+
+// Chunk is a piece of a document.
+type Chunk struct {
+	Index   int    // Position in the document, starting at 0
+	Heading string // Markdown heading path of the section, e.g. "Billing > Refunds"; "" before the first heading
+	Content string
+	Tokens  int // Estimated token count of Content
+}
+
+// EstimateTokens approximates the number of model tokens in text (about four characters per token
+// for English). It is deliberately tokenizer-independent so chunking does not depend on the embedding model.
+func EstimateTokens(text string) int {
+	return (utf8.RuneCountInString(text) + 3) / 4
+}
+
+// Split divides a Markdown or plain text document into chunks. Sections start at Markdown headings;
+// within a section, paragraphs are packed together up to the chunk size, and paragraphs that are too
+// long on their own are split by sentence and then by word. Code fences are never split on blank lines.
+func Split(text string, opts Options) []Chunk {
+	opts = opts.normalized()
+
+	var chunks []Chunk
+	for _, sec := range splitSections(text) {
+		for _, content := range packSection(sec.blocks, opts) {
+			chunks = append(chunks, Chunk{
+				Index:   len(chunks),
+				Heading: sec.heading,
+				Content: content,
+				Tokens:  EstimateTokens(content),
+			})
+		}
+	}
+	return chunks
+}
+
+func (o Options) normalized() Options {
+	if o.ChunkSize <= 0 {
+		o.ChunkSize = DefaultChunkSize
+	}
+	if o.ChunkSize < minChunkSize {
+		o.ChunkSize = minChunkSize
+	}
+	if o.ChunkOverlap < 0 {
+		o.ChunkOverlap = 0
+	}
+	if o.ChunkOverlap > o.ChunkSize/2 {
+		o.ChunkOverlap = o.ChunkSize / 2 // Keep chunks mostly new content
+	}
+	return o
+}
+
+type section struct {
+	heading string
+	blocks  []string // Paragraphs, list runs, tables or code fences
+}
+
+// splitSections groups the document's blocks under their heading path.
+func splitSections(text string) []section {
+	var (
+		sections []section
+		headings []string // Heading text per level (index 0 = level 1)
+		current  section
+		block    []string
+		inFence  bool
+	)
+
+	flushBlock := func() {
+		if b := strings.TrimSpace(strings.Join(block, "\n")); b != "" {
+			current.blocks = append(current.blocks, b)
+		}
+		block = block[:0]
+	}
+	flushSection := func() {
+		flushBlock()
+		if len(current.blocks) > 0 {
+			sections = append(sections, current)
+		}
+	}
+
+	for _, line := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") {
+			inFence = !inFence
+			block = append(block, line)
+			continue
+		}
+		if inFence {
+			block = append(block, line)
+			continue
+		}
+
+		if level, title := parseHeading(trimmed); level > 0 {
+			flushSection()
+			if level <= len(headings) {
+				headings = headings[:level-1]
+			}
+			for len(headings) < level-1 {
+				headings = append(headings, "") // Skipped levels, e.g. "#" followed by "###"
+			}
+			headings = append(headings, title)
+			current = section{heading: joinHeadings(headings)}
+			continue
+		}
+
+		if trimmed == "" {
+			flushBlock()
+			continue
+		}
+		block = append(block, line)
+	}
+	flushSection()
+	return sections
+}
+
+// parseHeading returns the level and text of an ATX Markdown heading ("## Title"), or 0.
+func parseHeading(line string) (int, string) {
+	level := 0
+	for level < len(line) && line[level] == '#' {
+		level++
+	}
+	if level == 0 || level > 6 || level >= len(line) || line[level] != ' ' {
+		return 0, ""
+	}
+	return level, strings.TrimSpace(line[level:])
+}
+
+func joinHeadings(headings []string) string {
+	parts := make([]string, 0, len(headings))
+	for _, h := range headings {
+		if h != "" {
+			parts = append(parts, h)
+		}
+	}
+	return strings.Join(parts, " > ")
+}
+
+// packSection greedily packs a section's blocks into chunks of at most opts.ChunkSize tokens.
+// Each chunk after the first starts with the tail of the previous one (opts.ChunkOverlap tokens).
+func packSection(blocks []string, opts Options) []string {
+	var pieces []string
+	for _, b := range blocks {
+		if EstimateTokens(b) <= opts.ChunkSize {
+			pieces = append(pieces, b)
+			continue
+		}
+		pieces = append(pieces, splitLong(b, opts.ChunkSize)...)
+	}
+
+	var (
+		chunks  []string
+		current []string
+		size    int
+	)
+	for _, piece := range pieces {
+		pieceTokens := EstimateTokens(piece)
+		if len(current) > 0 && size+pieceTokens > opts.ChunkSize {
+			chunk := strings.Join(current, "\n\n")
+			chunks = append(chunks, chunk)
+			current, size = nil, 0
+			if tail := overlapTail(chunk, opts.ChunkOverlap); tail != "" && EstimateTokens(tail)+pieceTokens <= opts.ChunkSize {
+				current, size = []string{tail}, EstimateTokens(tail)
+			}
+		}
+		current = append(current, piece)
+		size += pieceTokens
+	}
+	if len(current) > 0 {
+		chunks = append(chunks, strings.Join(current, "\n\n"))
+	}
+	return chunks
+}
+
+// splitLong splits a block larger than maxTokens into sentence groups, falling back to
+// word windows for sentences that are still too long.
+func splitLong(block string, maxTokens int) []string {
+	var (
+		pieces  []string
+		current strings.Builder
+	)
+	flush := func() {
+		if s := strings.TrimSpace(current.String()); s != "" {
+			pieces = append(pieces, s)
+		}
+		current.Reset()
+	}
+
+	for _, sentence := range splitSentences(block) {
+		if EstimateTokens(sentence) > maxTokens {
+			flush()
+			pieces = append(pieces, splitWords(sentence, maxTokens)...)
+			continue
+		}
+		if EstimateTokens(current.String()+" "+sentence) > maxTokens {
+			flush()
+		}
+		if current.Len() > 0 {
+			current.WriteString(" ")
+		}
+		current.WriteString(sentence)
+	}
+	flush()
+	return pieces
+}
+
+// splitSentences splits text after '.', '!' or '?' followed by whitespace, and at line breaks.
+func splitSentences(text string) []string {
+	var sentences []string
+	start := 0
+	runes := []rune(text)
+	for i, r := range runes {
+		end := false
+		switch {
+		case r == '\n':
+			end = true
+		case r == '.' || r == '!' || r == '?':
+			end = i+1 < len(runes) && unicode.IsSpace(runes[i+1])
+		}
+		if end {
+			if s := strings.TrimSpace(string(runes[start : i+1])); s != "" {
+				sentences = append(sentences, s)
+			}
+			start = i + 1
+		}
+	}
+	if s := strings.TrimSpace(string(runes[start:])); s != "" {
+		sentences = append(sentences, s)
+	}
+	return sentences
+}
+
+// splitWords cuts text into consecutive word windows of at most maxTokens.
+// A single word longer than maxTokens becomes its own window.
+func splitWords(text string, maxTokens int) []string {
+	var (
+		windows []string
+		current []string
+	)
+	for _, word := range strings.Fields(text) {
+		if len(current) > 0 && EstimateTokens(strings.Join(append(current, word), " ")) > maxTokens {
+			windows = append(windows, strings.Join(current, " "))
+			current = nil
+		}
+		current = append(current, word)
+	}
+	if len(current) > 0 {
+		windows = append(windows, strings.Join(current, " "))
+	}
+	return windows
+}
+
+// overlapTail returns the trailing whole words of text adding up to at most tokens.
+func overlapTail(text string, tokens int) string {
+	if tokens <= 0 {
+		return ""
+	}
+	words := strings.Fields(text)
+	start := len(words)
+	for start > 0 && EstimateTokens(strings.Join(words[start-1:], " ")) <= tokens {
+		start--
+	}
+	return strings.Join(words[start:], " ")
+}
@@ -0,0 +1,68 @@
+package chunking
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSplitSectionsByHeading(t *testing.T) {
+	doc := "Intro line.\n\n" +
+		"# Billing\n\nWe bill monthly.\n\n" +
+		"## Refunds\n\nRefunds take 5 days.\n\n" +
+		"```\n# not a heading\n\nstill code\n```\n\n" +
+		"# Support\n\nEmail us."
+
+	chunks := Split(doc, Options{})
+	want := []struct{ heading, content string }{
+		{"", "Intro line."},
+		{"Billing", "We bill monthly."},
+		{"Billing > Refunds", "Refunds take 5 days.\n\n```\n# not a heading\n\nstill code\n```"},
+		{"Support", "Email us."},
+	}
+	if len(chunks) != len(want) {
+		t.Fatalf("got %d chunks, want %d: %+v", len(chunks), len(want), chunks)
+	}
+	for i, w := range want {
+		if chunks[i].Index != i || chunks[i].Heading != w.heading || chunks[i].Content != w.content {
+			t.Errorf("chunk %d = %+v, want heading %q content %q", i, chunks[i], w.heading, w.content)
+		}
+	}
+}
+
+func TestSplitRespectsSizeAndOverlap(t *testing.T) {
+	var paragraphs []string
+	for i := 0; i < 30; i++ {
+		paragraphs = append(paragraphs, strings.Repeat("word ", 40)+"end.") // ~50 tokens each
+	}
+	opts := Options{ChunkSize: 120, ChunkOverlap: 20}
+	chunks := Split(strings.Join(paragraphs, "\n\n"), opts)
+
+	if len(chunks) < 10 {
+		t.Fatalf("expected the document to be split into many chunks, got %d", len(chunks))
+	}
+	for i, c := range chunks {
+		if c.Tokens > opts.ChunkSize {
+			t.Errorf("chunk %d has %d tokens, more than %d", i, c.Tokens, opts.ChunkSize)
+		}
+		if i > 0 && !strings.HasPrefix(c.Content, "word") {
+			t.Errorf("chunk %d does not start with the overlap from the previous chunk: %q", i, c.Content[:20])
+		}
+	}
+}
+
+func TestSplitLongParagraph(t *testing.T) {
+	long := strings.Repeat("This is a sentence that keeps going. ", 100)
+	chunks := Split(long, Options{ChunkSize: 60, ChunkOverlap: -1})
+
+	if len(chunks) < 2 {
+		t.Fatalf("expected a long paragraph to be split, got %d chunk(s)", len(chunks))
+	}
+	for i, c := range chunks {
+		if c.Tokens > 60 {
+			t.Errorf("chunk %d has %d tokens, more than 60", i, c.Tokens)
+		}
+		if !strings.HasSuffix(c.Content, ".") {
+			t.Errorf("chunk %d was not split on a sentence boundary: %q", i, c.Content)
+		}
+	}
+}
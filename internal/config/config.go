@@ -4,7 +4,9 @@ import (
 	"encoding/hex"
 	"log"
 	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
@@ -17,7 +19,40 @@ type Config struct {
 	HTTPPort        string
 	TokenExpiration time.Duration
 	EncryptionKey   []byte // Raw key bytes (32 for AES-256)
-	// Add other config fields like OpenAIKey, SlackToken, NotionKey, etc.
+
+	// LLM provider settings
+	LLMProvider     string // Default provider for chatbots without an explicit "<provider>:" prefix
+	LLMDefaultModel string // Model used when a chatbot has no llm_model set
+	OpenAIAPIKey    string
+	OpenAIBaseURL   string // Any OpenAI-compatible endpoint, e.g. https://api.openai.com/v1
+
+	ChatStreamTimeout  time.Duration // Maximum lifetime of a streamed (SSE) reply
+	RealtimeBroker     string        // "memory" (single instance) or "postgres" (LISTEN/NOTIFY across replicas)
+	KBSyncPollInterval time.Duration // How often scheduled knowledge base syncs are checked; 0 disables the scheduler
+	CrawlerUserAgent   string        // User-Agent of the website knowledge base crawler; its product token is matched against robots.txt
+	GitCacheDir        string        // Where Git knowledge bases keep their repository clones between syncs
+
+	// Embedding settings for knowledge base indexing
+	EmbeddingProvider   string // "openai" (any OpenAI-compatible /embeddings API) or "hashing" (offline, for tests and local development)
+	EmbeddingModel      string
+	EmbeddingDimensions int
+	EmbeddingBaseURL    string // Defaults to OpenAIBaseURL; uses OpenAIAPIKey
+
+	// Reranker used by chatbots with retrieval.rerank enabled
+	RerankerProvider string // "" (disabled), "lexical" (local BM25) or "http" (cross-encoder /rerank API)
+	RerankerBaseURL  string
+	RerankerModel    string
+	RerankerAPIKey   string
+
+	// Slack app that organizations install with "Add to Slack"; the flow is disabled without a client ID
+	SlackClientID         string
+	SlackClientSecret     string
+	SlackSigningSecret    string
+	SlackOAuthRedirectURL string   // Public URL of the OAuth callback, e.g. https://api.example.com/slack/oauth/callback
+	SlackInstallReturnURL string   // Frontend page the callback redirects to; the result is shown as JSON if empty
+	SlackBotScopes        []string // Defaults to the scopes channel replies need
+	SlackBaseURL          string   // https://slack.com, or a fake Slack server in development
+	// Add other config fields like NotionKey, etc.
 }
 
 // LoadConfig loads configuration from environment variables.
@@ -58,15 +93,78 @@ func LoadConfig() (*Config, error) {
 		log.Fatalf("FATAL: ENCRYPTION_KEY must be 32 bytes (64 hex characters) long, got %d bytes", len(encryptionKeyBytes))
 	}
 
+	// LLM settings. The API key is optional so local setups can run against the fake provider.
+	openAIAPIKey := os.Getenv("OPENAI_API_KEY")
+	if openAIAPIKey == "" {
+		log.Println("Warning: OPENAI_API_KEY is not set. Calls to the openai provider will fail.")
+	}
+
+	streamTimeoutStr := getEnv("CHAT_STREAM_TIMEOUT_SECONDS", "300") // Default 5 minutes
+	streamTimeoutSecs, err := strconv.Atoi(streamTimeoutStr)
+	if err != nil || streamTimeoutSecs <= 0 {
+		log.Printf("Warning: Invalid CHAT_STREAM_TIMEOUT_SECONDS '%s', using default 300s. Error: %v", streamTimeoutStr, err)
+		streamTimeoutSecs = 300
+	}
+
+	syncPollStr := getEnv("KB_SYNC_POLL_INTERVAL_SECONDS", "60")
+	syncPollSecs, err := strconv.Atoi(syncPollStr)
+	if err != nil || syncPollSecs < 0 {
+		log.Printf("Warning: Invalid KB_SYNC_POLL_INTERVAL_SECONDS '%s', using default 60s. Error: %v", syncPollStr, err)
+		syncPollSecs = 60
+	}
+
+	embeddingDimsStr := getEnv("EMBEDDING_DIMENSIONS", "1536")
+	embeddingDims, err := strconv.Atoi(embeddingDimsStr)
+	if err != nil || embeddingDims <= 0 {
+		log.Printf("Warning: Invalid EMBEDDING_DIMENSIONS '%s', using default 1536. Error: %v", embeddingDimsStr, err)
+		embeddingDims = 1536
+	}
+	openAIBaseURL := getEnv("OPENAI_BASE_URL", "https://api.openai.com/v1")
+
+	var slackBotScopes []string
+	for _, scope := range strings.Split(os.Getenv("SLACK_BOT_SCOPES"), ",") {
+		if scope = strings.TrimSpace(scope); scope != "" {
+			slackBotScopes = append(slackBotScopes, scope)
+		}
+	}
+
 	cfg := &Config{
 		HTTPPort:        port,
 		JWTSecret:       jwtSecret,
 		DatabaseURL:     dbURL,
 		TokenExpiration: time.Hour * time.Duration(tokenExpHours),
 		EncryptionKey:   encryptionKeyBytes,
+		LLMProvider:     getEnv("LLM_PROVIDER", "openai"),
+		LLMDefaultModel: getEnv("LLM_DEFAULT_MODEL", "gpt-4o-mini"),
+		OpenAIAPIKey:    openAIAPIKey,
+		OpenAIBaseURL:   openAIBaseURL,
+
+		ChatStreamTimeout:  time.Duration(streamTimeoutSecs) * time.Second,
+		RealtimeBroker:     getEnv("REALTIME_BROKER", "memory"),
+		KBSyncPollInterval: time.Duration(syncPollSecs) * time.Second,
+		CrawlerUserAgent:   getEnv("CRAWLER_USER_AGENT", ""),
+		GitCacheDir:        getEnv("GIT_CACHE_DIR", filepath.Join(os.TempDir(), "buildmychat-git")),
+
+		EmbeddingProvider:   getEnv("EMBEDDING_PROVIDER", "openai"),
+		EmbeddingModel:      getEnv("EMBEDDING_MODEL", "text-embedding-3-small"),
+		EmbeddingDimensions: embeddingDims,
+		EmbeddingBaseURL:    getEnv("EMBEDDING_BASE_URL", openAIBaseURL),
+
+		RerankerProvider: getEnv("RERANKER_PROVIDER", ""),
+		RerankerBaseURL:  getEnv("RERANKER_BASE_URL", ""),
+		RerankerModel:    getEnv("RERANKER_MODEL", ""),
+		RerankerAPIKey:   os.Getenv("RERANKER_API_KEY"),
+
+		SlackClientID:         os.Getenv("SLACK_CLIENT_ID"),
+		SlackClientSecret:     os.Getenv("SLACK_CLIENT_SECRET"),
+		SlackSigningSecret:    os.Getenv("SLACK_SIGNING_SECRET"),
+		SlackOAuthRedirectURL: os.Getenv("SLACK_OAUTH_REDIRECT_URL"),
+		SlackInstallReturnURL: os.Getenv("SLACK_INSTALL_RETURN_URL"),
+		SlackBotScopes:        slackBotScopes,
+		SlackBaseURL:          getEnv("SLACK_BASE_URL", "https://slack.com"),
 	}
 
-	log.Printf("Loaded config: Port=%s, DB_URL=***, TokenExp=%s, EncryptionKey=***", cfg.HTTPPort, cfg.TokenExpiration)
+	log.Printf("Loaded config: Port=%s, DB_URL=***, TokenExp=%s, EncryptionKey=***, LLMProvider=%s, LLMDefaultModel=%s, RealtimeBroker=%s, EmbeddingProvider=%s, EmbeddingModel=%s, RerankerProvider=%s, SlackInstall=%t", cfg.HTTPPort, cfg.TokenExpiration, cfg.LLMProvider, cfg.LLMDefaultModel, cfg.RealtimeBroker, cfg.EmbeddingProvider, cfg.EmbeddingModel, cfg.RerankerProvider, cfg.SlackClientID != "")
 
 	return cfg, nil
 }
@@ -0,0 +1,23 @@
+// Package embedding turns text into vectors for semantic retrieval.
+package embedding
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrEmptyEmbedding is returned when a provider answers without one vector per input.
+var ErrEmptyEmbedding = errors.New("embedding provider returned no embeddings")
+
+// Embedder is implemented by every embedding backend (OpenAI-compatible HTTP APIs, the offline hashing embedder, ...).
+// Vectors from different models are not comparable, so stored embeddings record Model().
+type Embedder interface {
+	// Model identifies the embedding model, e.g. "text-embedding-3-small".
+	Model() string
+
+	// Dimensions returns the length of every vector produced.
+	Dimensions() int
+
+	// Embed returns one vector per input text, in order.
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+}
@@ -0,0 +1,97 @@
+package embedding
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOpenAIEmbedderBatchesAndOrders(t *testing.T) {
+	var requests []openAIEmbeddingRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/embeddings" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		var req openAIEmbeddingRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("decode request: %v", err)
+		}
+		requests = append(requests, req)
+
+		// Answer in reverse order; the embedder must sort by index.
+		var resp openAIEmbeddingResponse
+		for i := len(req.Input) - 1; i >= 0; i-- {
+			resp.Data = append(resp.Data, struct {
+				Index     int       `json:"index"`
+				Embedding []float32 `json:"embedding"`
+			}{Index: i, Embedding: []float32{float32(len(req.Input[i])), 0}})
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+
+	texts := make([]string, openAIBatchSize+1)
+	for i := range texts {
+		texts[i] = string(make([]byte, i%5))
+	}
+
+	embedder := NewOpenAIEmbedder(server.URL, "sk-test", "text-embedding-3-small", 2, server.Client())
+	vectors, err := embedder.Embed(context.Background(), texts)
+	if err != nil {
+		t.Fatalf("Embed returned error: %v", err)
+	}
+
+	if len(requests) != 2 || len(requests[0].Input) != openAIBatchSize || len(requests[1].Input) != 1 {
+		t.Errorf("inputs were not batched by %d: %d requests", openAIBatchSize, len(requests))
+	}
+	if requests[0].Dimensions != 2 {
+		t.Errorf("dimensions not forwarded for a v3 model: %+v", requests[0])
+	}
+	for i, v := range vectors {
+		if int(v[0]) != i%5 {
+			t.Fatalf("vector %d out of order: %v", i, v)
+		}
+	}
+}
+
+func TestOpenAIEmbedderDimensionMismatch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data":[{"index":0,"embedding":[0.1,0.2,0.3]}]}`))
+	}))
+	defer server.Close()
+
+	embedder := NewOpenAIEmbedder(server.URL, "", "custom-model", 2, server.Client())
+	if _, err := embedder.Embed(context.Background(), []string{"hello"}); err == nil {
+		t.Fatal("expected an error for a vector of the wrong size")
+	}
+}
+
+func TestHashingEmbedderSimilarity(t *testing.T) {
+	embedder := NewHashingEmbedder(256)
+	vectors, err := embedder.Embed(context.Background(), []string{
+		"How do I reset my password?",
+		"Resetting your password: open settings and click reset password.",
+		"Our office is closed on public holidays.",
+	})
+	if err != nil {
+		t.Fatalf("Embed returned error: %v", err)
+	}
+
+	again, _ := embedder.Embed(context.Background(), []string{"How do I reset my password?"})
+	if dot(vectors[0], again[0]) < 0.999 {
+		t.Error("hashing embedder is not deterministic")
+	}
+	if related, unrelated := dot(vectors[0], vectors[1]), dot(vectors[0], vectors[2]); related <= unrelated {
+		t.Errorf("expected related texts to be closer: related=%f unrelated=%f", related, unrelated)
+	}
+}
+
+func dot(a, b []float32) float32 {
+	var sum float32
+	for i := range a {
+		sum += a[i] * b[i]
+	}
+	return sum
+}
@@ -0,0 +1,88 @@
+package embedding
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"strings"
+	"unicode"
+)
+
+// Ensure HashingEmbedder implements the Embedder interface.
+var _ Embedder = (*HashingEmbedder)(nil)
+
+// HashingEmbedder is a deterministic, offline embedder for tests and local development.
+// It hashes lowercase words and word bigrams into a fixed number of signed buckets (the
+// "hashing trick") and L2-normalizes the result, so texts sharing words have a positive
+// cosine similarity. It captures no meaning beyond word overlap.
+type HashingEmbedder struct {
+	dimensions int
+}
+
+// NewHashingEmbedder creates a HashingEmbedder producing vectors of the given length.
+func NewHashingEmbedder(dimensions int) *HashingEmbedder {
+	if dimensions <= 0 {
+		dimensions = 256
+	}
+	return &HashingEmbedder{dimensions: dimensions}
+}
+
+// Model returns "hashing-<dimensions>".
+func (e *HashingEmbedder) Model() string {
+	return fmt.Sprintf("hashing-%d", e.dimensions)
+}
+
+// Dimensions returns the vector length.
+func (e *HashingEmbedder) Dimensions() int {
+	return e.dimensions
+}
+
+// Embed returns one normalized vector per text. Empty texts get a zero vector.
+func (e *HashingEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors[i] = e.embed(text)
+	}
+	return vectors, nil
+}
+
+func (e *HashingEmbedder) embed(text string) []float32 {
+	vector := make([]float32, e.dimensions)
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+
+	add := func(feature string, weight float32) {
+		h := fnv.New64a()
+		h.Write([]byte(feature))
+		sum := h.Sum64()
+		bucket := int(sum % uint64(e.dimensions))
+		if sum&(1<<63) != 0 {
+			weight = -weight // Signed hashing keeps collisions from only ever adding up
+		}
+		vector[bucket] += weight
+	}
+	for i, word := range words {
+		add(word, 1)
+		if i > 0 {
+			add(words[i-1]+" "+word, 0.5)
+		}
+	}
+
+	var norm float64
+	for _, v := range vector {
+		norm += float64(v) * float64(v)
+	}
+	if norm > 0 {
+		scale := float32(1 / math.Sqrt(norm))
+		for i := range vector {
+			vector[i] *= scale
+		}
+	}
+	return vector
+}
@@ -0,0 +1,152 @@
+package embedding
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultOpenAIBaseURL is used when no base URL is configured.
+const DefaultOpenAIBaseURL = "https://api.openai.com/v1"
+
+// openAIBatchSize is the number of inputs sent per /embeddings request.
+const openAIBatchSize = 64
+
+// Ensure OpenAIEmbedder implements the Embedder interface.
+var _ Embedder = (*OpenAIEmbedder)(nil)
+
+// OpenAIEmbedder talks to any API implementing OpenAI's /embeddings endpoint.
+type OpenAIEmbedder struct {
+	baseURL    string
+	apiKey     string
+	model      string
+	dimensions int
+	httpClient *http.Client
+}
+
+// NewOpenAIEmbedder creates an embedder for an OpenAI-compatible API.
+// dimensions must match the model's output (or a reduced size the model supports, such as
+// the text-embedding-3 family). An empty baseURL falls back to DefaultOpenAIBaseURL;
+// a nil httpClient gets a client with a sane timeout.
+func NewOpenAIEmbedder(baseURL, apiKey, model string, dimensions int, httpClient *http.Client) *OpenAIEmbedder {
+	if baseURL == "" {
+		baseURL = DefaultOpenAIBaseURL
+	}
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 60 * time.Second}
+	}
+	return &OpenAIEmbedder{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		apiKey:     apiKey,
+		model:      model,
+		dimensions: dimensions,
+		httpClient: httpClient,
+	}
+}
+
+// Model returns the configured model name.
+func (e *OpenAIEmbedder) Model() string {
+	return e.model
+}
+
+// Dimensions returns the configured vector length.
+func (e *OpenAIEmbedder) Dimensions() int {
+	return e.dimensions
+}
+
+// --- Wire types for the OpenAI embeddings API ---
+
+type openAIEmbeddingRequest struct {
+	Model      string   `json:"model"`
+	Input      []string `json:"input"`
+	Dimensions int      `json:"dimensions,omitempty"`
+}
+
+type openAIEmbeddingResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+}
+
+type openAIErrorResponse struct {
+	Error struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// Embed calls POST {baseURL}/embeddings, batching inputs.
+func (e *OpenAIEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += openAIBatchSize {
+		end := start + openAIBatchSize
+		if end > len(texts) {
+			end = len(texts)
+		}
+		batch, err := e.embedBatch(ctx, texts[start:end])
+		if err != nil {
+			return nil, err
+		}
+		vectors = append(vectors, batch...)
+	}
+	return vectors, nil
+}
+
+func (e *OpenAIEmbedder) embedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	body := openAIEmbeddingRequest{Model: e.model, Input: texts}
+	if strings.HasPrefix(e.model, "text-embedding-3") {
+		body.Dimensions = e.dimensions // Only the v3 models accept a custom size
+	}
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal embeddings request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, e.baseURL+"/embeddings", bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create embeddings request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if e.apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+e.apiKey)
+	}
+
+	resp, err := e.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("embeddings request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+		var errResp openAIErrorResponse
+		if err := json.Unmarshal(respBody, &errResp); err == nil && errResp.Error.Message != "" {
+			return nil, fmt.Errorf("embeddings API error (status %d): %s", resp.StatusCode, errResp.Error.Message)
+		}
+		return nil, fmt.Errorf("embeddings API error (status %d): %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+
+	var decoded openAIEmbeddingResponse
+	if err := json.NewDecoder(resp.Body).Decode(&decoded); err != nil {
+		return nil, fmt.Errorf("failed to decode embeddings response: %w", err)
+	}
+	if len(decoded.Data) != len(texts) {
+		return nil, fmt.Errorf("%w: got %d vectors for %d inputs", ErrEmptyEmbedding, len(decoded.Data), len(texts))
+	}
+
+	sort.Slice(decoded.Data, func(i, j int) bool { return decoded.Data[i].Index < decoded.Data[j].Index })
+	vectors := make([][]float32, len(decoded.Data))
+	for i, d := range decoded.Data {
+		if len(d.Embedding) != e.dimensions {
+			return nil, fmt.Errorf("embedding model %s returned %d dimensions, expected %d", e.model, len(d.Embedding), e.dimensions)
+		}
+		vectors[i] = d.Embedding
+	}
+	return vectors, nil
+}
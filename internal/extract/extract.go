@@ -0,0 +1,105 @@
+// Package extract turns uploaded files (Markdown, plain text, HTML, PDF) into text documents
+// that can be chunked and indexed.
+package extract
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrUnsupportedFormat is returned for file types that cannot be extracted.
+var ErrUnsupportedFormat = errors.New("unsupported file format (expected .md, .txt, .html or .pdf)")
+
+// ErrNoText is returned when a file contains no extractable text, e.g. a scanned PDF.
+var ErrNoText = errors.New("file contains no extractable text")
+
+// Formats recognized by FromFile.
+const (
+	FormatMarkdown = "markdown"
+	FormatText     = "text"
+	FormatHTML     = "html"
+	FormatPDF      = "pdf"
+)
+
+// Document is the text extracted from a file. Content is Markdown for Markdown and HTML files,
+// and plain text otherwise.
+type Document struct {
+	Title   string
+	Content string
+	Format  string
+}
+
+// FormatForFilename returns the format of a file from its extension, or "" if it is not supported.
+func FormatForFilename(filename string) string {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".md", ".markdown":
+		return FormatMarkdown
+	case ".txt", ".text":
+		return FormatText
+	case ".html", ".htm":
+		return FormatHTML
+	case ".pdf":
+		return FormatPDF
+	default:
+		return ""
+	}
+}
+
+// FromFile extracts the text of a file, choosing the extractor by the file's extension.
+// The title is taken from the document (Markdown heading, HTML <title>) when it has one,
+// and from the file name otherwise.
+func FromFile(filename string, data []byte) (*Document, error) {
+	format := FormatForFilename(filename)
+
+	var (
+		doc *Document
+		err error
+	)
+	switch format {
+	case FormatMarkdown, FormatText:
+		if !utf8.Valid(data) {
+			return nil, fmt.Errorf("%s is not valid UTF-8 text", filename)
+		}
+		doc = &Document{Content: normalizeNewlines(string(data))}
+		if format == FormatMarkdown {
+			doc.Title = markdownTitle(doc.Content)
+		}
+	case FormatHTML:
+		doc, err = HTML(data)
+	case FormatPDF:
+		doc, err = PDF(data)
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	doc.Format = format
+	doc.Content = strings.TrimSpace(doc.Content)
+	if doc.Content == "" {
+		return nil, ErrNoText
+	}
+	if doc.Title == "" {
+		doc.Title = strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename))
+	}
+	return doc, nil
+}
+
+// markdownTitle returns the text of the first level-one heading, or "".
+func markdownTitle(content string) string {
+	for _, line := range strings.Split(content, "\n") {
+		if title, ok := strings.CutPrefix(strings.TrimSpace(line), "# "); ok {
+			return strings.TrimSpace(title)
+		}
+	}
+	return ""
+}
+
+func normalizeNewlines(s string) string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	return strings.ReplaceAll(s, "\r", "\n")
+}
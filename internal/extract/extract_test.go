@@ -0,0 +1,111 @@
+package extract
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestFromFileMarkdownAndText(t *testing.T) {
+	doc, err := FromFile("guide.md", []byte("Intro\r\n\r\n# Setup Guide\r\n\r\nRun the installer."))
+	if err != nil {
+		t.Fatalf("FromFile returned error: %v", err)
+	}
+	if doc.Title != "Setup Guide" || doc.Format != FormatMarkdown || strings.Contains(doc.Content, "\r") {
+		t.Errorf("unexpected Markdown document: %+v", doc)
+	}
+
+	doc, err = FromFile("notes/release-notes.TXT", []byte("  Version 2 fixes ERR_42.  "))
+	if err != nil {
+		t.Fatalf("FromFile returned error: %v", err)
+	}
+	if doc.Title != "release-notes" || doc.Content != "Version 2 fixes ERR_42." {
+		t.Errorf("unexpected text document: %+v", doc)
+	}
+}
+
+func TestFromFileRejectsUnsupportedAndEmpty(t *testing.T) {
+	if _, err := FromFile("slides.pptx", []byte("x")); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Errorf("expected ErrUnsupportedFormat, got %v", err)
+	}
+	if _, err := FromFile("empty.txt", []byte(" \n ")); !errors.Is(err, ErrNoText) {
+		t.Errorf("expected ErrNoText, got %v", err)
+	}
+	if _, err := FromFile("broken.pdf", []byte("not a pdf")); err == nil {
+		t.Error("expected an error for an invalid PDF")
+	}
+}
+
+func TestHTMLExtractsMainContentAsMarkdown(t *testing.T) {
+	page := `<!DOCTYPE html><html><head><title>Refund Policy</title><style>p{}</style></head>
+<body>
+<nav><a href="/">Home</a></nav>
+<main>
+  <h1>Refunds</h1>
+  <p>Refunds are issued within <b>14 days</b>. See <a href="/terms">the terms</a>.</p>
+  <ul><li>Card payments</li><li>Bank transfers</li></ul>
+  <pre><code>refund --order 42</code></pre>
+  <script>track()</script>
+</main>
+<footer>Copyright</footer>
+</body></html>`
+
+	doc, err := FromFile("refunds.html", []byte(page))
+	if err != nil {
+		t.Fatalf("FromFile returned error: %v", err)
+	}
+	want := "# Refunds\n\nRefunds are issued within 14 days. See [the terms](/terms).\n\n- Card payments\n- Bank transfers\n\n```\nrefund --order 42\n```"
+	if doc.Title != "Refund Policy" || doc.Content != want {
+		t.Errorf("unexpected HTML document:\ntitle: %q\ncontent:\n%s\nwant:\n%s", doc.Title, doc.Content, want)
+	}
+}
+
+func TestPDFExtractsTextPerLine(t *testing.T) {
+	data := buildPDF("Pricing Sheet", "Plan Basic costs 10 EUR", "Plan Pro costs 25 EUR")
+
+	doc, err := FromFile("pricing.pdf", data)
+	if err != nil {
+		t.Fatalf("FromFile returned error: %v", err)
+	}
+	if doc.Title != "Pricing Sheet" || doc.Format != FormatPDF {
+		t.Errorf("unexpected PDF document: %+v", doc)
+	}
+	if doc.Content != "Plan Basic costs 10 EUR\nPlan Pro costs 25 EUR" {
+		t.Errorf("unexpected PDF content: %q", doc.Content)
+	}
+}
+
+// buildPDF writes a one-page PDF showing each line in Helvetica, with a correct cross-reference table.
+func buildPDF(title string, lines ...string) []byte {
+	var stream strings.Builder
+	stream.WriteString("BT /F1 12 Tf 72 720 Td\n")
+	for _, line := range lines {
+		fmt.Fprintf(&stream, "(%s) Tj 0 -14 Td\n", line)
+	}
+	stream.WriteString("ET")
+
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Contents 4 0 R /Resources << /Font << /F1 5 0 R >> >> >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", stream.Len(), stream.String()),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>",
+		fmt.Sprintf("<< /Title (%s) >>", title),
+	}
+
+	var b strings.Builder
+	b.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = b.Len()
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&b, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&b, "trailer\n<< /Size %d /Root 1 0 R /Info 6 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return []byte(b.String())
+}
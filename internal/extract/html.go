@@ -0,0 +1,255 @@
+package extract
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// skippedElements never contain document content.
+var skippedElements = map[atom.Atom]bool{
+	atom.Script:   true,
+	atom.Style:    true,
+	atom.Noscript: true,
+	atom.Template: true,
+	atom.Svg:      true,
+	atom.Iframe:   true,
+	atom.Form:     true,
+	atom.Button:   true,
+}
+
+// boilerplateElements hold site chrome rather than content; they are skipped when extracting the main content.
+var boilerplateElements = map[atom.Atom]bool{
+	atom.Nav:    true,
+	atom.Header: true,
+	atom.Footer: true,
+	atom.Aside:  true,
+}
+
+// HTML extracts the main content of an HTML page as Markdown. The content of <main> or <article>
+// is used when the page has one; otherwise the whole <body> minus navigation, headers, footers and sidebars.
+// The title comes from <title>, or the first <h1>.
+func HTML(data []byte) (*Document, error) {
+	root, err := html.Parse(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse HTML: %w", err)
+	}
+
+	title := ""
+	if n := findElement(root, atom.Title); n != nil {
+		title = collapseSpace(textOf(n))
+	}
+	if title == "" {
+		if n := findElement(root, atom.H1); n != nil {
+			title = collapseSpace(textOf(n))
+		}
+	}
+
+	content := findElement(root, atom.Main)
+	if content == nil {
+		content = findElement(root, atom.Article)
+	}
+	if content == nil {
+		content = findElement(root, atom.Body)
+	}
+	if content == nil {
+		content = root
+	}
+
+	w := &markdownWriter{}
+	w.render(content)
+	return &Document{Title: title, Content: w.String()}, nil
+}
+
+// markdownWriter renders an HTML tree as Markdown: headings, paragraphs, lists, code blocks and links.
+type markdownWriter struct {
+	b         strings.Builder
+	inPre     bool
+	listDepth int
+}
+
+func (w *markdownWriter) String() string {
+	lines := strings.Split(w.b.String(), "\n")
+	var out []string
+	blank := 0
+	for _, line := range lines {
+		line = strings.TrimRight(line, " \t")
+		if line == "" {
+			blank++
+			if blank > 1 {
+				continue
+			}
+		} else {
+			blank = 0
+		}
+		out = append(out, line)
+	}
+	return strings.TrimSpace(strings.Join(out, "\n"))
+}
+
+// block starts a new paragraph-level block.
+func (w *markdownWriter) block() {
+	w.b.WriteString("\n\n")
+}
+
+func (w *markdownWriter) render(n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		if w.inPre {
+			w.b.WriteString(n.Data)
+		} else {
+			w.writeInline(n.Data)
+		}
+		return
+	case html.ElementNode:
+		if skippedElements[n.DataAtom] || boilerplateElements[n.DataAtom] {
+			return
+		}
+	case html.CommentNode, html.DoctypeNode:
+		return
+	}
+
+	switch n.DataAtom {
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		level := int(n.Data[1] - '0')
+		w.block()
+		w.b.WriteString(strings.Repeat("#", level) + " " + collapseSpace(textOf(n)))
+		w.block()
+		return
+	case atom.P, atom.Div, atom.Section, atom.Blockquote, atom.Table, atom.Dl:
+		w.block()
+		w.renderChildren(n)
+		w.block()
+		return
+	case atom.Tr:
+		w.b.WriteString("\n")
+		w.renderChildren(n)
+		return
+	case atom.Td, atom.Th:
+		w.b.WriteString(" | ")
+		w.renderChildren(n)
+		return
+	case atom.Br:
+		w.b.WriteString("\n")
+		return
+	case atom.Ul, atom.Ol:
+		w.listDepth++
+		w.b.WriteString("\n")
+		w.renderChildren(n)
+		w.listDepth--
+		w.b.WriteString("\n")
+		return
+	case atom.Li:
+		w.b.WriteString("\n" + strings.Repeat("  ", max(w.listDepth-1, 0)) + "- ")
+		w.renderChildren(n)
+		return
+	case atom.Pre:
+		w.block()
+		w.b.WriteString("```\n")
+		w.inPre = true
+		w.renderChildren(n)
+		w.inPre = false
+		w.b.WriteString("\n```")
+		w.block()
+		return
+	case atom.Code:
+		if !w.inPre {
+			w.b.WriteString("`" + textOf(n) + "`")
+			return
+		}
+	case atom.A:
+		text := collapseSpace(textOf(n))
+		href := attr(n, "href")
+		if text != "" && href != "" && !strings.HasPrefix(href, "#") && !strings.HasPrefix(strings.ToLower(href), "javascript:") {
+			w.writeInline(" ")
+			w.b.WriteString("[" + text + "](" + href + ")")
+			return
+		}
+	case atom.Img:
+		if alt := collapseSpace(attr(n, "alt")); alt != "" {
+			w.writeInline(" " + alt + " ")
+		}
+		return
+	}
+	w.renderChildren(n)
+}
+
+func (w *markdownWriter) renderChildren(n *html.Node) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		w.render(c)
+	}
+}
+
+// writeInline writes text with runs of whitespace collapsed to one space.
+func (w *markdownWriter) writeInline(s string) {
+	if s == "" {
+		return
+	}
+	collapsed := collapseSpace(s)
+	cur := w.b.String()
+	atLineStart := cur == "" || strings.HasSuffix(cur, "\n") || strings.HasSuffix(cur, "- ") || strings.HasSuffix(cur, "| ")
+	if startsWithSpace(s) && !atLineStart && !strings.HasSuffix(cur, " ") {
+		w.b.WriteString(" ")
+	}
+	w.b.WriteString(collapsed)
+	if collapsed != "" && endsWithSpace(s) {
+		w.b.WriteString(" ")
+	}
+}
+
+func startsWithSpace(s string) bool {
+	return s != "" && strings.TrimLeft(s, " \t\n\r") != s
+}
+
+func endsWithSpace(s string) bool {
+	return s != "" && strings.TrimRight(s, " \t\n\r") != s
+}
+
+// findElement returns the first element of the given type in document order, or nil.
+func findElement(n *html.Node, a atom.Atom) *html.Node {
+	if n.Type == html.ElementNode && n.DataAtom == a {
+		return n
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if found := findElement(c, a); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// textOf returns the concatenated text below n, skipping scripts and styles.
+func textOf(n *html.Node) string {
+	var b strings.Builder
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			b.WriteString(n.Data)
+			return
+		}
+		if n.Type == html.ElementNode && skippedElements[n.DataAtom] {
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return b.String()
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if strings.EqualFold(a.Key, key) {
+			return strings.TrimSpace(a.Val)
+		}
+	}
+	return ""
+}
+
+func collapseSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
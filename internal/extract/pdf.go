@@ -0,0 +1,82 @@
+package extract
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/ledongthuc/pdf"
+)
+
+// PDF extracts the text of a PDF file, one line per text row and a blank line between pages.
+// Encrypted PDFs and scanned pages without a text layer are not supported.
+func PDF(data []byte) (doc *Document, err error) {
+	// The PDF reader panics on some malformed files.
+	defer func() {
+		if r := recover(); r != nil {
+			doc, err = nil, fmt.Errorf("failed to read PDF: %v", r)
+		}
+	}()
+
+	reader, err := pdf.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PDF: %w", err)
+	}
+
+	title := ""
+	if info := reader.Trailer().Key("Info"); !info.IsNull() {
+		title = strings.TrimSpace(info.Key("Title").Text())
+	}
+
+	var b strings.Builder
+	for i := 1; i <= reader.NumPage(); i++ {
+		page := reader.Page(i)
+		if page.V.IsNull() {
+			continue
+		}
+		for _, line := range pageLines(page.Content().Text) {
+			b.WriteString(line + "\n")
+		}
+		b.WriteString("\n")
+	}
+	return &Document{Title: title, Content: b.String()}, nil
+}
+
+// pageLines groups the positioned glyphs of a page into lines, top to bottom. A space is inserted
+// where the gap between two glyphs is wider than a quarter of the font size.
+func pageLines(glyphs []pdf.Text) []string {
+	rows := map[int][]pdf.Text{}
+	for _, g := range glyphs {
+		y := int(math.Round(g.Y))
+		rows[y] = append(rows[y], g)
+	}
+
+	ys := make([]int, 0, len(rows))
+	for y := range rows {
+		ys = append(ys, y)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(ys))) // PDF coordinates grow upwards
+
+	lines := make([]string, 0, len(ys))
+	for _, y := range ys {
+		row := rows[y]
+		sort.SliceStable(row, func(i, j int) bool { return row[i].X < row[j].X })
+
+		var line strings.Builder
+		for i, g := range row {
+			if i > 0 {
+				prev := row[i-1]
+				if g.X-(prev.X+prev.W) > g.FontSize/4 && !strings.HasSuffix(prev.S, " ") && !strings.HasPrefix(g.S, " ") {
+					line.WriteByte(' ')
+				}
+			}
+			line.WriteString(g.S)
+		}
+		if text := collapseSpace(line.String()); text != "" {
+			lines = append(lines, text)
+		}
+	}
+	return lines
+}
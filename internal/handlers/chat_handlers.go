@@ -3,25 +3,35 @@ package handlers
 import (
 	"buildmychat-backend/internal/models"
 	"buildmychat-backend/internal/services"
+	"buildmychat-backend/internal/store"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log"
 	"net/http"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 )
 
+// replyWriteDeadline bounds writing the response of a request that waits for the chatbot's reply. It
+// outlasts the router's request timeout, so the reply or the timeout response still reaches the client
+// past the server's short write timeout.
+const replyWriteDeadline = 70 * time.Second
+
 // ChatHandlers handles HTTP requests related to chats.
 type ChatHandlers struct {
-	chatService *services.ChatService
+	chatService   *services.ChatService
+	streamTimeout time.Duration // Upper bound for a streamed reply (see HandleStreamUserMessage)
 }
 
 // NewChatHandlers creates a new ChatHandlers instance.
-func NewChatHandlers(chatService *services.ChatService) *ChatHandlers {
+func NewChatHandlers(chatService *services.ChatService, streamTimeout time.Duration) *ChatHandlers {
 	return &ChatHandlers{
-		chatService: chatService,
+		chatService:   chatService,
+		streamTimeout: streamTimeout,
 	}
 }
 
@@ -120,6 +130,11 @@ func (h *ChatHandlers) HandleAddUserMessage(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	// The reply is generated before responding, which takes longer than the server's write timeout
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Now().Add(replyWriteDeadline)); err != nil {
+		log.Printf("WARN [ChatHandlers] HandleAddUserMessage: Could not extend write deadline: %v", err)
+	}
+
 	// Call service to add message
 	updatedChat, err := h.chatService.AddMessageToChat(r.Context(), orgID, chatID, req.Message)
 	if err != nil {
@@ -199,6 +214,47 @@ func (h *ChatHandlers) HandleAddAssistantMessage(w http.ResponseWriter, r *http.
 	RespondWithJSON(w, http.StatusOK, updatedChat)
 }
 
+// HandleUpdateChatFeedback handles PUT /v1/chats/{chatID}/feedback, which sets the feedback of a chat:
+// -1 (negative), 0 (neutral) or 1 (positive). It responds with the updated chat.
+func (h *ChatHandlers) HandleUpdateChatFeedback(w http.ResponseWriter, r *http.Request) {
+	orgID, err := GetOrgIDFromContext(r.Context())
+	if err != nil {
+		RespondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	chatID, err := uuid.Parse(chi.URLParam(r, "chatID"))
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Invalid chat ID")
+		return
+	}
+
+	var req models.UpdateChatFeedbackRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := h.chatService.UpdateChatFeedback(r.Context(), orgID, chatID, req.Feedback); err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidFeedback):
+			RespondWithError(w, http.StatusBadRequest, err.Error())
+		case errors.Is(err, store.ErrNotFound):
+			RespondWithError(w, http.StatusNotFound, "Chat not found")
+		default:
+			RespondWithError(w, http.StatusInternalServerError, "Failed to update feedback: "+err.Error())
+		}
+		return
+	}
+
+	updatedChat, err := h.chatService.GetChatByID(r.Context(), orgID, chatID, false)
+	if err != nil {
+		RespondWithError(w, http.StatusInternalServerError, "Failed to get chat: "+err.Error())
+		return
+	}
+	RespondWithJSON(w, http.StatusOK, updatedChat)
+}
+
 // HandleListChats handles requests to list chats for the organization or chatbot.
 func (h *ChatHandlers) HandleListChats(w http.ResponseWriter, r *http.Request) {
 	// Extract organization ID from context
@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"buildmychat-backend/internal/models"
+	"buildmychat-backend/internal/store"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// SSE event names sent on the chat message stream.
+const (
+	sseEventDelta = "delta" // A chunk of the assistant reply
+	sseEventDone  = "done"  // Terminal: the reply was saved
+	sseEventError = "error" // Terminal: the reply could not be generated
+)
+
+// writeSSEEvent writes a single Server-Sent Event with a JSON payload and flushes it to the client.
+func writeSSEEvent(w http.ResponseWriter, rc *http.ResponseController, event string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s event: %w", event, err)
+	}
+	if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data); err != nil {
+		return err
+	}
+	return rc.Flush()
+}
+
+// HandleStreamUserMessage adds a user message to a chat and streams the assistant reply as
+// Server-Sent Events: a "delta" event per chunk, then a terminal "done" event carrying the
+// saved message and updated chat, or an "error" event if generation failed.
+// The global request timeout skips the route (see api.TimeoutExceptStreams); h.streamTimeout bounds it instead.
+func (h *ChatHandlers) HandleStreamUserMessage(w http.ResponseWriter, r *http.Request) {
+	// Extract organization ID from context
+	orgID, err := GetOrgIDFromContext(r.Context())
+	if err != nil {
+		RespondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	// Extract chat ID from URL
+	chatIDStr := chi.URLParam(r, "chatID")
+	chatID, err := uuid.Parse(chatIDStr)
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Invalid chat ID")
+		return
+	}
+
+	// Parse request body
+	var req models.AddMessageAsUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if strings.TrimSpace(req.Message) == "" {
+		RespondWithError(w, http.StatusBadRequest, "Message is required")
+		return
+	}
+
+	// Check the chat exists before committing to a stream, so errors can still be plain JSON
+	if _, err := h.chatService.GetChatByID(r.Context(), orgID, chatID, false); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			RespondWithError(w, http.StatusNotFound, "Chat not found")
+			return
+		}
+		RespondWithError(w, http.StatusInternalServerError, "Failed to get chat: "+err.Error())
+		return
+	}
+
+	// Lift the server-wide write timeout for this response and bound it by the stream timeout instead
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Now().Add(h.streamTimeout)); err != nil {
+		log.Printf("WARN [ChatHandlers] HandleStreamUserMessage: Could not extend write deadline: %v", err)
+	}
+	// Not cancelled when the client disconnects: the reply is still generated and saved to the chat
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), h.streamTimeout)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no") // Disable proxy buffering (nginx)
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		log.Printf("ERROR [ChatHandlers] HandleStreamUserMessage: Response does not support streaming: %v", err)
+		return
+	}
+
+	updatedChat, err := h.chatService.StreamAssistantReply(ctx, orgID, chatID, req.Message, func(delta string) error {
+		return writeSSEEvent(w, rc, sseEventDelta, models.ChatStreamDeltaEvent{Content: delta})
+	})
+	if err != nil {
+		log.Printf("ERROR [ChatHandlers] HandleStreamUserMessage: Streaming reply for chat %s failed: %v", chatID, err)
+		if writeErr := writeSSEEvent(w, rc, sseEventError, models.ErrorResponse{Error: err.Error()}); writeErr != nil {
+			log.Printf("WARN [ChatHandlers] HandleStreamUserMessage: Could not send error event: %v", writeErr)
+		}
+		return
+	}
+
+	done := models.ChatStreamDoneEvent{Chat: updatedChat}
+	if n := len(updatedChat.Chat); n > 0 {
+		done.Message = updatedChat.Chat[n-1]
+	}
+	if err := writeSSEEvent(w, rc, sseEventDone, done); err != nil {
+		log.Printf("WARN [ChatHandlers] HandleStreamUserMessage: Could not send done event: %v", err)
+	}
+}
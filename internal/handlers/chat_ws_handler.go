@@ -0,0 +1,226 @@
+package handlers
+
+import (
+	"buildmychat-backend/internal/models"
+	"buildmychat-backend/internal/realtime"
+	"buildmychat-backend/internal/store"
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+)
+
+// Client frame types accepted on the chat WebSocket.
+const (
+	wsRequestMessage = "message"
+	wsRequestTyping  = "typing"
+)
+
+const (
+	wsWriteWait      = 10 * time.Second    // Time allowed to write a frame
+	wsPongWait       = 60 * time.Second    // Time allowed between pongs from the client
+	wsPingPeriod     = wsPongWait * 9 / 10 // Must be less than wsPongWait
+	wsMaxMessageSize = 64 * 1024           // Maximum client frame size in bytes
+	wsSendBuffer     = 16                  // Direct (non-broadcast) frames queued per connection
+)
+
+var chatUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	// The widget is embedded on customer sites, so any origin may connect.
+	// Access is controlled by the JWT passed as access_token, not by cookies.
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// chatSocket is one WebSocket connection watching a chat.
+type chatSocket struct {
+	handlers *ChatHandlers
+	conn     *websocket.Conn
+	id       string // Identifies this connection in events it originates
+	orgID    uuid.UUID
+	chatID   uuid.UUID
+	direct   chan realtime.Event // Frames for this connection only (errors)
+	replying atomic.Bool         // True while a reply to this connection's message is being generated
+}
+
+// HandleChatWebSocket upgrades the request to a WebSocket for live updates on a chat.
+// Clients receive every event published on the chat (new messages from any writer, reply deltas,
+// status and typing changes) and may send {"type":"message","message":"..."} to talk to the chatbot
+// or {"type":"typing"} to signal typing.
+// The global request timeout skips the route (see api.TimeoutExceptStreams); replies are bounded by h.streamTimeout.
+func (h *ChatHandlers) HandleChatWebSocket(w http.ResponseWriter, r *http.Request) {
+	// Extract organization ID from context
+	orgID, err := GetOrgIDFromContext(r.Context())
+	if err != nil {
+		RespondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	// Extract chat ID from URL
+	chatIDStr := chi.URLParam(r, "chatID")
+	chatID, err := uuid.Parse(chatIDStr)
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Invalid chat ID")
+		return
+	}
+
+	// Subscribe before upgrading so a missing chat is still reported as plain JSON
+	events, unsubscribe, err := h.chatService.SubscribeToChat(r.Context(), orgID, chatID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			RespondWithError(w, http.StatusNotFound, "Chat not found")
+			return
+		}
+		RespondWithError(w, http.StatusInternalServerError, "Failed to subscribe to chat: "+err.Error())
+		return
+	}
+	defer unsubscribe()
+
+	conn, err := chatUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Printf("ERROR [ChatHandlers] HandleChatWebSocket: Upgrade failed for chat %s: %v", chatID, err)
+		return // Upgrade has already replied with an HTTP error
+	}
+	defer conn.Close()
+
+	socket := &chatSocket{
+		handlers: h,
+		conn:     conn,
+		id:       uuid.NewString(),
+		orgID:    orgID,
+		chatID:   chatID,
+		direct:   make(chan realtime.Event, wsSendBuffer),
+	}
+
+	ctx, cancel := context.WithCancel(r.Context())
+	writerDone := make(chan struct{})
+	go func() {
+		defer close(writerDone)
+		socket.writeLoop(ctx, events)
+	}()
+
+	socket.readLoop(ctx)
+	cancel()
+	<-writerDone
+}
+
+// readLoop handles client frames until the connection fails or is closed.
+func (c *chatSocket) readLoop(ctx context.Context) {
+	c.conn.SetReadLimit(wsMaxMessageSize)
+	c.conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	})
+
+	for {
+		var req models.ChatSocketRequest
+		if err := c.conn.ReadJSON(&req); err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				log.Printf("WARN [ChatHandlers] Chat socket %s for chat %s closed: %v", c.id, c.chatID, err)
+			}
+			return
+		}
+
+		switch req.Type {
+		case wsRequestMessage:
+			c.startReply(ctx, req.Message)
+		case wsRequestTyping:
+			c.handlers.chatService.PublishTyping(ctx, c.chatID, "user", c.id)
+		default:
+			c.sendError("Unknown frame type: " + req.Type)
+		}
+	}
+}
+
+// startReply generates the chatbot's reply to a user message in the background.
+// The user message, deltas, status changes and the final reply reach this connection
+// (and every other subscriber) through the broker.
+func (c *chatSocket) startReply(ctx context.Context, message string) {
+	if strings.TrimSpace(message) == "" {
+		c.sendError("Message is required")
+		return
+	}
+	if !c.replying.CompareAndSwap(false, true) {
+		c.sendError("A reply is already being generated for this connection")
+		return
+	}
+
+	go func() {
+		defer c.replying.Store(false)
+
+		// Not cancelled when the connection closes: the reply is still generated and saved to the chat
+		replyCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), c.handlers.streamTimeout)
+		defer cancel()
+
+		ignoreDelta := func(string) error { return nil } // Deltas are delivered as broker events
+		if _, err := c.handlers.chatService.StreamAssistantReply(replyCtx, c.orgID, c.chatID, message, ignoreDelta); err != nil {
+			log.Printf("ERROR [ChatHandlers] Chat socket %s: Reply for chat %s failed: %v", c.id, c.chatID, err)
+			c.sendError(err.Error())
+		}
+	}()
+}
+
+// sendError queues an error frame for this connection only.
+func (c *chatSocket) sendError(message string) {
+	event, err := realtime.NewEvent(realtime.EventError, c.chatID, models.ErrorResponse{Error: message})
+	if err != nil {
+		log.Printf("ERROR [ChatHandlers] Chat socket %s: Failed to build error frame: %v", c.id, err)
+		return
+	}
+	select {
+	case c.direct <- event:
+	default:
+		log.Printf("WARN [ChatHandlers] Chat socket %s: Dropping error frame, send buffer full", c.id)
+	}
+}
+
+// writeLoop is the only writer on the connection: it forwards broker events and direct frames,
+// and keeps the connection alive with pings until ctx is cancelled or a write fails.
+func (c *chatSocket) writeLoop(ctx context.Context, events <-chan realtime.Event) {
+	ticker := time.NewTicker(wsPingPeriod)
+	defer ticker.Stop()
+
+	for {
+		var event realtime.Event
+		select {
+		case <-ctx.Done():
+			c.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			c.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+			return
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				c.conn.Close() // Unblocks readLoop
+				return
+			}
+			continue
+		case e, ok := <-events:
+			if !ok {
+				// Broker shut down
+				c.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+				c.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down"))
+				c.conn.Close()
+				return
+			}
+			if e.SourceID != "" && e.SourceID == c.id {
+				continue // Our own typing indicator
+			}
+			event = e
+		case event = <-c.direct:
+		}
+
+		c.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+		if err := c.conn.WriteJSON(event); err != nil {
+			log.Printf("WARN [ChatHandlers] Chat socket %s: Write failed: %v", c.id, err)
+			c.conn.Close() // Unblocks readLoop
+			return
+		}
+	}
+}
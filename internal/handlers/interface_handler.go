@@ -58,6 +58,8 @@ func (h *InterfaceHandler) HandleCreateInterface(w http.ResponseWriter, r *http.
 			httputil.RespondError(w, http.StatusBadRequest, err.Error())
 		case errors.Is(err, services.ErrInterfaceCredentialMismatch):
 			httputil.RespondError(w, http.StatusBadRequest, err.Error())
+		case errors.Is(err, services.ErrInterfaceWorkspaceTaken):
+			httputil.RespondError(w, http.StatusConflict, err.Error())
 		case err.Error() == fmt.Sprintf("interface with name '%s' already exists in this organization", req.Name):
 			httputil.RespondError(w, http.StatusConflict, err.Error())
 		default:
@@ -151,6 +153,8 @@ func (h *InterfaceHandler) HandleUpdateInterface(w http.ResponseWriter, r *http.
 			httputil.RespondError(w, http.StatusBadRequest, err.Error())
 		case errors.Is(err, services.ErrInterfaceCredentialMismatch):
 			httputil.RespondError(w, http.StatusBadRequest, "Credential update not supported or invalid")
+		case errors.Is(err, services.ErrInterfaceWorkspaceTaken):
+			httputil.RespondError(w, http.StatusConflict, err.Error())
 		case err.Error() == fmt.Sprintf("interface with name '%s' already exists in this organization", req.Name):
 			httputil.RespondError(w, http.StatusConflict, err.Error())
 		default:
@@ -9,8 +9,11 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
@@ -23,15 +26,35 @@ type KBService interface {
 	ListKnowledgeBases(ctx context.Context, orgID uuid.UUID) ([]models.KnowledgeBaseResponse, error)
 	UpdateKnowledgeBase(ctx context.Context, id uuid.UUID, orgID uuid.UUID, req models.CreateKnowledgeBaseRequest) (*models.KnowledgeBaseResponse, error)
 	DeleteKnowledgeBase(ctx context.Context, id uuid.UUID, orgID uuid.UUID) error
+	ListKnowledgeBaseDocuments(ctx context.Context, id uuid.UUID, orgID uuid.UUID) ([]models.KBDocumentResponse, error)
+	SearchKnowledgeBase(ctx context.Context, id uuid.UUID, orgID uuid.UUID, query string, topK int) (*models.KBSearchResponse, error)
+	SearchChatbotKnowledgeBases(ctx context.Context, chatbotID uuid.UUID, orgID uuid.UUID, query string, topK int) (*models.KBSearchResponse, error)
+	UploadDocuments(ctx context.Context, id uuid.UUID, orgID uuid.UUID, files []services.KBUploadFile) ([]models.KBDocumentResponse, error)
+	ReplaceDocument(ctx context.Context, id uuid.UUID, documentID uuid.UUID, orgID uuid.UUID, file services.KBUploadFile) (*models.KBDocumentResponse, error)
+	DeleteDocument(ctx context.Context, id uuid.UUID, documentID uuid.UUID, orgID uuid.UUID) error
 }
 
+// KBSyncService defines the interface expected from the knowledge base sync service.
+type KBSyncService interface {
+	TriggerSync(ctx context.Context, kbID uuid.UUID, orgID uuid.UUID, full bool) (*models.KBSyncStatusResponse, error)
+	GetSyncStatus(ctx context.Context, kbID uuid.UUID, orgID uuid.UUID) (*models.KBSyncStatusResponse, error)
+}
+
+const (
+	maxUploadRequestBytes = 50 << 20 // Total size of a document upload request
+	maxUploadMemoryBytes  = 10 << 20 // Multipart data kept in memory; the rest spills to temp files
+	uploadDeadline        = 60 * time.Second
+)
+
 type KBHandler struct {
-	kbService KBService
+	kbService     KBService
+	kbSyncService KBSyncService
 }
 
-func NewKBHandler(kbSvc KBService) *KBHandler {
+func NewKBHandler(kbSvc KBService, kbSyncSvc KBSyncService) *KBHandler {
 	return &KBHandler{
-		kbService: kbSvc,
+		kbService:     kbSvc,
+		kbSyncService: kbSyncSvc,
 	}
 }
 
@@ -194,3 +217,360 @@ func (h *KBHandler) HandleDeleteKnowledgeBase(w http.ResponseWriter, r *http.Req
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// HandleTriggerKnowledgeBaseSync handles POST /v1/knowledge-bases/{kbID}/sync[?full=true]
+// The sync runs in the background; poll the status endpoint for progress.
+// Syncs are incremental unless full=true, which re-fetches every page (e.g. after changing the format).
+func (h *KBHandler) HandleTriggerKnowledgeBaseSync(w http.ResponseWriter, r *http.Request) {
+	orgID, ok := auth.GetOrgIDFromContext(r.Context())
+	if !ok {
+		httputil.RespondError(w, http.StatusUnauthorized, "Organization ID not found in token context")
+		return
+	}
+
+	kbIDStr := chi.URLParam(r, "kbID")
+	kbID, err := uuid.Parse(kbIDStr)
+	if err != nil {
+		httputil.RespondError(w, http.StatusBadRequest, "Invalid knowledge base ID format")
+		return
+	}
+
+	full := false
+	if fullStr := r.URL.Query().Get("full"); fullStr != "" {
+		if full, err = strconv.ParseBool(fullStr); err != nil {
+			httputil.RespondError(w, http.StatusBadRequest, "Invalid full parameter, expected true or false")
+			return
+		}
+	}
+
+	resp, err := h.kbSyncService.TriggerSync(r.Context(), kbID, orgID, full)
+	if err != nil {
+		log.Printf("ERROR [KBHandler] HandleTriggerKBSync for ID %s, OrgID %s: %v", kbID, orgID, err)
+		switch {
+		case errors.Is(err, services.ErrKBNotFound):
+			httputil.RespondError(w, http.StatusNotFound, err.Error())
+		case errors.Is(err, services.ErrKBSyncInProgress):
+			httputil.RespondError(w, http.StatusConflict, err.Error())
+		case errors.Is(err, services.ErrKBSyncUnsupported):
+			httputil.RespondError(w, http.StatusBadRequest, err.Error())
+		default:
+			httputil.RespondError(w, http.StatusInternalServerError, "Failed to start knowledge base sync")
+		}
+		return
+	}
+
+	httputil.RespondJSON(w, http.StatusAccepted, resp)
+}
+
+// HandleGetKnowledgeBaseSyncStatus handles GET /v1/knowledge-bases/{kbID}/sync/status
+func (h *KBHandler) HandleGetKnowledgeBaseSyncStatus(w http.ResponseWriter, r *http.Request) {
+	orgID, ok := auth.GetOrgIDFromContext(r.Context())
+	if !ok {
+		httputil.RespondError(w, http.StatusUnauthorized, "Organization ID not found in token context")
+		return
+	}
+
+	kbIDStr := chi.URLParam(r, "kbID")
+	kbID, err := uuid.Parse(kbIDStr)
+	if err != nil {
+		httputil.RespondError(w, http.StatusBadRequest, "Invalid knowledge base ID format")
+		return
+	}
+
+	resp, err := h.kbSyncService.GetSyncStatus(r.Context(), kbID, orgID)
+	if err != nil {
+		log.Printf("ERROR [KBHandler] HandleGetKBSyncStatus for ID %s, OrgID %s: %v", kbID, orgID, err)
+		if errors.Is(err, services.ErrKBNotFound) {
+			httputil.RespondError(w, http.StatusNotFound, err.Error())
+		} else {
+			httputil.RespondError(w, http.StatusInternalServerError, "Failed to get knowledge base sync status")
+		}
+		return
+	}
+
+	httputil.RespondJSON(w, http.StatusOK, resp)
+}
+
+// HandleListKnowledgeBaseDocuments handles GET /v1/knowledge-bases/{kbID}/documents
+func (h *KBHandler) HandleListKnowledgeBaseDocuments(w http.ResponseWriter, r *http.Request) {
+	orgID, ok := auth.GetOrgIDFromContext(r.Context())
+	if !ok {
+		httputil.RespondError(w, http.StatusUnauthorized, "Organization ID not found in token context")
+		return
+	}
+
+	kbIDStr := chi.URLParam(r, "kbID")
+	kbID, err := uuid.Parse(kbIDStr)
+	if err != nil {
+		httputil.RespondError(w, http.StatusBadRequest, "Invalid knowledge base ID format")
+		return
+	}
+
+	docs, err := h.kbService.ListKnowledgeBaseDocuments(r.Context(), kbID, orgID)
+	if err != nil {
+		log.Printf("ERROR [KBHandler] HandleListKBDocuments for ID %s, OrgID %s: %v", kbID, orgID, err)
+		if errors.Is(err, services.ErrKBNotFound) {
+			httputil.RespondError(w, http.StatusNotFound, err.Error())
+		} else {
+			httputil.RespondError(w, http.StatusInternalServerError, "Failed to list knowledge base documents")
+		}
+		return
+	}
+
+	httputil.RespondJSON(w, http.StatusOK, docs)
+}
+
+// HandleSearchKnowledgeBase handles GET /v1/knowledge-bases/{kbID}/search?q=...[&top_k=N]
+func (h *KBHandler) HandleSearchKnowledgeBase(w http.ResponseWriter, r *http.Request) {
+	orgID, ok := auth.GetOrgIDFromContext(r.Context())
+	if !ok {
+		httputil.RespondError(w, http.StatusUnauthorized, "Organization ID not found in token context")
+		return
+	}
+
+	kbIDStr := chi.URLParam(r, "kbID")
+	kbID, err := uuid.Parse(kbIDStr)
+	if err != nil {
+		httputil.RespondError(w, http.StatusBadRequest, "Invalid knowledge base ID format")
+		return
+	}
+
+	topK, ok := parseTopK(w, r)
+	if !ok {
+		return
+	}
+
+	resp, err := h.kbService.SearchKnowledgeBase(r.Context(), kbID, orgID, r.URL.Query().Get("q"), topK)
+	if err != nil {
+		log.Printf("ERROR [KBHandler] HandleSearchKB for ID %s, OrgID %s: %v", kbID, orgID, err)
+		switch {
+		case errors.Is(err, services.ErrKBNotFound):
+			httputil.RespondError(w, http.StatusNotFound, err.Error())
+		case errors.Is(err, services.ErrKBValidation):
+			httputil.RespondError(w, http.StatusBadRequest, err.Error())
+		default:
+			httputil.RespondError(w, http.StatusInternalServerError, "Failed to search knowledge base")
+		}
+		return
+	}
+
+	httputil.RespondJSON(w, http.StatusOK, resp)
+}
+
+// HandleSearchChatbotKnowledgeBases handles GET /v1/chatbots/{chatbotID}/knowledge-bases/search?q=...[&top_k=N]
+// It searches every active knowledge base mapped to the chatbot, with the chatbot's retrieval settings.
+func (h *KBHandler) HandleSearchChatbotKnowledgeBases(w http.ResponseWriter, r *http.Request) {
+	orgID, ok := auth.GetOrgIDFromContext(r.Context())
+	if !ok {
+		httputil.RespondError(w, http.StatusUnauthorized, "Organization ID not found in token context")
+		return
+	}
+
+	chatbotIDStr := chi.URLParam(r, "chatbotID")
+	chatbotID, err := uuid.Parse(chatbotIDStr)
+	if err != nil {
+		httputil.RespondError(w, http.StatusBadRequest, "Invalid chatbot ID format")
+		return
+	}
+
+	topK, ok := parseTopK(w, r)
+	if !ok {
+		return
+	}
+
+	resp, err := h.kbService.SearchChatbotKnowledgeBases(r.Context(), chatbotID, orgID, r.URL.Query().Get("q"), topK)
+	if err != nil {
+		log.Printf("ERROR [KBHandler] HandleSearchChatbotKBs for ChatbotID %s, OrgID %s: %v", chatbotID, orgID, err)
+		switch {
+		case errors.Is(err, services.ErrKBChatbotNotFound):
+			httputil.RespondError(w, http.StatusNotFound, err.Error())
+		case errors.Is(err, services.ErrKBValidation):
+			httputil.RespondError(w, http.StatusBadRequest, err.Error())
+		default:
+			httputil.RespondError(w, http.StatusInternalServerError, "Failed to search chatbot knowledge bases")
+		}
+		return
+	}
+
+	httputil.RespondJSON(w, http.StatusOK, resp)
+}
+
+// parseTopK reads the optional top_k query parameter (0 when absent).
+// It writes a 400 response and returns false if the value is invalid.
+func parseTopK(w http.ResponseWriter, r *http.Request) (int, bool) {
+	topKStr := r.URL.Query().Get("top_k")
+	if topKStr == "" {
+		return 0, true
+	}
+	topK, err := strconv.Atoi(topKStr)
+	if err != nil || topK <= 0 {
+		httputil.RespondError(w, http.StatusBadRequest, "Invalid top_k parameter, expected a positive integer")
+		return 0, false
+	}
+	return topK, true
+}
+
+// HandleUploadKnowledgeBaseDocuments handles POST /v1/knowledge-bases/{kbID}/documents
+// The request is multipart/form-data with one or more "file" parts (.md, .txt, .html or .pdf).
+func (h *KBHandler) HandleUploadKnowledgeBaseDocuments(w http.ResponseWriter, r *http.Request) {
+	orgID, ok := auth.GetOrgIDFromContext(r.Context())
+	if !ok {
+		httputil.RespondError(w, http.StatusUnauthorized, "Organization ID not found in token context")
+		return
+	}
+
+	kbIDStr := chi.URLParam(r, "kbID")
+	kbID, err := uuid.Parse(kbIDStr)
+	if err != nil {
+		httputil.RespondError(w, http.StatusBadRequest, "Invalid knowledge base ID format")
+		return
+	}
+
+	files, ok := readUploadFiles(w, r)
+	if !ok {
+		return
+	}
+
+	docs, err := h.kbService.UploadDocuments(r.Context(), kbID, orgID, files)
+	if err != nil {
+		log.Printf("ERROR [KBHandler] HandleUploadKBDocuments for ID %s, OrgID %s: %v", kbID, orgID, err)
+		respondDocumentError(w, err, "Failed to upload knowledge base documents")
+		return
+	}
+
+	httputil.RespondJSON(w, http.StatusCreated, docs)
+}
+
+// HandleReplaceKnowledgeBaseDocument handles PUT /v1/knowledge-bases/{kbID}/documents/{documentID}
+// The request is multipart/form-data with a single "file" part.
+func (h *KBHandler) HandleReplaceKnowledgeBaseDocument(w http.ResponseWriter, r *http.Request) {
+	orgID, ok := auth.GetOrgIDFromContext(r.Context())
+	if !ok {
+		httputil.RespondError(w, http.StatusUnauthorized, "Organization ID not found in token context")
+		return
+	}
+
+	kbID, documentID, ok := parseDocumentPath(w, r)
+	if !ok {
+		return
+	}
+
+	files, ok := readUploadFiles(w, r)
+	if !ok {
+		return
+	}
+	if len(files) != 1 {
+		httputil.RespondError(w, http.StatusBadRequest, "Exactly one file is required to replace a document")
+		return
+	}
+
+	doc, err := h.kbService.ReplaceDocument(r.Context(), kbID, documentID, orgID, files[0])
+	if err != nil {
+		log.Printf("ERROR [KBHandler] HandleReplaceKBDocument %s for KB %s, OrgID %s: %v", documentID, kbID, orgID, err)
+		respondDocumentError(w, err, "Failed to replace knowledge base document")
+		return
+	}
+
+	httputil.RespondJSON(w, http.StatusOK, doc)
+}
+
+// HandleDeleteKnowledgeBaseDocument handles DELETE /v1/knowledge-bases/{kbID}/documents/{documentID}
+func (h *KBHandler) HandleDeleteKnowledgeBaseDocument(w http.ResponseWriter, r *http.Request) {
+	orgID, ok := auth.GetOrgIDFromContext(r.Context())
+	if !ok {
+		httputil.RespondError(w, http.StatusUnauthorized, "Organization ID not found in token context")
+		return
+	}
+
+	kbID, documentID, ok := parseDocumentPath(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.kbService.DeleteDocument(r.Context(), kbID, documentID, orgID); err != nil {
+		log.Printf("ERROR [KBHandler] HandleDeleteKBDocument %s for KB %s, OrgID %s: %v", documentID, kbID, orgID, err)
+		respondDocumentError(w, err, "Failed to delete knowledge base document")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// parseDocumentPath reads the kbID and documentID URL parameters.
+// It writes a 400 response and returns false if either is invalid.
+func parseDocumentPath(w http.ResponseWriter, r *http.Request) (uuid.UUID, uuid.UUID, bool) {
+	kbID, err := uuid.Parse(chi.URLParam(r, "kbID"))
+	if err != nil {
+		httputil.RespondError(w, http.StatusBadRequest, "Invalid knowledge base ID format")
+		return uuid.Nil, uuid.Nil, false
+	}
+	documentID, err := uuid.Parse(chi.URLParam(r, "documentID"))
+	if err != nil {
+		httputil.RespondError(w, http.StatusBadRequest, "Invalid document ID format")
+		return uuid.Nil, uuid.Nil, false
+	}
+	return kbID, documentID, true
+}
+
+// readUploadFiles reads the "file" parts of a multipart upload. The server's short read and write
+// timeouts are extended for the request, since uploads and their indexing take longer than API calls.
+// It writes an error response and returns false if the request is not a valid upload.
+func readUploadFiles(w http.ResponseWriter, r *http.Request) ([]services.KBUploadFile, bool) {
+	rc := http.NewResponseController(w)
+	deadline := time.Now().Add(uploadDeadline)
+	if err := rc.SetReadDeadline(deadline); err != nil {
+		log.Printf("WARN [KBHandler] Upload: Could not extend read deadline: %v", err)
+	}
+	if err := rc.SetWriteDeadline(deadline); err != nil {
+		log.Printf("WARN [KBHandler] Upload: Could not extend write deadline: %v", err)
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadRequestBytes)
+	if err := r.ParseMultipartForm(maxUploadMemoryBytes); err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			httputil.RespondError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("Upload exceeds the %d MB limit", maxUploadRequestBytes>>20))
+		} else {
+			httputil.RespondError(w, http.StatusBadRequest, "Invalid multipart/form-data request")
+		}
+		return nil, false
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	headers := r.MultipartForm.File["file"]
+	if len(headers) == 0 {
+		httputil.RespondError(w, http.StatusBadRequest, "No files uploaded, expected one or more \"file\" parts")
+		return nil, false
+	}
+
+	files := make([]services.KBUploadFile, 0, len(headers))
+	for _, fh := range headers {
+		f, err := fh.Open()
+		if err != nil {
+			httputil.RespondError(w, http.StatusBadRequest, fmt.Sprintf("Could not read uploaded file %q", fh.Filename))
+			return nil, false
+		}
+		data, err := io.ReadAll(f)
+		f.Close()
+		if err != nil {
+			httputil.RespondError(w, http.StatusBadRequest, fmt.Sprintf("Could not read uploaded file %q", fh.Filename))
+			return nil, false
+		}
+		files = append(files, services.KBUploadFile{Filename: fh.Filename, Data: data})
+	}
+	return files, true
+}
+
+// respondDocumentError maps errors of the document upload, replace and delete calls to responses.
+func respondDocumentError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, services.ErrKBNotFound), errors.Is(err, services.ErrKBDocumentNotFound):
+		httputil.RespondError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, services.ErrKBUploadUnsupported):
+		httputil.RespondError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, services.ErrKBValidation):
+		httputil.RespondError(w, http.StatusBadRequest, err.Error())
+	default:
+		httputil.RespondError(w, http.StatusInternalServerError, fallback)
+	}
+}
@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"buildmychat-backend/internal/auth"
+	"buildmychat-backend/internal/models"
+	"buildmychat-backend/internal/services"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+
+	"github.com/google/uuid"
+)
+
+// slackInstallNonceCookie keeps the nonce the OAuth state of an installation is bound to, so that only
+// the browser that started the installation can complete it.
+const slackInstallNonceCookie = "slack_install_nonce"
+
+// slackInstallCookiePath limits the nonce cookie to the OAuth callback.
+const slackInstallCookiePath = "/slack/oauth"
+
+// SlackOAuthHandlers handles the "Add to Slack" installation flow.
+type SlackOAuthHandlers struct {
+	installService *services.SlackInstallService
+	returnURL      string // Frontend page the callback redirects to; "" answers with JSON
+}
+
+// NewSlackOAuthHandlers creates a new SlackOAuthHandlers instance.
+func NewSlackOAuthHandlers(installService *services.SlackInstallService, returnURL string) *SlackOAuthHandlers {
+	return &SlackOAuthHandlers{
+		installService: installService,
+		returnURL:      returnURL,
+	}
+}
+
+// HandleStartInstall handles GET /v1/slack/install?chatbot_id=...
+// It returns the Slack page on which the user installs the app for their organization. The optional
+// chatbot_id connects the installed interface to that chatbot. The response sets the HttpOnly cookie
+// the callback checks, so the frontend must send the request with credentials.
+func (h *SlackOAuthHandlers) HandleStartInstall(w http.ResponseWriter, r *http.Request) {
+	orgID, ok := auth.GetOrgIDFromContext(r.Context())
+	if !ok {
+		RespondWithError(w, http.StatusUnauthorized, "Organization ID not found in token context")
+		return
+	}
+	userID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok {
+		RespondWithError(w, http.StatusUnauthorized, "User ID not found in token context")
+		return
+	}
+
+	var chatbotID *uuid.UUID
+	if raw := r.URL.Query().Get("chatbot_id"); raw != "" {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			RespondWithError(w, http.StatusBadRequest, "Invalid chatbot_id")
+			return
+		}
+		chatbotID = &id
+	}
+
+	authorizeURL, nonce, err := h.installService.AuthorizeURL(r.Context(), userID, orgID, chatbotID)
+	if err != nil {
+		if errors.Is(err, services.ErrSlackInstallChatbotNotFound) {
+			RespondWithError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		log.Printf("ERROR [SlackOAuthHandlers] HandleStartInstall for OrgID %s: %v", orgID, err)
+		RespondWithError(w, http.StatusInternalServerError, "Failed to start Slack installation")
+		return
+	}
+
+	// SameSite=None, as the frontend usually calls the API cross-site
+	http.SetCookie(w, &http.Cookie{
+		Name:     slackInstallNonceCookie,
+		Value:    nonce,
+		Path:     slackInstallCookiePath,
+		MaxAge:   int(services.SlackInstallStateTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteNoneMode,
+	})
+	RespondWithJSON(w, http.StatusOK, models.SlackInstallURLResponse{AuthorizeURL: authorizeURL})
+}
+
+// HandleCallback handles GET /slack/oauth/callback, where Slack redirects the user after they
+// approved or cancelled the installation. The request is public; the signed state identifies the
+// organization, and must match the nonce cookie set by HandleStartInstall. The user is sent on to the
+// return URL with the result in its query, e.g. ?slack_install=success&interface_id=... or
+// ?slack_install=error&reason=access_denied.
+func (h *SlackOAuthHandlers) HandleCallback(w http.ResponseWriter, r *http.Request) {
+	var nonce string
+	if cookie, err := r.Cookie(slackInstallNonceCookie); err == nil {
+		nonce = cookie.Value
+	}
+	// The state is used at most once, so the nonce is not needed again
+	http.SetCookie(w, &http.Cookie{
+		Name:     slackInstallNonceCookie,
+		Path:     slackInstallCookiePath,
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteNoneMode,
+	})
+
+	query := r.URL.Query()
+	if reason := query.Get("error"); reason != "" {
+		// e.g. access_denied when the user cancelled
+		h.respondInstallError(w, r, http.StatusForbidden, reason, "Slack installation was not approved")
+		return
+	}
+
+	result, err := h.installService.CompleteInstall(r.Context(), query.Get("code"), query.Get("state"), nonce)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrSlackInstallState):
+			h.respondInstallError(w, r, http.StatusBadRequest, "invalid_state", "Invalid or expired installation request; please start again")
+		case errors.Is(err, services.ErrSlackInstallDenied):
+			h.respondInstallError(w, r, http.StatusForbidden, "access_denied", "Slack installation was not approved")
+		case errors.Is(err, services.ErrInterfaceWorkspaceTaken):
+			h.respondInstallError(w, r, http.StatusConflict, "workspace_taken", "Another active interface is connected to this Slack workspace")
+		default:
+			log.Printf("ERROR [SlackOAuthHandlers] HandleCallback: %v", err)
+			h.respondInstallError(w, r, http.StatusInternalServerError, "install_failed", "Failed to complete Slack installation")
+		}
+		return
+	}
+
+	if h.returnURL == "" {
+		RespondWithJSON(w, http.StatusCreated, result)
+		return
+	}
+	params := url.Values{}
+	params.Set("slack_install", "success")
+	params.Set("interface_id", result.Interface.ID.String())
+	if result.ChatbotID != nil {
+		params.Set("chatbot_id", result.ChatbotID.String())
+	}
+	http.Redirect(w, r, h.returnPage(params), http.StatusFound)
+}
+
+// respondInstallError sends the user to the return URL with the reason, or answers with a JSON error.
+func (h *SlackOAuthHandlers) respondInstallError(w http.ResponseWriter, r *http.Request, status int, reason, message string) {
+	if h.returnURL == "" {
+		RespondWithError(w, status, message)
+		return
+	}
+	params := url.Values{}
+	params.Set("slack_install", "error")
+	params.Set("reason", reason)
+	http.Redirect(w, r, h.returnPage(params), http.StatusFound)
+}
+
+// returnPage adds params to the query of the return URL.
+func (h *SlackOAuthHandlers) returnPage(params url.Values) string {
+	page, err := url.Parse(h.returnURL)
+	if err != nil {
+		return h.returnURL
+	}
+	query := page.Query()
+	for key, values := range params {
+		query[key] = values
+	}
+	page.RawQuery = query.Encode()
+	return page.String()
+}
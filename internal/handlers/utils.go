@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"buildmychat-backend/internal/auth"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -23,7 +24,13 @@ func RespondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
 }
 
 // GetOrgIDFromContext extracts the organization ID from the context.
+// It prefers the ID set by the JWT middleware and falls back to the legacy
+// "organization_id" key used by internally constructed requests.
 func GetOrgIDFromContext(ctx context.Context) (uuid.UUID, error) {
+	if orgID, ok := auth.GetOrgIDFromContext(ctx); ok {
+		return orgID, nil
+	}
+
 	orgIDVal := ctx.Value("organization_id")
 	if orgIDVal == nil {
 		return uuid.Nil, fmt.Errorf("organization_id not found in context")
@@ -0,0 +1,160 @@
+package integrations
+
+import (
+	"buildmychat-backend/internal/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// ChannelAdapter is implemented by integrations that connect chatbots to a messaging service through
+// interfaces. The adapter only translates between the service and normalized messages; finding the
+// chat, generating the reply and storing it is shared by every channel.
+type ChannelAdapter interface {
+	Integration
+
+	// VerifyWebhook checks that an inbound webhook request was sent by the service for the interface,
+	// e.g. by its signature. body is the raw request body.
+	VerifyWebhook(header http.Header, body []byte, iface ChannelInterface) error
+
+	// ParseWebhook parses a verified webhook request body. Messages the interface is not configured to
+	// answer, and messages sent by bots, are ignored.
+	ParseWebhook(body []byte, iface ChannelInterface) (*WebhookEvent, error)
+
+	// Conversation derives the conversation an inbound message belongs to, e.g. its thread. Messages
+	// with the same conversation key on an interface are added to the same chat.
+	Conversation(msg *InboundMessage, iface ChannelInterface) Conversation
+
+	// Deliver sends a message to a conversation and returns where it was posted, or nil for messages
+	// the service gives no ID for. Replies of a chat may carry controls for feedback on the chat, whose
+	// use comes back as an InboundAction.
+	Deliver(ctx context.Context, iface ChannelInterface, conv Conversation, msg OutboundMessage) (*models.ChatMessageDelivery, error)
+}
+
+// ConversationStarter is implemented by channel adapters that open a new conversation for commands,
+// e.g. by posting the question the command asked, so the reply can be threaded under it.
+type ConversationStarter interface {
+	// StartConversation opens the conversation of an inbound message with a Command. Adapters that
+	// implement it are asked instead of Conversation for such messages.
+	StartConversation(ctx context.Context, iface ChannelInterface, msg *InboundMessage) (Conversation, error)
+}
+
+// ReplyStreamer is implemented by channel adapters that show a reply while it is generated, by posting
+// a placeholder at once and editing it as the reply grows.
+type ReplyStreamer interface {
+	// StartReply posts the placeholder of a reply to a conversation. It returns a nil draft for
+	// conversations whose messages cannot be edited, which are answered with Deliver instead.
+	StartReply(ctx context.Context, iface ChannelInterface, conv Conversation) (ReplyDraft, error)
+}
+
+// ReplyDraft is a reply shown in a conversation while it is generated.
+type ReplyDraft interface {
+	// Update shows the text generated so far. It does not wait for the service; edits are made at the
+	// pace the service allows, and text superseded in between is skipped.
+	Update(text string)
+
+	// Finish replaces the placeholder with the final message, e.g. the reply or a failure notice, and
+	// returns where it was posted.
+	Finish(ctx context.Context, msg OutboundMessage) (*models.ChatMessageDelivery, error)
+}
+
+// WebhookRouter is implemented by channel adapters whose service sends the webhooks of every
+// workspace to one shared URL, so the interface a request is for must be found from its body.
+type WebhookRouter interface {
+	// RouteWebhook reads the workspace and app an unverified webhook request body is for. The
+	// WorkspaceID is "" for requests about the app itself, e.g. a URL verification challenge.
+	RouteWebhook(body []byte) (WebhookRoute, error)
+
+	// VerifyAppWebhook checks a request about the app itself with the app's own secret, as there is no
+	// interface to verify it with.
+	VerifyAppWebhook(header http.Header, body []byte) error
+}
+
+// ErrWorkspaceUnproven is returned by VerifyWorkspace when the service does not confirm that the
+// interface's credential belongs to the workspace and app of its configuration.
+var ErrWorkspaceUnproven = errors.New("the credential is not installed in the configured workspace")
+
+// WorkspaceVerifier is implemented by channel adapters whose interfaces name the workspace they are
+// for. Such IDs are typed in by users, while the webhooks of a workspace are routed by them, so they
+// must be confirmed by the service before another organization's workspace can be claimed.
+type WorkspaceVerifier interface {
+	// VerifyWorkspace asks the service, with the interface's credential, whether the credential is
+	// installed in the workspace and app of the interface's configuration. It returns an error wrapping
+	// ErrWorkspaceUnproven if it is not; interfaces that name no workspace pass.
+	VerifyWorkspace(ctx context.Context, iface ChannelInterface) error
+}
+
+// WebhookRoute identifies the interface of a webhook request sent to a shared URL.
+type WebhookRoute struct {
+	WorkspaceID string // e.g. the Slack team
+	AppID       string // App of the service the request was sent for; "" if the service does not say
+}
+
+// ChannelInterface is what a ChannelAdapter needs to talk to the service for an interface.
+type ChannelInterface struct {
+	ID            uuid.UUID
+	Configuration json.RawMessage // The interface's configuration
+	Credentials   json.RawMessage // Decrypted credential JSON
+}
+
+// WebhookEvent is a parsed webhook request. Events with no Response, Message or Action are
+// acknowledged and ignored.
+type WebhookEvent struct {
+	Response *WebhookResponse // Answered as is, e.g. a URL verification challenge; may come with a Message
+	Message  *InboundMessage  // A user message to reply to
+	Action   *InboundAction   // A user's feedback on a reply
+}
+
+// WebhookResponse is the body a webhook request is answered with.
+type WebhookResponse struct {
+	ContentType string
+	Body        []byte
+}
+
+// InboundMessage is a user message received from a messaging service.
+type InboundMessage struct {
+	EventID       string // ID of the delivery at the service, if it has one
+	WorkspaceID   string // e.g. the Slack team
+	ChannelID     string
+	UserID        string // Sender at the service
+	MessageID     string // e.g. the Slack message ts
+	ThreadID      string // Thread the message was posted in; "" outside threads
+	DirectMessage bool   // Sent in a one-to-one conversation with the bot
+	Text          string
+	Command       string // Command the message was sent with, e.g. the Slack slash command; "" for messages
+	ReplyURL      string // URL the service accepts replies to a command at, if it has one
+}
+
+// Actions users take on delivered replies.
+const (
+	ActionFeedbackPositive = "feedback_positive"
+	ActionFeedbackNegative = "feedback_negative"
+	ActionEscalate         = "escalate" // The user asks for a human to take over; recorded as negative feedback
+)
+
+// InboundAction is a user's action on a reply delivered for a chat, e.g. a click on its feedback buttons.
+type InboundAction struct {
+	EventID string    // ID of the interaction at the service, if it has one; an action is recorded once per ID
+	Action  string    // One of the Action constants
+	ChatID  uuid.UUID // Chat of the reply; it must belong to the interface
+	UserID  string    // User at the service
+	// Notice is sent to NoticeConversation once the action was recorded, e.g. a thank-you; "" sends nothing.
+	Notice             string
+	NoticeConversation Conversation
+}
+
+// OutboundMessage is a message delivered to a conversation.
+type OutboundMessage struct {
+	ChatID  uuid.UUID                  // Chat the message is a reply of; uuid.Nil for notices, which get no feedback controls
+	Text    string                     // Markdown
+	Sources []models.ChatMessageSource // Sources cited by the reply's markers, e.g. [1]
+}
+
+// Conversation identifies where a chat takes place at the service.
+type Conversation struct {
+	Key           string          // Stored as the chat's external chat ID
+	Configuration json.RawMessage // Stored as the chat's configuration; tells Deliver where to reply
+}
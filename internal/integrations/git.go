@@ -0,0 +1,260 @@
+package integrations
+
+import (
+	"buildmychat-backend/internal/extract"
+	"buildmychat-backend/internal/integrations/gitrepo"
+	integration_models "buildmychat-backend/internal/models/integrations"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"path"
+	"strings"
+	"unicode/utf8"
+)
+
+// Ensure GitIntegration implements the KnowledgeSource interface.
+var _ KnowledgeSource = (*GitIntegration)(nil)
+
+// GitIntegration handles Git repository knowledge bases. Public repositories need no credentials;
+// private ones use an HTTPS access token.
+type GitIntegration struct {
+	fetcher *gitrepo.Fetcher
+}
+
+// NewGitIntegration creates a new Git integration handler that clones repositories with fetcher.
+func NewGitIntegration(fetcher *gitrepo.Fetcher) *GitIntegration {
+	return &GitIntegration{fetcher: fetcher}
+}
+
+// ValidateConfig checks the GitKBConfig: an https repository URL without embedded credentials,
+// at most one of branch and tag, and well-formed globs.
+func (g *GitIntegration) ValidateConfig(configJSON json.RawMessage) error {
+	if len(configJSON) == 0 || string(configJSON) == "null" {
+		return errors.New("git configuration requires repository_url")
+	}
+
+	var config integration_models.GitKBConfig
+	if err := json.Unmarshal(configJSON, &config); err != nil {
+		return fmt.Errorf("invalid JSON format for git configuration: %w", err)
+	}
+
+	if config.RepositoryURL == "" {
+		return errors.New("git configuration requires repository_url")
+	}
+	u, err := url.Parse(config.RepositoryURL)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return fmt.Errorf("invalid repository_url %q: expected an https:// clone URL", config.RepositoryURL)
+	}
+	if u.User != nil {
+		return errors.New("repository_url must not contain credentials; use a GIT credential instead")
+	}
+	if config.Branch != "" && config.Tag != "" {
+		return errors.New("set either branch or tag, not both")
+	}
+	for _, ref := range []string{config.Branch, config.Tag} {
+		if strings.ContainsAny(ref, " ~^:?*[\\") || strings.Contains(ref, "..") {
+			return fmt.Errorf("invalid branch or tag name %q", ref)
+		}
+	}
+	filter := gitrepo.Filter{Include: config.Include, Exclude: config.Exclude}
+	return filter.ValidatePatterns()
+}
+
+// TestConnection checks that a token is present. Tokens are not bound to a repository, so access
+// is only verified when a knowledge base using the credential syncs.
+func (g *GitIntegration) TestConnection(ctx context.Context, decryptedCreds integration_models.DecryptedCredentials) (*integration_models.TestConnectionResult, error) {
+	if token, ok := decryptedCreds["token"]; !ok || strings.TrimSpace(token) == "" {
+		return &integration_models.TestConnectionResult{
+			Success: false,
+			Message: "Missing or empty 'token' in credentials",
+		}, nil
+	}
+	return &integration_models.TestConnectionResult{
+		Success: true,
+		Message: "Token present; repository access is checked on sync",
+	}, nil
+}
+
+// GetCredentialSchema returns the expected structure for Git credentials.
+func (g *GitIntegration) GetCredentialSchema() interface{} {
+	return integration_models.GitCredentials{}
+}
+
+// KnowledgeBaseCredentialPolicy allows an optional GIT credential, for private repositories.
+func (g *GitIntegration) KnowledgeBaseCredentialPolicy() CredentialPolicy {
+	return CredentialOptional
+}
+
+// ListDocuments fetches the repository and reads every selected file of the branch or tag, keyed by
+// path. The cursor records the commit and the include and exclude globs.
+func (g *GitIntegration) ListDocuments(ctx context.Context, kb SourceKnowledgeBase) (*DocumentList, error) {
+	config, snap, err := g.snapshot(ctx, kb)
+	if err != nil {
+		return nil, err
+	}
+	filter := gitFilter(config)
+	files, err := snap.Files(filter)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("[GitIntegration] ListDocuments: %s at %s (%s) has %d selected files for KB %s",
+		config.RepositoryURL, snap.Ref, snap.Commit(), len(files), kb.ID)
+
+	list := &DocumentList{Cursor: gitCursor(snap.Commit(), filter)}
+	for _, f := range files {
+		doc, err := gitDocument(config, snap, f)
+		if err != nil {
+			log.Printf("WARN [GitIntegration] Skipping %s in KB %s: %v", f.Path, kb.ID, err)
+			list.Skipped++
+			continue
+		}
+		list.Documents = append(list.Documents, DocumentRef{SourceID: f.Path, Document: doc})
+	}
+	return list, nil
+}
+
+// FetchDocument reads a file at the current commit of the branch or tag.
+func (g *GitIntegration) FetchDocument(ctx context.Context, kb SourceKnowledgeBase, ref DocumentRef) (*SourceDocument, error) {
+	config, snap, err := g.snapshot(ctx, kb)
+	if err != nil {
+		return nil, err
+	}
+	f, err := snap.File(ref.SourceID)
+	if err != nil {
+		return nil, err
+	}
+	return gitDocument(config, snap, *f)
+}
+
+// ChangesSince diffs the cursor's commit against the current one and reads only the added and changed
+// files. It returns ErrFullSyncRequired when the globs changed since the cursor, or the commit is no
+// longer in the repository, e.g. after a force push.
+func (g *GitIntegration) ChangesSince(ctx context.Context, kb SourceKnowledgeBase, cursor string) (*DocumentChanges, error) {
+	config, snap, err := g.snapshot(ctx, kb)
+	if err != nil {
+		return nil, err
+	}
+	filter := gitFilter(config)
+	commit, fingerprint, ok := strings.Cut(cursor, ":")
+	if !ok || fingerprint != filterFingerprint(filter) {
+		return nil, ErrFullSyncRequired
+	}
+
+	diff, err := snap.ChangesSince(commit, filter)
+	if err != nil {
+		if errors.Is(err, gitrepo.ErrUnknownCommit) {
+			log.Printf("WARN [GitIntegration] Last synced commit %s of KB %s is gone", commit, kb.ID)
+			return nil, ErrFullSyncRequired
+		}
+		return nil, err
+	}
+	log.Printf("[GitIntegration] ChangesSince: %s %s..%s changed %d and deleted %d selected files for KB %s",
+		config.RepositoryURL, commit, snap.Commit(), len(diff.Updated), len(diff.Deleted), kb.ID)
+
+	changes := &DocumentChanges{Deleted: diff.Deleted, Cursor: gitCursor(snap.Commit(), filter)}
+	for _, f := range diff.Updated {
+		doc, err := gitDocument(config, snap, f)
+		if err != nil {
+			log.Printf("WARN [GitIntegration] Skipping %s in KB %s: %v", f.Path, kb.ID, err)
+			changes.Skipped++
+			changes.Deleted = append(changes.Deleted, f.Path) // Drop the stored version, if any
+			continue
+		}
+		changes.Updated = append(changes.Updated, DocumentRef{SourceID: f.Path, Document: doc})
+	}
+	return changes, nil
+}
+
+// snapshot fetches the repository of a knowledge base, authenticating with its credential if it has one.
+func (g *GitIntegration) snapshot(ctx context.Context, kb SourceKnowledgeBase) (*integration_models.GitKBConfig, *gitrepo.Snapshot, error) {
+	var config integration_models.GitKBConfig
+	if len(kb.Configuration) > 0 && string(kb.Configuration) != "null" {
+		if err := json.Unmarshal(kb.Configuration, &config); err != nil {
+			return nil, nil, fmt.Errorf("invalid knowledge base configuration: %w", err)
+		}
+	}
+	if config.RepositoryURL == "" {
+		return nil, nil, errors.New("git knowledge base has no repository_url")
+	}
+
+	src := gitrepo.Source{URL: config.RepositoryURL, Branch: config.Branch, Tag: config.Tag}
+	if len(kb.Credentials) > 0 {
+		var creds integration_models.GitCredentials
+		if err := json.Unmarshal(kb.Credentials, &creds); err != nil {
+			return nil, nil, fmt.Errorf("failed to parse Git credential: %w", err)
+		}
+		if creds.Token == "" {
+			return nil, nil, errors.New("git credential has no token")
+		}
+		src.Token, src.Username = creds.Token, creds.Username
+	}
+
+	snap, err := g.fetcher.Fetch(ctx, kb.ID.String(), src)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &config, snap, nil
+}
+
+func gitFilter(config *integration_models.GitKBConfig) gitrepo.Filter {
+	return gitrepo.Filter{Include: config.Include, Exclude: config.Exclude}
+}
+
+// gitCursor identifies a synced commit and the globs it was synced with, so that changing the globs
+// triggers a full sync.
+func gitCursor(commit string, filter gitrepo.Filter) string {
+	return commit + ":" + filterFingerprint(filter)
+}
+
+func filterFingerprint(filter gitrepo.Filter) string {
+	sum := sha256.Sum256([]byte(strings.Join(filter.Include, "\x00") + "\x01" + strings.Join(filter.Exclude, "\x00")))
+	return hex.EncodeToString(sum[:8])
+}
+
+// gitDocument extracts the text of a repository file. Text files of formats extract does not know,
+// such as reStructuredText or MDX, are indexed as plain text.
+func gitDocument(config *integration_models.GitKBConfig, snap *gitrepo.Snapshot, f gitrepo.File) (*SourceDocument, error) {
+	doc, err := extract.FromFile(f.Path, f.Data)
+	if errors.Is(err, extract.ErrUnsupportedFormat) {
+		doc, err = plainTextDocument(f)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	committedAt := snap.CommittedAt()
+	return &SourceDocument{
+		SourceID:  f.Path,
+		Title:     doc.Title,
+		URL:       gitrepo.FileURL(config.RepositoryURL, snap.Ref, f.Path),
+		Content:   doc.Content,
+		UpdatedAt: &committedAt,
+		Metadata: map[string]interface{}{
+			"path":   f.Path,
+			"ref":    snap.Ref,
+			"commit": snap.Commit(),
+		},
+	}, nil
+}
+
+func plainTextDocument(f gitrepo.File) (*extract.Document, error) {
+	if !utf8.Valid(f.Data) || bytes.IndexByte(f.Data, 0) >= 0 {
+		return nil, errors.New("not a text file")
+	}
+	content := strings.TrimSpace(strings.ReplaceAll(string(f.Data), "\r\n", "\n"))
+	if content == "" {
+		return nil, extract.ErrNoText
+	}
+	name := path.Base(f.Path)
+	return &extract.Document{
+		Title:   strings.TrimSuffix(name, path.Ext(name)),
+		Content: content,
+		Format:  extract.FormatText,
+	}, nil
+}
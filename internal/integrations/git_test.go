@@ -0,0 +1,119 @@
+package integrations
+
+import (
+	"buildmychat-backend/internal/integrations/gitrepo"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/google/uuid"
+)
+
+// commitFiles writes files into a working repository (nil removes a file) and commits them.
+func commitFiles(t *testing.T, repo *git.Repository, dir string, files map[string][]byte) {
+	t.Helper()
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, data := range files {
+		if data == nil {
+			if _, err := wt.Remove(name); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := wt.Add(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, err = wt.Commit("update", &git.CommitOptions{
+		Author: &object.Signature{Name: "Test", Email: "test@example.com", When: time.Now()},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func sourceIDs(refs []DocumentRef) string {
+	ids := make([]string, len(refs))
+	for i, ref := range refs {
+		ids[i] = ref.SourceID
+	}
+	sort.Strings(ids)
+	return strings.Join(ids, " ")
+}
+
+func TestGitKnowledgeSourceSyncsIncrementally(t *testing.T) {
+	dir := t.TempDir()
+	repo, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	commitFiles(t, repo, dir, map[string][]byte{
+		"README.md":      []byte("# Project\n\nOverview."),
+		"docs/setup.rst": []byte("Setup\n=====\n\nRun it."),
+		"docs/logo.txt":  {0x89, 'P', 'N', 'G', 0},
+		"main.go":        []byte("package main"),
+	})
+
+	source := NewGitIntegration(gitrepo.NewFetcher(t.TempDir()))
+	config, _ := json.Marshal(map[string]interface{}{"repository_url": dir})
+	kb := SourceKnowledgeBase{ID: uuid.New(), Configuration: config}
+	ctx := context.Background()
+
+	list, err := source.ListDocuments(ctx, kb)
+	if err != nil {
+		t.Fatalf("ListDocuments: %v", err)
+	}
+	if got := sourceIDs(list.Documents); got != "README.md docs/setup.rst" {
+		t.Errorf("listed %q, want README.md and docs/setup.rst", got)
+	}
+	if list.Skipped != 1 { // docs/logo.txt is binary
+		t.Errorf("Skipped = %d, want 1", list.Skipped)
+	}
+	for _, ref := range list.Documents {
+		if ref.SourceID == "docs/setup.rst" && (ref.Document.Title != "setup" || !strings.Contains(ref.Document.Content, "Run it.")) {
+			t.Errorf("unexpected document %+v", ref.Document)
+		}
+	}
+
+	commitFiles(t, repo, dir, map[string][]byte{
+		"README.md":      []byte("# Project\n\nNew overview."),
+		"docs/setup.rst": nil,
+		"docs/faq.md":    []byte("# FAQ"),
+	})
+	changes, err := source.ChangesSince(ctx, kb, list.Cursor)
+	if err != nil {
+		t.Fatalf("ChangesSince: %v", err)
+	}
+	if got := sourceIDs(changes.Updated); got != "README.md docs/faq.md" {
+		t.Errorf("updated %q, want README.md and docs/faq.md", got)
+	}
+	if strings.Join(changes.Deleted, " ") != "docs/setup.rst" {
+		t.Errorf("deleted %v, want docs/setup.rst", changes.Deleted)
+	}
+	if changes.Cursor == list.Cursor {
+		t.Error("cursor did not advance")
+	}
+
+	kb.Configuration, _ = json.Marshal(map[string]interface{}{"repository_url": dir, "include": []string{"docs/**"}})
+	if _, err := source.ChangesSince(ctx, kb, changes.Cursor); !errors.Is(err, ErrFullSyncRequired) {
+		t.Errorf("ChangesSince after changing include = %v, want ErrFullSyncRequired", err)
+	}
+}
@@ -0,0 +1,393 @@
+// Package gitrepo clones and fetches Git repositories and reads the files of a commit,
+// for Git knowledge bases.
+package gitrepo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bmatcuk/doublestar/v4"
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/go-git/go-git/v5/utils/merkletrie"
+)
+
+const (
+	remoteName      = "origin"
+	defaultUsername = "x-access-token" // Accepted with a token by GitHub; GitLab and others ignore the user name
+	MaxFileBytes    = 1 << 20          // Larger files are not indexed
+)
+
+// DefaultInclude are the globs used when a Filter has no Include patterns.
+var DefaultInclude = []string{"**/*.md", "**/*.mdx", "**/*.markdown", "**/*.txt", "**/*.rst", "**/*.html", "**/*.htm"}
+
+// ErrUnknownCommit is returned by ChangesSince when the earlier commit is not in the repository,
+// e.g. after a force push or when the repository URL changed.
+var ErrUnknownCommit = errors.New("commit not found in repository")
+
+// Source identifies a repository and the branch or tag to read.
+type Source struct {
+	URL      string // https:// URL, or a local path or file:// URL
+	Branch   string // Branch to read; the remote's default branch when Branch and Tag are empty
+	Tag      string // Tag to read instead of a branch
+	Token    string // Optional HTTPS token, sent as the basic auth password
+	Username string // User name sent with Token; defaults to x-access-token
+}
+
+// Filter selects repository files by path, with doublestar globs such as "docs/**/*.md".
+type Filter struct {
+	Include []string // Empty uses DefaultInclude
+	Exclude []string
+}
+
+// Match reports whether a slash-separated repository path is selected.
+func (f Filter) Match(path string) bool {
+	include := f.Include
+	if len(include) == 0 {
+		include = DefaultInclude
+	}
+	matched := false
+	for _, p := range include {
+		if ok, _ := doublestar.Match(p, path); ok {
+			matched = true
+			break
+		}
+	}
+	if !matched {
+		return false
+	}
+	for _, p := range f.Exclude {
+		if ok, _ := doublestar.Match(p, path); ok {
+			return false
+		}
+	}
+	return true
+}
+
+// ValidatePatterns checks that every glob of the filter is well-formed.
+func (f Filter) ValidatePatterns() error {
+	for _, p := range append(append([]string{}, f.Include...), f.Exclude...) {
+		if !doublestar.ValidatePattern(p) {
+			return fmt.Errorf("invalid glob pattern %q", p)
+		}
+	}
+	return nil
+}
+
+// File is a selected file of a commit.
+type File struct {
+	Path string // Slash-separated path within the repository
+	Data []byte
+}
+
+// Changes are the selected files that differ between two commits.
+type Changes struct {
+	Updated []File   // Added or modified
+	Deleted []string // Paths removed (renamed files appear as deleted and added)
+}
+
+// Fetcher keeps a bare clone per repository key under a cache directory, so that later syncs only
+// fetch new objects. Calls for the same key must not run concurrently.
+type Fetcher struct {
+	dir string
+}
+
+// NewFetcher creates a Fetcher storing clones under dir.
+func NewFetcher(dir string) *Fetcher {
+	return &Fetcher{dir: dir}
+}
+
+// Fetch clones the repository into the clone for key, or fetches into the existing clone, and
+// returns the commit the source's branch or tag points to.
+func (f *Fetcher) Fetch(ctx context.Context, key string, src Source) (*Snapshot, error) {
+	repo, err := f.open(key, src.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	auth := src.auth()
+	err = repo.FetchContext(ctx, &git.FetchOptions{
+		RemoteName: remoteName,
+		Auth:       auth,
+		Tags:       git.NoTags, // Tags are covered by the refspecs
+		Force:      true,
+	})
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return nil, fmt.Errorf("failed to fetch %s: %w", redactURL(src.URL), err)
+	}
+
+	refName, err := f.resolveRefName(ctx, repo, src, auth)
+	if err != nil {
+		return nil, err
+	}
+	ref, err := repo.Reference(refName, true)
+	if err != nil {
+		return nil, fmt.Errorf("ref %s not found in %s: %w", refName.Short(), redactURL(src.URL), err)
+	}
+	commit, err := commitOf(repo, ref.Hash())
+	if err != nil {
+		return nil, err
+	}
+	return &Snapshot{repo: repo, commit: commit, Ref: strings.TrimPrefix(refName.Short(), remoteName+"/")}, nil
+}
+
+// open opens the clone for key, creating it when missing or when it was cloned from another URL.
+func (f *Fetcher) open(key string, url string) (*git.Repository, error) {
+	path := filepath.Join(f.dir, key)
+	repo, err := git.PlainOpen(path)
+	if err == nil {
+		remote, err := repo.Remote(remoteName)
+		if err == nil && len(remote.Config().URLs) > 0 && remote.Config().URLs[0] == url {
+			return repo, nil
+		}
+		log.Printf("[GitFetcher] Repository URL of %s changed, cloning again", key)
+	} else if !errors.Is(err, git.ErrRepositoryNotExists) {
+		log.Printf("WARN [GitFetcher] Clone of %s is unreadable, cloning again: %v", key, err)
+	}
+
+	if err := os.RemoveAll(path); err != nil {
+		return nil, fmt.Errorf("failed to reset clone directory: %w", err)
+	}
+	repo, err = git.PlainInit(path, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create clone directory: %w", err)
+	}
+	_, err = repo.CreateRemote(&config.RemoteConfig{
+		Name: remoteName,
+		URLs: []string{url},
+		Fetch: []config.RefSpec{
+			"+refs/heads/*:refs/remotes/origin/*",
+			"+refs/tags/*:refs/tags/*",
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure remote: %w", err)
+	}
+	return repo, nil
+}
+
+// resolveRefName returns the local ref of the source's tag or branch. Without either, the
+// remote's HEAD decides the branch.
+func (f *Fetcher) resolveRefName(ctx context.Context, repo *git.Repository, src Source, auth transport.AuthMethod) (plumbing.ReferenceName, error) {
+	if src.Tag != "" {
+		return plumbing.NewTagReferenceName(src.Tag), nil
+	}
+	if src.Branch != "" {
+		return plumbing.NewRemoteReferenceName(remoteName, src.Branch), nil
+	}
+
+	remote, err := repo.Remote(remoteName)
+	if err != nil {
+		return "", err
+	}
+	refs, err := remote.ListContext(ctx, &git.ListOptions{Auth: auth})
+	if err != nil {
+		return "", fmt.Errorf("failed to list refs of %s: %w", redactURL(src.URL), err)
+	}
+	for _, ref := range refs {
+		if ref.Name() == plumbing.HEAD && ref.Type() == plumbing.SymbolicReference && ref.Target().IsBranch() {
+			return plumbing.NewRemoteReferenceName(remoteName, ref.Target().Short()), nil
+		}
+	}
+	for _, branch := range []string{"main", "master"} {
+		name := plumbing.NewRemoteReferenceName(remoteName, branch)
+		if _, err := repo.Reference(name, false); err == nil {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("could not determine the default branch of %s; set a branch", redactURL(src.URL))
+}
+
+func (src Source) auth() transport.AuthMethod {
+	if src.Token == "" {
+		return nil
+	}
+	username := src.Username
+	if username == "" {
+		username = defaultUsername
+	}
+	return &githttp.BasicAuth{Username: username, Password: src.Token}
+}
+
+// Snapshot is the commit of a fetched branch or tag.
+type Snapshot struct {
+	repo   *git.Repository
+	commit *object.Commit
+	Ref    string // Branch or tag name, e.g. "main" or "v1.2"
+}
+
+// Commit returns the hash of the snapshot's commit.
+func (s *Snapshot) Commit() string {
+	return s.commit.Hash.String()
+}
+
+// CommittedAt returns the committer time of the snapshot's commit, in UTC.
+func (s *Snapshot) CommittedAt() time.Time {
+	return s.commit.Committer.When.UTC()
+}
+
+// Files returns every selected file of the commit. Files over MaxFileBytes are skipped.
+func (s *Snapshot) Files(filter Filter) ([]File, error) {
+	tree, err := s.commit.Tree()
+	if err != nil {
+		return nil, err
+	}
+	var files []File
+	err = tree.Files().ForEach(func(f *object.File) error {
+		if !filter.Match(f.Name) {
+			return nil
+		}
+		file, ok, err := readFile(f.Name, f)
+		if err != nil || !ok {
+			return err
+		}
+		files = append(files, file)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read files of commit %s: %w", s.Commit(), err)
+	}
+	return files, nil
+}
+
+// File returns a file of the commit. It returns an error for missing files and files over MaxFileBytes.
+func (s *Snapshot) File(path string) (*File, error) {
+	f, err := s.commit.File(path)
+	if err != nil {
+		return nil, fmt.Errorf("%s not found at %s: %w", path, s.Ref, err)
+	}
+	file, ok, err := readFile(path, f)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("%s is larger than %d bytes", path, MaxFileBytes)
+	}
+	return &file, nil
+}
+
+// ChangesSince returns the selected files that changed between the commit since and the snapshot.
+// It returns ErrUnknownCommit when since is not in the repository.
+func (s *Snapshot) ChangesSince(since string, filter Filter) (*Changes, error) {
+	old, err := commitOf(s.repo, plumbing.NewHash(since))
+	if err != nil {
+		return nil, ErrUnknownCommit
+	}
+	oldTree, err := old.Tree()
+	if err != nil {
+		return nil, err
+	}
+	newTree, err := s.commit.Tree()
+	if err != nil {
+		return nil, err
+	}
+	diff, err := object.DiffTree(oldTree, newTree)
+	if err != nil {
+		return nil, fmt.Errorf("failed to diff %s..%s: %w", since, s.Commit(), err)
+	}
+
+	changes := &Changes{}
+	for _, change := range diff {
+		action, err := change.Action()
+		if err != nil {
+			return nil, err
+		}
+		if action == merkletrie.Delete {
+			if filter.Match(change.From.Name) {
+				changes.Deleted = append(changes.Deleted, change.From.Name)
+			}
+			continue
+		}
+		if !filter.Match(change.To.Name) {
+			continue
+		}
+		_, to, err := change.Files()
+		if err != nil {
+			return nil, err
+		}
+		file, ok, err := readFile(change.To.Name, to) // to.Name is only the base name
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			changes.Deleted = append(changes.Deleted, change.To.Name) // Grew over the size limit
+			continue
+		}
+		changes.Updated = append(changes.Updated, file)
+	}
+	return changes, nil
+}
+
+// readFile reads the content of the file at path. It returns false for files over MaxFileBytes and
+// non-regular files.
+func readFile(path string, f *object.File) (File, bool, error) {
+	if !f.Mode.IsFile() || f.Size > MaxFileBytes {
+		return File{}, false, nil
+	}
+	r, err := f.Reader()
+	if err != nil {
+		return File{}, false, err
+	}
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return File{}, false, err
+	}
+	return File{Path: path, Data: data}, true, nil
+}
+
+// commitOf returns the commit of a hash, peeling annotated tags.
+func commitOf(repo *git.Repository, hash plumbing.Hash) (*object.Commit, error) {
+	if tag, err := repo.TagObject(hash); err == nil {
+		c, err := tag.Commit()
+		if err != nil {
+			return nil, fmt.Errorf("tag %s does not point to a commit: %w", tag.Name, err)
+		}
+		return c, nil
+	}
+	return repo.CommitObject(hash)
+}
+
+// FileURL returns the web page of a file on GitHub, GitLab or Bitbucket, or "" for other hosts.
+func FileURL(repoURL, ref, path string) string {
+	base, ok := strings.CutPrefix(repoURL, "https://")
+	if !ok {
+		return ""
+	}
+	if at := strings.Index(base, "@"); at >= 0 && at < strings.Index(base+"/", "/") {
+		base = base[at+1:]
+	}
+	base = strings.TrimSuffix(strings.TrimSuffix(base, "/"), ".git")
+	host, _, _ := strings.Cut(base, "/")
+	switch {
+	case host == "github.com":
+		return "https://" + base + "/blob/" + ref + "/" + path
+	case host == "gitlab.com" || strings.HasPrefix(host, "gitlab."):
+		return "https://" + base + "/-/blob/" + ref + "/" + path
+	case host == "bitbucket.org":
+		return "https://" + base + "/src/" + ref + "/" + path
+	default:
+		return ""
+	}
+}
+
+// redactURL removes user info from a URL for error messages.
+func redactURL(url string) string {
+	if scheme, rest, ok := strings.Cut(url, "://"); ok {
+		if at := strings.LastIndex(rest, "@"); at >= 0 && at < strings.IndexAny(rest+"/", "/") {
+			return scheme + "://" + rest[at+1:]
+		}
+	}
+	return url
+}
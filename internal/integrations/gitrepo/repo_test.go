@@ -0,0 +1,229 @@
+package gitrepo
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// origin is a bare repository fed by pushes from a working repository, standing in for a hosted remote.
+type origin struct {
+	t    *testing.T
+	url  string
+	work *git.Repository
+	dir  string
+}
+
+func newOrigin(t *testing.T) *origin {
+	t.Helper()
+	bare := filepath.Join(t.TempDir(), "origin.git")
+	if _, err := git.PlainInit(bare, true); err != nil {
+		t.Fatalf("init bare: %v", err)
+	}
+	dir := t.TempDir()
+	work, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatalf("init work: %v", err)
+	}
+	if _, err := work.CreateRemote(&config.RemoteConfig{Name: "origin", URLs: []string{bare}}); err != nil {
+		t.Fatalf("create remote: %v", err)
+	}
+	return &origin{t: t, url: bare, work: work, dir: dir}
+}
+
+// commit writes files (a nil value removes the file), commits and pushes, and returns the commit hash.
+func (o *origin) commit(files map[string][]byte) string {
+	o.t.Helper()
+	wt, err := o.work.Worktree()
+	if err != nil {
+		o.t.Fatal(err)
+	}
+	for name, data := range files {
+		path := filepath.Join(o.dir, filepath.FromSlash(name))
+		if data == nil {
+			if _, err := wt.Remove(name); err != nil {
+				o.t.Fatalf("remove %s: %v", name, err)
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			o.t.Fatal(err)
+		}
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			o.t.Fatal(err)
+		}
+		if _, err := wt.Add(name); err != nil {
+			o.t.Fatalf("add %s: %v", name, err)
+		}
+	}
+	hash, err := wt.Commit("update", &git.CommitOptions{
+		Author: &object.Signature{Name: "Test", Email: "test@example.com", When: time.Now()},
+	})
+	if err != nil {
+		o.t.Fatalf("commit: %v", err)
+	}
+	err = o.work.Push(&git.PushOptions{RemoteName: "origin", RefSpecs: []config.RefSpec{"+refs/heads/*:refs/heads/*", "+refs/tags/*:refs/tags/*"}})
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		o.t.Fatalf("push: %v", err)
+	}
+	return hash.String()
+}
+
+func paths(files []File) []string {
+	out := make([]string, len(files))
+	for i, f := range files {
+		out[i] = f.Path
+	}
+	sort.Strings(out)
+	return out
+}
+
+func TestFetchListsFilteredFiles(t *testing.T) {
+	o := newOrigin(t)
+	first := o.commit(map[string][]byte{
+		"README.md":           []byte("# Project"),
+		"docs/setup.md":       []byte("# Setup"),
+		"docs/drafts/wip.md":  []byte("# WIP"),
+		"docs/api.txt":        []byte("API notes"),
+		"main.go":             []byte("package main"),
+		"docs/huge.md":        []byte(strings.Repeat("x", MaxFileBytes+1)),
+		"docs/nested/deep.md": []byte("# Deep"),
+	})
+
+	fetcher := NewFetcher(t.TempDir())
+	snap, err := fetcher.Fetch(context.Background(), "kb", Source{URL: o.url})
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	if snap.Commit() != first || snap.Ref != "master" {
+		t.Errorf("Commit = %s (%s), want %s (master, the remote HEAD)", snap.Commit(), snap.Ref, first)
+	}
+
+	files, err := snap.Files(Filter{Include: []string{"docs/**/*.md"}, Exclude: []string{"docs/drafts/**"}})
+	if err != nil {
+		t.Fatalf("Files: %v", err)
+	}
+	want := []string{"docs/nested/deep.md", "docs/setup.md"}
+	if got := paths(files); strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("files = %v, want %v", got, want)
+	}
+
+	files, err = snap.Files(Filter{})
+	if err != nil {
+		t.Fatalf("Files: %v", err)
+	}
+	want = []string{"README.md", "docs/api.txt", "docs/drafts/wip.md", "docs/nested/deep.md", "docs/setup.md"}
+	if got := paths(files); strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("files with default filter = %v, want %v", got, want)
+	}
+}
+
+func TestChangesSinceDiffsCommits(t *testing.T) {
+	o := newOrigin(t)
+	first := o.commit(map[string][]byte{
+		"a.md":    []byte("# A"),
+		"b.md":    []byte("# B"),
+		"c.md":    []byte("# C"),
+		"main.go": []byte("package main"),
+	})
+
+	fetcher := NewFetcher(t.TempDir())
+	if _, err := fetcher.Fetch(context.Background(), "kb", Source{URL: o.url}); err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+
+	second := o.commit(map[string][]byte{
+		"a.md":      []byte("# A, edited"),
+		"b.md":      nil,
+		"docs/d.md": []byte("# D"),
+		"main.go":   []byte("package main // edited"),
+	})
+	snap, err := fetcher.Fetch(context.Background(), "kb", Source{URL: o.url, Branch: "master"})
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	if snap.Commit() != second {
+		t.Fatalf("Commit = %s, want %s", snap.Commit(), second)
+	}
+
+	changes, err := snap.ChangesSince(first, Filter{})
+	if err != nil {
+		t.Fatalf("ChangesSince: %v", err)
+	}
+	if got := paths(changes.Updated); strings.Join(got, " ") != "a.md docs/d.md" {
+		t.Errorf("updated = %v, want [a.md docs/d.md]", got)
+	}
+	if strings.Join(changes.Deleted, " ") != "b.md" {
+		t.Errorf("deleted = %v, want [b.md]", changes.Deleted)
+	}
+	for _, f := range changes.Updated {
+		if f.Path == "a.md" && string(f.Data) != "# A, edited" {
+			t.Errorf("a.md = %q, want the edited content", f.Data)
+		}
+	}
+
+	if _, err := snap.ChangesSince(strings.Repeat("0", 40), Filter{}); !errors.Is(err, ErrUnknownCommit) {
+		t.Errorf("ChangesSince unknown commit error = %v, want ErrUnknownCommit", err)
+	}
+}
+
+func TestFetchResolvesTags(t *testing.T) {
+	o := newOrigin(t)
+	tagged := o.commit(map[string][]byte{"guide.md": []byte("# v1")})
+	if _, err := o.work.CreateTag("v1.0", mustHead(t, o.work), &git.CreateTagOptions{
+		Tagger:  &object.Signature{Name: "Test", Email: "test@example.com", When: time.Now()},
+		Message: "v1.0",
+	}); err != nil {
+		t.Fatalf("tag: %v", err)
+	}
+	o.commit(map[string][]byte{"guide.md": []byte("# v2")})
+
+	snap, err := NewFetcher(t.TempDir()).Fetch(context.Background(), "kb", Source{URL: o.url, Tag: "v1.0"})
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	if snap.Commit() != tagged || snap.Ref != "v1.0" {
+		t.Errorf("Commit = %s (%s), want the tagged commit %s (v1.0)", snap.Commit(), snap.Ref, tagged)
+	}
+
+	if _, err := NewFetcher(t.TempDir()).Fetch(context.Background(), "kb", Source{URL: o.url, Branch: "missing"}); err == nil {
+		t.Error("Fetch of a missing branch succeeded, want an error")
+	}
+}
+
+func mustHead(t *testing.T, repo *git.Repository) plumbing.Hash {
+	t.Helper()
+	head, err := repo.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return head.Hash()
+}
+
+func TestFileURL(t *testing.T) {
+	tests := []struct {
+		repo, want string
+	}{
+		{"https://github.com/acme/docs.git", "https://github.com/acme/docs/blob/main/guide/setup.md"},
+		{"https://token@github.com/acme/docs/", "https://github.com/acme/docs/blob/main/guide/setup.md"},
+		{"https://gitlab.com/acme/team/docs", "https://gitlab.com/acme/team/docs/-/blob/main/guide/setup.md"},
+		{"https://bitbucket.org/acme/docs.git", "https://bitbucket.org/acme/docs/src/main/guide/setup.md"},
+		{"https://git.example.com/acme/docs.git", ""},
+		{"/srv/git/docs.git", ""},
+	}
+	for _, tt := range tests {
+		if got := FileURL(tt.repo, "main", "guide/setup.md"); got != tt.want {
+			t.Errorf("FileURL(%q) = %q, want %q", tt.repo, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package notion
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/jomei/notionapi"
+)
+
+// decodeBlocks parses blocks the way the Notion API returns them.
+func decodeBlocks(t *testing.T, raw string) []*Node {
+	t.Helper()
+	var blocks notionapi.Blocks
+	if err := json.Unmarshal([]byte(raw), &blocks); err != nil {
+		t.Fatalf("decode blocks: %v", err)
+	}
+	nodes := make([]*Node, len(blocks))
+	for i, block := range blocks {
+		nodes[i] = &Node{Block: block}
+	}
+	return nodes
+}
+
+const sampleBlocks = `[
+	{"object":"block","id":"1","type":"heading_1","heading_1":{"rich_text":[{"type":"text","plain_text":"Opening hours"}]}},
+	{"object":"block","id":"2","type":"paragraph","paragraph":{"rich_text":[
+		{"type":"text","plain_text":"We are open "},
+		{"type":"text","plain_text":"every day","annotations":{"bold":true}},
+		{"type":"text","plain_text":" except holidays."}]}},
+	{"object":"block","id":"3","type":"numbered_list_item","numbered_list_item":{"rich_text":[{"type":"text","plain_text":"Mon-Fri: 9-17"}]}},
+	{"object":"block","id":"4","type":"numbered_list_item","numbered_list_item":{"rich_text":[{"type":"text","plain_text":"Sat-Sun: 10-14"}]}},
+	{"object":"block","id":"5","type":"paragraph","paragraph":{"rich_text":[{"type":"text","plain_text":"See the ","href":""},{"type":"text","plain_text":"calendar","href":"https://example.com/cal"}]}},
+	{"object":"block","id":"6","type":"code","code":{"language":"bash","rich_text":[{"type":"text","plain_text":"curl example.com"}]}}
+]`
+
+func TestRenderMarkdown(t *testing.T) {
+	got := Renderer{}.Render(decodeBlocks(t, sampleBlocks))
+	want := "# Opening hours\n\n" +
+		"We are open **every day** except holidays.\n\n" +
+		"1. Mon-Fri: 9-17\n" +
+		"2. Sat-Sun: 10-14\n\n" +
+		"See the [calendar](https://example.com/cal)\n\n" +
+		"```bash\ncurl example.com\n```\n"
+	if got != want {
+		t.Errorf("unexpected markdown:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestRenderPlain(t *testing.T) {
+	got := Renderer{Plain: true}.Render(decodeBlocks(t, sampleBlocks))
+	want := "Opening hours\n\n" +
+		"We are open every day except holidays.\n\n" +
+		"1. Mon-Fri: 9-17\n" +
+		"2. Sat-Sun: 10-14\n\n" +
+		"See the calendar\n\n" +
+		"curl example.com\n"
+	if got != want {
+		t.Errorf("unexpected plain text:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestRenderNestedList(t *testing.T) {
+	nodes := decodeBlocks(t, `[
+		{"object":"block","id":"1","type":"bulleted_list_item","bulleted_list_item":{"rich_text":[{"type":"text","plain_text":"Parent"}]}},
+		{"object":"block","id":"2","type":"paragraph","paragraph":{"rich_text":[{"type":"text","plain_text":"After"}]}}
+	]`)
+	nodes[0].Children = decodeBlocks(t, `[
+		{"object":"block","id":"3","type":"to_do","to_do":{"checked":true,"rich_text":[{"type":"text","plain_text":"Child"}]}}
+	]`)
+
+	got := Renderer{}.Render(nodes)
+	want := "- Parent\n  - [x] Child\n\nAfter\n"
+	if got != want {
+		t.Errorf("unexpected nested list:\n%q\nwant:\n%q", got, want)
+	}
+}
+
+func TestNormalizeID(t *testing.T) {
+	const want = "0123456789abcdef0123456789abcdef"
+	tests := []struct{ in, want string }{
+		{"0123456789abcdef0123456789ABCDEF", want},
+		{"01234567-89ab-cdef-0123-456789abcdef", want},
+		{"https://www.notion.so/acme/FAQ-0123456789abcdef0123456789abcdef?pvs=4", want},
+		{"not-an-id", ""},
+		{"https://www.notion.so/acme/Page-zzzz456789abcdef0123456789abcdef", ""},
+	}
+	for _, tt := range tests {
+		if got := NormalizeID(tt.in); got != tt.want {
+			t.Errorf("NormalizeID(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSelectPages(t *testing.T) {
+	const (
+		root     = "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+		database = "bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb"
+		row      = "cccccccccccccccccccccccccccccccc"
+		column   = "dddddddddddddddddddddddddddddddd" // A block between a page and its sub-page
+		nested   = "eeeeeeeeeeeeeeeeeeeeeeeeeeeeeeee"
+		other    = "ffffffffffffffffffffffffffffffff"
+	)
+	parents := map[string]string{
+		root:     "",
+		database: root,
+		row:      database,
+		column:   root,
+		nested:   column,
+		other:    "",
+	}
+	pages := []PageRef{{ID: root}, {ID: row}, {ID: nested}, {ID: other}}
+	parentOf := func(id string) (string, error) { return parents[id], nil }
+
+	selected, err := selectPages(pages, map[string]bool{database: true}, parentOf)
+	if err != nil {
+		t.Fatalf("selectPages returned error: %v", err)
+	}
+	if len(selected) != 1 || selected[0].ID != row {
+		t.Errorf("database selection: got %+v, want only the row", selected)
+	}
+
+	selected, err = selectPages(pages, map[string]bool{root: true}, parentOf)
+	if err != nil {
+		t.Fatalf("selectPages returned error: %v", err)
+	}
+	if len(selected) != 3 {
+		t.Errorf("root selection: got %+v, want root, row and nested page", selected)
+	}
+}
@@ -0,0 +1,292 @@
+package notion
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jomei/notionapi"
+)
+
+// Node is a Notion block together with its (already fetched) children.
+type Node struct {
+	Block    notionapi.Block
+	Children []*Node
+}
+
+// Renderer converts Notion block trees to text.
+type Renderer struct {
+	// Plain renders plain text instead of Markdown: no emphasis, heading markers or code fences.
+	Plain bool
+}
+
+// Render converts a page's block tree into a single document.
+func (r Renderer) Render(nodes []*Node) string {
+	var b strings.Builder
+	r.renderNodes(&b, nodes, 0)
+	return strings.TrimSpace(b.String()) + "\n"
+}
+
+// RichText converts Notion rich text to Markdown (or plain text when r.Plain is set).
+func (r Renderer) RichText(parts []notionapi.RichText) string {
+	var b strings.Builder
+	for _, part := range parts {
+		text := part.PlainText
+		if text == "" && part.Text != nil {
+			text = part.Text.Content
+		}
+		if r.Plain || text == "" {
+			b.WriteString(text)
+			continue
+		}
+
+		if a := part.Annotations; a != nil && strings.TrimSpace(text) != "" {
+			switch {
+			case a.Code:
+				text = "`" + text + "`"
+			default:
+				if a.Bold {
+					text = "**" + text + "**"
+				}
+				if a.Italic {
+					text = "_" + text + "_"
+				}
+				if a.Strikethrough {
+					text = "~~" + text + "~~"
+				}
+			}
+		}
+		if href := richTextHref(part); href != "" {
+			text = "[" + text + "](" + href + ")"
+		}
+		b.WriteString(text)
+	}
+	return b.String()
+}
+
+func richTextHref(part notionapi.RichText) string {
+	if part.Href != "" {
+		return part.Href
+	}
+	if part.Text != nil && part.Text.Link != nil {
+		return part.Text.Link.Url
+	}
+	return ""
+}
+
+// renderNodes renders sibling blocks. Consecutive numbered list items are numbered in sequence,
+// and a blank line separates a run of list items from the block after it.
+func (r Renderer) renderNodes(b *strings.Builder, nodes []*Node, depth int) {
+	number := 0
+	inList := false
+	for _, node := range nodes {
+		if _, ok := node.Block.(*notionapi.NumberedListItemBlock); ok {
+			number++
+		} else {
+			number = 0
+		}
+
+		listItem := isListItem(node.Block)
+		if inList && !listItem {
+			b.WriteString("\n")
+		}
+		inList = listItem
+
+		r.renderNode(b, node, depth, number)
+	}
+	if inList {
+		b.WriteString("\n")
+	}
+}
+
+func isListItem(block notionapi.Block) bool {
+	switch block.(type) {
+	case *notionapi.BulletedListItemBlock, *notionapi.NumberedListItemBlock, *notionapi.ToDoBlock, *notionapi.ToggleBlock:
+		return true
+	}
+	return false
+}
+
+// renderNode renders one block and its children. depth is the list nesting level.
+func (r Renderer) renderNode(b *strings.Builder, node *Node, depth, number int) {
+	indent := strings.Repeat("  ", depth)
+
+	switch block := node.Block.(type) {
+	case *notionapi.ParagraphBlock:
+		r.writeParagraph(b, indent, r.RichText(block.Paragraph.RichText))
+		r.renderNodes(b, node.Children, depth)
+
+	case *notionapi.Heading1Block:
+		r.writeHeading(b, 1, r.RichText(block.Heading1.RichText))
+		r.renderNodes(b, node.Children, depth)
+	case *notionapi.Heading2Block:
+		r.writeHeading(b, 2, r.RichText(block.Heading2.RichText))
+		r.renderNodes(b, node.Children, depth)
+	case *notionapi.Heading3Block:
+		r.writeHeading(b, 3, r.RichText(block.Heading3.RichText))
+		r.renderNodes(b, node.Children, depth)
+
+	case *notionapi.BulletedListItemBlock:
+		r.writeListItem(b, indent, "- ", r.RichText(block.BulletedListItem.RichText), node, depth)
+	case *notionapi.NumberedListItemBlock:
+		r.writeListItem(b, indent, fmt.Sprintf("%d. ", number), r.RichText(block.NumberedListItem.RichText), node, depth)
+	case *notionapi.ToDoBlock:
+		marker := "- [ ] "
+		if block.ToDo.Checked {
+			marker = "- [x] "
+		}
+		r.writeListItem(b, indent, marker, r.RichText(block.ToDo.RichText), node, depth)
+	case *notionapi.ToggleBlock:
+		r.writeListItem(b, indent, "- ", r.RichText(block.Toggle.RichText), node, depth)
+
+	case *notionapi.QuoteBlock:
+		r.writeQuote(b, indent, r.RichText(block.Quote.RichText))
+		r.renderNodes(b, node.Children, depth)
+	case *notionapi.CalloutBlock:
+		text := r.RichText(block.Callout.RichText)
+		if block.Callout.Icon != nil && block.Callout.Icon.Emoji != nil {
+			text = string(*block.Callout.Icon.Emoji) + " " + text
+		}
+		r.writeQuote(b, indent, text)
+		r.renderNodes(b, node.Children, depth)
+
+	case *notionapi.CodeBlock:
+		code := r.RichText(block.Code.RichText)
+		if r.Plain {
+			r.writeParagraph(b, indent, code)
+		} else {
+			fmt.Fprintf(b, "%s```%s\n%s\n%s```\n\n", indent, block.Code.Language, code, indent)
+		}
+	case *notionapi.EquationBlock:
+		if r.Plain {
+			r.writeParagraph(b, indent, block.Equation.Expression)
+		} else {
+			r.writeParagraph(b, indent, "$$"+block.Equation.Expression+"$$")
+		}
+	case *notionapi.DividerBlock:
+		if !r.Plain {
+			b.WriteString("---\n\n")
+		}
+
+	case *notionapi.TableBlock: // Rows are the children
+		r.writeTable(b, node.Children)
+
+	case *notionapi.BookmarkBlock:
+		r.writeLink(b, indent, r.RichText(block.Bookmark.Caption), block.Bookmark.URL)
+	case *notionapi.EmbedBlock:
+		r.writeLink(b, indent, r.RichText(block.Embed.Caption), block.Embed.URL)
+	case *notionapi.LinkPreviewBlock:
+		r.writeLink(b, indent, "", block.LinkPreview.URL)
+
+	// File-like blocks: their URLs are signed and expire, so only captions are kept.
+	case *notionapi.ImageBlock:
+		r.writeCaption(b, indent, "Image", r.RichText(block.Image.Caption))
+	case *notionapi.VideoBlock:
+		r.writeCaption(b, indent, "Video", r.RichText(block.Video.Caption))
+	case *notionapi.FileBlock:
+		r.writeCaption(b, indent, "File", r.RichText(block.File.Caption))
+	case *notionapi.PdfBlock:
+		r.writeCaption(b, indent, "PDF", r.RichText(block.Pdf.Caption))
+
+	// Sub-pages and databases are ingested as their own documents; only reference them here.
+	case *notionapi.ChildPageBlock:
+		r.writeParagraph(b, indent, "Page: "+block.ChildPage.Title)
+	case *notionapi.ChildDatabaseBlock:
+		r.writeParagraph(b, indent, "Database: "+block.ChildDatabase.Title)
+
+	case *notionapi.TableOfContentsBlock, *notionapi.BreadcrumbBlock, *notionapi.UnsupportedBlock:
+		// Navigation only, nothing to index
+
+	default:
+		// Containers (columns, synced blocks, templates) and unknown blocks: render whatever they hold
+		if text := node.Block.GetRichTextString(); strings.TrimSpace(text) != "" {
+			r.writeParagraph(b, indent, text)
+		}
+		r.renderNodes(b, node.Children, depth)
+	}
+}
+
+func (r Renderer) writeParagraph(b *strings.Builder, indent, text string) {
+	if strings.TrimSpace(text) == "" {
+		return
+	}
+	b.WriteString(indent + text + "\n\n")
+}
+
+func (r Renderer) writeHeading(b *strings.Builder, level int, text string) {
+	if strings.TrimSpace(text) == "" {
+		return
+	}
+	if !r.Plain {
+		text = strings.Repeat("#", level) + " " + text
+	}
+	b.WriteString(text + "\n\n")
+}
+
+// writeListItem writes a list line followed by its nested children one level deeper.
+// Nested content is kept tight (no blank lines) so it stays part of the item.
+func (r Renderer) writeListItem(b *strings.Builder, indent, marker, text string, node *Node, depth int) {
+	b.WriteString(indent + marker + text + "\n")
+	if len(node.Children) > 0 {
+		var children strings.Builder
+		r.renderNodes(&children, node.Children, depth+1)
+		b.WriteString(strings.ReplaceAll(strings.TrimRight(children.String(), "\n")+"\n", "\n\n", "\n"))
+	}
+}
+
+func (r Renderer) writeQuote(b *strings.Builder, indent, text string) {
+	if strings.TrimSpace(text) == "" {
+		return
+	}
+	if r.Plain {
+		r.writeParagraph(b, indent, text)
+		return
+	}
+	b.WriteString(indent + "> " + strings.ReplaceAll(text, "\n", "\n"+indent+"> ") + "\n\n")
+}
+
+func (r Renderer) writeLink(b *strings.Builder, indent, caption, url string) {
+	if url == "" {
+		return
+	}
+	if caption == "" {
+		caption = url
+	}
+	if r.Plain {
+		r.writeParagraph(b, indent, caption+" ("+url+")")
+		return
+	}
+	r.writeParagraph(b, indent, "["+caption+"]("+url+")")
+}
+
+func (r Renderer) writeCaption(b *strings.Builder, indent, kind, caption string) {
+	if strings.TrimSpace(caption) == "" {
+		return
+	}
+	r.writeParagraph(b, indent, kind+": "+caption)
+}
+
+// writeTable renders a table block. The first row is used as the header in Markdown.
+func (r Renderer) writeTable(b *strings.Builder, rows []*Node) {
+	wroteHeader := false
+	for _, rowNode := range rows {
+		row, ok := rowNode.Block.(*notionapi.TableRowBlock)
+		if !ok {
+			continue
+		}
+		cells := make([]string, 0, len(row.TableRow.Cells))
+		for _, cell := range row.TableRow.Cells {
+			cells = append(cells, strings.ReplaceAll(r.RichText(cell), "|", "\\|"))
+		}
+
+		if r.Plain {
+			b.WriteString(strings.Join(cells, "\t") + "\n")
+			continue
+		}
+		b.WriteString("| " + strings.Join(cells, " | ") + " |\n")
+		if !wroteHeader {
+			b.WriteString("|" + strings.Repeat(" --- |", len(cells)) + "\n")
+			wroteHeader = true
+		}
+	}
+	b.WriteString("\n")
+}
@@ -0,0 +1,343 @@
+package notion
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jomei/notionapi"
+)
+
+const (
+	pageSize        = 100 // Maximum page size accepted by the Notion API
+	maxBlockDepth   = 10  // Nested blocks deeper than this are not fetched
+	maxAncestorHops = 50  // Guards against parent cycles when resolving ancestry
+)
+
+// PageRef identifies a page found while listing a workspace, before its content is fetched.
+type PageRef struct {
+	ID             string // Notion page ID as returned by the API
+	Title          string
+	URL            string
+	LastEditedTime time.Time
+	Properties     notionapi.Properties // Database row properties (only the title for regular pages)
+}
+
+// Document is a fully rendered Notion page.
+type Document struct {
+	PageID         string
+	Title          string
+	URL            string
+	Content        string
+	LastEditedTime time.Time
+}
+
+// Walker lists and fetches the pages a Notion integration has access to.
+type Walker struct {
+	client   *notionapi.Client
+	renderer Renderer
+
+	parents map[string]string // Normalized object ID -> normalized parent ID ("" for workspace roots)
+}
+
+// NewWalker creates a Walker that renders pages as Markdown, or as plain text when plain is true.
+func NewWalker(client *notionapi.Client, plain bool) *Walker {
+	return &Walker{
+		client:   client,
+		renderer: Renderer{Plain: plain},
+		parents:  make(map[string]string),
+	}
+}
+
+// ListPages returns the non-archived pages shared with the integration.
+// When objectIDs is not empty, only pages that are (or descend from) one of those
+// pages or databases are returned. IDs may be given with or without dashes, or as Notion URLs.
+func (w *Walker) ListPages(ctx context.Context, objectIDs []string) ([]PageRef, error) {
+	var pages []PageRef
+	req := &notionapi.SearchRequest{PageSize: pageSize}
+	for {
+		resp, err := w.client.Search.Do(ctx, req)
+		if err != nil {
+			return nil, fmt.Errorf("notion search failed: %w", err)
+		}
+
+		for _, obj := range resp.Results {
+			switch o := obj.(type) {
+			case *notionapi.Page:
+				w.parents[NormalizeID(o.ID.String())] = parentID(o.Parent)
+				if !o.Archived {
+					pages = append(pages, PageRef{
+						ID:             o.ID.String(),
+						Title:          pageTitle(o.Properties),
+						URL:            o.URL,
+						LastEditedTime: o.LastEditedTime,
+						Properties:     o.Properties,
+					})
+				}
+			case *notionapi.Database:
+				w.parents[NormalizeID(o.ID.String())] = parentID(o.Parent)
+			}
+		}
+
+		if !resp.HasMore || resp.NextCursor == "" {
+			break
+		}
+		req.StartCursor = resp.NextCursor
+	}
+
+	if len(objectIDs) == 0 {
+		return pages, nil
+	}
+
+	wanted := make(map[string]bool, len(objectIDs))
+	for _, id := range objectIDs {
+		if normalized := NormalizeID(id); normalized != "" {
+			wanted[normalized] = true
+		}
+	}
+	return selectPages(pages, wanted, func(id string) (string, error) {
+		return w.parentOf(ctx, id)
+	})
+}
+
+// FetchDocument fetches a page's blocks and renders them.
+// Database rows get their properties rendered above the page body.
+func (w *Walker) FetchDocument(ctx context.Context, page PageRef) (*Document, error) {
+	nodes, err := w.fetchChildren(ctx, notionapi.BlockID(page.ID), 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch blocks of page %s: %w", page.ID, err)
+	}
+
+	content := w.renderer.Render(nodes)
+	if props := w.renderProperties(page.Properties); props != "" {
+		content = props + "\n" + content
+	}
+
+	return &Document{
+		PageID:         page.ID,
+		Title:          page.Title,
+		URL:            page.URL,
+		Content:        content,
+		LastEditedTime: page.LastEditedTime,
+	}, nil
+}
+
+// fetchChildren fetches the children of a block (or page), recursing into nested blocks.
+// Child pages and databases are not descended into: they are listed as documents of their own.
+func (w *Walker) fetchChildren(ctx context.Context, id notionapi.BlockID, depth int) ([]*Node, error) {
+	var nodes []*Node
+	pagination := &notionapi.Pagination{PageSize: pageSize}
+	for {
+		resp, err := w.client.Block.GetChildren(ctx, id, pagination)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, block := range resp.Results {
+			node := &Node{Block: block}
+			if block.GetHasChildren() && depth < maxBlockDepth && !isChildObject(block) {
+				if node.Children, err = w.fetchChildren(ctx, block.GetID(), depth+1); err != nil {
+					return nil, err
+				}
+			}
+			nodes = append(nodes, node)
+		}
+
+		if !resp.HasMore || resp.NextCursor == "" {
+			break
+		}
+		pagination.StartCursor = notionapi.Cursor(resp.NextCursor)
+	}
+	return nodes, nil
+}
+
+// parentOf returns the normalized parent ID of a page, database or block ("" at the workspace root).
+// Objects not seen in the search results (blocks, or pages the integration cannot read) are looked up
+// as blocks; objects the integration has no access to are treated as roots.
+func (w *Walker) parentOf(ctx context.Context, id string) (string, error) {
+	if parent, ok := w.parents[id]; ok {
+		return parent, nil
+	}
+
+	block, err := w.client.Block.Get(ctx, notionapi.BlockID(id))
+	if err != nil {
+		var notionErr *notionapi.Error
+		if errors.As(err, &notionErr) && (notionErr.Status == http.StatusNotFound || notionErr.Status == http.StatusForbidden) {
+			w.parents[id] = ""
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to resolve parent of %s: %w", id, err)
+	}
+
+	parent := ""
+	if p := block.GetParent(); p != nil {
+		parent = parentID(*p)
+	}
+	w.parents[id] = parent
+	return parent, nil
+}
+
+// selectPages keeps the pages that are, or descend from, one of the wanted (normalized) IDs.
+func selectPages(pages []PageRef, wanted map[string]bool, parentOf func(id string) (string, error)) ([]PageRef, error) {
+	var selected []PageRef
+	for _, page := range pages {
+		id := NormalizeID(page.ID)
+		for hop := 0; id != "" && hop < maxAncestorHops; hop++ {
+			if wanted[id] {
+				selected = append(selected, page)
+				break
+			}
+			parent, err := parentOf(id)
+			if err != nil {
+				return nil, err
+			}
+			id = parent
+		}
+	}
+	return selected, nil
+}
+
+// NormalizeID converts a Notion ID or URL to its canonical form: 32 lowercase hex characters.
+// It returns "" when no ID can be found.
+func NormalizeID(id string) string {
+	if i := strings.IndexAny(id, "?#"); i >= 0 {
+		id = id[:i]
+	}
+	id = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(id), "-", ""))
+	if len(id) < 32 {
+		return ""
+	}
+	id = id[len(id)-32:] // URLs end with the ID, after the page title slug
+	for _, c := range id {
+		if !strings.ContainsRune("0123456789abcdef", c) {
+			return ""
+		}
+	}
+	return id
+}
+
+func parentID(p notionapi.Parent) string {
+	switch p.Type {
+	case notionapi.ParentTypePageID:
+		return NormalizeID(p.PageID.String())
+	case notionapi.ParentTypeDatabaseID:
+		return NormalizeID(p.DatabaseID.String())
+	case notionapi.ParentTypeBlockID:
+		return NormalizeID(p.BlockID.String())
+	}
+	return "" // Workspace
+}
+
+func isChildObject(block notionapi.Block) bool {
+	switch block.(type) {
+	case *notionapi.ChildPageBlock, *notionapi.ChildDatabaseBlock:
+		return true
+	}
+	return false
+}
+
+func pageTitle(props notionapi.Properties) string {
+	for _, prop := range props {
+		if title, ok := prop.(*notionapi.TitleProperty); ok {
+			return richTextPlain(title.Title)
+		}
+	}
+	return ""
+}
+
+// renderProperties renders the non-title properties of a database row as a list, sorted by name.
+func (w *Walker) renderProperties(props notionapi.Properties) string {
+	names := make([]string, 0, len(props))
+	for name := range props {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		value := propertyValue(props[name])
+		if value == "" {
+			continue
+		}
+		if w.renderer.Plain {
+			b.WriteString(name + ": " + value + "\n")
+		} else {
+			b.WriteString("- **" + name + "**: " + value + "\n")
+		}
+	}
+	return b.String()
+}
+
+// propertyValue returns a database property's value as text. Titles, files and
+// properties without a readable value return "".
+func propertyValue(prop notionapi.Property) string {
+	switch p := prop.(type) {
+	case *notionapi.RichTextProperty:
+		return richTextPlain(p.RichText)
+	case *notionapi.NumberProperty:
+		return strconv.FormatFloat(p.Number, 'f', -1, 64)
+	case *notionapi.SelectProperty:
+		return p.Select.Name
+	case *notionapi.StatusProperty:
+		return p.Status.Name
+	case *notionapi.MultiSelectProperty:
+		names := make([]string, 0, len(p.MultiSelect))
+		for _, option := range p.MultiSelect {
+			names = append(names, option.Name)
+		}
+		return strings.Join(names, ", ")
+	case *notionapi.DateProperty:
+		return dateValue(p.Date)
+	case *notionapi.CheckboxProperty:
+		if p.Checkbox {
+			return "Yes"
+		}
+		return "No"
+	case *notionapi.URLProperty:
+		return p.URL
+	case *notionapi.EmailProperty:
+		return p.Email
+	case *notionapi.PhoneNumberProperty:
+		return p.PhoneNumber
+	case *notionapi.PeopleProperty:
+		names := make([]string, 0, len(p.People))
+		for _, person := range p.People {
+			if person.Name != "" {
+				names = append(names, person.Name)
+			}
+		}
+		return strings.Join(names, ", ")
+	case *notionapi.FormulaProperty:
+		switch {
+		case p.Formula.String != "":
+			return p.Formula.String
+		case p.Formula.Date != nil:
+			return dateValue(p.Formula.Date)
+		case p.Formula.Type == "number":
+			return strconv.FormatFloat(p.Formula.Number, 'f', -1, 64)
+		case p.Formula.Type == "boolean":
+			return strconv.FormatBool(p.Formula.Boolean)
+		}
+	}
+	return ""
+}
+
+func dateValue(d *notionapi.DateObject) string {
+	if d == nil || d.Start == nil {
+		return ""
+	}
+	value := d.Start.String()
+	if d.End != nil {
+		value += " - " + d.End.String()
+	}
+	return value
+}
+
+func richTextPlain(parts []notionapi.RichText) string {
+	return Renderer{Plain: true}.RichText(parts)
+}
@@ -62,3 +62,30 @@ func (r *Registry) MustGet(serviceType string) Integration {
 	}
 	return integration
 }
+
+// KnowledgeBase returns the integration of a service type that backs knowledge bases.
+func (r *Registry) KnowledgeBase(serviceType string) (KnowledgeBaseIntegration, error) {
+	integration, err := r.Get(serviceType)
+	if err != nil {
+		return nil, err
+	}
+	kb, ok := integration.(KnowledgeBaseIntegration)
+	if !ok {
+		return nil, fmt.Errorf("service type %s does not support knowledge bases", serviceType)
+	}
+	return kb, nil
+}
+
+// KnowledgeSource returns the integration of a service type whose knowledge bases are synced from a
+// source. It returns false for unknown service types and for knowledge bases that are not synced.
+func (r *Registry) KnowledgeSource(serviceType string) (KnowledgeSource, bool) {
+	source, ok := r.integrations[serviceType].(KnowledgeSource)
+	return source, ok
+}
+
+// Channel returns the integration of a service type that chatbots can talk through. It returns false
+// for unknown service types and for integrations that are not messaging channels.
+func (r *Registry) Channel(serviceType string) (ChannelAdapter, bool) {
+	adapter, ok := r.integrations[serviceType].(ChannelAdapter)
+	return adapter, ok
+}
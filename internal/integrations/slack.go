@@ -1,27 +1,48 @@
 package integrations
 
 import (
+	slack_sender "buildmychat-backend/internal/integrations/slack"
+	"buildmychat-backend/internal/models"
 	integration_models "buildmychat-backend/internal/models/integrations"
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
+	"net/url"
+	"regexp"
 	"strings"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/slack-go/slack"
 )
 
-// Ensure SlackIntegration implements the Integration interface.
-var _ Integration = (*SlackIntegration)(nil)
+// Ensure SlackIntegration implements the ChannelAdapter, ConversationStarter, WebhookRouter and
+// WorkspaceVerifier interfaces.
+var (
+	_ ChannelAdapter      = (*SlackIntegration)(nil)
+	_ ConversationStarter = (*SlackIntegration)(nil)
+	_ WebhookRouter       = (*SlackIntegration)(nil)
+	_ WorkspaceVerifier   = (*SlackIntegration)(nil)
+)
+
+// slackFeedbackBlockID is the block of the feedback buttons under replies.
+const slackFeedbackBlockID = "chat_feedback"
 
 // SlackIntegration handles Slack-specific logic.
 type SlackIntegration struct {
-	// Add any Slack-specific dependencies here if needed later (e.g., Slack API client)
+	appSigningSecret string // Signing secret of the server's own Slack app, if it has one
+	apiURL           string // Slack Web API URL; a fake Slack server in tests
 }
 
-// NewSlackIntegration creates a new Slack integration handler.
-func NewSlackIntegration() *SlackIntegration {
-	return &SlackIntegration{}
+// NewSlackIntegration creates a new Slack integration handler. appSigningSecret is the signing secret
+// of the server's own Slack app, which verifies requests about the app sent to the shared events URL,
+// such as its URL verification challenge; it may be empty.
+func NewSlackIntegration(appSigningSecret string) *SlackIntegration {
+	return &SlackIntegration{appSigningSecret: appSigningSecret, apiURL: slack.APIURL}
 }
 
 // ValidateConfig checks if the provided JSON conforms to the SlackInterfaceConfig structure.
@@ -43,6 +64,21 @@ func (s *SlackIntegration) ValidateConfig(configJSON json.RawMessage) error {
 		// Making SlackTeamID optional for now, adjust if required
 		// return errors.New("'slack_team_id' is required in Slack configuration")
 	}
+	switch config.ReplyPolicy {
+	case "", integration_models.SlackReplyDirectMessages, integration_models.SlackReplyMentions, integration_models.SlackReplyAll:
+	default:
+		return fmt.Errorf("invalid reply_policy %q: expected %q, %q or %q", config.ReplyPolicy,
+			integration_models.SlackReplyDirectMessages, integration_models.SlackReplyMentions, integration_models.SlackReplyAll)
+	}
+	if config.SlashCommand != "" && (!strings.HasPrefix(config.SlashCommand, "/") || len(config.SlashCommand) < 2 || strings.ContainsAny(config.SlashCommand, " \t\n")) {
+		return fmt.Errorf("invalid slash_command %q: expected a command such as %q", config.SlashCommand, integration_models.SlackDefaultSlashCommand)
+	}
+	switch config.SlashCommandReplies {
+	case "", integration_models.SlackCommandRepliesThread, integration_models.SlackCommandRepliesEphemeral:
+	default:
+		return fmt.Errorf("invalid slash_command_replies %q: expected %q or %q", config.SlashCommandReplies,
+			integration_models.SlackCommandRepliesThread, integration_models.SlackCommandRepliesEphemeral)
+	}
 
 	return nil // Configuration is valid
 }
@@ -109,3 +145,608 @@ func (s *SlackIntegration) TestConnection(ctx context.Context, decryptedCreds in
 func (s *SlackIntegration) GetCredentialSchema() interface{} {
 	return integration_models.SlackCredentials{}
 }
+
+// slackConversation is the chat configuration of a Slack conversation: where replies are posted.
+type slackConversation struct {
+	ChannelID   string `json:"channel_id,omitempty"`
+	ThreadTS    string `json:"thread_ts,omitempty"`
+	ResponseURL string `json:"response_url,omitempty"` // Set for ephemeral replies to a slash command
+}
+
+// VerifyWebhook checks the request's v0 signature with the signing secret of the interface's
+// credential, and rejects requests older than five minutes.
+func (s *SlackIntegration) VerifyWebhook(header http.Header, body []byte, iface ChannelInterface) error {
+	var creds integration_models.SlackCredentials
+	if len(iface.Credentials) > 0 {
+		if err := json.Unmarshal(iface.Credentials, &creds); err != nil {
+			return fmt.Errorf("failed to parse Slack credential: %w", err)
+		}
+	}
+	if creds.SigningSecret == "" {
+		return errors.New("the interface's Slack credential has no signing_secret")
+	}
+	return slack_sender.VerifyRequest(header, body, creds.SigningSecret, time.Now())
+}
+
+// RouteWebhook reads the team and app of an Events API, interactivity or slash command request. URL
+// verification requests, which Slack sends when the Request URL is entered in the app's settings, have
+// no team.
+func (s *SlackIntegration) RouteWebhook(body []byte) (WebhookRoute, error) {
+	if form, ok := slackForm(body); ok {
+		return routeSlackForm(form)
+	}
+
+	var envelope struct {
+		Type     string `json:"type"`
+		TeamID   string `json:"team_id"`
+		APIAppID string `json:"api_app_id"`
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return WebhookRoute{}, fmt.Errorf("could not determine payload type: %w", err)
+	}
+	if envelope.Type == "url_verification" {
+		return WebhookRoute{}, nil
+	}
+	if envelope.TeamID == "" {
+		return WebhookRoute{}, fmt.Errorf("missing team_id in %q payload", envelope.Type)
+	}
+	return WebhookRoute{WorkspaceID: envelope.TeamID, AppID: envelope.APIAppID}, nil
+}
+
+// routeSlackForm reads the team and app of an interactivity or slash command request.
+func routeSlackForm(form url.Values) (WebhookRoute, error) {
+	if payload := form.Get("payload"); payload != "" {
+		var interaction models.SlackInteractionPayload
+		if err := json.Unmarshal([]byte(payload), &interaction); err != nil {
+			return WebhookRoute{}, fmt.Errorf("invalid interaction payload: %w", err)
+		}
+		teamID := interaction.Team.ID
+		if teamID == "" {
+			teamID = interaction.User.TeamID
+		}
+		if teamID == "" {
+			return WebhookRoute{}, fmt.Errorf("missing team in %q interaction", interaction.Type)
+		}
+		return WebhookRoute{WorkspaceID: teamID, AppID: interaction.APIAppID}, nil
+	}
+	if form.Get("team_id") == "" {
+		return WebhookRoute{}, fmt.Errorf("missing team_id in %s command", form.Get("command"))
+	}
+	return WebhookRoute{WorkspaceID: form.Get("team_id"), AppID: form.Get("api_app_id")}, nil
+}
+
+// slackForm parses the form body of an interactivity request (a "payload" field) or a slash command
+// (a "command" field). Events API requests, which have a JSON body, are not forms.
+func slackForm(body []byte) (url.Values, bool) {
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) == 0 || trimmed[0] == '{' {
+		return nil, false
+	}
+	form, err := url.ParseQuery(string(trimmed))
+	if err != nil || (!form.Has("payload") && !form.Has("command")) {
+		return nil, false
+	}
+	return form, true
+}
+
+// VerifyAppWebhook checks the request's v0 signature with the signing secret of the server's own
+// Slack app.
+func (s *SlackIntegration) VerifyAppWebhook(header http.Header, body []byte) error {
+	if s.appSigningSecret == "" {
+		return errors.New("no Slack app signing secret is configured")
+	}
+	return slack_sender.VerifyRequest(header, body, s.appSigningSecret, time.Now())
+}
+
+// ParseWebhook parses an Events API request. URL verification requests are answered with their
+// challenge. "message" and "app_mention" events become inbound messages if the interface's reply
+// policy answers them, with the bot's mentions removed from the text; other events, and messages
+// posted, edited or deleted by bots (including this one), are ignored. Events for another team or app
+// than the one the interface is configured with are rejected.
+// Interactivity requests become actions if a reply's feedback buttons were clicked, and the interface's
+// slash command becomes an inbound message with the command; see parseSlackInteraction and parseSlackCommand.
+func (s *SlackIntegration) ParseWebhook(body []byte, iface ChannelInterface) (*WebhookEvent, error) {
+	if form, ok := slackForm(body); ok {
+		config, err := slackInterfaceConfig(iface)
+		if err != nil {
+			return nil, err
+		}
+		if form.Has("payload") {
+			return parseSlackInteraction(form.Get("payload"), config)
+		}
+		return parseSlackCommand(form, config)
+	}
+
+	var typeFinder struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(body, &typeFinder); err != nil {
+		return nil, fmt.Errorf("could not determine payload type: %w", err)
+	}
+
+	switch typeFinder.Type {
+	case "url_verification":
+		var challengeReq models.SlackChallengeRequest
+		if err := json.Unmarshal(body, &challengeReq); err != nil {
+			return nil, fmt.Errorf("invalid Slack challenge request: %w", err)
+		}
+		return &WebhookEvent{Response: &WebhookResponse{ContentType: "text/plain", Body: []byte(challengeReq.Challenge)}}, nil
+
+	case "event_callback":
+		var payload models.SlackEventPayload
+		if err := json.Unmarshal(body, &payload); err != nil {
+			return nil, fmt.Errorf("invalid Slack event payload: %w", err)
+		}
+		config, err := slackInterfaceConfig(iface)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkSlackWorkspace(config, payload.TeamID, payload.APIAppID); err != nil {
+			return nil, fmt.Errorf("event %s %v", payload.EventID, err)
+		}
+
+		if reason := ignoreSlackEvent(&payload, config.ReplyPolicy); reason != "" {
+			log.Printf("[SlackIntegration] ParseWebhook: Ignoring %s event %s: %s", payload.Event.Type, payload.EventID, reason)
+			return &WebhookEvent{}, nil
+		}
+		if payload.TeamID == "" || payload.Event.Channel == "" || payload.Event.User == "" {
+			return nil, fmt.Errorf("missing team_id, channel or user in %s event %s", payload.Event.Type, payload.EventID)
+		}
+
+		text := stripSlackMentions(payload.Event.Text, slackBotUserIDs(&payload))
+		if text == "" {
+			log.Printf("[SlackIntegration] ParseWebhook: Ignoring %s event %s: no text besides the mention", payload.Event.Type, payload.EventID)
+			return &WebhookEvent{}, nil
+		}
+		return &WebhookEvent{Message: &InboundMessage{
+			EventID:       payload.EventID,
+			WorkspaceID:   payload.TeamID,
+			ChannelID:     payload.Event.Channel,
+			UserID:        payload.Event.User,
+			MessageID:     payload.Event.Timestamp,
+			ThreadID:      payload.Event.ThreadTs,
+			DirectMessage: payload.Event.ChannelType == "im",
+			Text:          text,
+		}}, nil
+
+	default:
+		return nil, fmt.Errorf("unhandled payload type: %q", typeFinder.Type)
+	}
+}
+
+// parseSlackCommand parses a slash command. The interface's command (SlackDefaultSlashCommand unless
+// configured) becomes an inbound message that starts a new conversation; it is acknowledged with the
+// question when the reply is ephemeral, and with an empty response when the question is posted in the
+// channel. Other commands, and the command without a question, are answered with how to use it.
+func parseSlackCommand(form url.Values, config integration_models.SlackInterfaceConfig) (*WebhookEvent, error) {
+	command, teamID := form.Get("command"), form.Get("team_id")
+	if err := checkSlackWorkspace(config, teamID, form.Get("api_app_id")); err != nil {
+		return nil, fmt.Errorf("command %s %v", command, err)
+	}
+
+	want := config.SlashCommand
+	if want == "" {
+		want = integration_models.SlackDefaultSlashCommand
+	}
+	usage := fmt.Sprintf("Ask the chatbot a question with `%s <question>`.", want)
+	if command != want {
+		log.Printf("[SlackIntegration] ParseWebhook: Answering unknown command %s in team %s with the usage of %s", command, teamID, want)
+		return &WebhookEvent{Response: slackEphemeralResponse(fmt.Sprintf("`%s` is not a command of this chatbot. %s", command, usage))}, nil
+	}
+	text := strings.TrimSpace(form.Get("text"))
+	if text == "" {
+		return &WebhookEvent{Response: slackEphemeralResponse(usage)}, nil
+	}
+	if teamID == "" || form.Get("channel_id") == "" || form.Get("user_id") == "" {
+		return nil, fmt.Errorf("missing team_id, channel_id or user_id in %s command", command)
+	}
+
+	response := &WebhookResponse{ContentType: "text/plain"}
+	if config.SlashCommandReplies == integration_models.SlackCommandRepliesEphemeral {
+		response = slackEphemeralResponse("*You asked:* " + text)
+	}
+	return &WebhookEvent{
+		Response: response,
+		Message: &InboundMessage{
+			EventID:       form.Get("trigger_id"), // Unique to each use of the command
+			WorkspaceID:   teamID,
+			ChannelID:     form.Get("channel_id"),
+			UserID:        form.Get("user_id"),
+			MessageID:     form.Get("trigger_id"),
+			DirectMessage: form.Get("channel_name") == "directmessage",
+			Text:          text,
+			Command:       command,
+			ReplyURL:      form.Get("response_url"),
+		},
+	}, nil
+}
+
+// parseSlackInteraction parses an interactivity request. A click on the feedback or escalation button
+// of a reply becomes an action on the reply's chat, acknowledged with an empty response. Feedback is
+// thanked for privately; an escalation is announced in the conversation, with the interface's
+// escalation_message. Other interactions are ignored.
+func parseSlackInteraction(payloadJSON string, config integration_models.SlackInterfaceConfig) (*WebhookEvent, error) {
+	var payload models.SlackInteractionPayload
+	if err := json.Unmarshal([]byte(payloadJSON), &payload); err != nil {
+		return nil, fmt.Errorf("invalid interaction payload: %w", err)
+	}
+	teamID := payload.Team.ID
+	if teamID == "" {
+		teamID = payload.User.TeamID
+	}
+	if err := checkSlackWorkspace(config, teamID, payload.APIAppID); err != nil {
+		return nil, fmt.Errorf("%s interaction %v", payload.Type, err)
+	}
+	ack := &WebhookResponse{ContentType: "text/plain"}
+
+	if payload.Type != "block_actions" {
+		log.Printf("[SlackIntegration] ParseWebhook: Ignoring %s interaction in team %s", payload.Type, teamID)
+		return &WebhookEvent{Response: ack}, nil
+	}
+	var clicked *models.SlackElementAction
+	for i := range payload.Actions {
+		switch payload.Actions[i].ActionID {
+		case ActionFeedbackPositive, ActionFeedbackNegative, ActionEscalate:
+			clicked = &payload.Actions[i]
+		}
+	}
+	if clicked == nil {
+		log.Printf("[SlackIntegration] ParseWebhook: Ignoring block_actions interaction in team %s: no feedback action", teamID)
+		return &WebhookEvent{Response: ack}, nil
+	}
+	chatID, err := uuid.Parse(clicked.Value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid chat ID %q in %s action: %w", clicked.Value, clicked.ActionID, err)
+	}
+	if payload.User.ID == "" {
+		return nil, fmt.Errorf("missing user in %s action", clicked.ActionID)
+	}
+
+	// The trigger is unique to each click; the action's timestamp is unique to the clicked message
+	eventID := payload.TriggerID
+	if eventID == "" && clicked.ActionTs != "" {
+		eventID = clicked.ActionID + ":" + clicked.ActionTs
+	}
+	action := &InboundAction{EventID: eventID, Action: clicked.ActionID, ChatID: chatID, UserID: payload.User.ID}
+	notice := slackConversation{ResponseURL: payload.ResponseURL}
+	if clicked.ActionID == ActionEscalate {
+		action.Notice = strings.TrimSpace(fmt.Sprintf("<@%s> asked for a human to take over. %s", payload.User.ID, config.EscalationMessage))
+		channelID := payload.Channel.ID
+		if channelID == "" {
+			channelID = payload.Container.ChannelID
+		}
+		if !payload.Container.IsEphemeral && channelID != "" {
+			threadTS := payload.Message.ThreadTs
+			if threadTS == "" {
+				threadTS = payload.Container.ThreadTs
+			}
+			notice = slackConversation{ChannelID: channelID, ThreadTS: threadTS}
+		}
+	} else {
+		action.Notice = "Thanks for your feedback!"
+	}
+	if notice == (slackConversation{}) {
+		action.Notice = "" // Nowhere to send it
+	}
+	noticeJSON, _ := json.Marshal(notice)
+	action.NoticeConversation = Conversation{Configuration: noticeJSON}
+	return &WebhookEvent{Response: ack, Action: action}, nil
+}
+
+// slackEphemeralResponse answers a slash command with a message only the user sees.
+func slackEphemeralResponse(text string) *WebhookResponse {
+	var body bytes.Buffer
+	encoder := json.NewEncoder(&body)
+	encoder.SetEscapeHTML(false) // Keep mrkdwn such as <@U1> readable
+	_ = encoder.Encode(struct {
+		ResponseType string `json:"response_type"`
+		Text         string `json:"text"`
+	}{slack.ResponseTypeEphemeral, text})
+	return &WebhookResponse{ContentType: "application/json", Body: body.Bytes()}
+}
+
+// slackInterfaceConfig parses the configuration of an interface.
+func slackInterfaceConfig(iface ChannelInterface) (integration_models.SlackInterfaceConfig, error) {
+	var config integration_models.SlackInterfaceConfig
+	if len(iface.Configuration) > 0 {
+		if err := json.Unmarshal(iface.Configuration, &config); err != nil {
+			return config, fmt.Errorf("invalid Slack interface configuration: %w", err)
+		}
+	}
+	return config, nil
+}
+
+// VerifyWorkspace checks with the interface's bot token that the bot is installed in the team of the
+// interface's configuration, and belongs to its app if it names one. Finding the bot's app requires
+// the users:read scope.
+func (s *SlackIntegration) VerifyWorkspace(ctx context.Context, iface ChannelInterface) error {
+	config, err := slackInterfaceConfig(iface)
+	if err != nil {
+		return err
+	}
+	if config.SlackTeamID == "" {
+		return nil
+	}
+	botToken, err := slackBotToken(iface)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrWorkspaceUnproven, err)
+	}
+	client := slack.New(botToken, slack.OptionAPIURL(s.apiURL))
+
+	identity, err := client.AuthTestContext(ctx)
+	if err != nil {
+		return slackVerifyError("auth.test", err)
+	}
+	if identity.TeamID != config.SlackTeamID {
+		return fmt.Errorf("%w: the bot token is for team %s, not %s", ErrWorkspaceUnproven, identity.TeamID, config.SlackTeamID)
+	}
+	if config.SlackAppID == "" {
+		return nil
+	}
+	bot, err := client.GetBotInfoContext(ctx, slack.GetBotInfoParameters{Bot: identity.BotID})
+	if err != nil {
+		return slackVerifyError("bots.info", err)
+	}
+	if bot.AppID != config.SlackAppID {
+		return fmt.Errorf("%w: the bot token is for app %s, not %s", ErrWorkspaceUnproven, bot.AppID, config.SlackAppID)
+	}
+	return nil
+}
+
+// slackVerifyError wraps the error of a Slack API call made by VerifyWorkspace. Slack's refusals, e.g.
+// a revoked token, leave the workspace unproven; other errors, e.g. a timeout, do not say either way.
+func slackVerifyError(method string, err error) error {
+	var apiErr slack.SlackErrorResponse
+	if errors.As(err, &apiErr) {
+		return fmt.Errorf("%w: Slack %s failed: %s", ErrWorkspaceUnproven, method, apiErr.Err)
+	}
+	return fmt.Errorf("slack %s failed: %w", method, err)
+}
+
+// checkSlackWorkspace rejects requests for another team or app than the one the interface is
+// configured with.
+func checkSlackWorkspace(config integration_models.SlackInterfaceConfig, teamID, appID string) error {
+	if config.SlackTeamID != "" && teamID != config.SlackTeamID {
+		return fmt.Errorf("is for team %q, the interface is for team %q", teamID, config.SlackTeamID)
+	}
+	if config.SlackAppID != "" && appID != "" && appID != config.SlackAppID {
+		return fmt.Errorf("is for app %q, the interface is for app %q", appID, config.SlackAppID)
+	}
+	return nil
+}
+
+// slackUserMessageSubtypes are the message subtypes still written by a user. Other subtypes are edits,
+// deletions, bot messages and channel notices.
+var slackUserMessageSubtypes = map[string]bool{
+	"":                 true,
+	"file_share":       true,
+	"thread_broadcast": true,
+}
+
+// ignoreSlackEvent returns why an event is not answered, or "" if it is.
+// A mention in a channel the bot is in fires both an "app_mention" and a "message" event. Under the
+// "mentions" policy only the first is answered; under "all" only the second, as it covers all messages.
+func ignoreSlackEvent(payload *models.SlackEventPayload, policy string) string {
+	event := &payload.Event
+	if event.Type != "message" && event.Type != "app_mention" {
+		return "unhandled event type"
+	}
+	if !slackUserMessageSubtypes[event.Subtype] {
+		return "message subtype " + event.Subtype
+	}
+	if event.BotID != "" {
+		return "posted by bot " + event.BotID
+	}
+	for _, auth := range payload.Authorizations {
+		if auth.UserID != "" && auth.UserID == event.User {
+			return "posted by this bot"
+		}
+	}
+
+	if policy == "" {
+		policy = integration_models.SlackReplyMentions
+	}
+	directMessage := event.Type == "message" && event.ChannelType == "im"
+	switch {
+	case directMessage:
+		return ""
+	case policy == integration_models.SlackReplyMentions && event.Type == "app_mention":
+		return ""
+	case policy == integration_models.SlackReplyAll && event.Type == "message":
+		return ""
+	}
+	return "not answered under reply policy " + policy
+}
+
+// slackBotUserIDs returns the user IDs of the bot the event was delivered to.
+func slackBotUserIDs(payload *models.SlackEventPayload) []string {
+	var ids []string
+	for _, auth := range payload.Authorizations {
+		if auth.IsBot && auth.UserID != "" {
+			ids = append(ids, auth.UserID)
+		}
+	}
+	return ids
+}
+
+// slackMention matches user mentions, such as <@U0LAN0Z89> or <@U0LAN0Z89|bot>.
+var slackMention = regexp.MustCompile(`<@([UW][A-Z0-9]+)(\|[^>]*)?>`)
+
+// stripSlackMentions removes the mentions of the given users from a message. Without user IDs, the
+// mention a message starts with is removed, as that is how users address the bot.
+func stripSlackMentions(text string, userIDs []string) string {
+	if len(userIDs) == 0 {
+		if loc := slackMention.FindStringIndex(strings.TrimSpace(text)); loc != nil && loc[0] == 0 {
+			text = strings.TrimSpace(text)[loc[1]:]
+		}
+		return strings.TrimSpace(text)
+	}
+	text = slackMention.ReplaceAllStringFunc(text, func(mention string) string {
+		id := slackMention.FindStringSubmatch(mention)[1]
+		for _, userID := range userIDs {
+			if id == userID {
+				return ""
+			}
+		}
+		return mention
+	})
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// Conversation makes each thread its own chat, keyed by team, channel and the ts of the thread's first
+// message (the message itself when it starts a thread), and replies in that thread.
+// A direct message conversation is one chat, keyed by team and channel and answered outside threads,
+// unless the interface sets direct_message_threads. Messages written in a thread are still answered there.
+func (s *SlackIntegration) Conversation(msg *InboundMessage, iface ChannelInterface) Conversation {
+	var config integration_models.SlackInterfaceConfig
+	if len(iface.Configuration) > 0 {
+		_ = json.Unmarshal(iface.Configuration, &config) // Validated when the interface was saved
+	}
+
+	target := slackConversation{ChannelID: msg.ChannelID, ThreadTS: msg.ThreadID}
+	key := msg.WorkspaceID + "_" + msg.ChannelID
+	if !msg.DirectMessage || config.DirectMessageThreads {
+		if target.ThreadTS == "" {
+			target.ThreadTS = msg.MessageID
+		}
+		key += "_" + target.ThreadTS
+	}
+	configJSON, _ := json.Marshal(target)
+	return Conversation{Key: key, Configuration: configJSON}
+}
+
+// StartConversation opens the conversation of a slash command, a new chat keyed by team, channel and
+// the message it is answered under. By default the question is posted in the channel for the user, and
+// answered in a thread under it. Interfaces with slash_command_replies "ephemeral" answer only the user,
+// through the command's response URL; such a chat is keyed by the command's trigger ID. If the question
+// cannot be posted, e.g. because the bot is not in the channel, the reply is ephemeral too.
+func (s *SlackIntegration) StartConversation(ctx context.Context, iface ChannelInterface, msg *InboundMessage) (Conversation, error) {
+	config, err := slackInterfaceConfig(iface)
+	if err != nil {
+		return Conversation{}, err
+	}
+
+	if config.SlashCommandReplies != integration_models.SlackCommandRepliesEphemeral {
+		botToken, err := slackBotToken(iface)
+		if err != nil {
+			return Conversation{}, err
+		}
+		question := fmt.Sprintf("<@%s> asked: %s", msg.UserID, msg.Text)
+		ts, err := slack_sender.PostMessage(ctx, botToken, msg.ChannelID, "", question, nil)
+		if err == nil {
+			target := slackConversation{ChannelID: msg.ChannelID, ThreadTS: ts}
+			configJSON, _ := json.Marshal(target)
+			return Conversation{Key: msg.WorkspaceID + "_" + msg.ChannelID + "_" + ts, Configuration: configJSON}, nil
+		}
+		if msg.ReplyURL == "" {
+			return Conversation{}, fmt.Errorf("failed to post the question of %s: %w", msg.Command, err)
+		}
+		log.Printf("WARN [SlackIntegration] StartConversation: Answering %s in channel %s privately: %v", msg.Command, msg.ChannelID, err)
+	}
+
+	if msg.ReplyURL == "" {
+		return Conversation{}, fmt.Errorf("%s has no response URL to reply to", msg.Command)
+	}
+	target := slackConversation{ChannelID: msg.ChannelID, ResponseURL: msg.ReplyURL}
+	configJSON, _ := json.Marshal(target)
+	return Conversation{Key: msg.WorkspaceID + "_" + msg.ChannelID + "_" + msg.MessageID, Configuration: configJSON}, nil
+}
+
+// Deliver posts a message with the bot token of the interface's credential, or sends it to the
+// response URL of an ephemeral conversation. The message's Markdown is converted to mrkdwn blocks, with
+// its source markers linked, and long messages are posted in several parts (see slack.FormatMessage).
+// Replies of a chat end with thumbs up and down buttons, and an "Escalate to human" button if the
+// interface sets escalate_button. The delivery lists the ts of each post; ephemeral messages have none,
+// and return a nil delivery. If a later part fails, the posts made so far are returned with the error.
+func (s *SlackIntegration) Deliver(ctx context.Context, iface ChannelInterface, conv Conversation, msg OutboundMessage) (*models.ChatMessageDelivery, error) {
+	var target slackConversation
+	if len(conv.Configuration) > 0 {
+		if err := json.Unmarshal(conv.Configuration, &target); err != nil {
+			return nil, fmt.Errorf("invalid Slack chat configuration: %w", err)
+		}
+	}
+	config, _ := slackInterfaceConfig(iface) // Validated when the interface was saved
+	messages := slackMessages(msg, config.EscalateButton)
+	if len(messages) == 0 {
+		return nil, errors.New("the message is empty")
+	}
+
+	if target.ResponseURL != "" {
+		for _, m := range messages {
+			if err := slack_sender.PostEphemeralResponse(ctx, target.ResponseURL, m.Text, m.Blocks); err != nil {
+				return nil, err
+			}
+		}
+		return nil, nil
+	}
+
+	botToken, err := slackBotToken(iface)
+	if err != nil {
+		return nil, err
+	}
+	if target.ChannelID == "" {
+		// Chats created before the channel was stored in the configuration: team_channel[_...]
+		parts := strings.Split(conv.Key, "_")
+		if len(parts) < 2 {
+			return nil, fmt.Errorf("invalid external chat ID format: %s", conv.Key)
+		}
+		target.ChannelID = parts[1]
+	}
+
+	var delivery *models.ChatMessageDelivery
+	for _, m := range messages {
+		ts, err := slack_sender.PostMessage(ctx, botToken, target.ChannelID, target.ThreadTS, m.Text, m.Blocks)
+		if err != nil {
+			return delivery, err
+		}
+		if delivery == nil {
+			delivery = &models.ChatMessageDelivery{ServiceType: models.ServiceTypeSlack, ChannelID: target.ChannelID}
+		}
+		delivery.MessageIDs = append(delivery.MessageIDs, ts)
+	}
+	return delivery, nil
+}
+
+// slackMessages formats a message as Slack posts, and adds the feedback buttons under the last post
+// if the message is a reply of a chat. The buttons carry the chat ID, which comes back when they are
+// clicked.
+func slackMessages(msg OutboundMessage, escalate bool) []slack_sender.Message {
+	messages := slack_sender.FormatMessage(msg.Text, msg.Sources)
+	if msg.ChatID == uuid.Nil {
+		return messages
+	}
+
+	chatID := msg.ChatID.String()
+	buttons := []slack.BlockElement{
+		slack.NewButtonBlockElement(ActionFeedbackPositive, chatID, slack.NewTextBlockObject(slack.PlainTextType, ":thumbsup:", true, false)),
+		slack.NewButtonBlockElement(ActionFeedbackNegative, chatID, slack.NewTextBlockObject(slack.PlainTextType, ":thumbsdown:", true, false)),
+	}
+	if escalate {
+		buttons = append(buttons, slack.NewButtonBlockElement(ActionEscalate, chatID,
+			slack.NewTextBlockObject(slack.PlainTextType, "Escalate to human", false, false)).WithStyle(slack.StyleDanger))
+	}
+	feedback := slack.NewActionBlock(slackFeedbackBlockID, buttons...)
+	if last := len(messages) - 1; last >= 0 && len(messages[last].Blocks) < slack_sender.MaxBlocks {
+		messages[last].Blocks = append(messages[last].Blocks, feedback)
+		return messages
+	}
+	return append(messages, slack_sender.Message{Text: "Was this answer helpful?", Blocks: []slack.Block{feedback}})
+}
+
+// slackBotToken returns the bot token of an interface's credential, or of its configuration for
+// interfaces set up with the token there.
+func slackBotToken(iface ChannelInterface) (string, error) {
+	if len(iface.Credentials) > 0 {
+		var creds integration_models.SlackCredentials
+		if err := json.Unmarshal(iface.Credentials, &creds); err != nil {
+			return "", fmt.Errorf("failed to parse Slack credential: %w", err)
+		}
+		if creds.BotToken != "" {
+			return creds.BotToken, nil
+		}
+	}
+	if token, err := slack_sender.ExtractTokenFromConfig(iface.Configuration); err == nil {
+		return token, nil
+	}
+	return "", errors.New("no bot_token in the interface's Slack credential")
+}
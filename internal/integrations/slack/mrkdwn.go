@@ -0,0 +1,581 @@
+package slack
+
+import (
+	"buildmychat-backend/internal/models"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/slack-go/slack"
+)
+
+// Limits of Slack messages that FormatMessage keeps to.
+const (
+	MaxSectionText     = 3000 // Characters of the mrkdwn text of a section block
+	MaxHeaderText      = 150  // Characters of the text of a header block
+	MaxBlocks          = 50   // Blocks of a message
+	MaxContextElements = 10   // Elements of a context block
+	maxFallbackText    = 3000 // Characters of the notification text of a message
+)
+
+// Message is one Slack post of a formatted reply.
+type Message struct {
+	Text   string        `json:"text"` // Shown in notifications and by clients that cannot show the blocks
+	Blocks []slack.Block `json:"blocks"`
+}
+
+// FormatMessage converts a reply written in Markdown, as LLMs write them, into Slack posts of Block
+// Kit blocks with mrkdwn text:
+//
+//   - **bold**, *italic*, ~~strikethrough~~, `code` and [links](url) become their mrkdwn forms, and &, <
+//     and > are escaped, except in Slack's own <@user>, <#channel>, <!here> and <url|label> forms;
+//   - headings become header blocks (levels 1 and 2) or bold lines, thematic breaks divider blocks;
+//   - lists are bulleted or numbered lines, block quotes quoted lines, and fenced code blocks and tables
+//     preformatted blocks, tables with aligned columns.
+//
+// Source markers such as [1] link to the URL of the source with that marker, and the cited sources
+// are listed in a context block at the end. Text longer than a section is split between sections,
+// at line breaks where possible, and blocks beyond a message's limit continue in another message.
+// Indented code blocks and HTML are not recognized. Blank markdown yields no messages.
+func FormatMessage(markdown string, sources []models.ChatMessageSource) []Message {
+	citations := citationURLs(sources)
+	var blocks []slack.Block
+	var section []string // Parts of the section being built, joined by blank lines
+	sectionLen := 0
+	flushSection := func() {
+		if len(section) > 0 {
+			blocks = append(blocks, mrkdwnSection(strings.Join(section, "\n\n")))
+			section, sectionLen = nil, 0
+		}
+	}
+	for _, part := range parseMarkdown(markdown, citations) {
+		switch part.kind {
+		case partHeader:
+			flushSection()
+			blocks = append(blocks, slack.NewHeaderBlock(slack.NewTextBlockObject(slack.PlainTextType, truncateRunes(part.text, MaxHeaderText), false, false)))
+		case partDivider:
+			flushSection()
+			blocks = append(blocks, slack.NewDividerBlock())
+		default:
+			for _, chunk := range splitPart(part) {
+				n := utf8.RuneCountInString(chunk)
+				if len(section) > 0 && sectionLen+2+n > MaxSectionText {
+					flushSection()
+				}
+				section = append(section, chunk)
+				if len(section) > 1 {
+					sectionLen += 2 // The blank line between parts
+				}
+				sectionLen += n
+			}
+		}
+	}
+	flushSection()
+
+	if cited := citedSources(markdown, sources); len(cited) > 0 {
+		blocks = append(blocks, sourcesContext(cited))
+	}
+	return splitMessages(blocks)
+}
+
+// splitMessages spreads blocks over messages of at most MaxBlocks blocks.
+func splitMessages(blocks []slack.Block) []Message {
+	var messages []Message
+	for len(blocks) > 0 {
+		n := min(len(blocks), MaxBlocks)
+		messages = append(messages, Message{Text: fallbackText(blocks[:n]), Blocks: blocks[:n:n]})
+		blocks = blocks[n:]
+	}
+	return messages
+}
+
+// fallbackText is the notification text of a message: its text blocks, truncated.
+func fallbackText(blocks []slack.Block) string {
+	var parts []string
+	for _, block := range blocks {
+		switch b := block.(type) {
+		case *slack.HeaderBlock:
+			parts = append(parts, b.Text.Text)
+		case *slack.SectionBlock:
+			parts = append(parts, b.Text.Text)
+		}
+	}
+	return truncateRunes(strings.Join(parts, "\n\n"), maxFallbackText)
+}
+
+// mrkdwnSection is a section block with mrkdwn text.
+func mrkdwnSection(text string) *slack.SectionBlock {
+	return slack.NewSectionBlock(slack.NewTextBlockObject(slack.MarkdownType, text, false, false), nil, nil)
+}
+
+// Kinds of the parts of a converted Markdown document.
+const (
+	partText   = iota // mrkdwn lines: a paragraph, list or block quote
+	partCode          // A preformatted block; text is the unescaped code
+	partHeader        // Plain text
+	partDivider
+)
+
+// markdownPart is a block-level part of a converted Markdown document.
+type markdownPart struct {
+	kind int
+	text string
+}
+
+var (
+	mdFence         = regexp.MustCompile("^\\s*(`{3,}|~{3,})")
+	mdHeading       = regexp.MustCompile(`^\s{0,3}(#{1,6})(?:\s+(.*?))?(?:\s+#+)?\s*$`)
+	mdThematicBreak = regexp.MustCompile(`^\s{0,3}(?:(?:\*\s*){3,}|(?:-\s*){3,}|(?:_\s*){3,})$`)
+	mdSetextLine    = regexp.MustCompile(`^\s{0,3}(=+|-+)\s*$`)
+	mdQuote         = regexp.MustCompile(`^\s{0,3}>\s?(.*)$`)
+	mdListItem      = regexp.MustCompile(`^(\s*)([-*+]|\d{1,9}[.)])\s+(.*)$`)
+	mdTaskBox       = regexp.MustCompile(`^\[([ xX])\]\s+`)
+	mdTableDelim    = regexp.MustCompile(`^\s*\|?\s*:?-+:?\s*(\|\s*:?-+:?\s*)*\|?\s*$`)
+)
+
+// parseMarkdown splits Markdown into block-level parts, with inline formatting converted to mrkdwn.
+func parseMarkdown(markdown string, citations map[int]string) []markdownPart {
+	lines := strings.Split(strings.ReplaceAll(markdown, "\r\n", "\n"), "\n")
+	var parts []markdownPart
+	var text []string // Lines of the text part being built
+	lastPlain := ""   // The last line of text if it is a paragraph line, which a setext underline makes a heading
+	flush := func() {
+		if len(text) > 0 {
+			parts = append(parts, markdownPart{kind: partText, text: strings.Join(text, "\n")})
+			text = nil
+		}
+		lastPlain = ""
+	}
+	heading := func(level int, raw string) {
+		flush()
+		title := plainInline(raw, false)
+		switch {
+		case title == "":
+		case level <= 2:
+			parts = append(parts, markdownPart{kind: partHeader, text: title})
+		default:
+			parts = append(parts, markdownPart{kind: partText, text: "*" + escapeMrkdwn(title) + "*"})
+		}
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := strings.ReplaceAll(lines[i], "\t", "    ")
+		trimmed := strings.TrimSpace(line)
+
+		if m := mdFence.FindStringSubmatch(line); m != nil {
+			flush()
+			fence := m[1]
+			var code []string
+			for i++; i < len(lines); i++ {
+				if closing := strings.TrimSpace(lines[i]); strings.HasPrefix(closing, fence) && strings.Trim(closing, fence[:1]) == "" {
+					break
+				}
+				code = append(code, lines[i])
+			}
+			parts = append(parts, markdownPart{kind: partCode, text: strings.Join(code, "\n")})
+			continue
+		}
+
+		switch {
+		case trimmed == "":
+			flush()
+		case lastPlain != "" && mdSetextLine.MatchString(line):
+			raw := lastPlain
+			text = text[:len(text)-1]
+			level := 2
+			if strings.HasPrefix(trimmed, "=") {
+				level = 1
+			}
+			heading(level, raw)
+		case mdThematicBreak.MatchString(line):
+			flush()
+			parts = append(parts, markdownPart{kind: partDivider})
+		case mdHeading.MatchString(line):
+			m := mdHeading.FindStringSubmatch(line)
+			heading(len(m[1]), m[2])
+		case strings.Contains(line, "|") && i+1 < len(lines) && strings.Contains(lines[i+1], "|") && mdTableDelim.MatchString(lines[i+1]):
+			flush()
+			rows := [][]string{tableCells(line)}
+			for i += 2; i < len(lines) && strings.Contains(lines[i], "|") && strings.TrimSpace(lines[i]) != ""; i++ {
+				rows = append(rows, tableCells(lines[i]))
+			}
+			i--
+			parts = append(parts, markdownPart{kind: partCode, text: renderTable(rows)})
+		case mdQuote.MatchString(line):
+			text = append(text, "> "+inlineMrkdwn(mdQuote.FindStringSubmatch(line)[1], citations))
+			lastPlain = ""
+		case mdListItem.MatchString(line):
+			m := mdListItem.FindStringSubmatch(line)
+			marker, item := "•", m[3]
+			if m[2][0] >= '0' && m[2][0] <= '9' {
+				marker = strings.TrimRight(m[2], ".)") + "."
+			}
+			if box := mdTaskBox.FindStringSubmatch(item); box != nil {
+				marker = "☐"
+				if box[1] != " " {
+					marker = "☑"
+				}
+				item = item[len(box[0]):]
+			}
+			indent := strings.Repeat("    ", len(m[1])/2)
+			text = append(text, indent+marker+" "+inlineMrkdwn(item, citations))
+			lastPlain = ""
+		default:
+			if lastPlain != "" || len(text) == 0 {
+				text = append(text, inlineMrkdwn(trimmed, citations))
+				lastPlain = trimmed
+			} else {
+				// Continuation of a list item or quote
+				text = append(text, strings.Repeat(" ", len(line)-len(strings.TrimLeft(line, " ")))+inlineMrkdwn(trimmed, citations))
+			}
+		}
+	}
+	flush()
+	return parts
+}
+
+// splitPart returns the mrkdwn of a text or code part in chunks that fit a section. Code is split
+// between lines, and each chunk is fenced on its own.
+func splitPart(part markdownPart) []string {
+	if part.kind != partCode {
+		return splitText(part.text, MaxSectionText)
+	}
+	const fences = len("```\n\n```")
+	var chunks []string
+	for _, code := range splitText(escapeMrkdwn(part.text), MaxSectionText-fences) {
+		chunks = append(chunks, "```\n"+code+"\n```")
+	}
+	if len(chunks) == 0 {
+		chunks = append(chunks, "```\n \n```")
+	}
+	return chunks
+}
+
+// splitText splits text into parts of at most limit characters, preferably at line breaks, then at
+// spaces.
+func splitText(text string, limit int) []string {
+	var parts []string
+	runes := []rune(strings.Trim(text, "\n"))
+	for len(runes) > limit {
+		cut := limit
+		if i := lastRuneIndex(runes[:limit+1], '\n'); i > 0 {
+			cut = i
+		} else if i := lastRuneIndex(runes[:limit+1], ' '); i > 0 {
+			cut = i
+		}
+		parts = append(parts, strings.TrimRight(string(runes[:cut]), " \n"))
+		runes = []rune(strings.TrimLeft(string(runes[cut:]), " \n"))
+	}
+	if len(runes) > 0 {
+		parts = append(parts, string(runes))
+	}
+	return parts
+}
+
+// lastRuneIndex returns the index of the last r in runes, or -1.
+func lastRuneIndex(runes []rune, r rune) int {
+	for i := len(runes) - 1; i >= 0; i-- {
+		if runes[i] == r {
+			return i
+		}
+	}
+	return -1
+}
+
+// truncateRunes shortens text to at most limit characters, ending it with an ellipsis if it was cut.
+func truncateRunes(text string, limit int) string {
+	runes := []rune(text)
+	if len(runes) <= limit {
+		return text
+	}
+	return strings.TrimSpace(string(runes[:limit-1])) + "…"
+}
+
+// tableCells splits a table row into its cells, as plain text with the links' URLs.
+func tableCells(row string) []string {
+	row = strings.TrimSpace(strings.ReplaceAll(row, `\|`, "\x00"))
+	row = strings.TrimSuffix(strings.TrimPrefix(row, "|"), "|")
+	var cells []string
+	for _, cell := range strings.Split(row, "|") {
+		cells = append(cells, plainInline(strings.ReplaceAll(strings.TrimSpace(cell), "\x00", "|"), true))
+	}
+	return cells
+}
+
+// renderTable lays out table rows in aligned columns, with a rule under the header row.
+func renderTable(rows [][]string) string {
+	var widths []int
+	for _, row := range rows {
+		for i, cell := range row {
+			if i == len(widths) {
+				widths = append(widths, 0)
+			}
+			widths[i] = max(widths[i], utf8.RuneCountInString(cell))
+		}
+	}
+	var b strings.Builder
+	writeRow := func(cells []string, pad string) {
+		for i, width := range widths {
+			cell := ""
+			if i < len(cells) {
+				cell = cells[i]
+			}
+			if i > 0 {
+				b.WriteString(" | ")
+			}
+			b.WriteString(cell + strings.Repeat(pad, width-utf8.RuneCountInString(cell)))
+		}
+		b.WriteString("\n")
+	}
+	writeRow(rows[0], " ")
+	rule := make([]string, len(widths))
+	writeRow(rule, "-")
+	for _, row := range rows[1:] {
+		writeRow(row, " ")
+	}
+	lines := strings.Split(strings.TrimRight(b.String(), "\n"), "\n")
+	for i := range lines {
+		lines[i] = strings.TrimRight(lines[i], " ")
+	}
+	return strings.Join(lines, "\n")
+}
+
+var (
+	mdLink        = regexp.MustCompile(`(!?)\[([^\]]*)\]\(\s*<?([^)\s>]+)>?(?:\s+"[^"]*")?\s*\)`)
+	mdSlackToken  = regexp.MustCompile(`<([@#!][^<>\s][^<>]*|(?:https?://|mailto:)[^<>\s]+)>`)
+	mdCitation    = regexp.MustCompile(`\[(\d{1,3})\]`)
+	mdBoldItalic  = regexp.MustCompile(`\*\*\*(\S|\S.*?\S)\*\*\*`)
+	mdBold        = regexp.MustCompile(`\*\*(\S|\S.*?\S)\*\*|__(\S|\S.*?\S)__`)
+	mdItalic      = regexp.MustCompile(`\*(\S|\S.*?\S)\*`)
+	mdStrike      = regexp.MustCompile(`~~(\S|\S.*?\S)~~`)
+	mdPlaceholder = regexp.MustCompile("\x00(\\d+)\x00")
+)
+
+// Markers of converted emphasis, replaced with the mrkdwn markers once all emphasis is converted, so
+// that **bold** converted to *bold* is not taken for italics.
+const (
+	boldMarker   = "\x01"
+	italicMarker = "\x02"
+	strikeMarker = "\x03"
+)
+
+// inlineMrkdwn converts the inline Markdown of a line to mrkdwn. citations maps source markers to URLs.
+func inlineMrkdwn(line string, citations map[int]string) string {
+	var b strings.Builder
+	for _, span := range codeSpans(line) {
+		if span.code {
+			// Slack ends inline code at any backtick; ˋ looks alike
+			b.WriteString("`" + escapeMrkdwn(strings.ReplaceAll(span.text, "`", "ˋ")) + "`")
+			continue
+		}
+		b.WriteString(inlineText(span.text, citations))
+	}
+	return b.String()
+}
+
+// inlineText converts Markdown without code spans to mrkdwn. Converted links and Slack's own forms are
+// set aside while the rest is escaped and its emphasis converted.
+func inlineText(text string, citations map[int]string) string {
+	var kept []string
+	keep := func(s string) string {
+		kept = append(kept, s)
+		return "\x00" + strconv.Itoa(len(kept)-1) + "\x00"
+	}
+
+	text = mdSlackToken.ReplaceAllStringFunc(text, func(token string) string {
+		return keep(escapeAmpersands(token))
+	})
+	text = mdLink.ReplaceAllStringFunc(text, func(link string) string {
+		m := mdLink.FindStringSubmatch(link)
+		label := plainInline(m[2], false)
+		if label == "" {
+			return keep("<" + escapeMrkdwn(m[3]) + ">")
+		}
+		return keep("<" + escapeMrkdwn(m[3]) + "|" + escapeMrkdwn(label) + ">")
+	})
+	text = replaceCitations(text, func(marker string, url string) string {
+		return keep("<" + escapeMrkdwn(url) + "|" + marker + ">")
+	}, citations)
+
+	text = escapeMrkdwn(text)
+	text = mdBoldItalic.ReplaceAllString(text, boldMarker+italicMarker+"${1}"+italicMarker+boldMarker)
+	text = mdBold.ReplaceAllString(text, boldMarker+"${1}${2}"+boldMarker)
+	text = mdItalic.ReplaceAllString(text, italicMarker+"${1}"+italicMarker)
+	text = mdStrike.ReplaceAllString(text, strikeMarker+"${1}"+strikeMarker)
+	text = strings.NewReplacer(boldMarker, "*", italicMarker, "_", strikeMarker, "~").Replace(text)
+
+	return mdPlaceholder.ReplaceAllStringFunc(text, func(placeholder string) string {
+		i, _ := strconv.Atoi(mdPlaceholder.FindStringSubmatch(placeholder)[1])
+		return kept[i]
+	})
+}
+
+// replaceCitations replaces the source markers, e.g. [1], of sources with a URL.
+func replaceCitations(text string, replace func(marker, url string) string, citations map[int]string) string {
+	if len(citations) == 0 {
+		return text
+	}
+	return mdCitation.ReplaceAllStringFunc(text, func(marker string) string {
+		n, _ := strconv.Atoi(mdCitation.FindStringSubmatch(marker)[1])
+		if url, ok := citations[n]; ok {
+			return replace(marker, url)
+		}
+		return marker
+	})
+}
+
+// plainInline removes the inline Markdown of text, for header blocks and preformatted tables. Links
+// become their label, followed by the URL in parentheses if withURLs is set.
+func plainInline(text string, withURLs bool) string {
+	var b strings.Builder
+	for _, span := range codeSpans(text) {
+		if span.code {
+			b.WriteString(span.text)
+			continue
+		}
+		s := mdLink.ReplaceAllStringFunc(span.text, func(link string) string {
+			m := mdLink.FindStringSubmatch(link)
+			label := plainInline(m[2], false)
+			switch {
+			case label == "":
+				return m[3]
+			case withURLs && label != m[3]:
+				return label + " (" + m[3] + ")"
+			}
+			return label
+		})
+		s = mdBoldItalic.ReplaceAllString(s, "${1}")
+		s = mdBold.ReplaceAllString(s, "${1}${2}")
+		s = mdItalic.ReplaceAllString(s, "${1}")
+		s = mdStrike.ReplaceAllString(s, "${1}")
+		b.WriteString(s)
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// inlineSpan is a code span, or the text between code spans.
+type inlineSpan struct {
+	text string
+	code bool
+}
+
+// codeSpans splits a line into code spans and the text between them. A code span starts with a run of
+// backticks and ends with a run of as many; an unmatched run is text.
+func codeSpans(line string) []inlineSpan {
+	var spans []inlineSpan
+	start := 0 // Start of the current text span
+	for i := 0; i < len(line); {
+		if line[i] != '`' {
+			i++
+			continue
+		}
+		run := i
+		for run < len(line) && line[run] == '`' {
+			run++
+		}
+		ticks := line[i:run]
+		end := closingTicks(line, run, len(ticks))
+		if end < 0 {
+			i = run
+			continue
+		}
+		if i > start {
+			spans = append(spans, inlineSpan{text: line[start:i]})
+		}
+		code := line[run:end]
+		if len(code) > 1 && code[0] == ' ' && code[len(code)-1] == ' ' && strings.Trim(code, " ") != "" {
+			code = code[1 : len(code)-1]
+		}
+		spans = append(spans, inlineSpan{text: code, code: true})
+		i = end + len(ticks)
+		start = i
+	}
+	if start < len(line) {
+		spans = append(spans, inlineSpan{text: line[start:]})
+	}
+	return spans
+}
+
+// closingTicks returns the index of the next run of exactly n backticks from i, or -1.
+func closingTicks(line string, i, n int) int {
+	for i < len(line) {
+		if line[i] != '`' {
+			i++
+			continue
+		}
+		run := i
+		for run < len(line) && line[run] == '`' {
+			run++
+		}
+		if run-i == n {
+			return i
+		}
+		i = run
+	}
+	return -1
+}
+
+// escapeMrkdwn escapes the characters Slack gives a meaning in text: &, < and >.
+func escapeMrkdwn(text string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(text)
+}
+
+// escapeAmpersands escapes the & of URLs in Slack's own forms.
+func escapeAmpersands(token string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(token, "&amp;", "&"), "&", "&amp;")
+}
+
+// citationURLs maps the markers of sources with a URL to the URL.
+func citationURLs(sources []models.ChatMessageSource) map[int]string {
+	urls := make(map[int]string)
+	for _, source := range sources {
+		if source.URL != "" {
+			urls[source.Marker] = source.URL
+		}
+	}
+	return urls
+}
+
+// citedSources returns the sources whose marker the reply cites, in the order of their markers.
+func citedSources(markdown string, sources []models.ChatMessageSource) []models.ChatMessageSource {
+	if len(sources) == 0 {
+		return nil
+	}
+	markers := make(map[int]bool)
+	for _, m := range mdCitation.FindAllStringSubmatch(markdown, -1) {
+		n, _ := strconv.Atoi(m[1])
+		markers[n] = true
+	}
+	var cited []models.ChatMessageSource
+	for _, source := range sources {
+		if markers[source.Marker] {
+			cited = append(cited, source)
+			delete(markers, source.Marker) // Sources are listed once
+		}
+	}
+	sort.SliceStable(cited, func(i, j int) bool { return cited[i].Marker < cited[j].Marker })
+	return cited
+}
+
+// sourcesContext lists the cited sources in a context block, linked to their URL if they have one.
+func sourcesContext(sources []models.ChatMessageSource) *slack.ContextBlock {
+	var elements []slack.MixedElement
+	for i, source := range sources {
+		if i == MaxContextElements {
+			break
+		}
+		title := source.Title
+		if title == "" {
+			title = source.SourceID
+		}
+		text := fmt.Sprintf("[%d] %s", source.Marker, escapeMrkdwn(truncateRunes(title, 200)))
+		if source.URL != "" {
+			text = "<" + escapeMrkdwn(source.URL) + "|" + text + ">"
+		}
+		elements = append(elements, slack.NewTextBlockObject(slack.MarkdownType, text, false, false))
+	}
+	return slack.NewContextBlock("sources", elements...)
+}
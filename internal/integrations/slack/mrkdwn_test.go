@@ -0,0 +1,119 @@
+package slack
+
+import (
+	"buildmychat-backend/internal/models"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/slack-go/slack"
+)
+
+// update rewrites the golden files: go test ./internal/integrations/slack -run TestFormatMessageGolden -update
+var update = flag.Bool("update", false, "rewrite the golden files in testdata/markdown")
+
+// testSources are the sources of the replies in testdata/markdown.
+var testSources = []models.ChatMessageSource{
+	{Marker: 1, Title: "Refund policy", SourceID: "refunds", URL: "https://example.com/refunds"},
+	{Marker: 2, Title: "Billing FAQ", SourceID: "billing.md"},
+	{Marker: 3, Title: "Uncited", SourceID: "other", URL: "https://example.com/other"},
+}
+
+// TestFormatMessageGolden converts each testdata/markdown/*.md reply and compares the messages with
+// the .golden file next to it.
+func TestFormatMessageGolden(t *testing.T) {
+	inputs, err := filepath.Glob(filepath.Join("testdata", "markdown", "*.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(inputs) == 0 {
+		t.Fatal("no testdata/markdown/*.md files")
+	}
+	for _, input := range inputs {
+		name := strings.TrimSuffix(filepath.Base(input), ".md")
+		t.Run(name, func(t *testing.T) {
+			markdown, err := os.ReadFile(input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := json.MarshalIndent(FormatMessage(string(markdown), testSources), "", "  ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, '\n')
+
+			golden := strings.TrimSuffix(input, ".md") + ".golden"
+			if *update {
+				if err := os.WriteFile(golden, got, 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("%v (run with -update to create it)", err)
+			}
+			if string(got) != string(want) {
+				t.Errorf("FormatMessage(%s) differs from %s:\n%s", input, golden, got)
+			}
+		})
+	}
+}
+
+func TestFormatMessageSplitsLongReplies(t *testing.T) {
+	paragraph := strings.Repeat("word ", 199) + "end." // 1,000 characters
+	var markdown []string
+	for i := 0; i < 200; i++ {
+		markdown = append(markdown, paragraph)
+	}
+	messages := FormatMessage(strings.Join(markdown, "\n\n"), nil)
+
+	if len(messages) != 2 {
+		t.Fatalf("%d messages for 200,000 characters, want 2", len(messages))
+	}
+	sections := 0
+	for _, msg := range messages {
+		if len(msg.Blocks) > MaxBlocks {
+			t.Errorf("message of %d blocks", len(msg.Blocks))
+		}
+		if utf8.RuneCountInString(msg.Text) > maxFallbackText {
+			t.Errorf("fallback text of %d characters", utf8.RuneCountInString(msg.Text))
+		}
+		for _, block := range msg.Blocks {
+			text := block.(*slack.SectionBlock).Text.Text
+			if n := utf8.RuneCountInString(text); n > MaxSectionText {
+				t.Errorf("section of %d characters", n)
+			}
+			if strings.Count(text, paragraph) != strings.Count(text, "end.") {
+				t.Error("a paragraph was split although it fits a section")
+			}
+			sections++
+		}
+	}
+	if sections != 100 { // Two paragraphs and their blank line fit a section, three do not
+		t.Errorf("%d sections, want 100", sections)
+	}
+
+	line := strings.Repeat("x", 120)
+	var code []string
+	for i := 0; i < 60; i++ {
+		code = append(code, line)
+	}
+	messages = FormatMessage("```\n"+strings.Join(code, "\n")+"\n```", nil)
+	if len(messages) != 1 || len(messages[0].Blocks) != 3 {
+		t.Fatalf("7,260 characters of code = %+v, want 3 sections", messages)
+	}
+	for _, block := range messages[0].Blocks {
+		text := block.(*slack.SectionBlock).Text.Text
+		if !strings.HasPrefix(text, "```\n") || !strings.HasSuffix(text, "\n```") || utf8.RuneCountInString(text) > MaxSectionText {
+			t.Errorf("code section of %d characters is not fenced on its own", utf8.RuneCountInString(text))
+		}
+	}
+
+	if messages := FormatMessage(" \n\n", nil); len(messages) != 0 {
+		t.Errorf("blank reply = %+v, want no messages", messages)
+	}
+}
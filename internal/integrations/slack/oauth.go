@@ -0,0 +1,141 @@
+package slack
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultBaseURL is where Slack serves the OAuth authorize page and its Web API.
+const DefaultBaseURL = "https://slack.com"
+
+// DefaultBotScopes are the bot token scopes requested when installing the app: enough to receive
+// mentions and messages and to reply to them.
+var DefaultBotScopes = []string{
+	"app_mentions:read",
+	"channels:history",
+	"groups:history",
+	"im:history",
+	"chat:write",
+	"commands",
+}
+
+// ErrAccessDenied is returned by Exchange when Slack rejects the authorization code, e.g. because it
+// expired or was already used.
+var ErrAccessDenied = errors.New("slack rejected the OAuth authorization")
+
+// OAuthClient installs the app in Slack workspaces through OAuth v2: the user approves the app on
+// the authorize page, and the code Slack redirects back with is exchanged for a bot token.
+type OAuthClient struct {
+	baseURL      string
+	clientID     string
+	clientSecret string
+	httpClient   *http.Client
+}
+
+// NewOAuthClient creates an OAuth client for the app with clientID and clientSecret. baseURL is
+// DefaultBaseURL, or a fake Slack server in tests; a nil httpClient gets a client with a sane timeout.
+func NewOAuthClient(baseURL, clientID, clientSecret string, httpClient *http.Client) *OAuthClient {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 30 * time.Second}
+	}
+	return &OAuthClient{
+		baseURL:      strings.TrimRight(baseURL, "/"),
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		httpClient:   httpClient,
+	}
+}
+
+// ClientID returns the app's client ID.
+func (c *OAuthClient) ClientID() string {
+	return c.clientID
+}
+
+// AuthorizeURL returns the page that asks the user to install the app with the bot scopes. Slack
+// redirects to redirectURI with the code and the state afterwards.
+func (c *OAuthClient) AuthorizeURL(state, redirectURI string, scopes []string) string {
+	query := url.Values{}
+	query.Set("client_id", c.clientID)
+	query.Set("scope", strings.Join(scopes, ","))
+	query.Set("state", state)
+	if redirectURI != "" {
+		query.Set("redirect_uri", redirectURI)
+	}
+	return c.baseURL + "/oauth/v2/authorize?" + query.Encode()
+}
+
+// OAuthAccess is the result of installing the app in a workspace.
+type OAuthAccess struct {
+	AccessToken string `json:"access_token"` // Bot token, xoxb-...
+	TokenType   string `json:"token_type"`   // "bot"
+	Scope       string `json:"scope"`        // Granted scopes, comma separated
+	BotUserID   string `json:"bot_user_id"`
+	AppID       string `json:"app_id"`
+	Team        struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"team"`
+	Enterprise *struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"enterprise"`
+	AuthedUser struct {
+		ID string `json:"id"` // The user who installed the app
+	} `json:"authed_user"`
+}
+
+type oauthAccessResponse struct {
+	OK    bool   `json:"ok"`
+	Error string `json:"error"`
+	OAuthAccess
+}
+
+// Exchange trades the code of an authorize redirect for a bot token with oauth.v2.access.
+// redirectURI must be the one the authorize URL was created with.
+func (c *OAuthClient) Exchange(ctx context.Context, code, redirectURI string) (*OAuthAccess, error) {
+	if code == "" {
+		return nil, fmt.Errorf("%w: missing authorization code", ErrAccessDenied)
+	}
+	form := url.Values{}
+	form.Set("code", code)
+	if redirectURI != "" {
+		form.Set("redirect_uri", redirectURI)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/api/oauth.v2.access", strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create oauth.v2.access request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	httpReq.SetBasicAuth(c.clientID, c.clientSecret)
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("oauth.v2.access request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, fmt.Errorf("oauth.v2.access returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+
+	var parsed oauthAccessResponse
+	if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil {
+		return nil, fmt.Errorf("failed to decode oauth.v2.access response: %w", err)
+	}
+	if !parsed.OK {
+		return nil, fmt.Errorf("%w: %s", ErrAccessDenied, parsed.Error)
+	}
+	if parsed.TokenType != "bot" || parsed.AccessToken == "" || parsed.Team.ID == "" {
+		return nil, fmt.Errorf("oauth.v2.access response has no bot token for a team")
+	}
+	return &parsed.OAuthAccess, nil
+}
@@ -0,0 +1,73 @@
+package slack
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Headers Slack signs its requests with.
+const (
+	HeaderRequestTimestamp = "X-Slack-Request-Timestamp"
+	HeaderSignature        = "X-Slack-Signature"
+)
+
+// MaxRequestAge is how far a request timestamp may be from the current time. Older requests are
+// rejected, so recorded requests cannot be replayed.
+const MaxRequestAge = 5 * time.Minute
+
+// Request verification errors.
+var (
+	ErrMissingSignature = errors.New("missing Slack request signature or timestamp")
+	ErrStaleRequest     = errors.New("slack request timestamp is too far from the current time")
+	ErrInvalidSignature = errors.New("invalid Slack request signature")
+)
+
+// VerifyRequest checks the v0 signature of a request from Slack: an HMAC-SHA256, keyed with the app's
+// signing secret, of "v0:<timestamp>:<body>". body must be the raw request body. Requests whose
+// timestamp is more than MaxRequestAge away from now are rejected.
+func VerifyRequest(header http.Header, body []byte, signingSecret string, now time.Time) error {
+	if signingSecret == "" {
+		return errors.New("no signing secret to verify the Slack request with")
+	}
+	timestamp := header.Get(HeaderRequestTimestamp)
+	signature := header.Get(HeaderSignature)
+	if timestamp == "" || signature == "" {
+		return ErrMissingSignature
+	}
+
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: invalid timestamp %q", ErrMissingSignature, timestamp)
+	}
+	if age := now.Sub(time.Unix(seconds, 0)); age > MaxRequestAge || age < -MaxRequestAge {
+		return fmt.Errorf("%w: request is %s old", ErrStaleRequest, age.Truncate(time.Second))
+	}
+
+	hexSignature, ok := strings.CutPrefix(signature, "v0=")
+	if !ok {
+		return fmt.Errorf("%w: unsupported version", ErrInvalidSignature)
+	}
+	got, err := hex.DecodeString(hexSignature)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	if !hmac.Equal(got, Sign(body, timestamp, signingSecret)) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// Sign returns the v0 HMAC-SHA256 of a request body sent at timestamp (Unix seconds).
+func Sign(body []byte, timestamp string, signingSecret string) []byte {
+	mac := hmac.New(sha256.New, []byte(signingSecret))
+	mac.Write([]byte("v0:" + timestamp + ":"))
+	mac.Write(body)
+	return mac.Sum(nil)
+}
@@ -0,0 +1,87 @@
+package slack
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// testSigningSecret signed the requests in testdata. slash_command.http is the example of Slack's
+// request verification guide.
+const testSigningSecret = "8f742231b10e8888abcd99yyyzzz85a5"
+
+// readFixture reads a recorded request, returning its headers, raw body and the time it was sent.
+func readFixture(t *testing.T, name string) (http.Header, []byte, time.Time) {
+	t.Helper()
+	f, err := os.Open(filepath.Join("testdata", name+".http"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	req, err := http.ReadRequest(bufio.NewReader(f))
+	if err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seconds, err := strconv.ParseInt(req.Header.Get(HeaderRequestTimestamp), 10, 64)
+	if err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	return req.Header, body, time.Unix(seconds, 0)
+}
+
+func TestVerifyRequestAcceptsRecordedRequests(t *testing.T) {
+	for _, name := range []string{"slash_command", "url_verification", "app_mention"} {
+		header, body, sentAt := readFixture(t, name)
+		if err := VerifyRequest(header, body, testSigningSecret, sentAt.Add(30*time.Second)); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+}
+
+func TestVerifyRequestRejects(t *testing.T) {
+	header, body, sentAt := readFixture(t, "app_mention")
+
+	tampered := append([]byte(nil), body...)
+	tampered[len(tampered)-2] = ' '
+
+	unsigned := header.Clone()
+	unsigned.Del(HeaderSignature)
+
+	otherVersion := header.Clone()
+	otherVersion.Set(HeaderSignature, "v1="+header.Get(HeaderSignature)[3:])
+
+	tests := []struct {
+		name   string
+		header http.Header
+		body   []byte
+		secret string
+		now    time.Time
+		want   error
+	}{
+		{"stale", header, body, testSigningSecret, sentAt.Add(MaxRequestAge + time.Second), ErrStaleRequest},
+		{"from the future", header, body, testSigningSecret, sentAt.Add(-MaxRequestAge - time.Second), ErrStaleRequest},
+		{"tampered body", header, tampered, testSigningSecret, sentAt, ErrInvalidSignature},
+		{"other secret", header, body, "0123456789abcdef0123456789abcdef", sentAt, ErrInvalidSignature},
+		{"unsigned", unsigned, body, testSigningSecret, sentAt, ErrMissingSignature},
+		{"unknown version", otherVersion, body, testSigningSecret, sentAt, ErrInvalidSignature},
+	}
+	for _, tt := range tests {
+		if err := VerifyRequest(tt.header, tt.body, tt.secret, tt.now); !errors.Is(err, tt.want) {
+			t.Errorf("%s: VerifyRequest = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+
+	if err := VerifyRequest(header, body, "", sentAt); err == nil {
+		t.Error("VerifyRequest without a signing secret succeeded")
+	}
+}
@@ -0,0 +1,205 @@
+package integrations
+
+import (
+	slack_sender "buildmychat-backend/internal/integrations/slack"
+	"buildmychat-backend/internal/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+// Ensure SlackIntegration implements the ReplyStreamer interface.
+var _ ReplyStreamer = (*SlackIntegration)(nil)
+
+const (
+	// slackDraftPlaceholder is posted as soon as a message is received, before the reply is generated.
+	slackDraftPlaceholder = "_Thinking…_"
+	// slackDraftInterval is the least time between edits of a reply being generated. chat.update allows
+	// about 50 calls a minute per workspace, and Slack asks for at most one message a second per channel.
+	slackDraftInterval = 1500 * time.Millisecond
+)
+
+// StartReply posts the "Thinking…" placeholder of a reply where Deliver would post the reply. Replies to
+// ephemeral slash commands are not streamed, as Slack accepts only a few messages per response URL.
+func (s *SlackIntegration) StartReply(ctx context.Context, iface ChannelInterface, conv Conversation) (ReplyDraft, error) {
+	var target slackConversation
+	if len(conv.Configuration) > 0 {
+		if err := json.Unmarshal(conv.Configuration, &target); err != nil {
+			return nil, fmt.Errorf("invalid Slack chat configuration: %w", err)
+		}
+	}
+	if target.ResponseURL != "" || target.ChannelID == "" {
+		return nil, nil
+	}
+	botToken, err := slackBotToken(iface)
+	if err != nil {
+		return nil, err
+	}
+	config, _ := slackInterfaceConfig(iface) // Validated when the interface was saved
+
+	ts, err := slack_sender.PostMessage(ctx, botToken, target.ChannelID, target.ThreadTS, slackDraftPlaceholder, nil)
+	if err != nil {
+		return nil, err
+	}
+	draft := newSlackDraft(target.ChannelID, ts, config.EscalateButton, slackDraftInterval,
+		func(ctx context.Context, text string, blocks []slack.Block) error {
+			return slack_sender.UpdateMessage(ctx, botToken, target.ChannelID, ts, text, blocks)
+		},
+		func(ctx context.Context, text string, blocks []slack.Block) (string, error) {
+			return slack_sender.PostMessage(ctx, botToken, target.ChannelID, target.ThreadTS, text, blocks)
+		})
+	go draft.run(ctx)
+	return draft, nil
+}
+
+// slackDraft is a reply being generated, shown by editing its placeholder message.
+type slackDraft struct {
+	channelID string
+	ts        string // The placeholder message
+	escalate  bool   // Whether the final reply gets the "Escalate to human" button
+	interval  time.Duration
+	update    func(ctx context.Context, text string, blocks []slack.Block) error           // Edits the placeholder
+	post      func(ctx context.Context, text string, blocks []slack.Block) (string, error) // Posts further parts of the reply
+
+	mu      sync.Mutex
+	text    string        // The latest text generated
+	changed chan struct{} // Signals a new text to run
+	stop    chan struct{}
+	stopped chan struct{}
+	once    sync.Once
+}
+
+func newSlackDraft(channelID, ts string, escalate bool, interval time.Duration,
+	update func(ctx context.Context, text string, blocks []slack.Block) error,
+	post func(ctx context.Context, text string, blocks []slack.Block) (string, error)) *slackDraft {
+	return &slackDraft{
+		channelID: channelID,
+		ts:        ts,
+		escalate:  escalate,
+		interval:  interval,
+		update:    update,
+		post:      post,
+		changed:   make(chan struct{}, 1),
+		stop:      make(chan struct{}),
+		stopped:   make(chan struct{}),
+	}
+}
+
+// Update records the text generated so far, which run shows with the next edit.
+func (d *slackDraft) Update(text string) {
+	d.mu.Lock()
+	d.text = text
+	d.mu.Unlock()
+	d.signal()
+}
+
+// signal wakes run up, unless it already has a text to show.
+func (d *slackDraft) signal() {
+	select {
+	case d.changed <- struct{}{}:
+	default:
+	}
+}
+
+// run edits the placeholder with the latest text, at most once per interval, until Finish or ctx ends.
+// When Slack rate limits an edit, the next one waits as long as Slack asks.
+func (d *slackDraft) run(ctx context.Context) {
+	defer close(d.stopped)
+	var shown string
+	var next time.Time // Earliest time of the next edit
+	for {
+		select {
+		case <-d.changed:
+		case <-d.stop:
+			return
+		case <-ctx.Done():
+			return
+		}
+		if wait := time.Until(next); wait > 0 {
+			select {
+			case <-time.After(wait):
+			case <-d.stop:
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		d.mu.Lock()
+		text := d.text
+		d.mu.Unlock()
+		if text == shown {
+			continue
+		}
+		fallback, blocks := slackDraftBlocks(text)
+		err := d.update(ctx, fallback, blocks)
+		next = time.Now().Add(d.interval)
+		var rateLimited *slack.RateLimitedError
+		switch {
+		case errors.As(err, &rateLimited):
+			next = time.Now().Add(rateLimited.RetryAfter)
+			d.signal() // Show the text once Slack allows it
+			continue
+		case err != nil:
+			log.Printf("WARN [SlackIntegration] Failed to update draft %s in channel %s: %v", d.ts, d.channelID, err)
+		}
+		shown = text
+	}
+}
+
+// Finish stops the edits of the text generated so far and replaces the placeholder with the first post
+// of the final message. Further posts of a long message follow it. A rate limited edit is retried once
+// Slack allows it.
+func (d *slackDraft) Finish(ctx context.Context, msg OutboundMessage) (*models.ChatMessageDelivery, error) {
+	d.once.Do(func() { close(d.stop) })
+	<-d.stopped
+
+	messages := slackMessages(msg, d.escalate)
+	if len(messages) == 0 {
+		return nil, errors.New("the message is empty")
+	}
+	err := d.update(ctx, messages[0].Text, messages[0].Blocks)
+	var rateLimited *slack.RateLimitedError
+	if errors.As(err, &rateLimited) {
+		select {
+		case <-time.After(rateLimited.RetryAfter):
+			err = d.update(ctx, messages[0].Text, messages[0].Blocks)
+		case <-ctx.Done():
+			err = ctx.Err()
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	delivery := &models.ChatMessageDelivery{ServiceType: models.ServiceTypeSlack, ChannelID: d.channelID, MessageIDs: []string{d.ts}}
+	for _, m := range messages[1:] {
+		ts, err := d.post(ctx, m.Text, m.Blocks)
+		if err != nil {
+			return delivery, err
+		}
+		delivery.MessageIDs = append(delivery.MessageIDs, ts)
+	}
+	return delivery, nil
+}
+
+// slackDraftBlocks lays out the text of a reply being generated: as much of it as fits a message, and
+// a note that more is coming.
+func slackDraftBlocks(text string) (string, []slack.Block) {
+	writing := slack.NewContextBlock("draft", slack.NewTextBlockObject(slack.MarkdownType, "_Writing…_", false, false))
+	messages := slack_sender.FormatMessage(text, nil)
+	if len(messages) == 0 {
+		return slackDraftPlaceholder, []slack.Block{writing}
+	}
+	blocks := messages[0].Blocks
+	if len(blocks) >= slack_sender.MaxBlocks {
+		blocks = blocks[:slack_sender.MaxBlocks-1]
+	}
+	return messages[0].Text, append(blocks[:len(blocks):len(blocks)], writing)
+}
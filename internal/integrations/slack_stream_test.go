@@ -0,0 +1,140 @@
+package integrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/slack-go/slack"
+)
+
+// fakeSlackMessage records the edits and posts of a slackDraft.
+type fakeSlackMessage struct {
+	mu          sync.Mutex
+	edits       []string // Fallback text of each edit
+	lastBlocks  []slack.Block
+	posts       int
+	rateLimited int // Edits to answer with a rate limit error
+}
+
+func (f *fakeSlackMessage) update(ctx context.Context, text string, blocks []slack.Block) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.rateLimited > 0 {
+		f.rateLimited--
+		return &slack.RateLimitedError{RetryAfter: 50 * time.Millisecond}
+	}
+	f.edits = append(f.edits, text)
+	f.lastBlocks = blocks
+	return nil
+}
+
+func (f *fakeSlackMessage) post(ctx context.Context, text string, blocks []slack.Block) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.posts++
+	return fmt.Sprintf("1700000000.%06d", f.posts+1), nil
+}
+
+func (f *fakeSlackMessage) editCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.edits)
+}
+
+func TestSlackDraftThrottlesEdits(t *testing.T) {
+	fake := &fakeSlackMessage{}
+	draft := newSlackDraft("C1", "1700000000.000001", false, 100*time.Millisecond, fake.update, fake.post)
+	go draft.run(context.Background())
+
+	var text strings.Builder
+	for i := 0; i < 20; i++ {
+		text.WriteString("word ")
+		draft.Update(text.String())
+		time.Sleep(10 * time.Millisecond)
+	}
+	time.Sleep(150 * time.Millisecond)
+
+	// 200ms of updates at one edit per 100ms, and the latest text once the interval has passed
+	if n := fake.editCount(); n < 2 || n > 4 {
+		t.Errorf("%d edits for 20 updates over 200ms, want 2 to 4", n)
+	}
+	fake.mu.Lock()
+	last := fake.edits[len(fake.edits)-1]
+	writing := fake.lastBlocks[len(fake.lastBlocks)-1]
+	fake.mu.Unlock()
+	if last != strings.TrimSpace(text.String()) {
+		t.Errorf("last edit = %q, want the latest text", last)
+	}
+	if writing.BlockType() != slack.MBTContext {
+		t.Errorf("last block of a draft = %s, want the \"Writing…\" context", writing.BlockType())
+	}
+
+	chatID := uuid.New()
+	delivery, err := draft.Finish(context.Background(), OutboundMessage{ChatID: chatID, Text: "The **final** reply."})
+	if err != nil {
+		t.Fatalf("Finish: %v", err)
+	}
+	if delivery == nil || delivery.ChannelID != "C1" || len(delivery.MessageIDs) != 1 || delivery.MessageIDs[0] != "1700000000.000001" {
+		t.Errorf("delivery = %+v, want the placeholder in C1", delivery)
+	}
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	if last := fake.edits[len(fake.edits)-1]; last != "The *final* reply." {
+		t.Errorf("final edit = %q, want the reply", last)
+	}
+	actions, ok := fake.lastBlocks[len(fake.lastBlocks)-1].(*slack.ActionBlock)
+	if !ok || actions.BlockID != slackFeedbackBlockID {
+		t.Errorf("last block of the reply = %+v, want the feedback buttons", fake.lastBlocks[len(fake.lastBlocks)-1])
+	}
+}
+
+func TestSlackDraftRateLimited(t *testing.T) {
+	fake := &fakeSlackMessage{rateLimited: 1}
+	draft := newSlackDraft("C1", "1700000000.000001", false, 10*time.Millisecond, fake.update, fake.post)
+	go draft.run(context.Background())
+
+	draft.Update("Partial")
+	deadline := time.Now().Add(time.Second)
+	for fake.editCount() == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if fake.editCount() != 1 {
+		t.Fatal("the rate limited edit was not retried")
+	}
+
+	// Finish retries a rate limited final edit, and posts the rest of a long reply
+	fake.mu.Lock()
+	fake.rateLimited = 1
+	fake.mu.Unlock()
+	paragraph := strings.Repeat("word ", 199) + "end."
+	long := strings.TrimSpace(strings.Repeat(paragraph+"\n\n", 120))
+	delivery, err := draft.Finish(context.Background(), OutboundMessage{ChatID: uuid.New(), Text: long})
+	if err != nil {
+		t.Fatalf("Finish: %v", err)
+	}
+	if len(delivery.MessageIDs) != 2 || fake.posts != 1 {
+		t.Errorf("delivery = %+v after %d posts, want the placeholder and one more post", delivery, fake.posts)
+	}
+}
+
+func TestSlackDraftFinishFails(t *testing.T) {
+	fake := &fakeSlackMessage{}
+	failing := func(ctx context.Context, text string, blocks []slack.Block) error {
+		return errors.New("message_not_found")
+	}
+	draft := newSlackDraft("C1", "1700000000.000001", false, time.Second, failing, fake.post)
+	go draft.run(context.Background())
+
+	if _, err := draft.Finish(context.Background(), OutboundMessage{Text: "Sorry"}); err == nil {
+		t.Error("Finish succeeded although the placeholder could not be edited")
+	}
+	if fake.posts != 0 {
+		t.Errorf("%d posts after the placeholder could not be edited, want none", fake.posts)
+	}
+}
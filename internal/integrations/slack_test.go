@@ -0,0 +1,480 @@
+package integrations
+
+import (
+	slack_sender "buildmychat-backend/internal/integrations/slack"
+	"buildmychat-backend/internal/models"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/slack-go/slack"
+)
+
+func TestSlackParseWebhook(t *testing.T) {
+	s := NewSlackIntegration("")
+
+	event, err := s.ParseWebhook([]byte(`{"type":"url_verification","challenge":"abc123","token":"x"}`), ChannelInterface{})
+	if err != nil {
+		t.Fatalf("url_verification: %v", err)
+	}
+	if event.Response == nil || string(event.Response.Body) != "abc123" || event.Response.ContentType != "text/plain" {
+		t.Errorf("url_verification response = %+v, want the challenge as text/plain", event.Response)
+	}
+
+	event, err = s.ParseWebhook([]byte(`{"type":"event_callback","team_id":"T1","event_id":"Ev1",
+		"event":{"type":"app_mention","user":"U1","channel":"C1","text":"hi","ts":"1700000000.000100"}}`), ChannelInterface{})
+	if err != nil {
+		t.Fatalf("app_mention: %v", err)
+	}
+	want := InboundMessage{EventID: "Ev1", WorkspaceID: "T1", ChannelID: "C1", UserID: "U1", MessageID: "1700000000.000100", Text: "hi"}
+	if event.Message == nil || *event.Message != want {
+		t.Fatalf("app_mention message = %+v, want %+v", event.Message, want)
+	}
+
+	event, err = s.ParseWebhook([]byte(`{"type":"event_callback","team_id":"T1","event":{"type":"reaction_added","user":"U1"}}`), ChannelInterface{})
+	if err != nil || event.Response != nil || event.Message != nil {
+		t.Errorf("reaction_added = %+v, %v; want an ignored event", event, err)
+	}
+
+	for _, body := range []string{
+		`not json`,
+		`{"type":"app_rate_limited"}`,
+		`{"type":"event_callback","team_id":"T1","event":{"type":"app_mention","channel":"C1","text":"no user"}}`,
+	} {
+		if _, err := s.ParseWebhook([]byte(body), ChannelInterface{}); err == nil {
+			t.Errorf("ParseWebhook(%s) succeeded, want an error", body)
+		}
+	}
+}
+
+func TestSlackConversation(t *testing.T) {
+	s := NewSlackIntegration("")
+	threadsInDMs := ChannelInterface{Configuration: json.RawMessage(`{"direct_message_threads":true}`)}
+
+	tests := []struct {
+		name       string
+		msg        InboundMessage
+		iface      ChannelInterface
+		wantKey    string
+		wantThread string
+	}{
+		{"channel message", InboundMessage{WorkspaceID: "T1", ChannelID: "C1", MessageID: "100.1"}, ChannelInterface{}, "T1_C1_100.1", "100.1"},
+		{"reply in channel thread", InboundMessage{WorkspaceID: "T1", ChannelID: "C1", MessageID: "105.3", ThreadID: "100.1"}, ChannelInterface{}, "T1_C1_100.1", "100.1"},
+		{"direct message", InboundMessage{WorkspaceID: "T1", ChannelID: "D1", MessageID: "200.1", DirectMessage: true}, ChannelInterface{}, "T1_D1", ""},
+		{"reply in direct message thread", InboundMessage{WorkspaceID: "T1", ChannelID: "D1", MessageID: "205.1", ThreadID: "200.1", DirectMessage: true}, ChannelInterface{}, "T1_D1", "200.1"},
+		{"direct message, threads", InboundMessage{WorkspaceID: "T1", ChannelID: "D1", MessageID: "200.1", DirectMessage: true}, threadsInDMs, "T1_D1_200.1", "200.1"},
+		{"reply in direct message thread, threads", InboundMessage{WorkspaceID: "T1", ChannelID: "D1", MessageID: "205.1", ThreadID: "200.1", DirectMessage: true}, threadsInDMs, "T1_D1_200.1", "200.1"},
+	}
+	for _, tt := range tests {
+		conv := s.Conversation(&tt.msg, tt.iface)
+		var target slackConversation
+		if err := json.Unmarshal(conv.Configuration, &target); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if conv.Key != tt.wantKey || target.ChannelID != tt.msg.ChannelID || target.ThreadTS != tt.wantThread {
+			t.Errorf("%s: key %q, configuration %s; want key %q, thread %q", tt.name, conv.Key, conv.Configuration, tt.wantKey, tt.wantThread)
+		}
+	}
+}
+
+func TestSlackParseWebhookFiltersMessages(t *testing.T) {
+	s := NewSlackIntegration("")
+	event := func(fields string) []byte {
+		return []byte(`{"type":"event_callback","team_id":"T1","event_id":"Ev1",
+			"authorizations":[{"team_id":"T1","user_id":"UBOT","is_bot":true}],
+			"event":{"channel":"C1","ts":"1700000000.000100",` + fields + `}}`)
+	}
+	mention := event(`"type":"app_mention","user":"U1","text":"<@UBOT> what is <@U2>'s plan?"`)
+	channelMessage := event(`"type":"message","channel_type":"channel","user":"U1","text":"<@UBOT> what is <@U2>'s plan?"`)
+	directMessage := event(`"type":"message","channel_type":"im","user":"U1","text":"hello"`)
+
+	tests := []struct {
+		name     string
+		body     []byte
+		policy   string
+		wantText string // "" if the event is ignored
+	}{
+		{"mention", mention, "", "what is <@U2>'s plan?"},
+		{"message with mention", channelMessage, "", ""},
+		{"direct message", directMessage, "", "hello"},
+		{"mention, all policy", mention, "all", ""},
+		{"channel message, all policy", channelMessage, "all", "what is <@U2>'s plan?"},
+		{"mention, direct_messages policy", mention, "direct_messages", ""},
+		{"direct message, direct_messages policy", directMessage, "direct_messages", "hello"},
+		{"bot message", event(`"type":"message","channel_type":"im","subtype":"bot_message","bot_id":"B1","text":"hi"`), "", ""},
+		{"other bot", event(`"type":"message","channel_type":"im","user":"U3","bot_id":"B2","text":"hi"`), "", ""},
+		{"own reply", event(`"type":"message","channel_type":"im","user":"UBOT","text":"Here you go"`), "", ""},
+		{"edit", event(`"type":"message","channel_type":"im","subtype":"message_changed","message":{"user":"U1","text":"hi"}`), "", ""},
+		{"deletion", event(`"type":"message","channel_type":"im","subtype":"message_deleted","deleted_ts":"1"`), "", ""},
+		{"file share", event(`"type":"message","channel_type":"im","subtype":"file_share","user":"U1","text":"see file"`), "", "see file"},
+		{"bare mention", event(`"type":"app_mention","user":"U1","text":"<@UBOT|bot> "`), "", ""},
+	}
+	for _, tt := range tests {
+		config, _ := json.Marshal(map[string]string{"reply_policy": tt.policy})
+		got, err := s.ParseWebhook(tt.body, ChannelInterface{Configuration: config})
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		var gotText string
+		if got.Message != nil {
+			gotText = got.Message.Text
+		}
+		if gotText != tt.wantText {
+			t.Errorf("%s: text = %q, want %q", tt.name, gotText, tt.wantText)
+		}
+	}
+}
+
+func TestStripSlackMentions(t *testing.T) {
+	tests := []struct {
+		text    string
+		userIDs []string
+		want    string
+	}{
+		{"<@UBOT> hi", []string{"UBOT"}, "hi"},
+		{"ask <@UBOT|helper> about <@U2>", []string{"UBOT"}, "ask about <@U2>"},
+		{"<@U9> hi <@U2>", nil, "hi <@U2>"},
+		{"hi <@U9>", nil, "hi <@U9>"},
+	}
+	for _, tt := range tests {
+		if got := stripSlackMentions(tt.text, tt.userIDs); got != tt.want {
+			t.Errorf("stripSlackMentions(%q, %v) = %q, want %q", tt.text, tt.userIDs, got, tt.want)
+		}
+	}
+}
+
+func TestSlackValidateConfig(t *testing.T) {
+	s := NewSlackIntegration("")
+	if err := s.ValidateConfig(json.RawMessage(`{"slack_team_id":"T1","reply_policy":"all"}`)); err != nil {
+		t.Errorf("valid configuration: %v", err)
+	}
+	if err := s.ValidateConfig(json.RawMessage(`{"reply_policy":"everything"}`)); err == nil {
+		t.Error("unknown reply_policy was accepted")
+	}
+	if err := s.ValidateConfig(json.RawMessage(`{"slash_command":"/ask","slash_command_replies":"ephemeral","escalate_button":true}`)); err != nil {
+		t.Errorf("valid slash command configuration: %v", err)
+	}
+	for _, config := range []string{`{"slash_command":"ask"}`, `{"slash_command":"/"}`, `{"slash_command":"/ask me"}`, `{"slash_command_replies":"channel"}`} {
+		if err := s.ValidateConfig(json.RawMessage(config)); err == nil {
+			t.Errorf("invalid configuration %s was accepted", config)
+		}
+	}
+}
+
+func TestSlackVerifyWebhook(t *testing.T) {
+	s := NewSlackIntegration("")
+	body := []byte(`{"type":"url_verification","challenge":"abc123"}`)
+	iface := ChannelInterface{Credentials: json.RawMessage(`{"bot_token":"xoxb-1","signing_secret":"secret"}`)}
+
+	signed := func(secret string, sentAt time.Time) http.Header {
+		timestamp := strconv.FormatInt(sentAt.Unix(), 10)
+		header := http.Header{}
+		header.Set(slack_sender.HeaderRequestTimestamp, timestamp)
+		header.Set(slack_sender.HeaderSignature, "v0="+hex.EncodeToString(slack_sender.Sign(body, timestamp, secret)))
+		return header
+	}
+
+	if err := s.VerifyWebhook(signed("secret", time.Now()), body, iface); err != nil {
+		t.Errorf("signed request: %v", err)
+	}
+	if err := s.VerifyWebhook(signed("other", time.Now()), body, iface); err == nil {
+		t.Error("request signed with another secret was accepted")
+	}
+	if err := s.VerifyWebhook(signed("secret", time.Now().Add(-10*time.Minute)), body, iface); err == nil {
+		t.Error("replayed request was accepted")
+	}
+	if err := s.VerifyWebhook(http.Header{}, body, iface); err == nil {
+		t.Error("unsigned request was accepted")
+	}
+	noSecret := ChannelInterface{Credentials: json.RawMessage(`{"bot_token":"xoxb-1"}`)}
+	if err := s.VerifyWebhook(signed("", time.Now()), body, noSecret); err == nil {
+		t.Error("request for a credential without signing_secret was accepted")
+	}
+}
+
+func TestSlackRouteWebhook(t *testing.T) {
+	s := NewSlackIntegration("")
+	tests := []struct {
+		body    string
+		want    WebhookRoute
+		wantErr bool
+	}{
+		{`{"type":"url_verification","challenge":"abc123"}`, WebhookRoute{}, false},
+		{`{"type":"event_callback","team_id":"T1","api_app_id":"A1","event":{"type":"app_mention"}}`, WebhookRoute{WorkspaceID: "T1", AppID: "A1"}, false},
+		{`{"type":"app_rate_limited","team_id":"T1","api_app_id":"A1"}`, WebhookRoute{WorkspaceID: "T1", AppID: "A1"}, false},
+		{`{"type":"event_callback","event":{"type":"app_mention"}}`, WebhookRoute{}, true},
+		{`not json`, WebhookRoute{}, true},
+		{"command=%2Fask&text=hi&team_id=T1&api_app_id=A1", WebhookRoute{WorkspaceID: "T1", AppID: "A1"}, false},
+		{"command=%2Fask&text=hi", WebhookRoute{}, true},
+		{"payload=" + url.QueryEscape(`{"type":"block_actions","team":{"id":"T1"},"api_app_id":"A1"}`), WebhookRoute{WorkspaceID: "T1", AppID: "A1"}, false},
+		{"payload=" + url.QueryEscape(`{"type":"block_actions","team":null,"user":{"id":"U1","team_id":"T2"}}`), WebhookRoute{WorkspaceID: "T2"}, false},
+		{"payload=" + url.QueryEscape(`{"type":"block_actions"}`), WebhookRoute{}, true},
+	}
+	for _, tt := range tests {
+		got, err := s.RouteWebhook([]byte(tt.body))
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("RouteWebhook(%s) = %+v, %v; want %+v, error %t", tt.body, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestSlackVerifyAppWebhook(t *testing.T) {
+	body := []byte(`{"type":"url_verification","challenge":"abc123"}`)
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	header := http.Header{}
+	header.Set(slack_sender.HeaderRequestTimestamp, timestamp)
+	header.Set(slack_sender.HeaderSignature, "v0="+hex.EncodeToString(slack_sender.Sign(body, timestamp, "app-secret")))
+
+	if err := NewSlackIntegration("app-secret").VerifyAppWebhook(header, body); err != nil {
+		t.Errorf("request signed with the app secret: %v", err)
+	}
+	if err := NewSlackIntegration("other").VerifyAppWebhook(header, body); err == nil {
+		t.Error("request signed with another secret was accepted")
+	}
+	if err := NewSlackIntegration("").VerifyAppWebhook(header, body); err == nil {
+		t.Error("request was accepted without an app signing secret")
+	}
+}
+
+func TestSlackParseWebhookChecksTeam(t *testing.T) {
+	s := NewSlackIntegration("")
+	body := []byte(`{"type":"event_callback","team_id":"T1","api_app_id":"A1","event_id":"Ev1",
+		"event":{"type":"app_mention","user":"U1","channel":"C1","text":"hi","ts":"1700000000.000100"}}`)
+
+	for _, config := range []string{`{}`, `{"slack_team_id":"T1"}`, `{"slack_team_id":"T1","slack_app_id":"A1"}`} {
+		event, err := s.ParseWebhook(body, ChannelInterface{Configuration: json.RawMessage(config)})
+		if err != nil || event.Message == nil {
+			t.Errorf("interface %s: %+v, %v; want the message", config, event, err)
+		}
+	}
+	for _, config := range []string{`{"slack_team_id":"T2"}`, `{"slack_team_id":"T1","slack_app_id":"A2"}`} {
+		if _, err := s.ParseWebhook(body, ChannelInterface{Configuration: json.RawMessage(config)}); err == nil {
+			t.Errorf("interface %s accepted an event for team T1 and app A1", config)
+		}
+	}
+}
+
+// slashCommand is the form body of a slash command in team T1 and channel C1.
+func slashCommand(command, text string) []byte {
+	return []byte(url.Values{
+		"command":      {command},
+		"text":         {text},
+		"team_id":      {"T1"},
+		"api_app_id":   {"A1"},
+		"channel_id":   {"C1"},
+		"channel_name": {"general"},
+		"user_id":      {"U1"},
+		"trigger_id":   {"398738663015.47445629121.803a0bc887a14d10d2c447fce8b6703c"},
+		"response_url": {"https://hooks.slack.com/commands/T1/1/abc"},
+	}.Encode())
+}
+
+func TestSlackParseSlashCommand(t *testing.T) {
+	s := NewSlackIntegration("")
+
+	event, err := s.ParseWebhook(slashCommand("/ask", " What is our refund policy? "), ChannelInterface{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := InboundMessage{EventID: "398738663015.47445629121.803a0bc887a14d10d2c447fce8b6703c", WorkspaceID: "T1", ChannelID: "C1", UserID: "U1", MessageID: "398738663015.47445629121.803a0bc887a14d10d2c447fce8b6703c",
+		Text: "What is our refund policy?", Command: "/ask", ReplyURL: "https://hooks.slack.com/commands/T1/1/abc"}
+	if event.Message == nil || *event.Message != want {
+		t.Fatalf("message = %+v, want %+v", event.Message, want)
+	}
+	if event.Response == nil || len(event.Response.Body) != 0 {
+		t.Errorf("response = %+v, want an empty acknowledgement", event.Response)
+	}
+
+	ephemeral := ChannelInterface{Configuration: json.RawMessage(`{"slash_command":"/support","slash_command_replies":"ephemeral"}`)}
+	event, err = s.ParseWebhook(slashCommand("/support", "hi"), ephemeral)
+	if err != nil || event.Message == nil || event.Response == nil || event.Response.ContentType != "application/json" ||
+		!strings.Contains(string(event.Response.Body), `"response_type":"ephemeral"`) {
+		t.Errorf("ephemeral command = %+v, %v; want the message and an ephemeral acknowledgement", event, err)
+	}
+
+	for _, body := range [][]byte{slashCommand("/support", "hi"), slashCommand("/ask", "  ")} {
+		event, err := s.ParseWebhook(body, ChannelInterface{})
+		if err != nil || event.Message != nil || event.Response == nil || !strings.Contains(string(event.Response.Body), "/ask <question>") {
+			t.Errorf("ParseWebhook(%s) = %+v, %v; want the usage", body, event, err)
+		}
+	}
+
+	if _, err := s.ParseWebhook(slashCommand("/ask", "hi"), ChannelInterface{Configuration: json.RawMessage(`{"slack_team_id":"T2"}`)}); err == nil {
+		t.Error("command for another team was accepted")
+	}
+}
+
+// blockAction is the form body of a click on a button of a message in thread 1700000000.000100.
+func blockAction(actionID, value string, ephemeral bool) []byte {
+	payload, _ := json.Marshal(map[string]interface{}{
+		"type":         "block_actions",
+		"trigger_id":   "13345224609.738474920.8088930838d88f008e0",
+		"api_app_id":   "A1",
+		"team":         map[string]string{"id": "T1"},
+		"user":         map[string]string{"id": "U1", "team_id": "T1"},
+		"channel":      map[string]string{"id": "C1"},
+		"container":    map[string]interface{}{"type": "message", "channel_id": "C1", "message_ts": "1700000000.000200", "is_ephemeral": ephemeral},
+		"message":      map[string]string{"ts": "1700000000.000200", "thread_ts": "1700000000.000100"},
+		"response_url": "https://hooks.slack.com/actions/T1/1/abc",
+		"actions":      []map[string]string{{"type": "button", "action_id": actionID, "block_id": slackFeedbackBlockID, "value": value}},
+	})
+	return []byte(url.Values{"payload": {string(payload)}}.Encode())
+}
+
+func TestSlackParseInteraction(t *testing.T) {
+	s := NewSlackIntegration("")
+	chatID := uuid.New()
+	iface := ChannelInterface{Configuration: json.RawMessage(`{"slack_team_id":"T1","escalation_message":"Support will follow up."}`)}
+
+	event, err := s.ParseWebhook(blockAction(ActionFeedbackPositive, chatID.String(), false), iface)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.Action == nil || event.Action.Action != ActionFeedbackPositive || event.Action.ChatID != chatID || event.Action.UserID != "U1" || event.Response == nil {
+		t.Fatalf("feedback = %+v", event)
+	}
+	if event.Action.EventID != "13345224609.738474920.8088930838d88f008e0" {
+		t.Errorf("feedback event ID = %q, want the trigger ID", event.Action.EventID)
+	}
+	if got := string(event.Action.NoticeConversation.Configuration); got != `{"response_url":"https://hooks.slack.com/actions/T1/1/abc"}` {
+		t.Errorf("feedback notice goes to %s, want the response URL", got)
+	}
+
+	event, err = s.ParseWebhook(blockAction(ActionEscalate, chatID.String(), false), iface)
+	if err != nil || event.Action == nil {
+		t.Fatalf("escalation = %+v, %v", event, err)
+	}
+	if event.Action.Notice != "<@U1> asked for a human to take over. Support will follow up." {
+		t.Errorf("escalation notice = %q", event.Action.Notice)
+	}
+	if got := string(event.Action.NoticeConversation.Configuration); got != `{"channel_id":"C1","thread_ts":"1700000000.000100"}` {
+		t.Errorf("escalation notice goes to %s, want the reply's thread", got)
+	}
+	event, err = s.ParseWebhook(blockAction(ActionEscalate, chatID.String(), true), iface)
+	if err != nil || event.Action == nil || !strings.Contains(string(event.Action.NoticeConversation.Configuration), "response_url") {
+		t.Errorf("escalation of an ephemeral reply = %+v, %v; want the notice sent to the response URL", event, err)
+	}
+
+	event, err = s.ParseWebhook(blockAction("other_button", "x", false), iface)
+	if err != nil || event.Action != nil || event.Message != nil {
+		t.Errorf("other button = %+v, %v; want an ignored interaction", event, err)
+	}
+	if _, err := s.ParseWebhook(blockAction(ActionFeedbackNegative, "not-a-chat", false), iface); err == nil {
+		t.Error("action with an invalid chat ID was accepted")
+	}
+	if _, err := s.ParseWebhook(blockAction(ActionFeedbackNegative, chatID.String(), false), ChannelInterface{Configuration: json.RawMessage(`{"slack_team_id":"T2"}`)}); err == nil {
+		t.Error("action for another team was accepted")
+	}
+}
+
+func TestSlackMessages(t *testing.T) {
+	chatID := uuid.New()
+	messages := slackMessages(OutboundMessage{ChatID: chatID, Text: "**Hello** [1]", Sources: []models.ChatMessageSource{{Marker: 1, Title: "Guide", URL: "https://example.com/guide"}}}, true)
+	if len(messages) != 1 {
+		t.Fatalf("%d messages, want 1", len(messages))
+	}
+	blocks := messages[0].Blocks
+	if len(blocks) != 3 || blocks[0].BlockType() != slack.MBTSection || blocks[1].BlockType() != slack.MBTContext || blocks[2].BlockType() != slack.MBTAction {
+		t.Fatalf("blocks = %+v, want the text, its sources and the buttons", blocks)
+	}
+	if text := blocks[0].(*slack.SectionBlock).Text.Text; text != "*Hello* <https://example.com/guide|[1]>" {
+		t.Errorf("text = %q, want mrkdwn", text)
+	}
+	buttons := blocks[2].(*slack.ActionBlock).Elements.ElementSet
+	if len(buttons) != 3 {
+		t.Fatalf("%d buttons, want feedback and escalation", len(buttons))
+	}
+	for i, actionID := range []string{ActionFeedbackPositive, ActionFeedbackNegative, ActionEscalate} {
+		if b := buttons[i].(*slack.ButtonBlockElement); b.ActionID != actionID || b.Value != chatID.String() {
+			t.Errorf("button %d = %s %s, want %s %s", i, b.ActionID, b.Value, actionID, chatID)
+		}
+	}
+
+	if messages := slackMessages(OutboundMessage{Text: "Thanks"}, true); len(messages) != 1 || len(messages[0].Blocks) != 1 {
+		t.Errorf("notice = %+v, want only its text", messages)
+	}
+
+	// 50 paragraphs of 2,000 characters fill a message; the buttons follow in another
+	long := strings.Repeat(strings.Repeat("x", 2000)+"\n\n", slack_sender.MaxBlocks)
+	messages = slackMessages(OutboundMessage{ChatID: chatID, Text: long}, false)
+	if len(messages) != 2 || len(messages[0].Blocks) != slack_sender.MaxBlocks || len(messages[1].Blocks) != 1 || messages[1].Blocks[0].BlockType() != slack.MBTAction {
+		t.Errorf("long reply = %d messages, want the text and then the buttons", len(messages))
+	}
+}
+
+func TestSlackDeliverEphemeral(t *testing.T) {
+	var got slack.WebhookMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("response body: %v", err)
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	conv := Conversation{Key: "T1_C1_1", Configuration: json.RawMessage(`{"channel_id":"C1","response_url":"` + server.URL + `"}`)}
+	// No bot token is needed to answer at the response URL
+	delivery, err := NewSlackIntegration("").Deliver(context.Background(), ChannelInterface{}, conv, OutboundMessage{ChatID: uuid.New(), Text: "Hi"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if delivery != nil {
+		t.Errorf("delivery = %+v, want none for an ephemeral reply", delivery)
+	}
+	if got.ResponseType != slack.ResponseTypeEphemeral || got.Text != "Hi" || got.Blocks == nil || len(got.Blocks.BlockSet) != 2 {
+		t.Errorf("posted %+v, want an ephemeral reply with its buttons", got)
+	}
+}
+
+func TestSlackVerifyWorkspace(t *testing.T) {
+	// The token of team T1 belongs to app A1; a revoked token is refused
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.FormValue("token") != "xoxb-t1" {
+			w.Write([]byte(`{"ok":false,"error":"invalid_auth"}`))
+			return
+		}
+		switch r.URL.Path {
+		case "/auth.test":
+			w.Write([]byte(`{"ok":true,"team_id":"T1","user_id":"U1","bot_id":"B1"}`))
+		case "/bots.info":
+			if r.FormValue("bot") != "B1" {
+				t.Errorf("bots.info for bot %q, want B1", r.FormValue("bot"))
+			}
+			w.Write([]byte(`{"ok":true,"bot":{"id":"B1","app_id":"A1"}}`))
+		default:
+			t.Errorf("unexpected call to %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+	s := NewSlackIntegration("")
+	s.apiURL = server.URL + "/"
+
+	iface := func(config, token string) ChannelInterface {
+		return ChannelInterface{Configuration: json.RawMessage(config), Credentials: json.RawMessage(`{"bot_token":"` + token + `"}`)}
+	}
+	for _, config := range []string{`{}`, `{"slack_team_id":"T1"}`, `{"slack_team_id":"T1","slack_app_id":"A1"}`} {
+		if err := s.VerifyWorkspace(context.Background(), iface(config, "xoxb-t1")); err != nil {
+			t.Errorf("interface %s: %v", config, err)
+		}
+	}
+	for _, tt := range []struct{ config, token string }{
+		{`{"slack_team_id":"T2"}`, "xoxb-t1"},
+		{`{"slack_team_id":"T1","slack_app_id":"A2"}`, "xoxb-t1"},
+		{`{"slack_team_id":"T1"}`, "xoxb-revoked"},
+	} {
+		if err := s.VerifyWorkspace(context.Background(), iface(tt.config, tt.token)); !errors.Is(err, ErrWorkspaceUnproven) {
+			t.Errorf("interface %s with token %s: error = %v, want ErrWorkspaceUnproven", tt.config, tt.token, err)
+		}
+	}
+}
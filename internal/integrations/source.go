@@ -0,0 +1,92 @@
+package integrations
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrFullSyncRequired is returned by KnowledgeSource.ChangesSince when the source cannot report the
+// changes since the cursor, because it has no change feed or the cursor is no longer valid. The
+// sync then lists every document instead.
+var ErrFullSyncRequired = errors.New("changes since the cursor are not available; a full listing is required")
+
+// CredentialPolicy states whether knowledge bases of a service type use a credential.
+type CredentialPolicy int
+
+const (
+	CredentialNone     CredentialPolicy = iota // Knowledge bases must not have a credential
+	CredentialOptional                         // A credential is used when given, e.g. for private sources
+	CredentialRequired                         // Knowledge bases cannot be created without a credential
+)
+
+// KnowledgeBaseIntegration is implemented by integrations that back knowledge bases. Service types
+// whose integration does not implement it cannot be used for knowledge bases.
+type KnowledgeBaseIntegration interface {
+	Integration
+
+	// KnowledgeBaseCredentialPolicy returns whether knowledge bases of this type use a credential.
+	// A credential must be of the same service type as the knowledge base.
+	KnowledgeBaseCredentialPolicy() CredentialPolicy
+}
+
+// KnowledgeSource is implemented by knowledge base integrations whose documents are synced from an
+// external source. The sync engine stores, indexes and deletes documents; sources only read.
+type KnowledgeSource interface {
+	KnowledgeBaseIntegration
+
+	// ListDocuments lists every document the knowledge base selects, with a cursor for ChangesSince.
+	ListDocuments(ctx context.Context, kb SourceKnowledgeBase) (*DocumentList, error)
+
+	// FetchDocument returns the content of a listed document.
+	FetchDocument(ctx context.Context, kb SourceKnowledgeBase, ref DocumentRef) (*SourceDocument, error)
+
+	// ChangesSince lists the documents added, changed or removed since a cursor returned by an earlier
+	// listing. It returns ErrFullSyncRequired when it cannot.
+	ChangesSince(ctx context.Context, kb SourceKnowledgeBase, cursor string) (*DocumentChanges, error)
+}
+
+// SourceKnowledgeBase is what a KnowledgeSource needs to read a knowledge base's documents.
+type SourceKnowledgeBase struct {
+	ID            uuid.UUID
+	Configuration json.RawMessage // The knowledge base's configuration
+	Credentials   json.RawMessage // Decrypted credential JSON; nil for knowledge bases without a credential
+}
+
+// DocumentRef identifies a document of a source. Listing may already read the document, in which case
+// Document is set and FetchDocument is not called.
+type DocumentRef struct {
+	SourceID  string     // Stable ID of the document within the knowledge base, e.g. a page ID, URL or path
+	UpdatedAt *time.Time // Edit time known before fetching; documents unchanged since they were stored are not fetched
+	Handle    any        // Source-specific state passed back to FetchDocument
+	Document  *SourceDocument
+}
+
+// SourceDocument is the text content of a document.
+type SourceDocument struct {
+	SourceID  string
+	Title     string
+	URL       string
+	Content   string
+	UpdatedAt *time.Time             // Edit time at the source; nil uses the sync time
+	Metadata  map[string]interface{} // Stored with the document, along with "source": the service type
+}
+
+// DocumentList is every document a knowledge base selects.
+type DocumentList struct {
+	Documents []DocumentRef
+	Failed    []string // Source IDs that could not be read; their stored documents are kept
+	Skipped   int      // Source items deliberately left out, e.g. disallowed, duplicate or unreadable
+	Cursor    string   // Passed to ChangesSince by the next incremental sync; "" if the source has no change feed
+}
+
+// DocumentChanges are the documents added, changed or removed since a cursor.
+type DocumentChanges struct {
+	Updated []DocumentRef // Added or changed
+	Deleted []string      // Source IDs of removed documents
+	Skipped int
+	Cursor  string
+}
@@ -0,0 +1,50 @@
+package integrations
+
+import (
+	integration_models "buildmychat-backend/internal/models/integrations"
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// Ensure UploadIntegration implements the KnowledgeBaseIntegration interface.
+var _ KnowledgeBaseIntegration = (*UploadIntegration)(nil)
+
+// UploadIntegration handles knowledge bases filled by file uploads. They are not synced, so it is
+// not a KnowledgeSource.
+type UploadIntegration struct{}
+
+// NewUploadIntegration creates a new upload integration handler.
+func NewUploadIntegration() *UploadIntegration {
+	return &UploadIntegration{}
+}
+
+// ValidateConfig checks that the configuration parses as an UploadKBConfig.
+func (u *UploadIntegration) ValidateConfig(configJSON json.RawMessage) error {
+	if len(configJSON) == 0 || string(configJSON) == "null" {
+		return nil
+	}
+	var config integration_models.UploadKBConfig
+	if err := json.Unmarshal(configJSON, &config); err != nil {
+		return fmt.Errorf("invalid JSON format for upload configuration: %w", err)
+	}
+	return nil
+}
+
+// TestConnection always succeeds: upload knowledge bases do not use credentials.
+func (u *UploadIntegration) TestConnection(ctx context.Context, decryptedCreds integration_models.DecryptedCredentials) (*integration_models.TestConnectionResult, error) {
+	return &integration_models.TestConnectionResult{
+		Success: true,
+		Message: "Upload knowledge bases do not use credentials",
+	}, nil
+}
+
+// GetCredentialSchema returns an empty struct, as no credential keys are expected.
+func (u *UploadIntegration) GetCredentialSchema() interface{} {
+	return struct{}{}
+}
+
+// KnowledgeBaseCredentialPolicy rejects credentials: documents are uploaded directly.
+func (u *UploadIntegration) KnowledgeBaseCredentialPolicy() CredentialPolicy {
+	return CredentialNone
+}
@@ -0,0 +1,163 @@
+package integrations
+
+import (
+	"buildmychat-backend/internal/integrations/website"
+	integration_models "buildmychat-backend/internal/models/integrations"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// Ensure WebsiteIntegration implements the KnowledgeSource interface.
+var _ KnowledgeSource = (*WebsiteIntegration)(nil)
+
+// WebsiteIntegration handles website (crawler) knowledge bases. Public sites need no credentials.
+type WebsiteIntegration struct {
+	crawler *website.Crawler
+}
+
+// NewWebsiteIntegration creates a new website integration handler that crawls with crawler.
+func NewWebsiteIntegration(crawler *website.Crawler) *WebsiteIntegration {
+	return &WebsiteIntegration{crawler: crawler}
+}
+
+// ValidateConfig checks the WebsiteKBConfig: at least one absolute http(s) seed URL, path prefixes
+// starting with "/", and limits within range.
+func (w *WebsiteIntegration) ValidateConfig(configJSON json.RawMessage) error {
+	if len(configJSON) == 0 || string(configJSON) == "null" {
+		return errors.New("website configuration requires seed_urls")
+	}
+
+	var config integration_models.WebsiteKBConfig
+	if err := json.Unmarshal(configJSON, &config); err != nil {
+		return fmt.Errorf("invalid JSON format for website configuration: %w", err)
+	}
+
+	if len(config.SeedURLs) == 0 {
+		return errors.New("website configuration requires at least one seed URL in seed_urls")
+	}
+	for _, raw := range config.SeedURLs {
+		if _, err := website.ParseURL(raw); err != nil {
+			return err
+		}
+	}
+	for _, d := range config.AllowedDomains {
+		if d = strings.TrimSpace(d); d == "" || strings.ContainsAny(d, "/:") {
+			return fmt.Errorf("invalid allowed domain %q: expected a host name such as docs.example.com", d)
+		}
+	}
+	for _, p := range config.AllowedPathPrefixes {
+		if !strings.HasPrefix(p, "/") {
+			return fmt.Errorf("invalid allowed path prefix %q: must start with /", p)
+		}
+	}
+	if config.MaxDepth < 0 {
+		return errors.New("max_depth cannot be negative")
+	}
+	if config.MaxPages < 0 || config.MaxPages > website.MaxPagesLimit {
+		return fmt.Errorf("max_pages must be between 0 (default %d) and %d", website.DefaultMaxPages, website.MaxPagesLimit)
+	}
+	return nil
+}
+
+// TestConnection always succeeds: website knowledge bases crawl public pages without credentials.
+func (w *WebsiteIntegration) TestConnection(ctx context.Context, decryptedCreds integration_models.DecryptedCredentials) (*integration_models.TestConnectionResult, error) {
+	return &integration_models.TestConnectionResult{
+		Success: true,
+		Message: "Website knowledge bases do not use credentials",
+	}, nil
+}
+
+// GetCredentialSchema returns an empty struct, as no credential keys are expected.
+func (w *WebsiteIntegration) GetCredentialSchema() interface{} {
+	return struct{}{}
+}
+
+// KnowledgeBaseCredentialPolicy rejects credentials: only public pages are crawled.
+func (w *WebsiteIntegration) KnowledgeBaseCredentialPolicy() CredentialPolicy {
+	return CredentialNone
+}
+
+// ListDocuments crawls the site. Pages are returned with their content, keyed by canonical URL.
+func (w *WebsiteIntegration) ListDocuments(ctx context.Context, kb SourceKnowledgeBase) (*DocumentList, error) {
+	config, err := parseWebsiteKBConfig(kb.Configuration)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := w.crawler.Crawl(ctx, website.Options{
+		SeedURLs:            config.SeedURLs,
+		AllowedDomains:      config.AllowedDomains,
+		AllowedPathPrefixes: config.AllowedPathPrefixes,
+		MaxDepth:            config.MaxDepth,
+		MaxPages:            config.MaxPages,
+		UseSitemap:          config.UseSitemap,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("crawl failed: %w", err)
+	}
+	log.Printf("[WebsiteIntegration] ListDocuments: Crawled %d pages for KB %s (%d failed, %d disallowed, %d duplicates)",
+		len(result.Pages), kb.ID, len(result.Failed), result.Disallowed, result.Duplicates)
+
+	refs := make([]DocumentRef, len(result.Pages))
+	for i, page := range result.Pages {
+		refs[i] = DocumentRef{SourceID: page.URL, Document: pageDocument(page)}
+	}
+	return &DocumentList{
+		Documents: refs,
+		Failed:    result.Failed,
+		Skipped:   result.Disallowed + result.Duplicates,
+	}, nil
+}
+
+// FetchDocument fetches a single page again.
+func (w *WebsiteIntegration) FetchDocument(ctx context.Context, kb SourceKnowledgeBase, ref DocumentRef) (*SourceDocument, error) {
+	config, err := parseWebsiteKBConfig(kb.Configuration)
+	if err != nil {
+		return nil, err
+	}
+	result, err := w.crawler.Crawl(ctx, website.Options{
+		SeedURLs:            []string{ref.SourceID},
+		AllowedDomains:      config.AllowedDomains,
+		AllowedPathPrefixes: config.AllowedPathPrefixes,
+		MaxPages:            1,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %w", ref.SourceID, err)
+	}
+	return pageDocument(result.Pages[0]), nil
+}
+
+// ChangesSince always returns ErrFullSyncRequired: every sync crawls the whole site, and only pages
+// whose content changed are re-indexed.
+func (w *WebsiteIntegration) ChangesSince(ctx context.Context, kb SourceKnowledgeBase, cursor string) (*DocumentChanges, error) {
+	return nil, ErrFullSyncRequired
+}
+
+// pageDocument converts a crawled page. Pages without a Last-Modified header are dated by the sync.
+func pageDocument(page website.Page) *SourceDocument {
+	return &SourceDocument{
+		SourceID:  page.URL,
+		Title:     page.Title,
+		URL:       page.URL,
+		Content:   page.Content,
+		UpdatedAt: page.LastModified,
+		Metadata:  map[string]interface{}{"depth": page.Depth},
+	}
+}
+
+func parseWebsiteKBConfig(raw json.RawMessage) (*integration_models.WebsiteKBConfig, error) {
+	var config integration_models.WebsiteKBConfig
+	if len(raw) > 0 && string(raw) != "null" {
+		if err := json.Unmarshal(raw, &config); err != nil {
+			return nil, fmt.Errorf("invalid knowledge base configuration: %w", err)
+		}
+	}
+	if len(config.SeedURLs) == 0 {
+		return nil, errors.New("website knowledge base has no seed_urls")
+	}
+	return &config, nil
+}
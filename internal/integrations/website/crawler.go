@@ -0,0 +1,390 @@
+// Package website crawls web sites into text documents for website knowledge bases.
+package website
+
+import (
+	"buildmychat-backend/internal/extract"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultMaxDepth = 3   // Link depth used when Options.MaxDepth is 0
+	DefaultMaxPages = 100 // Page limit used when Options.MaxPages is 0
+	MaxPagesLimit   = 5000
+
+	DefaultUserAgent = "BuildMyChatBot/1.0"
+
+	maxBodyBytes     = 5 << 20         // Larger responses are truncated
+	maxCrawlDelay    = 5 * time.Second // Cap on a robots.txt Crawl-delay
+	maxSitemapFiles  = 20              // Sitemaps fetched per crawl, including nested sitemap indexes
+	fetchesPerPage   = 3               // Fetch budget per page limit, covering duplicates and non-HTML responses
+	maxFetchFailures = 50              // Failed fetches after which the crawl gives up
+)
+
+// ErrNoPages is returned when not a single page could be crawled, e.g. because the site is down.
+var ErrNoPages = errors.New("no pages could be crawled")
+
+// skippedExtensions are links that are never HTML pages and are not fetched.
+var skippedExtensions = map[string]bool{
+	".png": true, ".jpg": true, ".jpeg": true, ".gif": true, ".svg": true, ".webp": true, ".ico": true,
+	".css": true, ".js": true, ".json": true, ".xml": true, ".pdf": true, ".zip": true, ".gz": true,
+	".mp3": true, ".mp4": true, ".webm": true, ".woff": true, ".woff2": true, ".ttf": true,
+}
+
+// Options configures a crawl.
+type Options struct {
+	SeedURLs            []string // Where the crawl starts (depth 0)
+	AllowedDomains      []string // Hosts that may be crawled, including their subdomains. Empty = the seed hosts.
+	AllowedPathPrefixes []string // URL path prefixes that may be crawled. Empty = all paths.
+	MaxDepth            int      // Links followed from a seed; 0 uses DefaultMaxDepth
+	MaxPages            int      // Pages returned at most; 0 uses DefaultMaxPages
+	UseSitemap          bool     // Also crawl the URLs listed in the sitemaps of the seed hosts
+}
+
+// Page is a crawled HTML page reduced to its main content.
+type Page struct {
+	URL          string // Canonical URL
+	Title        string
+	Content      string // Markdown
+	ContentHash  string // SHA-256 of Content, hex encoded
+	Depth        int
+	LastModified *time.Time // From the Last-Modified header, if any
+}
+
+// Result is the outcome of a crawl.
+type Result struct {
+	Pages      []Page
+	Failed     []string // URLs that could not be fetched; their stored documents should be kept
+	Disallowed int      // URLs skipped because of robots.txt or a noindex directive
+	Duplicates int      // Pages skipped because their canonical URL or content was already crawled
+}
+
+// Crawler fetches pages over HTTP, one at a time.
+type Crawler struct {
+	client    *http.Client
+	userAgent string
+	robotName string // User agent product token matched against robots.txt groups
+}
+
+// NewCrawler creates a Crawler using client, identifying itself as userAgent ("" uses DefaultUserAgent).
+func NewCrawler(client *http.Client, userAgent string) *Crawler {
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	if userAgent == "" {
+		userAgent = DefaultUserAgent
+	}
+	robotName, _, _ := strings.Cut(userAgent, "/")
+	return &Crawler{
+		client:    client,
+		userAgent: userAgent,
+		robotName: strings.TrimSpace(robotName),
+	}
+}
+
+// queued is a URL waiting to be fetched.
+type queued struct {
+	url   *url.URL
+	depth int
+}
+
+// crawl holds the state of a single Crawl call.
+type crawl struct {
+	*Crawler
+	opts    Options
+	domains []string
+	robots  map[string]*robotsRules // By scheme://host
+
+	seen   map[string]bool // Normalized URLs queued or fetched
+	pages  map[string]bool // Canonical URLs of returned pages
+	hashes map[string]bool // Content hashes of returned pages
+	queue  []queued
+	result Result
+}
+
+// Crawl fetches the seed URLs (and sitemap URLs, if enabled) and follows links breadth-first within
+// the allowed domains and path prefixes, until MaxDepth or MaxPages is reached. robots.txt rules and
+// Crawl-delay are honored; pages marked noindex are not returned, and links of nofollow pages are
+// not followed. Failing pages are reported in Result.Failed rather than failing the crawl, unless no
+// page could be crawled at all.
+func (c *Crawler) Crawl(ctx context.Context, opts Options) (*Result, error) {
+	if opts.MaxDepth <= 0 {
+		opts.MaxDepth = DefaultMaxDepth
+	}
+	if opts.MaxPages <= 0 {
+		opts.MaxPages = DefaultMaxPages
+	}
+	opts.MaxPages = min(opts.MaxPages, MaxPagesLimit)
+
+	cr := &crawl{
+		Crawler: c,
+		opts:    opts,
+		robots:  make(map[string]*robotsRules),
+		seen:    make(map[string]bool),
+		pages:   make(map[string]bool),
+		hashes:  make(map[string]bool),
+	}
+
+	seeds := make([]*url.URL, 0, len(opts.SeedURLs))
+	for _, raw := range opts.SeedURLs {
+		u, err := ParseURL(raw)
+		if err != nil {
+			return nil, err
+		}
+		seeds = append(seeds, u)
+	}
+	if len(seeds) == 0 {
+		return nil, errors.New("at least one seed URL is required")
+	}
+
+	for _, d := range opts.AllowedDomains {
+		cr.domains = append(cr.domains, strings.ToLower(strings.TrimPrefix(strings.TrimSpace(d), ".")))
+	}
+	if len(cr.domains) == 0 {
+		for _, u := range seeds {
+			cr.domains = append(cr.domains, u.Hostname())
+		}
+	}
+
+	for _, u := range seeds {
+		cr.enqueue(u, 0)
+	}
+	if opts.UseSitemap {
+		cr.enqueueSitemaps(ctx, seeds)
+	}
+
+	if err := cr.run(ctx); err != nil {
+		return nil, err
+	}
+	if len(cr.result.Pages) == 0 && len(cr.result.Failed) > 0 {
+		return nil, fmt.Errorf("%w: %d URLs failed, first %s", ErrNoPages, len(cr.result.Failed), cr.result.Failed[0])
+	}
+	return &cr.result, nil
+}
+
+// run processes the queue until it is empty or a limit is reached.
+func (cr *crawl) run(ctx context.Context) error {
+	budget := cr.opts.MaxPages*fetchesPerPage + len(cr.opts.SeedURLs)
+	for len(cr.queue) > 0 && len(cr.result.Pages) < cr.opts.MaxPages && budget > 0 {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if len(cr.result.Failed) >= maxFetchFailures {
+			log.Printf("WARN [WebsiteCrawler] Giving up after %d failed fetches", len(cr.result.Failed))
+			break
+		}
+
+		next := cr.queue[0]
+		cr.queue = cr.queue[1:]
+
+		rules := cr.robotsFor(ctx, next.url)
+		if rules.unavailable {
+			cr.result.Failed = append(cr.result.Failed, next.url.String())
+			continue
+		}
+		if !rules.allowed(next.url) {
+			cr.result.Disallowed++
+			continue
+		}
+		if err := rules.wait(ctx); err != nil {
+			return err
+		}
+
+		budget--
+		if err := cr.visit(ctx, next); err != nil {
+			log.Printf("WARN [WebsiteCrawler] Failed to crawl %s: %v", next.url, err)
+			cr.result.Failed = append(cr.result.Failed, next.url.String())
+		}
+	}
+	return nil
+}
+
+// visit fetches one page, records it and queues its links.
+func (cr *crawl) visit(ctx context.Context, item queued) error {
+	body, resp, err := cr.fetch(ctx, item.url.String(), "text/html,application/xhtml+xml")
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone {
+		return nil // Dead link; a page that disappeared is dropped from the knowledge base
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	if mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return nil // Not a page
+	}
+
+	// Redirects may lead elsewhere; the final URL is what the links are relative to
+	finalURL := normalizeURL(resp.Request.URL)
+	if !cr.inScope(finalURL) {
+		return nil
+	}
+	cr.seen[finalURL.String()] = true
+
+	meta := parseHTMLMeta(body, finalURL)
+	if !meta.noFollow && item.depth < cr.opts.MaxDepth {
+		for _, link := range meta.links {
+			cr.enqueue(link, item.depth+1)
+		}
+	}
+	if meta.noIndex || strings.Contains(strings.ToLower(resp.Header.Get("X-Robots-Tag")), "noindex") {
+		cr.result.Disallowed++
+		return nil
+	}
+
+	canonical := finalURL
+	if meta.canonical != nil && cr.inScope(meta.canonical) {
+		canonical = meta.canonical
+	}
+	if cr.pages[canonical.String()] {
+		cr.result.Duplicates++
+		return nil
+	}
+
+	doc, err := extract.HTML(body)
+	if err != nil {
+		if errors.Is(err, extract.ErrNoText) {
+			return nil
+		}
+		return err
+	}
+	sum := sha256.Sum256([]byte(doc.Content))
+	hash := hex.EncodeToString(sum[:])
+	if cr.hashes[hash] {
+		cr.result.Duplicates++
+		return nil
+	}
+
+	title := doc.Title
+	if title == "" {
+		title = canonical.String()
+	}
+	page := Page{
+		URL:         canonical.String(),
+		Title:       title,
+		Content:     doc.Content,
+		ContentHash: hash,
+		Depth:       item.depth,
+	}
+	if lm, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		lm = lm.UTC()
+		page.LastModified = &lm
+	}
+
+	cr.pages[page.URL] = true
+	cr.hashes[hash] = true
+	cr.result.Pages = append(cr.result.Pages, page)
+	return nil
+}
+
+// enqueue queues a URL that is in scope and was not seen before.
+func (cr *crawl) enqueue(u *url.URL, depth int) {
+	u = normalizeURL(u)
+	key := u.String()
+	if cr.seen[key] || !cr.inScope(u) || skippedExtensions[strings.ToLower(path.Ext(u.Path))] {
+		return
+	}
+	cr.seen[key] = true
+	cr.queue = append(cr.queue, queued{url: u, depth: depth})
+}
+
+// inScope reports whether a URL is within the allowed domains and path prefixes.
+func (cr *crawl) inScope(u *url.URL) bool {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	hostAllowed := false
+	for _, d := range cr.domains {
+		if host == d || strings.HasSuffix(host, "."+d) {
+			hostAllowed = true
+			break
+		}
+	}
+	if !hostAllowed {
+		return false
+	}
+	if len(cr.opts.AllowedPathPrefixes) == 0 {
+		return true
+	}
+	p := u.EscapedPath()
+	if p == "" {
+		p = "/"
+	}
+	for _, prefix := range cr.opts.AllowedPathPrefixes {
+		if strings.HasPrefix(p, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// fetch GETs a URL and reads at most maxBodyBytes of the response body.
+func (cr *crawl) fetch(ctx context.Context, rawURL string, accept string) ([]byte, *http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("User-Agent", cr.userAgent)
+	req.Header.Set("Accept", accept)
+
+	resp, err := cr.client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	var buf bytes.Buffer
+	if _, err := io.Copy(&buf, io.LimitReader(resp.Body, maxBodyBytes)); err != nil {
+		return nil, nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	return buf.Bytes(), resp, nil
+}
+
+// ParseURL parses an absolute http(s) URL.
+func ParseURL(raw string) (*url.URL, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL %q: %w", raw, err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid URL %q: expected an absolute http or https URL", raw)
+	}
+	return u, nil
+}
+
+// normalizeURL returns a copy of u without fragment, user info or default port, with a lowercase
+// host and a non-empty path, so that equivalent URLs compare equal.
+func normalizeURL(u *url.URL) *url.URL {
+	n := *u
+	n.Fragment = ""
+	n.RawFragment = ""
+	n.User = nil
+	n.Scheme = strings.ToLower(n.Scheme)
+	host := strings.ToLower(n.Hostname())
+	if port := n.Port(); port != "" && !(n.Scheme == "http" && port == "80") && !(n.Scheme == "https" && port == "443") {
+		host = net.JoinHostPort(host, port)
+	} else if strings.Contains(host, ":") {
+		host = "[" + host + "]" // IPv6 literal
+	}
+	n.Host = host
+	if n.Path == "" {
+		n.Path = "/"
+		n.RawPath = ""
+	}
+	return &n
+}
@@ -0,0 +1,184 @@
+package website
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// site serves fixed pages; paths missing from pages answer 404.
+type site map[string]string
+
+func (s site) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	key := r.URL.Path
+	if r.URL.RawQuery != "" {
+		key += "?" + r.URL.RawQuery
+	}
+	body, ok := s[key]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	switch {
+	case strings.HasSuffix(r.URL.Path, ".txt"):
+		w.Header().Set("Content-Type", "text/plain")
+	case strings.HasSuffix(r.URL.Path, ".xml"):
+		w.Header().Set("Content-Type", "application/xml")
+	default:
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	}
+	fmt.Fprint(w, body)
+}
+
+func page(title, body string) string {
+	return "<html><head><title>" + title + "</title></head><body><main>" + body + "</main></body></html>"
+}
+
+// crawledPaths returns the URL paths of the crawled pages, sorted.
+func crawledPaths(t *testing.T, base string, res *Result) []string {
+	t.Helper()
+	paths := make([]string, len(res.Pages))
+	for i, p := range res.Pages {
+		if !strings.HasPrefix(p.URL, base) {
+			t.Fatalf("page %s is outside %s", p.URL, base)
+		}
+		paths[i] = strings.TrimPrefix(p.URL, base)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func TestCrawlFollowsLinksWithinScope(t *testing.T) {
+	pages := site{
+		"/robots.txt": "User-agent: *\nDisallow: /private\n",
+		"/": page("Home", `<p>Welcome home.</p>
+			<a href="/docs/a">A</a> <a href="docs/b#intro">B</a> <a href="/private/secret">Secret</a>
+			<a href="https://elsewhere.example/">Elsewhere</a> <a href="/docs/a?ref=nav">A again</a>
+			<a href="/mirror">Mirror</a> <a href="/hidden">Hidden</a> <a href="/logo.png">Logo</a>
+			<a href="/sponsored" rel="sponsored nofollow">Ad</a>`),
+		"/docs/a": page("A", `<p>Page A.</p><a href="/docs/deep">Deep</a>`),
+		"/docs/a?ref=nav": `<html><head><link rel="canonical" href="/docs/a"></head>
+			<body><p>Page A, tracked.</p></body></html>`,
+		"/docs/b":         page("B", `<p>Page B.</p>`),
+		"/mirror":         page("Mirror of B", `<p>Page B.</p>`),
+		"/hidden":         `<html><head><meta name="robots" content="noindex"></head><body><p>Hidden.</p></body></html>`,
+		"/docs/deep":      page("Deep", `<p>Two links away.</p>`),
+		"/private/secret": page("Secret", `<p>Secret.</p>`),
+		"/sponsored":      page("Sponsored", `<p>Sponsored.</p>`),
+	}
+	srv := httptest.NewServer(pages)
+	defer srv.Close()
+
+	crawler := NewCrawler(srv.Client(), "")
+	res, err := crawler.Crawl(context.Background(), Options{SeedURLs: []string{srv.URL}, MaxDepth: 1})
+	if err != nil {
+		t.Fatalf("Crawl: %v", err)
+	}
+
+	want := []string{"/", "/docs/a", "/docs/b"}
+	if got := crawledPaths(t, srv.URL, res); strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("crawled %v, want %v", got, want)
+	}
+	if res.Disallowed != 2 { // /private/secret (robots.txt), /hidden (noindex)
+		t.Errorf("Disallowed = %d, want 2", res.Disallowed)
+	}
+	if res.Duplicates != 2 { // /docs/a?ref=nav (canonical), /mirror (content)
+		t.Errorf("Duplicates = %d, want 2", res.Duplicates)
+	}
+	if len(res.Failed) != 0 {
+		t.Errorf("Failed = %v, want none", res.Failed)
+	}
+	for _, p := range res.Pages {
+		if p.URL == srv.URL+"/docs/a" && (p.Title != "A" || !strings.Contains(p.Content, "Page A.") || p.Depth != 1) {
+			t.Errorf("unexpected page %+v", p)
+		}
+	}
+
+	res, err = crawler.Crawl(context.Background(), Options{SeedURLs: []string{srv.URL}, MaxDepth: 2, MaxPages: 4})
+	if err != nil {
+		t.Fatalf("Crawl: %v", err)
+	}
+	want = []string{"/", "/docs/a", "/docs/b", "/docs/deep"}
+	if got := crawledPaths(t, srv.URL, res); strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("crawled %v with depth 2, want %v", got, want)
+	}
+
+	res, err = crawler.Crawl(context.Background(), Options{SeedURLs: []string{srv.URL}, MaxPages: 2})
+	if err != nil {
+		t.Fatalf("Crawl: %v", err)
+	}
+	if len(res.Pages) != 2 {
+		t.Errorf("crawled %d pages, want the page limit of 2", len(res.Pages))
+	}
+}
+
+func TestCrawlUsesSitemapAndPathPrefixes(t *testing.T) {
+	pages := site{
+		"/guide/":     page("Guide", `<p>The guide.</p>`),
+		"/guide/one":  page("One", `<p>Only listed in the sitemap.</p>`),
+		"/blog/two":   page("Two", `<p>Outside the allowed prefix.</p>`),
+		"/robots.txt": "", // Filled in below, once the server URL is known
+	}
+	srv := httptest.NewServer(pages)
+	defer srv.Close()
+	srvURL := srv.URL
+
+	pages["/robots.txt"] = "User-agent: *\nAllow: /\nSitemap: " + srvURL + "/sitemap_index.xml\n"
+	pages["/sitemap_index.xml"] = `<?xml version="1.0" encoding="UTF-8"?>
+<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <sitemap><loc>` + srvURL + `/sitemap-pages.xml</loc></sitemap>
+</sitemapindex>`
+	pages["/sitemap-pages.xml"] = `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url><loc>` + srvURL + `/guide/one</loc></url>
+  <url><loc>` + srvURL + `/blog/two</loc></url>
+</urlset>`
+
+	res, err := NewCrawler(srv.Client(), "").Crawl(context.Background(), Options{
+		SeedURLs:            []string{srvURL + "/guide/"},
+		AllowedPathPrefixes: []string{"/guide/"},
+		UseSitemap:          true,
+	})
+	if err != nil {
+		t.Fatalf("Crawl: %v", err)
+	}
+
+	want := []string{"/guide/", "/guide/one"}
+	if got := crawledPaths(t, srvURL, res); strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("crawled %v, want %v", got, want)
+	}
+}
+
+func TestCrawlRespectsAgentSpecificRobotsRules(t *testing.T) {
+	pages := site{
+		"/robots.txt": "User-agent: *\nAllow: /\n\nUser-agent: BuildMyChatBot\nDisallow: /\n",
+		"/":           page("Home", `<p>Home.</p>`),
+	}
+	srv := httptest.NewServer(pages)
+	defer srv.Close()
+
+	res, err := NewCrawler(srv.Client(), "").Crawl(context.Background(), Options{SeedURLs: []string{srv.URL}})
+	if err != nil {
+		t.Fatalf("Crawl: %v", err)
+	}
+	if len(res.Pages) != 0 || res.Disallowed != 1 {
+		t.Errorf("got %d pages, %d disallowed; want 0 pages, 1 disallowed", len(res.Pages), res.Disallowed)
+	}
+}
+
+func TestCrawlFailsWhenSiteIsDown(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	_, err := NewCrawler(srv.Client(), "").Crawl(context.Background(), Options{SeedURLs: []string{srv.URL}})
+	if !errors.Is(err, ErrNoPages) {
+		t.Fatalf("Crawl error = %v, want ErrNoPages", err)
+	}
+}
@@ -0,0 +1,98 @@
+package website
+
+import (
+	"bytes"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// htmlMeta is what the crawler needs from a page besides its content.
+type htmlMeta struct {
+	canonical *url.URL   // <link rel="canonical">, resolved
+	links     []*url.URL // <a href> targets, resolved, without rel="nofollow" links
+	noIndex   bool       // <meta name="robots" content="noindex">
+	noFollow  bool       // <meta name="robots" content="nofollow">
+}
+
+// parseHTMLMeta reads the canonical URL, links and robots directives of a page located at pageURL.
+// Relative URLs are resolved against <base href> when present.
+func parseHTMLMeta(data []byte, pageURL *url.URL) htmlMeta {
+	var meta htmlMeta
+	root, err := html.Parse(bytes.NewReader(data))
+	if err != nil {
+		return meta
+	}
+
+	base := pageURL
+	var canonical string
+	var hrefs []string
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch n.DataAtom {
+			case atom.Base:
+				if href := attr(n, "href"); href != "" {
+					if u, err := pageURL.Parse(href); err == nil {
+						base = u
+					}
+				}
+			case atom.Link:
+				if canonical == "" && hasToken(attr(n, "rel"), "canonical") {
+					canonical = attr(n, "href")
+				}
+			case atom.Meta:
+				if name := strings.ToLower(attr(n, "name")); name == "robots" || name == "bot" {
+					content := strings.ToLower(attr(n, "content"))
+					meta.noIndex = meta.noIndex || strings.Contains(content, "noindex") || strings.Contains(content, "none")
+					meta.noFollow = meta.noFollow || strings.Contains(content, "nofollow") || strings.Contains(content, "none")
+				}
+			case atom.A, atom.Area:
+				if href := attr(n, "href"); href != "" && !hasToken(attr(n, "rel"), "nofollow") {
+					hrefs = append(hrefs, href)
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(root)
+
+	if canonical != "" {
+		if u, err := base.Parse(strings.TrimSpace(canonical)); err == nil {
+			meta.canonical = normalizeURL(u)
+		}
+	}
+	for _, href := range hrefs {
+		href = strings.TrimSpace(href)
+		if strings.HasPrefix(href, "#") {
+			continue
+		}
+		if u, err := base.Parse(href); err == nil {
+			meta.links = append(meta.links, u)
+		}
+	}
+	return meta
+}
+
+// hasToken reports whether a space-separated attribute value such as rel contains token.
+func hasToken(value, token string) bool {
+	for _, f := range strings.Fields(value) {
+		if strings.EqualFold(f, token) {
+			return true
+		}
+	}
+	return false
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
@@ -0,0 +1,85 @@
+package website
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+
+	"github.com/temoto/robotstxt"
+)
+
+// robotsRules are the robots.txt rules of one host, along with its crawl delay.
+type robotsRules struct {
+	data     *robotstxt.RobotsData
+	agent    string
+	sitemaps []string
+	delay    time.Duration
+	last     time.Time // When the host was last fetched from
+
+	unavailable bool // robots.txt could not be loaded; nothing on the host may be fetched
+}
+
+// robotsFor returns the robots.txt rules of the URL's host, fetching them on first use.
+// When robots.txt cannot be fetched or answers with a server error, the host is unavailable.
+func (cr *crawl) robotsFor(ctx context.Context, u *url.URL) *robotsRules {
+	origin := u.Scheme + "://" + u.Host
+	if rules, ok := cr.robots[origin]; ok {
+		return rules
+	}
+
+	var data *robotstxt.RobotsData
+	body, resp, err := cr.fetch(ctx, origin+"/robots.txt", "text/plain")
+	if err == nil {
+		if resp.StatusCode >= 500 {
+			err = fmt.Errorf("unexpected status %d", resp.StatusCode)
+		} else {
+			data, err = robotstxt.FromStatusAndBytes(resp.StatusCode, body)
+		}
+	}
+	if err != nil {
+		log.Printf("WARN [WebsiteCrawler] Failed to load robots.txt of %s, not crawling it: %v", origin, err)
+		rules := &robotsRules{unavailable: true}
+		cr.robots[origin] = rules
+		return rules
+	}
+
+	rules := &robotsRules{
+		data:     data,
+		agent:    cr.robotName,
+		sitemaps: data.Sitemaps,
+		delay:    min(data.FindGroup(cr.robotName).CrawlDelay, maxCrawlDelay),
+	}
+	cr.robots[origin] = rules
+	return rules
+}
+
+// allowed reports whether the URL may be fetched.
+func (r *robotsRules) allowed(u *url.URL) bool {
+	if r.unavailable {
+		return false
+	}
+	p := u.EscapedPath()
+	if u.RawQuery != "" {
+		p += "?" + u.RawQuery
+	}
+	return r.data.TestAgent(p, r.agent)
+}
+
+// wait sleeps until the host's crawl delay has passed since its last fetch, then records a fetch.
+func (r *robotsRules) wait(ctx context.Context) error {
+	if r.delay > 0 && !r.last.IsZero() {
+		if d := time.Until(r.last.Add(r.delay)); d > 0 {
+			t := time.NewTimer(d)
+			defer t.Stop()
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-t.C:
+			}
+		}
+	}
+	r.last = time.Now()
+	return nil
+}
@@ -0,0 +1,97 @@
+package website
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// sitemapDocument covers both <urlset> sitemaps and <sitemapindex> files.
+type sitemapDocument struct {
+	XMLName  xml.Name
+	URLs     []sitemapLoc `xml:"url"`
+	Sitemaps []sitemapLoc `xml:"sitemap"`
+}
+
+type sitemapLoc struct {
+	Loc string `xml:"loc"`
+}
+
+// enqueueSitemaps queues the pages listed in the sitemaps of the seed hosts, at depth 0. Sitemaps are
+// taken from robots.txt, falling back to /sitemap.xml. Sitemap indexes are followed, up to maxSitemapFiles.
+func (cr *crawl) enqueueSitemaps(ctx context.Context, seeds []*url.URL) {
+	var pending []string
+	origins := make(map[string]bool)
+	for _, u := range seeds {
+		origin := u.Scheme + "://" + u.Host
+		if origins[origin] {
+			continue
+		}
+		origins[origin] = true
+
+		if sitemaps := cr.robotsFor(ctx, u).sitemaps; len(sitemaps) > 0 {
+			pending = append(pending, sitemaps...)
+		} else {
+			pending = append(pending, origin+"/sitemap.xml")
+		}
+	}
+
+	fetched := make(map[string]bool)
+	for len(pending) > 0 && len(fetched) < maxSitemapFiles {
+		sitemapURL := strings.TrimSpace(pending[0])
+		pending = pending[1:]
+		if fetched[sitemapURL] {
+			continue
+		}
+		fetched[sitemapURL] = true
+
+		doc, err := cr.fetchSitemap(ctx, sitemapURL)
+		if err != nil {
+			log.Printf("WARN [WebsiteCrawler] Failed to read sitemap %s: %v", sitemapURL, err)
+			continue
+		}
+		if doc == nil {
+			continue
+		}
+		for _, s := range doc.Sitemaps {
+			pending = append(pending, s.Loc)
+		}
+		for _, loc := range doc.URLs {
+			if u, err := ParseURL(loc.Loc); err == nil {
+				cr.enqueue(u, 0)
+			}
+		}
+	}
+}
+
+// fetchSitemap fetches and parses a sitemap. It returns nil without an error when there is none.
+func (cr *crawl) fetchSitemap(ctx context.Context, sitemapURL string) (*sitemapDocument, error) {
+	u, err := ParseURL(sitemapURL)
+	if err != nil {
+		return nil, err
+	}
+	if !cr.robotsFor(ctx, u).allowed(u) {
+		return nil, nil
+	}
+
+	body, resp, err := cr.fetch(ctx, u.String(), "application/xml,text/xml")
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	var doc sitemapDocument
+	if err := xml.Unmarshal(body, &doc); err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
@@ -0,0 +1,95 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Ensure FakeProvider implements the Provider interface.
+var _ Provider = (*FakeProvider)(nil)
+
+// FakeProvider is a deterministic, offline provider used in tests and local development.
+// By default it answers with a fixed template built from the latest user message, so
+// the same conversation always produces the same reply.
+type FakeProvider struct {
+	// Reply, when set, overrides the default echo behaviour.
+	Reply func(req ChatRequest) (string, error)
+
+	mu       sync.Mutex
+	requests []ChatRequest
+}
+
+// NewFakeProvider creates a FakeProvider with the default echo behaviour.
+func NewFakeProvider() *FakeProvider {
+	return &FakeProvider{}
+}
+
+// Name returns "fake".
+func (p *FakeProvider) Name() string {
+	return "fake"
+}
+
+// ChatCompletion records the request and returns a deterministic reply.
+func (p *FakeProvider) ChatCompletion(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	p.requests = append(p.requests, req)
+	p.mu.Unlock()
+
+	content, err := p.reply(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ChatResponse{
+		Provider:     p.Name(),
+		Content:      content,
+		Model:        req.Model,
+		FinishReason: "stop",
+	}, nil
+}
+
+// StreamChatCompletion behaves like ChatCompletion but delivers the reply word by word.
+func (p *FakeProvider) StreamChatCompletion(ctx context.Context, req ChatRequest, onDelta DeltaFunc) (*ChatResponse, error) {
+	resp, err := p.ChatCompletion(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, word := range strings.SplitAfter(resp.Content, " ") {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if err := onDelta(word); err != nil {
+			return nil, err
+		}
+	}
+	return resp, nil
+}
+
+// Requests returns a copy of every request the provider has received.
+func (p *FakeProvider) Requests() []ChatRequest {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]ChatRequest(nil), p.requests...)
+}
+
+func (p *FakeProvider) reply(req ChatRequest) (string, error) {
+	if p.Reply != nil {
+		return p.Reply(req)
+	}
+
+	lastUserMessage := ""
+	for i := len(req.Messages) - 1; i >= 0; i-- {
+		if req.Messages[i].Role == RoleUser {
+			lastUserMessage = req.Messages[i].Content
+			break
+		}
+	}
+	return fmt.Sprintf("[fake:%s] You said: %s", req.Model, lastUserMessage), nil
+}
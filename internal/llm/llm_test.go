@@ -0,0 +1,153 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOpenAIProviderChatCompletion(t *testing.T) {
+	var got openAIChatRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/chat/completions" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if auth := r.Header.Get("Authorization"); auth != "Bearer sk-test" {
+			t.Errorf("unexpected Authorization header %q", auth)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Fatalf("decode request: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"model":"gpt-test","choices":[{"message":{"role":"assistant","content":"Hi there"},"finish_reason":"stop"}],"usage":{"prompt_tokens":5,"completion_tokens":2,"total_tokens":7}}`))
+	}))
+	defer server.Close()
+
+	temperature := 0.3
+	provider := NewOpenAIProvider("openai", server.URL, "sk-test", server.Client())
+	resp, err := provider.ChatCompletion(context.Background(), ChatRequest{
+		Model:        "gpt-test",
+		SystemPrompt: "Be brief.",
+		Messages:     []Message{{Role: RoleUser, Content: "Hello"}},
+		Temperature:  &temperature,
+		MaxTokens:    64,
+	})
+	if err != nil {
+		t.Fatalf("ChatCompletion returned error: %v", err)
+	}
+
+	if resp.Content != "Hi there" || resp.Usage.TotalTokens != 7 {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+	if len(got.Messages) != 2 || got.Messages[0].Role != RoleSystem || got.Messages[0].Content != "Be brief." {
+		t.Errorf("system prompt not prepended: %+v", got.Messages)
+	}
+	if got.MaxTokens != 64 || got.Temperature == nil || *got.Temperature != 0.3 {
+		t.Errorf("generation parameters not forwarded: %+v", got)
+	}
+}
+
+func TestOpenAIProviderAPIError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error":{"message":"Incorrect API key provided","type":"invalid_request_error"}}`))
+	}))
+	defer server.Close()
+
+	provider := NewOpenAIProvider("openai", server.URL, "bad", server.Client())
+	_, err := provider.ChatCompletion(context.Background(), ChatRequest{Model: "gpt-test"})
+	if err == nil || err.Error() != "openai API error (status 401): Incorrect API key provided" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestOpenAIProviderStreamChatCompletion(t *testing.T) {
+	var got openAIChatRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Fatalf("decode request: %v", err)
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte(`data: {"model":"gpt-test","choices":[{"delta":{"role":"assistant"}}]}` + "\n\n"))
+		w.Write([]byte(`data: {"model":"gpt-test","choices":[{"delta":{"content":"Hi"}}]}` + "\n\n"))
+		w.Write([]byte(`data: {"model":"gpt-test","choices":[{"delta":{"content":" there"},"finish_reason":"stop"}]}` + "\n\n"))
+		w.Write([]byte(`data: {"model":"gpt-test","choices":[],"usage":{"prompt_tokens":5,"completion_tokens":2,"total_tokens":7}}` + "\n\n"))
+		w.Write([]byte("data: [DONE]\n\n"))
+	}))
+	defer server.Close()
+
+	provider := NewOpenAIProvider("openai", server.URL, "sk-test", server.Client())
+	var deltas []string
+	resp, err := provider.StreamChatCompletion(context.Background(), ChatRequest{
+		Model:    "gpt-test",
+		Messages: []Message{{Role: RoleUser, Content: "Hello"}},
+	}, func(delta string) error {
+		deltas = append(deltas, delta)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("StreamChatCompletion returned error: %v", err)
+	}
+
+	if !got.Stream || got.StreamOptions == nil || !got.StreamOptions.IncludeUsage {
+		t.Errorf("streaming not requested: %+v", got)
+	}
+	if len(deltas) != 2 || deltas[0] != "Hi" || deltas[1] != " there" {
+		t.Errorf("unexpected deltas: %q", deltas)
+	}
+	if resp.Content != "Hi there" || resp.FinishReason != "stop" || resp.Usage.TotalTokens != 7 {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+}
+
+func TestRegistryResolve(t *testing.T) {
+	registry := NewRegistry("openai", "gpt-default")
+	registry.Register(NewOpenAIProvider("openai", "", "", nil))
+	registry.Register(NewFakeProvider())
+
+	tests := []struct {
+		name         string
+		llmModel     *string
+		wantProvider string
+		wantModel    string
+	}{
+		{"nil uses defaults", nil, "openai", "gpt-default"},
+		{"bare model uses default provider", strPtr("gpt-4o"), "openai", "gpt-4o"},
+		{"provider prefix", strPtr("fake:echo"), "fake", "echo"},
+		{"provider prefix without model", strPtr("fake:"), "fake", "gpt-default"},
+		{"unknown prefix is part of the model name", strPtr("llama3:8b"), "openai", "llama3:8b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider, model, err := registry.Resolve(tt.llmModel)
+			if err != nil {
+				t.Fatalf("Resolve returned error: %v", err)
+			}
+			if provider.Name() != tt.wantProvider || model != tt.wantModel {
+				t.Errorf("got (%s, %s), want (%s, %s)", provider.Name(), model, tt.wantProvider, tt.wantModel)
+			}
+		})
+	}
+}
+
+func TestFakeProviderIsDeterministic(t *testing.T) {
+	provider := NewFakeProvider()
+	req := ChatRequest{Model: "echo", Messages: []Message{{Role: RoleUser, Content: "ping"}}}
+
+	first, err := provider.ChatCompletion(context.Background(), req)
+	if err != nil {
+		t.Fatalf("ChatCompletion returned error: %v", err)
+	}
+	second, _ := provider.ChatCompletion(context.Background(), req)
+
+	if first.Content != "[fake:echo] You said: ping" || first.Content != second.Content {
+		t.Errorf("unexpected fake replies %q / %q", first.Content, second.Content)
+	}
+	if len(provider.Requests()) != 2 {
+		t.Errorf("expected 2 recorded requests, got %d", len(provider.Requests()))
+	}
+}
+
+func strPtr(s string) *string { return &s }
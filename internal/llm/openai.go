@@ -0,0 +1,245 @@
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultOpenAIBaseURL is used when no base URL is configured.
+const DefaultOpenAIBaseURL = "https://api.openai.com/v1"
+
+// Ensure OpenAIProvider implements the Provider interface.
+var _ Provider = (*OpenAIProvider)(nil)
+
+// OpenAIProvider talks to any API implementing OpenAI's /chat/completions endpoint
+// (OpenAI itself, Azure-style gateways, OpenRouter, vLLM, Ollama, ...).
+type OpenAIProvider struct {
+	name       string
+	baseURL    string
+	apiKey     string
+	httpClient *http.Client
+}
+
+// NewOpenAIProvider creates a provider for an OpenAI-compatible API.
+// An empty baseURL falls back to DefaultOpenAIBaseURL; a nil httpClient gets a client with a sane timeout.
+func NewOpenAIProvider(name, baseURL, apiKey string, httpClient *http.Client) *OpenAIProvider {
+	if baseURL == "" {
+		baseURL = DefaultOpenAIBaseURL
+	}
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 120 * time.Second}
+	}
+	return &OpenAIProvider{
+		name:       name,
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		apiKey:     apiKey,
+		httpClient: httpClient,
+	}
+}
+
+// Name returns the name the provider was registered with.
+func (p *OpenAIProvider) Name() string {
+	return p.name
+}
+
+// --- Wire types for the OpenAI chat completions API ---
+
+type openAIChatRequest struct {
+	Model         string               `json:"model"`
+	Messages      []Message            `json:"messages"`
+	Temperature   *float64             `json:"temperature,omitempty"`
+	MaxTokens     int                  `json:"max_tokens,omitempty"`
+	Stream        bool                 `json:"stream,omitempty"`
+	StreamOptions *openAIStreamOptions `json:"stream_options,omitempty"`
+}
+
+type openAIStreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+// openAIStreamChunk is one "data:" event of a streamed completion.
+// Usage is only present on the final chunk, and only when requested through stream_options.
+type openAIStreamChunk struct {
+	Model   string `json:"model"`
+	Choices []struct {
+		Delta struct {
+			Content string `json:"content"`
+		} `json:"delta"`
+		FinishReason *string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *Usage `json:"usage"`
+}
+
+type openAIChatResponse struct {
+	Model   string `json:"model"`
+	Choices []struct {
+		Message      Message `json:"message"`
+		FinishReason string  `json:"finish_reason"`
+	} `json:"choices"`
+	Usage Usage `json:"usage"`
+}
+
+type openAIErrorResponse struct {
+	Error struct {
+		Message string `json:"message"`
+		Type    string `json:"type"`
+		Code    any    `json:"code"`
+	} `json:"error"`
+}
+
+// buildRequest converts a ChatRequest into the OpenAI wire format, prepending the system prompt.
+func (p *OpenAIProvider) buildRequest(req ChatRequest) openAIChatRequest {
+	messages := make([]Message, 0, len(req.Messages)+1)
+	if req.SystemPrompt != "" {
+		messages = append(messages, Message{Role: RoleSystem, Content: req.SystemPrompt})
+	}
+	messages = append(messages, req.Messages...)
+
+	return openAIChatRequest{
+		Model:       req.Model,
+		Messages:    messages,
+		Temperature: req.Temperature,
+		MaxTokens:   req.MaxTokens,
+	}
+}
+
+// newHTTPRequest prepares an authenticated POST to the given API path.
+func (p *OpenAIProvider) newHTTPRequest(ctx context.Context, path string, body any) (*http.Request, error) {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal %s request: %w", p.name, err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+path, bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s request: %w", p.name, err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if p.apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+	return httpReq, nil
+}
+
+// apiError turns a non-2xx response into a readable error.
+func (p *OpenAIProvider) apiError(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	var errResp openAIErrorResponse
+	if err := json.Unmarshal(body, &errResp); err == nil && errResp.Error.Message != "" {
+		return fmt.Errorf("%s API error (status %d): %s", p.name, resp.StatusCode, errResp.Error.Message)
+	}
+	return fmt.Errorf("%s API error (status %d): %s", p.name, resp.StatusCode, strings.TrimSpace(string(body)))
+}
+
+// ChatCompletion calls POST {baseURL}/chat/completions and returns the first choice.
+func (p *OpenAIProvider) ChatCompletion(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
+	httpReq, err := p.newHTTPRequest(ctx, "/chat/completions", p.buildRequest(req))
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := p.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("%s chat completion request failed: %w", p.name, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, p.apiError(resp)
+	}
+
+	var completion openAIChatResponse
+	if err := json.NewDecoder(resp.Body).Decode(&completion); err != nil {
+		return nil, fmt.Errorf("failed to decode %s chat completion response: %w", p.name, err)
+	}
+	if len(completion.Choices) == 0 || completion.Choices[0].Message.Content == "" {
+		return nil, ErrEmptyCompletion
+	}
+
+	return &ChatResponse{
+		Provider:     p.name,
+		Content:      completion.Choices[0].Message.Content,
+		Model:        completion.Model,
+		FinishReason: completion.Choices[0].FinishReason,
+		Usage:        completion.Usage,
+	}, nil
+}
+
+// StreamChatCompletion calls POST {baseURL}/chat/completions with stream=true and forwards
+// every content delta to onDelta while accumulating the full reply.
+func (p *OpenAIProvider) StreamChatCompletion(ctx context.Context, req ChatRequest, onDelta DeltaFunc) (*ChatResponse, error) {
+	body := p.buildRequest(req)
+	body.Stream = true
+	body.StreamOptions = &openAIStreamOptions{IncludeUsage: true}
+
+	httpReq, err := p.newHTTPRequest(ctx, "/chat/completions", body)
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Accept", "text/event-stream")
+
+	resp, err := p.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("%s streaming chat completion request failed: %w", p.name, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, p.apiError(resp)
+	}
+
+	result := &ChatResponse{Provider: p.name, Model: req.Model}
+	var content strings.Builder
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "data:") {
+			continue // Blank separators, comments and non-data fields
+		}
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "[DONE]" {
+			break
+		}
+
+		var chunk openAIStreamChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return nil, fmt.Errorf("failed to decode %s stream chunk: %w", p.name, err)
+		}
+		if chunk.Model != "" {
+			result.Model = chunk.Model
+		}
+		if chunk.Usage != nil {
+			result.Usage = *chunk.Usage
+		}
+		for _, choice := range chunk.Choices {
+			if choice.FinishReason != nil {
+				result.FinishReason = *choice.FinishReason
+			}
+			if choice.Delta.Content == "" {
+				continue
+			}
+			content.WriteString(choice.Delta.Content)
+			if err := onDelta(choice.Delta.Content); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s stream: %w", p.name, err)
+	}
+
+	if content.Len() == 0 {
+		return nil, ErrEmptyCompletion
+	}
+	result.Content = content.String()
+	return result, nil
+}
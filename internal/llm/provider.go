@@ -0,0 +1,65 @@
+package llm
+
+import (
+	"context"
+	"errors"
+)
+
+// Message roles understood by every provider.
+const (
+	RoleSystem    = "system"
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+)
+
+// ErrEmptyCompletion is returned when a provider answers without any content.
+var ErrEmptyCompletion = errors.New("llm provider returned an empty completion")
+
+// Message is a single turn in the conversation sent to the model.
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// ChatRequest describes a chat completion call.
+// SystemPrompt is kept separate from Messages so providers can place it wherever their API expects it.
+type ChatRequest struct {
+	Model        string
+	SystemPrompt string
+	Messages     []Message
+	Temperature  *float64 // nil = provider default
+	MaxTokens    int      // 0 = provider default
+}
+
+// Usage reports token accounting when the provider returns it.
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// ChatResponse is the result of a chat completion call.
+type ChatResponse struct {
+	Provider     string // Name of the provider that produced the reply
+	Content      string
+	Model        string
+	FinishReason string
+	Usage        Usage
+}
+
+// DeltaFunc receives each chunk of reply content as the provider produces it.
+// Returning an error aborts the stream and is returned from StreamChatCompletion.
+type DeltaFunc func(delta string) error
+
+// Provider is implemented by every LLM backend (OpenAI-compatible HTTP APIs, fakes for tests, ...).
+type Provider interface {
+	// Name returns the identifier the provider is registered under (e.g. "openai").
+	Name() string
+
+	// ChatCompletion sends the conversation to the model and returns the full reply.
+	ChatCompletion(ctx context.Context, req ChatRequest) (*ChatResponse, error)
+
+	// StreamChatCompletion sends the conversation to the model and calls onDelta for every
+	// content chunk as it arrives. The returned response holds the full, concatenated reply.
+	StreamChatCompletion(ctx context.Context, req ChatRequest, onDelta DeltaFunc) (*ChatResponse, error)
+}
@@ -0,0 +1,69 @@
+package llm
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+// Registry holds the configured providers and resolves a chatbot's llm_model value to one of them.
+//
+// A model reference is either a bare model name ("gpt-4o-mini"), which uses the default
+// provider, or "<provider>:<model>" ("fake:echo", "openai:gpt-4o") to pick a provider explicitly.
+type Registry struct {
+	providers       map[string]Provider
+	defaultProvider string
+	defaultModel    string
+}
+
+// NewRegistry creates an empty registry with the given defaults.
+func NewRegistry(defaultProvider, defaultModel string) *Registry {
+	return &Registry{
+		providers:       make(map[string]Provider),
+		defaultProvider: defaultProvider,
+		defaultModel:    defaultModel,
+	}
+}
+
+// Register adds a provider under its Name().
+func (r *Registry) Register(provider Provider) {
+	name := provider.Name()
+	if _, exists := r.providers[name]; exists {
+		log.Printf("WARN [LLMRegistry] Provider '%s' is already registered. Overwriting.", name)
+	}
+	r.providers[name] = provider
+	log.Printf("[LLMRegistry] Registered LLM provider: %s", name)
+}
+
+// Get returns the provider registered under name.
+func (r *Registry) Get(name string) (Provider, error) {
+	provider, ok := r.providers[name]
+	if !ok {
+		return nil, fmt.Errorf("no LLM provider registered with name: %s", name)
+	}
+	return provider, nil
+}
+
+// Resolve picks the provider and model for a chatbot's (nullable) llm_model value.
+func (r *Registry) Resolve(llmModel *string) (Provider, string, error) {
+	ref := ""
+	if llmModel != nil {
+		ref = strings.TrimSpace(*llmModel)
+	}
+
+	providerName, model := r.defaultProvider, ref
+	if name, rest, found := strings.Cut(ref, ":"); found {
+		if _, ok := r.providers[name]; ok {
+			providerName, model = name, rest
+		}
+	}
+	if model == "" {
+		model = r.defaultModel
+	}
+
+	provider, err := r.Get(providerName)
+	if err != nil {
+		return nil, "", err
+	}
+	return provider, model, nil
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"buildmychat-backend/internal/models/integrations"
 	"encoding/json"
 	"time"
 
@@ -50,8 +51,11 @@ type ErrorResponse struct {
 type ServiceType string
 
 const (
-	ServiceTypeNotion ServiceType = "NOTION"
-	ServiceTypeSlack  ServiceType = "SLACK"
+	ServiceTypeNotion  ServiceType = "NOTION"
+	ServiceTypeSlack   ServiceType = "SLACK"
+	ServiceTypeUpload  ServiceType = "UPLOAD"  // Knowledge base filled by file uploads; needs no credential
+	ServiceTypeWebsite ServiceType = "WEBSITE" // Knowledge base filled by crawling a web site; needs no credential
+	ServiceTypeGit     ServiceType = "GIT"     // Knowledge base synced from a Git repository; the token credential is optional
 	// Add other service types here
 )
 
@@ -87,7 +91,8 @@ type TestCredentialResponse struct {
 // CreateKnowledgeBaseRequest defines the body for creating a knowledge base.
 type CreateKnowledgeBaseRequest struct {
 	Name          string          `json:"name"`
-	CredentialID  uuid.UUID       `json:"credential_id"`           // Must be a credential of type NOTION
+	ServiceType   ServiceType     `json:"service_type,omitempty"`  // NOTION (default), UPLOAD, WEBSITE or GIT; ignored on update
+	CredentialID  uuid.UUID       `json:"credential_id"`           // A NOTION credential for NOTION, an optional GIT credential for GIT; omitted for UPLOAD and WEBSITE
 	Configuration json.RawMessage `json:"configuration,omitempty"` // Service-specific config (e.g., {"object_ids": [...]})
 }
 
@@ -95,8 +100,8 @@ type CreateKnowledgeBaseRequest struct {
 type KnowledgeBaseResponse struct {
 	ID             uuid.UUID       `json:"id"`
 	OrganizationID uuid.UUID       `json:"organization_id"`
-	CredentialID   uuid.UUID       `json:"credential_id"`
-	ServiceType    ServiceType     `json:"service_type"` // Should always be NOTION for now
+	CredentialID   uuid.UUID       `json:"credential_id"` // Nil UUID for UPLOAD, WEBSITE and public GIT knowledge bases
+	ServiceType    ServiceType     `json:"service_type"`  // NOTION, UPLOAD, WEBSITE or GIT
 	Name           string          `json:"name"`
 	Configuration  json.RawMessage `json:"configuration,omitempty"`
 	IsActive       bool            `json:"is_active"`
@@ -104,6 +109,52 @@ type KnowledgeBaseResponse struct {
 	UpdatedAt      time.Time       `json:"updated_at"`
 }
 
+// KBSyncStatusResponse describes the sync state of a knowledge base.
+type KBSyncStatusResponse struct {
+	KnowledgeBaseID     uuid.UUID               `json:"knowledge_base_id"`
+	SyncStatus          string                  `json:"sync_status"` // "" if the knowledge base was never synced
+	SyncError           string                  `json:"sync_error,omitempty"`
+	LastSyncStartedAt   *time.Time              `json:"last_sync_started_at,omitempty"`
+	LastSyncedAt        *time.Time              `json:"last_synced_at,omitempty"`
+	LastSyncStats       *integrations.SyncStats `json:"last_sync_stats,omitempty"`
+	SyncIntervalMinutes int                     `json:"sync_interval_minutes,omitempty"` // 0 when no periodic sync is configured
+	DocumentCount       int                     `json:"document_count"`
+}
+
+// KBSearchResult is a knowledge base chunk matched by a search, with the document it belongs to.
+type KBSearchResult struct {
+	Score           float64   `json:"score"` // Cosine similarity for vector search; fused or reranker score otherwise
+	KnowledgeBaseID uuid.UUID `json:"knowledge_base_id"`
+	DocumentID      uuid.UUID `json:"document_id"`
+	ChunkID         uuid.UUID `json:"chunk_id"`
+	ChunkIndex      int       `json:"chunk_index"`
+	Heading         string    `json:"heading,omitempty"`
+	Content         string    `json:"content"`
+	SourceID        string    `json:"source_id"`
+	Title           string    `json:"title"`
+	URL             string    `json:"url,omitempty"`
+}
+
+// KBSearchResponse lists the chunks matching a knowledge base search, best match first.
+type KBSearchResponse struct {
+	Query            string           `json:"query"`
+	KnowledgeBaseIDs []uuid.UUID      `json:"knowledge_base_ids"` // Knowledge bases that were searched
+	Results          []KBSearchResult `json:"results"`
+}
+
+// KBDocumentResponse describes a document ingested into a knowledge base, without its content.
+type KBDocumentResponse struct {
+	ID              uuid.UUID  `json:"id"`
+	KnowledgeBaseID uuid.UUID  `json:"knowledge_base_id"`
+	SourceID        string     `json:"source_id"`
+	Title           string     `json:"title"`
+	URL             string     `json:"url,omitempty"`
+	SourceUpdatedAt *time.Time `json:"source_updated_at,omitempty"` // Last edit time reported by the source
+	ChunkCount      int        `json:"chunk_count"`
+	CreatedAt       time.Time  `json:"created_at"`
+	SyncedAt        time.Time  `json:"synced_at"` // When the document was last fetched from the source
+}
+
 // --- Interface DTOs ---
 
 // CreateInterfaceRequest defines the body for creating an interface.
@@ -111,6 +162,7 @@ type CreateInterfaceRequest struct {
 	Name          string          `json:"name"`
 	CredentialID  uuid.UUID       `json:"credential_id"`           // Must be a credential of type SLACK
 	Configuration json.RawMessage `json:"configuration,omitempty"` // Service-specific config (e.g., {"slack_team_id": "T123"})
+	IsActive      *bool           `json:"is_active,omitempty"`     // Inactive interfaces get no events at shared webhook URLs; default false
 }
 
 // InterfaceResponse defines the data returned for an interface.
@@ -126,6 +178,18 @@ type InterfaceResponse struct {
 	UpdatedAt      time.Time       `json:"updated_at"`
 }
 
+// SlackInstallURLResponse is returned when starting the "Add to Slack" flow.
+type SlackInstallURLResponse struct {
+	AuthorizeURL string `json:"authorize_url"` // Slack page the user approves the installation on
+}
+
+// SlackInstallResponse describes a completed Slack installation.
+type SlackInstallResponse struct {
+	Credential CredentialResponse `json:"credential"`
+	Interface  InterfaceResponse  `json:"interface"`
+	ChatbotID  *uuid.UUID         `json:"chatbot_id,omitempty"` // Chatbot the interface was connected to, if any
+}
+
 // --- Chatbot DTOs ---
 
 // CreateChatbotRequest defines the payload for creating a new chatbot.
@@ -169,6 +233,46 @@ type UpdateChatbotStatusRequest struct {
 	IsActive bool `json:"is_active"`
 }
 
+// ChatbotConfiguration lists the known keys of a chatbot's configuration JSON.
+// Unknown keys are preserved in the stored JSON but ignored by the backend.
+type ChatbotConfiguration struct {
+	Temperature *float64         `json:"temperature,omitempty"` // Sampling temperature passed to the LLM
+	MaxTokens   *int             `json:"max_tokens,omitempty"`  // Upper bound on reply length in tokens
+	Retrieval   *RetrievalConfig `json:"retrieval,omitempty"`   // Knowledge base retrieval for replies
+}
+
+// RetrievalConfig tunes how knowledge base excerpts are retrieved for a chatbot's replies.
+// Unset fields use the backend defaults.
+type RetrievalConfig struct {
+	TopK             *int     `json:"top_k,omitempty"`              // Maximum number of chunks retrieved
+	ScoreThreshold   *float64 `json:"score_threshold,omitempty"`    // Minimum embedding similarity (-1 to 1) of a chunk found by vector search
+	MaxContextTokens *int     `json:"max_context_tokens,omitempty"` // Token budget for the excerpts added to the prompt
+	Mode             *string  `json:"mode,omitempty"`               // "vector" or "hybrid"; overrides the knowledge bases' retrieval_mode
+	Rerank           *bool    `json:"rerank,omitempty"`             // Re-order the candidates with the server's reranker, if one is configured
+}
+
+// ChatMessageSource is a knowledge base document cited by an assistant reply.
+// Generated replies list them under "sources" in ChatMessage.Metadata; Marker is the
+// number used in the reply's source markers, e.g. [1].
+type ChatMessageSource struct {
+	Marker          int       `json:"marker"`
+	KnowledgeBaseID uuid.UUID `json:"knowledge_base_id"`
+	DocumentID      uuid.UUID `json:"document_id"`
+	SourceID        string    `json:"source_id"`
+	Title           string    `json:"title"`
+	URL             string    `json:"url,omitempty"`
+	Score           float64   `json:"score"` // Best retrieval score among the document's excerpts
+}
+
+// ChatMessageDelivery records where an assistant reply was posted at a messaging service, so later
+// edits and deletions of the message can be mirrored there. Replies delivered to a channel list it
+// under "delivery" in ChatMessage.Metadata.
+type ChatMessageDelivery struct {
+	ServiceType ServiceType `json:"service_type"`
+	ChannelID   string      `json:"channel_id"`
+	MessageIDs  []string    `json:"message_ids"` // e.g. the Slack ts of each post of the reply; the first is the one edited while streaming
+}
+
 // ListChatbotsResponse defines the response structure for listing chatbots.
 type ListChatbotsResponse struct {
 	Chatbots []ChatbotResponse `json:"chatbots"`
@@ -261,6 +365,23 @@ type AddMessageAsUserRequest struct {
 	// Media   []MediaAttachment `json:"media,omitempty"` // Reverted: Optional media attachments
 }
 
+// ChatStreamDeltaEvent is the payload of a "delta" event on the chat message stream.
+type ChatStreamDeltaEvent struct {
+	Content string `json:"content"` // The next chunk of the assistant reply
+}
+
+// ChatStreamDoneEvent is the payload of the terminal "done" event on the chat message stream.
+type ChatStreamDoneEvent struct {
+	Message ChatMessage   `json:"message"` // The assistant message as saved in chat_data
+	Chat    *ChatResponse `json:"chat"`    // The updated chat
+}
+
+// ChatSocketRequest is a frame sent by a client over the chat WebSocket.
+type ChatSocketRequest struct {
+	Type    string `json:"type"`              // "message" to send a user message, "typing" to signal typing
+	Message string `json:"message,omitempty"` // The user message, for type "message"
+}
+
 // AddMessageAsAssistantRequest defines the payload for adding an assistant message to a chat.
 type AddMessageAsAssistantRequest struct {
 	Message         string           `json:"message"`                     // The assistant message content
@@ -52,6 +52,59 @@ type KnowledgeBase struct {
 	UpdatedAt      time.Time       `db:"updated_at"`
 }
 
+// KBDocument is a single source document (e.g. a Notion page) ingested into a knowledge base.
+type KBDocument struct {
+	ID              uuid.UUID       `db:"id"`
+	KnowledgeBaseID uuid.UUID       `db:"knowledge_base_id"`
+	OrganizationID  uuid.UUID       `db:"organization_id"`
+	SourceID        string          `db:"source_id"` // ID in the source system, e.g. the Notion page ID
+	Title           string          `db:"title"`
+	URL             string          `db:"url"`
+	Content         string          `db:"content"`           // Rendered text (Markdown or plain text)
+	Metadata        json.RawMessage `db:"metadata"`          // Stored as JSONB
+	SourceUpdatedAt *time.Time      `db:"source_updated_at"` // Last edit time reported by the source
+	ContentHash     string          `db:"content_hash"`      // SHA-256 of title and content, hex encoded
+	CreatedAt       time.Time       `db:"created_at"`
+	UpdatedAt       time.Time       `db:"updated_at"`
+}
+
+// KBDocumentSummary is a stored document without its content, for listings.
+type KBDocumentSummary struct {
+	ID              uuid.UUID  `db:"id"`
+	KnowledgeBaseID uuid.UUID  `db:"knowledge_base_id"`
+	SourceID        string     `db:"source_id"`
+	Title           string     `db:"title"`
+	URL             string     `db:"url"`
+	SourceUpdatedAt *time.Time `db:"source_updated_at"`
+	ChunkCount      int        `db:"chunk_count"` // Number of kb_chunks rows of the document
+	CreatedAt       time.Time  `db:"created_at"`
+	UpdatedAt       time.Time  `db:"updated_at"`
+}
+
+// KBChunk is a retrieval-sized piece of a KBDocument together with its embedding.
+type KBChunk struct {
+	ID              uuid.UUID       `db:"id"`
+	DocumentID      uuid.UUID       `db:"document_id"`
+	KnowledgeBaseID uuid.UUID       `db:"knowledge_base_id"`
+	OrganizationID  uuid.UUID       `db:"organization_id"`
+	ChunkIndex      int             `db:"chunk_index"` // Position within the document
+	Heading         string          `db:"heading"`     // Section heading path
+	Content         string          `db:"content"`
+	TokenCount      int             `db:"token_count"`
+	Embedding       []float32       `db:"embedding"`
+	EmbeddingModel  string          `db:"embedding_model"`
+	Metadata        json.RawMessage `db:"metadata"` // Source metadata (source_id, title, url)
+	CreatedAt       time.Time       `db:"created_at"`
+}
+
+// KBDocumentVersion is the change-tracking state of a stored document, used by incremental syncs.
+type KBDocumentVersion struct {
+	SourceID        string     `db:"source_id"`
+	SourceUpdatedAt *time.Time `db:"source_updated_at"`
+	ContentHash     string     `db:"content_hash"`
+	UpdatedAt       time.Time  `db:"updated_at"` // When the document was last fetched from the source
+}
+
 // Interface represents a configured chat interface instance.
 type Interface struct {
 	ID             uuid.UUID       `db:"id"`
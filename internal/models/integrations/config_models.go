@@ -10,17 +10,141 @@ import (
 // ServiceType represents a supported service integration type
 type ServiceType string
 
+// Knowledge base sync statuses, stored in the KB configuration's sync_status.
+const (
+	SyncStatusPending   = "PENDING"   // A sync was requested and is waiting to start
+	SyncStatusSyncing   = "SYNCING"   // A sync is running
+	SyncStatusCompleted = "COMPLETED" // The last sync succeeded
+	SyncStatusFailed    = "FAILED"    // The last sync failed; see sync_error
+)
+
+// Document formats a Notion knowledge base can be rendered to.
+const (
+	NotionFormatMarkdown = "markdown"
+	NotionFormatPlain    = "plain"
+)
+
+// MinSyncIntervalMinutes is the shortest accepted periodic sync interval.
+const MinSyncIntervalMinutes = 5
+
+// ChunkingConfig controls how a knowledge base's documents are split for retrieval.
+// Sizes are in estimated tokens; 0 uses the defaults. Changing them requires a full sync.
+type ChunkingConfig struct {
+	ChunkSize    int `json:"chunk_size,omitempty"`    // Default 400
+	ChunkOverlap int `json:"chunk_overlap,omitempty"` // Default 60
+}
+
+// Retrieval modes of a knowledge base or chatbot.
+const (
+	RetrievalModeVector = "vector" // Embedding similarity only (default)
+	RetrievalModeHybrid = "hybrid" // Embedding similarity and full-text search, fused by reciprocal rank
+)
+
+// KBRetrievalConfig selects how a knowledge base is searched. A chatbot's retrieval mode, when set,
+// takes precedence for the knowledge bases mapped to it.
+type KBRetrievalConfig struct {
+	RetrievalMode string `json:"retrieval_mode,omitempty"` // "vector" (default) or "hybrid"
+}
+
+// KBSyncState is the sync schedule and state shared by the configurations of synced knowledge bases.
+type KBSyncState struct {
+	SyncIntervalMinutes int `json:"sync_interval_minutes,omitempty"` // Periodic sync interval; 0 disables scheduled syncs
+
+	// Sync state, maintained by the sync engine
+	SyncStatus        string     `json:"sync_status,omitempty"` // e.g., PENDING, SYNCING, COMPLETED, FAILED
+	SyncError         string     `json:"sync_error,omitempty"`  // Reason of the last failure
+	LastSyncStartedAt *time.Time `json:"last_sync_started_at,omitempty"`
+	LastSyncedAt      *time.Time `json:"last_synced_at,omitempty"` // End of the last successful sync
+	LastSyncStats     *SyncStats `json:"last_sync_stats,omitempty"`
+	SyncCursor        string     `json:"sync_cursor,omitempty"` // Source position of the last successful sync, for incremental syncs
+}
+
 // Defines the expected configuration structure for a Notion Knowledge Base.
 type NotionKBConfig struct {
-	NotionObjectIDs []string `json:"notion_object_ids"` // List of Notion Page or Database IDs to index.
-	// Add other Notion-specific config fields here if needed, e.g., SyncStatus
-	SyncStatus string `json:"sync_status,omitempty"` // e.g., PENDING, SYNCING, COMPLETED, FAILED
+	ChunkingConfig
+	KBRetrievalConfig
+	KBSyncState
+
+	NotionObjectIDs []string `json:"notion_object_ids"` // List of Notion Page or Database IDs to index. Empty = everything shared with the integration.
+	Format          string   `json:"format,omitempty"`  // "markdown" (default) or "plain"
+}
+
+// Defines the expected configuration structure for a WEBSITE Knowledge Base, which is crawled.
+type WebsiteKBConfig struct {
+	ChunkingConfig
+	KBRetrievalConfig
+	KBSyncState
+
+	SeedURLs            []string `json:"seed_urls"`                       // Where the crawl starts; at least one is required
+	AllowedDomains      []string `json:"allowed_domains,omitempty"`       // Hosts to crawl, including subdomains. Empty = the seed hosts.
+	AllowedPathPrefixes []string `json:"allowed_path_prefixes,omitempty"` // URL path prefixes to crawl, e.g. "/docs/". Empty = all paths.
+	MaxDepth            int      `json:"max_depth,omitempty"`             // Links followed from a seed URL; default 3
+	MaxPages            int      `json:"max_pages,omitempty"`             // Pages indexed at most; default 100
+	UseSitemap          bool     `json:"use_sitemap,omitempty"`           // Also crawl the pages listed in sitemap.xml
+}
+
+// Defines the expected configuration structure for a GIT Knowledge Base, which indexes files of a repository.
+type GitKBConfig struct {
+	ChunkingConfig
+	KBRetrievalConfig
+	KBSyncState
+
+	RepositoryURL string   `json:"repository_url"`    // https:// clone URL; required
+	Branch        string   `json:"branch,omitempty"`  // Branch to index; default is the repository's default branch
+	Tag           string   `json:"tag,omitempty"`     // Tag to index instead of a branch
+	Include       []string `json:"include,omitempty"` // Globs of files to index, e.g. "docs/**/*.md". Empty = Markdown, text, reStructuredText and HTML files.
+	Exclude       []string `json:"exclude,omitempty"` // Globs of files to skip
+}
+
+// Defines the expected configuration structure for an UPLOAD Knowledge Base, whose documents are
+// uploaded as files rather than synced from a source.
+type UploadKBConfig struct {
+	ChunkingConfig
+	KBRetrievalConfig
+}
+
+// SyncStats counts what a sync did with each source page.
+type SyncStats struct {
+	Updated   int `json:"updated"`          // Fetched, stored and re-indexed (new or changed content)
+	Unchanged int `json:"unchanged"`        // Fetched, but the content hash matched the stored document
+	Skipped   int `json:"skipped"`          // Not fetched: not edited since the last sync (Notion), disallowed or duplicate (website), or not extractable (Git)
+	Deleted   int `json:"deleted"`          // Removed because the page is gone or no longer shared
+	Failed    int `json:"failed,omitempty"` // Could not be fetched; stored documents are kept (website)
 }
 
+// Reply policies of Slack interfaces: which messages the chatbot answers.
+const (
+	SlackReplyDirectMessages = "direct_messages" // Direct messages only
+	SlackReplyMentions       = "mentions"        // Direct messages, and channel messages that mention the bot (default)
+	SlackReplyAll            = "all"             // Direct messages and every message in channels the bot is in
+)
+
+// Where the chatbot answers the slash command of a Slack interface.
+const (
+	SlackCommandRepliesThread    = "thread"    // In a thread under the question, posted in the channel (default)
+	SlackCommandRepliesEphemeral = "ephemeral" // Only to the user who ran the command
+)
+
+// SlackDefaultSlashCommand is the slash command Slack interfaces answer if they do not configure one.
+const SlackDefaultSlashCommand = "/ask"
+
 // Defines the expected configuration structure for a Slack Interface.
 type SlackInterfaceConfig struct {
-	SlackTeamID string `json:"slack_team_id"` // The Slack Workspace/Team ID.
-	// Add other Slack-specific config fields here, e.g., default channel, app ID?
+	SlackTeamID    string `json:"slack_team_id"`               // The Slack Workspace/Team ID.
+	SlackAppID     string `json:"slack_app_id,omitempty"`      // The installed app; set by the "Add to Slack" flow
+	SlackBotUserID string `json:"slack_bot_user_id,omitempty"` // The app's bot user in the workspace; set by the "Add to Slack" flow
+	ReplyPolicy    string `json:"reply_policy,omitempty"`      // "direct_messages", "mentions" (default) or "all"
+	// DirectMessageThreads makes each thread of a direct message its own chat, as in channels.
+	// By default a direct message conversation is one chat, answered outside threads.
+	DirectMessageThreads bool `json:"direct_message_threads,omitempty"`
+	// SlashCommand is the command that starts a new conversation with the chatbot, as defined in the
+	// Slack app; default "/ask". Each use is a new chat, answered as set by SlashCommandReplies.
+	SlashCommand        string `json:"slash_command,omitempty"`
+	SlashCommandReplies string `json:"slash_command_replies,omitempty"` // "thread" (default) or "ephemeral"
+	// EscalateButton adds an "Escalate to human" button next to the feedback buttons of replies.
+	// EscalationMessage is added to the notice posted when it is clicked, e.g. who will follow up.
+	EscalateButton    bool   `json:"escalate_button,omitempty"`
+	EscalationMessage string `json:"escalation_message,omitempty"`
 }
 
 // Defines the expected structure for Notion API credentials (stored encrypted).
@@ -32,8 +156,15 @@ type NotionCredentials struct {
 type SlackCredentials struct {
 	BotToken      string `json:"bot_token"`               // xoxb-... token
 	SigningSecret string `json:"signing_secret"`          // Used for webhook verification
-	ClientID      string `json:"client_id,omitempty"`     // Optional: For OAuth flow if implemented later
-	ClientSecret  string `json:"client_secret,omitempty"` // Optional: For OAuth flow if implemented later
+	ClientID      string `json:"client_id,omitempty"`     // Set for credentials created by the "Add to Slack" flow: the app that was installed
+	ClientSecret  string `json:"client_secret,omitempty"` // Optional: not stored by the "Add to Slack" flow, which uses the server's app secret
+}
+
+// Defines the expected structure for Git credentials (stored encrypted): an HTTPS access token,
+// such as a GitHub or GitLab personal access token.
+type GitCredentials struct {
+	Token    string `json:"token"`
+	Username string `json:"username,omitempty"` // Sent with the token; default x-access-token
 }
 
 // Represents the standard structure for testing an integration's connection.
@@ -16,10 +16,13 @@ type SlackEventPayload struct {
 
 // SlackEvent represents the actual event details within the payload.
 type SlackEvent struct {
-	User        string  `json:"user"` // User ID of the sender
-	Type        string  `json:"type"` // e.g., "message", "app_mention"
-	Text        string  `json:"text"` // Message content
-	Timestamp   string  `json:"ts"`   // Timestamp of the message
+	User        string  `json:"user"`      // User ID of the sender
+	Type        string  `json:"type"`      // e.g., "message", "app_mention"
+	Subtype     string  `json:"subtype"`   // e.g., "bot_message", "message_changed"; empty for plain user messages
+	BotID       string  `json:"bot_id"`    // Set for messages posted by bots and apps
+	Text        string  `json:"text"`      // Message content
+	Timestamp   string  `json:"ts"`        // Timestamp of the message
+	ThreadTs    string  `json:"thread_ts"` // Timestamp of the thread's first message; empty outside threads
 	ClientMsgID string  `json:"client_msg_id"`
 	Team        string  `json:"team"`    // Team ID where the event occurred
 	Blocks      []Block `json:"blocks"`  // Rich text blocks
@@ -65,3 +68,54 @@ type SlackChallengeRequest struct {
 	Challenge string `json:"challenge"`
 	Type      string `json:"type"` // "url_verification"
 }
+
+// SlackInteractionPayload is the "payload" form field of an interactivity request, sent when a user
+// clicks a button of a message. Only the fields of "block_actions" interactions are read.
+type SlackInteractionPayload struct {
+	Type        string               `json:"type"` // e.g., "block_actions"
+	TriggerID   string               `json:"trigger_id"`
+	APIAppID    string               `json:"api_app_id"`
+	Team        SlackTeamRef         `json:"team"`
+	User        SlackUserRef         `json:"user"`
+	Channel     SlackChannelRef      `json:"channel"`
+	Container   SlackContainer       `json:"container"`
+	Message     SlackEvent           `json:"message"`      // The message the button belongs to; empty for ephemeral messages
+	ResponseURL string               `json:"response_url"` // Accepts messages in reply to the interaction for 30 minutes
+	Actions     []SlackElementAction `json:"actions"`
+}
+
+// SlackTeamRef identifies the team of an interaction.
+type SlackTeamRef struct {
+	ID     string `json:"id"`
+	Domain string `json:"domain"`
+}
+
+// SlackUserRef identifies the user who interacted.
+type SlackUserRef struct {
+	ID     string `json:"id"`
+	TeamID string `json:"team_id"`
+}
+
+// SlackChannelRef identifies the channel of an interaction.
+type SlackChannelRef struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// SlackContainer is where the interaction took place.
+type SlackContainer struct {
+	Type        string `json:"type"` // e.g., "message"
+	MessageTs   string `json:"message_ts"`
+	ThreadTs    string `json:"thread_ts"`
+	ChannelID   string `json:"channel_id"`
+	IsEphemeral bool   `json:"is_ephemeral"`
+}
+
+// SlackElementAction is a clicked element of a block_actions interaction.
+type SlackElementAction struct {
+	Type     string `json:"type"` // e.g., "button"
+	ActionID string `json:"action_id"`
+	BlockID  string `json:"block_id"`
+	Value    string `json:"value"`
+	ActionTs string `json:"action_ts"`
+}
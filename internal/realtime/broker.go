@@ -0,0 +1,61 @@
+// Package realtime fans out live chat events (new messages, reply deltas, status and typing
+// changes) to every connection watching a chat. The Broker interface lets a single process use
+// the in-memory implementation while several replicas share events through Postgres; reply deltas
+// only reach the connections on the replica generating the reply.
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/google/uuid"
+)
+
+// Event types published on a chat.
+const (
+	EventMessage = "message" // A message was added to chat_data; Data is a models.ChatMessage
+	EventDelta   = "delta"   // A chunk of an assistant reply being streamed; Data is {"content": "..."}
+	EventStatus  = "status"  // The chat status changed; Data is {"status": "PROCESSING"}
+	EventTyping  = "typing"  // A participant is typing; Data is {"sender": "user"}
+
+	// EventError is never published; it is sent only to the connection whose request failed.
+	EventError = "error" // Data is {"error": "..."}
+)
+
+// Event is a single real-time update for a chat.
+type Event struct {
+	Type   string          `json:"type"`
+	ChatID uuid.UUID       `json:"chat_id"`
+	Data   json.RawMessage `json:"data,omitempty"`
+
+	// SourceID identifies the connection that originated the event (e.g. a typing indicator),
+	// so that connection can skip its own echo. Empty for server-generated events.
+	SourceID string `json:"source_id,omitempty"`
+
+	// Truncated is set when Data was dropped because the event was too large for the transport.
+	// Clients should re-fetch the chat to get the full state.
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// NewEvent builds an event with the given payload marshaled as Data.
+func NewEvent(eventType string, chatID uuid.UUID, data interface{}) (Event, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return Event{}, err
+	}
+	return Event{Type: eventType, ChatID: chatID, Data: raw}, nil
+}
+
+// Broker delivers chat events to subscribers, possibly across server replicas.
+type Broker interface {
+	// Publish sends an event to every subscriber of event.ChatID.
+	// Delivery is best effort: slow subscribers may miss events rather than block publishers.
+	Publish(ctx context.Context, event Event) error
+
+	// Subscribe registers for events on a chat. The returned function unsubscribes
+	// and closes the channel; it is safe to call more than once.
+	Subscribe(chatID uuid.UUID) (<-chan Event, func())
+
+	// Close stops the broker and releases its resources.
+	Close() error
+}
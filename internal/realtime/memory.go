@@ -0,0 +1,94 @@
+package realtime
+
+import (
+	"context"
+	"log"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// subscriberBuffer is how many events a subscriber can fall behind before events are dropped for it.
+const subscriberBuffer = 64
+
+// Ensure MemoryBroker implements the Broker interface.
+var _ Broker = (*MemoryBroker)(nil)
+
+// MemoryBroker fans out events to subscribers in the same process.
+type MemoryBroker struct {
+	mu          sync.RWMutex
+	subscribers map[uuid.UUID]map[chan Event]struct{}
+	closed      bool
+}
+
+// NewMemoryBroker creates an empty in-process broker.
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{subscribers: make(map[uuid.UUID]map[chan Event]struct{})}
+}
+
+// Publish delivers the event to every local subscriber of the chat without blocking.
+func (b *MemoryBroker) Publish(ctx context.Context, event Event) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for ch := range b.subscribers[event.ChatID] {
+		select {
+		case ch <- event:
+		default:
+			log.Printf("WARN [MemoryBroker] Dropping %s event for chat %s: subscriber is not keeping up", event.Type, event.ChatID)
+		}
+	}
+	return nil
+}
+
+// Subscribe registers a buffered channel for the chat's events.
+func (b *MemoryBroker) Subscribe(chatID uuid.UUID) (<-chan Event, func()) {
+	ch := make(chan Event, subscriberBuffer)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		close(ch)
+		return ch, func() {}
+	}
+	if b.subscribers[chatID] == nil {
+		b.subscribers[chatID] = make(map[chan Event]struct{})
+	}
+	b.subscribers[chatID][ch] = struct{}{}
+
+	var once sync.Once
+	unsubscribe := func() {
+		once.Do(func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			if _, ok := b.subscribers[chatID][ch]; !ok {
+				return // Already closed by Close
+			}
+			delete(b.subscribers[chatID], ch)
+			if len(b.subscribers[chatID]) == 0 {
+				delete(b.subscribers, chatID)
+			}
+			close(ch)
+		})
+	}
+	return ch, unsubscribe
+}
+
+// Close closes every subscriber channel. Later subscriptions receive an already-closed channel.
+func (b *MemoryBroker) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return nil
+	}
+	b.closed = true
+	for chatID, subs := range b.subscribers {
+		for ch := range subs {
+			close(ch)
+		}
+		delete(b.subscribers, chatID)
+	}
+	return nil
+}
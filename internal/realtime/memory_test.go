@@ -0,0 +1,75 @@
+package realtime
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestMemoryBrokerDeliversToChatSubscribersOnly(t *testing.T) {
+	broker := NewMemoryBroker()
+	defer broker.Close()
+
+	chatID, otherChatID := uuid.New(), uuid.New()
+	events, unsubscribe := broker.Subscribe(chatID)
+	defer unsubscribe()
+	otherEvents, unsubscribeOther := broker.Subscribe(otherChatID)
+	defer unsubscribeOther()
+
+	event, err := NewEvent(EventStatus, chatID, map[string]string{"status": "PROCESSING"})
+	if err != nil {
+		t.Fatalf("NewEvent returned error: %v", err)
+	}
+	if err := broker.Publish(context.Background(), event); err != nil {
+		t.Fatalf("Publish returned error: %v", err)
+	}
+
+	select {
+	case got := <-events:
+		if got.Type != EventStatus || string(got.Data) != `{"status":"PROCESSING"}` {
+			t.Errorf("unexpected event: %+v", got)
+		}
+	default:
+		t.Fatal("subscriber did not receive the event")
+	}
+
+	select {
+	case got := <-otherEvents:
+		t.Errorf("subscriber of another chat received %+v", got)
+	default:
+	}
+}
+
+func TestMemoryBrokerUnsubscribeClosesChannel(t *testing.T) {
+	broker := NewMemoryBroker()
+	defer broker.Close()
+
+	chatID := uuid.New()
+	events, unsubscribe := broker.Subscribe(chatID)
+	unsubscribe()
+	unsubscribe() // Safe to call twice
+
+	if _, ok := <-events; ok {
+		t.Fatal("expected channel to be closed after unsubscribe")
+	}
+	if err := broker.Publish(context.Background(), Event{Type: EventTyping, ChatID: chatID}); err != nil {
+		t.Fatalf("Publish after unsubscribe returned error: %v", err)
+	}
+}
+
+func TestMemoryBrokerDropsEventsForSlowSubscribers(t *testing.T) {
+	broker := NewMemoryBroker()
+	defer broker.Close()
+
+	chatID := uuid.New()
+	events, unsubscribe := broker.Subscribe(chatID)
+	defer unsubscribe()
+
+	for i := 0; i < subscriberBuffer+10; i++ {
+		broker.Publish(context.Background(), Event{Type: EventDelta, ChatID: chatID})
+	}
+	if len(events) != subscriberBuffer {
+		t.Fatalf("expected %d buffered events, got %d", subscriberBuffer, len(events))
+	}
+}
@@ -0,0 +1,152 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	// postgresChannel is the LISTEN/NOTIFY channel shared by all replicas.
+	postgresChannel = "buildmychat_chat_events"
+
+	// maxNotifyPayload keeps us under Postgres' 8000 byte NOTIFY payload limit.
+	maxNotifyPayload = 7900
+
+	// listenRetryDelay is how long the listener waits before reconnecting after an error.
+	listenRetryDelay = 2 * time.Second
+)
+
+// Ensure PostgresBroker implements the Broker interface.
+var _ Broker = (*PostgresBroker)(nil)
+
+// postgresEnvelope wraps an event on the wire so replicas can ignore their own notifications.
+type postgresEnvelope struct {
+	Origin string `json:"origin"`
+	Event  Event  `json:"event"`
+}
+
+// PostgresBroker shares events between server replicas using Postgres LISTEN/NOTIFY.
+// Events are delivered to local subscribers directly and to other replicas through NOTIFY, except
+// reply deltas: a reply streams hundreds of them, and a NOTIFY each would tie up pool connections for
+// the whole reply. Subscribers on other replicas get the reply from its message event instead.
+type PostgresBroker struct {
+	pool       *pgxpool.Pool
+	local      *MemoryBroker
+	instanceID string
+	cancel     context.CancelFunc
+	done       chan struct{}
+}
+
+// NewPostgresBroker creates the broker and starts listening for notifications from other replicas.
+// The listener holds one dedicated connection taken from the pool until Close is called.
+func NewPostgresBroker(pool *pgxpool.Pool) *PostgresBroker {
+	ctx, cancel := context.WithCancel(context.Background())
+	b := &PostgresBroker{
+		pool:       pool,
+		local:      NewMemoryBroker(),
+		instanceID: uuid.NewString(),
+		cancel:     cancel,
+		done:       make(chan struct{}),
+	}
+	go b.listen(ctx)
+	return b
+}
+
+// Publish delivers the event locally and notifies the other replicas of all but delta events.
+// Events too large for a NOTIFY payload are forwarded without Data and flagged as Truncated.
+func (b *PostgresBroker) Publish(ctx context.Context, event Event) error {
+	if err := b.local.Publish(ctx, event); err != nil {
+		return err
+	}
+	if event.Type == EventDelta {
+		return nil
+	}
+
+	payload, err := json.Marshal(postgresEnvelope{Origin: b.instanceID, Event: event})
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+	if len(payload) > maxNotifyPayload {
+		event.Data = nil
+		event.Truncated = true
+		if payload, err = json.Marshal(postgresEnvelope{Origin: b.instanceID, Event: event}); err != nil {
+			return fmt.Errorf("failed to marshal truncated event: %w", err)
+		}
+	}
+
+	if _, err := b.pool.Exec(ctx, "SELECT pg_notify($1, $2)", postgresChannel, string(payload)); err != nil {
+		return fmt.Errorf("failed to notify %s event for chat %s: %w", event.Type, event.ChatID, err)
+	}
+	return nil
+}
+
+// Subscribe registers for events on a chat published by any replica.
+func (b *PostgresBroker) Subscribe(chatID uuid.UUID) (<-chan Event, func()) {
+	return b.local.Subscribe(chatID)
+}
+
+// Close stops the listener, releases its connection and closes all subscriptions.
+func (b *PostgresBroker) Close() error {
+	b.cancel()
+	<-b.done
+	return b.local.Close()
+}
+
+// listen keeps a LISTEN connection open, reconnecting after failures until ctx is cancelled.
+func (b *PostgresBroker) listen(ctx context.Context) {
+	defer close(b.done)
+
+	for {
+		err := b.listenOnce(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("ERROR [PostgresBroker] Listener stopped, reconnecting in %s: %v", listenRetryDelay, err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(listenRetryDelay):
+		}
+	}
+}
+
+// listenOnce takes a connection out of the pool, listens on the channel and dispatches
+// notifications from other replicas until an error occurs.
+func (b *PostgresBroker) listenOnce(ctx context.Context) error {
+	poolConn, err := b.pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire connection: %w", err)
+	}
+	// The connection is in LISTEN mode, so never hand it back to the pool.
+	conn := poolConn.Hijack()
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+postgresChannel); err != nil {
+		return fmt.Errorf("failed to LISTEN: %w", err)
+	}
+	log.Printf("[PostgresBroker] Listening for chat events on %s", postgresChannel)
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return fmt.Errorf("failed waiting for notification: %w", err)
+		}
+
+		var envelope postgresEnvelope
+		if err := json.Unmarshal([]byte(notification.Payload), &envelope); err != nil {
+			log.Printf("WARN [PostgresBroker] Ignoring malformed notification: %v", err)
+			continue
+		}
+		if envelope.Origin == b.instanceID {
+			continue // Already delivered locally in Publish
+		}
+		b.local.Publish(ctx, envelope.Event)
+	}
+}
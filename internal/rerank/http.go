@@ -0,0 +1,108 @@
+package rerank
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Ensure HTTPReranker implements the Reranker interface.
+var _ Reranker = (*HTTPReranker)(nil)
+
+// HTTPReranker calls a cross-encoder service exposing the /rerank API shared by Cohere, Jina and
+// self-hosted servers such as Infinity.
+type HTTPReranker struct {
+	baseURL    string
+	apiKey     string
+	model      string
+	httpClient *http.Client
+}
+
+// NewHTTPReranker creates a reranker for the service at baseURL. apiKey may be empty for services without
+// authentication; a nil httpClient gets a client with a sane timeout.
+func NewHTTPReranker(baseURL, apiKey, model string, httpClient *http.Client) *HTTPReranker {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 30 * time.Second}
+	}
+	return &HTTPReranker{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		apiKey:     apiKey,
+		model:      model,
+		httpClient: httpClient,
+	}
+}
+
+// Name returns "http:" followed by the model.
+func (r *HTTPReranker) Name() string {
+	return "http:" + r.model
+}
+
+type httpRerankRequest struct {
+	Model     string   `json:"model,omitempty"`
+	Query     string   `json:"query"`
+	Documents []string `json:"documents"`
+	TopN      int      `json:"top_n"`
+}
+
+type httpRerankResponse struct {
+	Results []struct {
+		Index          int     `json:"index"`
+		RelevanceScore float64 `json:"relevance_score"`
+	} `json:"results"`
+}
+
+// Score sends all documents in one request and maps the returned results back to input order.
+func (r *HTTPReranker) Score(ctx context.Context, query string, documents []string) ([]float64, error) {
+	if len(documents) == 0 {
+		return []float64{}, nil
+	}
+
+	body, err := json.Marshal(httpRerankRequest{Model: r.model, Query: query, Documents: documents, TopN: len(documents)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal rerank request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, r.baseURL+"/rerank", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create rerank request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if r.apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+r.apiKey)
+	}
+
+	resp, err := r.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("rerank request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, fmt.Errorf("reranker returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+
+	var parsed httpRerankResponse
+	if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil {
+		return nil, fmt.Errorf("failed to decode rerank response: %w", err)
+	}
+	if len(parsed.Results) != len(documents) {
+		return nil, ErrScoreCount
+	}
+
+	scores := make([]float64, len(documents))
+	seen := make([]bool, len(documents))
+	for _, res := range parsed.Results {
+		if res.Index < 0 || res.Index >= len(documents) || seen[res.Index] {
+			return nil, fmt.Errorf("reranker returned an invalid result index %d", res.Index)
+		}
+		seen[res.Index] = true
+		scores[res.Index] = res.RelevanceScore
+	}
+	return scores, nil
+}
@@ -0,0 +1,90 @@
+package rerank
+
+import (
+	"context"
+	"math"
+	"strings"
+	"unicode"
+)
+
+// BM25 parameters used by LexicalReranker.
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// Ensure LexicalReranker implements the Reranker interface.
+var _ Reranker = LexicalReranker{}
+
+// LexicalReranker is a local, dependency-free scorer: Okapi BM25 with term statistics taken from the
+// candidate documents themselves. It favours passages containing the query's rarer terms verbatim,
+// such as product codes and error strings.
+type LexicalReranker struct{}
+
+// NewLexicalReranker creates a LexicalReranker.
+func NewLexicalReranker() LexicalReranker {
+	return LexicalReranker{}
+}
+
+// Name returns "lexical".
+func (LexicalReranker) Name() string {
+	return "lexical"
+}
+
+// Score returns the BM25 score of each document for the query.
+func (LexicalReranker) Score(ctx context.Context, query string, documents []string) ([]float64, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	docTerms := make([][]string, len(documents))
+	docFreq := map[string]int{}
+	totalLen := 0
+	for i, doc := range documents {
+		docTerms[i] = terms(doc)
+		totalLen += len(docTerms[i])
+		seen := map[string]bool{}
+		for _, t := range docTerms[i] {
+			if !seen[t] {
+				seen[t] = true
+				docFreq[t]++
+			}
+		}
+	}
+
+	scores := make([]float64, len(documents))
+	if len(documents) == 0 || totalLen == 0 {
+		return scores, nil
+	}
+	avgLen := float64(totalLen) / float64(len(documents))
+	n := float64(len(documents))
+
+	queryTerms := map[string]bool{}
+	for _, t := range terms(query) {
+		queryTerms[t] = true
+	}
+
+	for i, dt := range docTerms {
+		freq := map[string]int{}
+		for _, t := range dt {
+			if queryTerms[t] {
+				freq[t]++
+			}
+		}
+		norm := bm25K1 * (1 - bm25B + bm25B*float64(len(dt))/avgLen)
+		for t, f := range freq {
+			df := float64(docFreq[t])
+			idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+			scores[i] += idf * float64(f) * (bm25K1 + 1) / (float64(f) + norm)
+		}
+	}
+	return scores, nil
+}
+
+// terms splits text into lower-case words of letters and digits, keeping hyphenated and
+// underscored codes such as "ERR_TIMEOUT" or "SKU-1234" together.
+func terms(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-' && r != '_'
+	})
+}
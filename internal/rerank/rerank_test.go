@@ -0,0 +1,69 @@
+package rerank
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHTTPRerankerMapsResultsToInputOrder(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/rerank" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer key" {
+			t.Errorf("unexpected Authorization header %q", got)
+		}
+		var req httpRerankRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("decode request: %v", err)
+		}
+		if req.Model != "cross-encoder" || req.Query != "refunds" || len(req.Documents) != 3 || req.TopN != 3 {
+			t.Errorf("unexpected request: %+v", req)
+		}
+		// Results come back sorted by relevance, not by input index.
+		w.Write([]byte(`{"results":[{"index":2,"relevance_score":0.9},{"index":0,"relevance_score":0.5},{"index":1,"relevance_score":0.1}]}`))
+	}))
+	defer server.Close()
+
+	r := NewHTTPReranker(server.URL+"/", "key", "cross-encoder", server.Client())
+	scores, err := r.Score(context.Background(), "refunds", []string{"a", "b", "c"})
+	if err != nil {
+		t.Fatalf("Score returned error: %v", err)
+	}
+	if scores[0] != 0.5 || scores[1] != 0.1 || scores[2] != 0.9 {
+		t.Errorf("unexpected scores: %v", scores)
+	}
+}
+
+func TestHTTPRerankerRejectsIncompleteResults(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"results":[{"index":0,"relevance_score":0.5}]}`))
+	}))
+	defer server.Close()
+
+	_, err := NewHTTPReranker(server.URL, "", "m", server.Client()).Score(context.Background(), "q", []string{"a", "b"})
+	if err != ErrScoreCount {
+		t.Errorf("expected ErrScoreCount, got %v", err)
+	}
+}
+
+func TestLexicalRerankerPrefersRareExactTerms(t *testing.T) {
+	docs := []string{
+		"How to configure the printer and the network settings.",
+		"Error ERR_PAPER_JAM: open the printer tray and remove the paper.",
+		"The printer supports duplex printing.",
+	}
+	scores, err := NewLexicalReranker().Score(context.Background(), "printer shows ERR_PAPER_JAM", docs)
+	if err != nil {
+		t.Fatalf("Score returned error: %v", err)
+	}
+	if !(scores[1] > scores[0] && scores[1] > scores[2]) {
+		t.Errorf("document with the error code should score highest: %v", scores)
+	}
+	if scores[0] <= 0 {
+		t.Errorf("documents sharing a query term should score above zero: %v", scores)
+	}
+}
@@ -0,0 +1,21 @@
+// Package rerank re-orders retrieved passages by their relevance to a query.
+package rerank
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrScoreCount is returned when a backend does not return one score per document.
+var ErrScoreCount = errors.New("reranker returned the wrong number of scores")
+
+// Reranker scores candidate passages against a query. Implementations include a cross-encoder
+// behind an HTTP API and the local LexicalReranker.
+type Reranker interface {
+	// Name identifies the reranker in logs, e.g. "http:rerank-english-v3.0" or "lexical".
+	Name() string
+
+	// Score returns one relevance score per document, in order. Higher is more relevant;
+	// scores are only comparable within one call.
+	Score(ctx context.Context, query string, documents []string) ([]float64, error)
+}
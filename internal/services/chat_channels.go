@@ -0,0 +1,472 @@
+package services
+
+import (
+	"buildmychat-backend/internal/integrations"
+	"buildmychat-backend/internal/models"
+	"buildmychat-backend/internal/store"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Channel webhook errors.
+var (
+	ErrChannelUnsupported    = errors.New("service type is not a messaging channel")
+	ErrChannelNotFound       = errors.New("active chatbot or interface for the webhook not found")
+	ErrChannelUnauthorized   = errors.New("webhook request could not be verified")
+	ErrChannelInvalidPayload = errors.New("invalid webhook payload")
+)
+
+// Background processing of inbound channel messages.
+const (
+	maxConcurrentInboundReplies = 8
+	inboundReplyTimeout         = 2 * time.Minute
+	channelEventRetention       = 24 * time.Hour // Longer than services keep redelivering an event
+	channelEventPruneInterval   = time.Hour
+	channelEventReleaseTimeout  = 5 * time.Second
+)
+
+// inboundReplyFailedNotice replaces a streamed reply whose generation failed.
+const inboundReplyFailedNotice = "Sorry, something went wrong while answering. Please try again."
+
+// HandleChannelWebhook runs an inbound webhook request of a messaging channel through the shared
+// pipeline: the request is verified and parsed by the channel adapter of serviceType, and a message
+// is queued to be added to the chat of its conversation (created on the first message), and to have
+// the chatbot's reply generated, stored and delivered back to the conversation.
+// It returns as soon as the message is queued, as services expect webhooks to be acknowledged within
+// seconds. Messages and actions with an event ID are claimed in the store first, so an event delivered
+// again or concurrently is only processed once; the claim is released if the event is dropped or could
+// not be stored, so that a redelivery is processed. The returned response, if any, is what the request must
+// be answered with. Failures after the message was queued are only logged.
+func (s *ChatService) HandleChannelWebhook(ctx context.Context, serviceType models.ServiceType, chatbotID uuid.UUID, header http.Header, body []byte) (*integrations.WebhookResponse, error) {
+	adapter, ok := s.integrations.Channel(string(serviceType))
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrChannelUnsupported, serviceType)
+	}
+
+	chatbot, err := s.store.GetChatbotByIDOnly(ctx, chatbotID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, fmt.Errorf("%w: chatbot %s", ErrChannelNotFound, chatbotID)
+		}
+		return nil, fmt.Errorf("failed to get chatbot %s: %w", chatbotID, err)
+	}
+	iface, err := s.chatbotInterface(ctx, chatbot, serviceType)
+	if err != nil {
+		return nil, err
+	}
+	return s.handleInterfaceWebhook(ctx, adapter, chatbot, iface, header, body)
+}
+
+// HandleSharedChannelWebhook runs a webhook request sent to the URL a messaging channel shares between
+// all interfaces through the pipeline of HandleChannelWebhook. The interface is the active one
+// configured for the workspace and app the request is for, or else for the workspace without an app,
+// and the chatbot is the one the interface is mapped to. Requests for unknown workspaces are rejected,
+// as unauthorized unless they are signed, so the workspaces that are configured cannot be probed.
+// Requests about the app itself, such as a URL verification challenge, are verified with the app's own
+// secret and only answered.
+func (s *ChatService) HandleSharedChannelWebhook(ctx context.Context, serviceType models.ServiceType, header http.Header, body []byte) (*integrations.WebhookResponse, error) {
+	adapter, ok := s.integrations.Channel(string(serviceType))
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrChannelUnsupported, serviceType)
+	}
+	router, ok := adapter.(integrations.WebhookRouter)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s webhooks are not sent to a shared URL", ErrChannelUnsupported, serviceType)
+	}
+
+	route, err := router.RouteWebhook(body)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrChannelInvalidPayload, err)
+	}
+	if route.WorkspaceID == "" {
+		if err := router.VerifyAppWebhook(header, body); err != nil {
+			log.Printf("WARN [ChatService] HandleSharedChannelWebhook: Rejected %s app webhook: %v", serviceType, err)
+			return nil, fmt.Errorf("%w: %v", ErrChannelUnauthorized, err)
+		}
+		event, err := adapter.ParseWebhook(body, integrations.ChannelInterface{})
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrChannelInvalidPayload, err)
+		}
+		return event.Response, nil
+	}
+
+	iface, err := s.workspaceInterface(ctx, serviceType, route)
+	var chatbot models.Chatbot
+	if err == nil {
+		chatbot, err = s.interfaceChatbot(ctx, iface)
+	}
+	if errors.Is(err, ErrChannelNotFound) && !s.signedSharedWebhook(ctx, adapter, router, iface, header, body) {
+		// Unsigned requests must not learn which workspaces are configured
+		log.Printf("WARN [ChatService] HandleSharedChannelWebhook: Rejected unsigned %s webhook for workspace %s: %v", serviceType, route.WorkspaceID, err)
+		return nil, fmt.Errorf("%w: invalid signature", ErrChannelUnauthorized)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.handleInterfaceWebhook(ctx, adapter, chatbot, iface, header, body)
+}
+
+// signedSharedWebhook reports whether a request sent to a shared URL is signed with the app's own
+// secret, or with that of iface if it is set.
+func (s *ChatService) signedSharedWebhook(ctx context.Context, adapter integrations.ChannelAdapter, router integrations.WebhookRouter, iface *models.Interface, header http.Header, body []byte) bool {
+	if router.VerifyAppWebhook(header, body) == nil {
+		return true
+	}
+	if iface == nil {
+		return false
+	}
+	channelIface, err := s.channelInterface(ctx, iface)
+	if err != nil {
+		log.Printf("WARN [ChatService] signedSharedWebhook: %v", err)
+		return false
+	}
+	return adapter.VerifyWebhook(header, body, channelIface) == nil
+}
+
+// handleInterfaceWebhook verifies and parses a webhook request for an interface, and queues its message
+// to be answered by the chatbot, or its action to be recorded.
+func (s *ChatService) handleInterfaceWebhook(ctx context.Context, adapter integrations.ChannelAdapter, chatbot models.Chatbot, iface *models.Interface, header http.Header, body []byte) (*integrations.WebhookResponse, error) {
+	channelIface, err := s.channelInterface(ctx, iface)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := adapter.VerifyWebhook(header, body, channelIface); err != nil {
+		log.Printf("WARN [ChatService] handleInterfaceWebhook: Rejected %s webhook for interface %s: %v", iface.ServiceType, iface.ID, err)
+		return nil, fmt.Errorf("%w: %v", ErrChannelUnauthorized, err)
+	}
+	event, err := adapter.ParseWebhook(body, channelIface)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrChannelInvalidPayload, err)
+	}
+
+	if action := event.Action; action != nil {
+		claimed, err := s.claimChannelEvent(ctx, iface.ID, action.EventID)
+		if err != nil {
+			return nil, err
+		}
+		if claimed {
+			s.runInBackground(fmt.Sprintf("%s action on chat %s", action.Action, action.ChatID), iface.ID, func(ctx context.Context) {
+				s.recordInboundAction(ctx, adapter, iface, channelIface, action)
+			}, func() {
+				s.releaseChannelEvent(s.baseCtx, iface.ID, action.EventID)
+			})
+		}
+	}
+	if msg := event.Message; msg != nil {
+		claimed, err := s.claimChannelEvent(ctx, iface.ID, msg.EventID)
+		if err != nil {
+			return nil, err
+		}
+		if !claimed {
+			return event.Response, nil
+		}
+		s.runInBackground("inbound message "+msg.EventID, iface.ID, func(ctx context.Context) {
+			s.replyToInboundMessage(ctx, adapter, chatbot, iface.ID, channelIface, msg)
+		}, func() {
+			s.releaseChannelEvent(s.baseCtx, iface.ID, msg.EventID)
+		})
+	}
+	return event.Response, nil
+}
+
+// runInBackground runs work for an interface once one of the inbound reply slots is free, with the
+// inbound reply timeout. Work still waiting for a slot when the service shuts down is dropped, and
+// dropped is called instead, if set.
+func (s *ChatService) runInBackground(what string, interfaceID uuid.UUID, work func(ctx context.Context), dropped func()) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		select {
+		case s.inboundSlots <- struct{}{}:
+		case <-s.baseCtx.Done():
+			log.Printf("WARN [ChatService] Dropping %s of interface %s: shutting down", what, interfaceID)
+			if dropped != nil {
+				dropped()
+			}
+			return
+		}
+		defer func() { <-s.inboundSlots }()
+
+		workCtx, cancel := context.WithTimeout(s.baseCtx, inboundReplyTimeout)
+		defer cancel()
+		work(workCtx)
+		s.pruneChannelEvents(workCtx)
+	}()
+}
+
+// replyToInboundMessage adds an inbound message to the chat of its conversation, and generates and
+// delivers the chatbot's reply. Commands start a new conversation. The claim of the message's event is
+// released if the message could not be stored.
+func (s *ChatService) replyToInboundMessage(ctx context.Context, adapter integrations.ChannelAdapter, chatbot models.Chatbot, interfaceID uuid.UUID, channelIface integrations.ChannelInterface, msg *integrations.InboundMessage) {
+	conv := adapter.Conversation(msg, channelIface)
+	if starter, ok := adapter.(integrations.ConversationStarter); ok && msg.Command != "" {
+		var err error
+		if conv, err = starter.StartConversation(ctx, channelIface, msg); err != nil {
+			log.Printf("ERROR [ChatService] Command %s on interface %s: Failed to start a conversation: %v", msg.Command, interfaceID, err)
+			s.releaseChannelEvent(ctx, interfaceID, msg.EventID)
+			return
+		}
+	}
+
+	userMessage := models.Message{Role: "user", Content: msg.Text, Timestamp: time.Now().UTC()}
+	chat, err := s.FindOrCreateChatForExternalID(ctx, chatbot.OrganizationID, chatbot.ID, interfaceID, conv.Key, userMessage, conv.Configuration)
+	if err != nil {
+		log.Printf("ERROR [ChatService] Inbound message %s on interface %s: Failed to find or create chat %q: %v", msg.EventID, interfaceID, conv.Key, err)
+		s.releaseChannelEvent(ctx, interfaceID, msg.EventID)
+		return
+	}
+
+	if streamer, ok := adapter.(integrations.ReplyStreamer); ok {
+		draft, err := streamer.StartReply(ctx, channelIface, conv)
+		if err != nil {
+			log.Printf("WARN [ChatService] Inbound message %s: Failed to start streamed reply of chat %s; delivering it when done: %v", msg.EventID, chat.ID, err)
+		} else if draft != nil {
+			s.streamInboundReply(ctx, chatbot.OrganizationID, chat.ID, interfaceID, msg, draft)
+			return
+		}
+	}
+
+	s.deliverInboundReply(ctx, adapter, chatbot.OrganizationID, chat.ID, interfaceID, channelIface, conv, msg)
+}
+
+// deliverInboundReply generates the reply to an inbound message, delivers it to its conversation and
+// stores the reply with where it was posted. Failed generations are recorded on the chat like in
+// GenerateAssistantReply; there is nothing to deliver then.
+func (s *ChatService) deliverInboundReply(ctx context.Context, adapter integrations.ChannelAdapter, orgID, chatID, interfaceID uuid.UUID, channelIface integrations.ChannelInterface, conv integrations.Conversation, msg *integrations.InboundMessage) {
+	if err := s.setChatStatus(ctx, orgID, chatID, chatStatusProcessing); err != nil {
+		log.Printf("ERROR [ChatService] Inbound message %s: Failed to update status of chat %s: %v", msg.EventID, chatID, err)
+		return
+	}
+	completion, sources, err := s.generateReply(ctx, orgID, chatID)
+	if err != nil {
+		s.recordReplyError(context.WithoutCancel(ctx), orgID, chatID, err)
+		log.Printf("ERROR [ChatService] Inbound message %s: Reply for chat %s failed: %v", msg.EventID, chatID, err)
+		return
+	}
+
+	// The reply is stored even if it could not be delivered in full; delivery lists the posts that were made
+	delivery, err := adapter.Deliver(ctx, channelIface, conv, integrations.OutboundMessage{ChatID: chatID, Text: completion.Content, Sources: sources})
+	if err != nil {
+		log.Printf("ERROR [ChatService] Inbound message %s: Failed to deliver reply of chat %s to interface %s: %v", msg.EventID, chatID, interfaceID, err)
+	}
+	metadata, err := marshalReplyMetadata(completion, sources, delivery)
+	if err != nil {
+		log.Printf("ERROR [ChatService] Inbound message %s: %v", msg.EventID, err)
+		return
+	}
+	if _, err := s.AddAssistantMessageToChat(context.WithoutCancel(ctx), orgID, chatID, completion.Content, metadata); err != nil {
+		log.Printf("ERROR [ChatService] Inbound message %s: Failed to store reply of chat %s: %v", msg.EventID, chatID, err)
+		return
+	}
+	log.Printf("[ChatService] Delivered reply of chat %s to interface %s", chatID, interfaceID)
+}
+
+// streamInboundReply generates the reply to an inbound message into a draft shown in its conversation,
+// and stores the reply with where it was posted. When generation fails the draft is replaced with a
+// failure notice, as the user has already seen the reply being started.
+func (s *ChatService) streamInboundReply(ctx context.Context, orgID, chatID, interfaceID uuid.UUID, msg *integrations.InboundMessage, draft integrations.ReplyDraft) {
+	var text strings.Builder
+	completion, sources, err := s.streamReply(ctx, orgID, chatID, func(delta string) error {
+		text.WriteString(delta)
+		draft.Update(text.String())
+		return nil
+	})
+	if err != nil {
+		log.Printf("ERROR [ChatService] Inbound message %s: Reply for chat %s failed: %v", msg.EventID, chatID, err)
+		// ctx may be what ended the generation
+		if _, err := draft.Finish(context.WithoutCancel(ctx), integrations.OutboundMessage{Text: inboundReplyFailedNotice}); err != nil {
+			log.Printf("WARN [ChatService] Inbound message %s: Failed to send failure notice of chat %s to interface %s: %v", msg.EventID, chatID, interfaceID, err)
+		}
+		return
+	}
+
+	// The reply is stored even if it could not be posted in full; delivery lists the posts that were made
+	delivery, err := draft.Finish(ctx, integrations.OutboundMessage{ChatID: chatID, Text: completion.Content, Sources: sources})
+	if err != nil {
+		log.Printf("ERROR [ChatService] Inbound message %s: Failed to deliver reply of chat %s to interface %s: %v", msg.EventID, chatID, interfaceID, err)
+	}
+	metadata, err := marshalReplyMetadata(completion, sources, delivery)
+	if err != nil {
+		log.Printf("ERROR [ChatService] Inbound message %s: %v", msg.EventID, err)
+		return
+	}
+	if _, err := s.AddAssistantMessageToChat(context.WithoutCancel(ctx), orgID, chatID, completion.Content, metadata); err != nil {
+		log.Printf("ERROR [ChatService] Inbound message %s: Failed to store reply of chat %s: %v", msg.EventID, chatID, err)
+		return
+	}
+	log.Printf("[ChatService] Streamed reply of chat %s to interface %s", chatID, interfaceID)
+}
+
+// recordInboundAction records a user's feedback on a reply as the feedback of its chat, and sends the
+// action's notice. Escalations are recorded as negative feedback. Actions on chats of other interfaces
+// are ignored. The claim of the action's event is released if the feedback could not be recorded.
+func (s *ChatService) recordInboundAction(ctx context.Context, adapter integrations.ChannelAdapter, iface *models.Interface, channelIface integrations.ChannelInterface, action *integrations.InboundAction) {
+	chat, err := s.store.GetChatByID(ctx, action.ChatID, iface.OrganizationID)
+	if err != nil {
+		if !errors.Is(err, store.ErrNotFound) {
+			log.Printf("ERROR [ChatService] %s action on interface %s: Failed to get chat %s: %v", action.Action, iface.ID, action.ChatID, err)
+			s.releaseChannelEvent(ctx, iface.ID, action.EventID)
+			return
+		}
+		log.Printf("WARN [ChatService] %s action on interface %s: Chat %s not found", action.Action, iface.ID, action.ChatID)
+		return
+	}
+	if chat.InterfaceID != iface.ID {
+		log.Printf("WARN [ChatService] %s action on interface %s: Chat %s belongs to interface %s; ignoring it", action.Action, iface.ID, chat.ID, chat.InterfaceID)
+		return
+	}
+
+	var feedback int8 = -1
+	if action.Action == integrations.ActionFeedbackPositive {
+		feedback = 1
+	}
+	if err := s.UpdateChatFeedback(ctx, iface.OrganizationID, chat.ID, feedback); err != nil {
+		log.Printf("ERROR [ChatService] %s action on interface %s: %v", action.Action, iface.ID, err)
+		s.releaseChannelEvent(ctx, iface.ID, action.EventID)
+		return
+	}
+	log.Printf("[ChatService] Recorded %s of user %s on chat %s", action.Action, action.UserID, chat.ID)
+
+	if action.Notice == "" {
+		return
+	}
+	if _, err := adapter.Deliver(ctx, channelIface, action.NoticeConversation, integrations.OutboundMessage{Text: action.Notice}); err != nil {
+		log.Printf("WARN [ChatService] %s action on interface %s: Failed to send notice for chat %s: %v", action.Action, iface.ID, chat.ID, err)
+	}
+}
+
+// claimChannelEvent claims an inbound event of an interface in the store. It reports false for an
+// event that was already claimed, which must not be processed again. Events without an ID are always
+// processed.
+func (s *ChatService) claimChannelEvent(ctx context.Context, interfaceID uuid.UUID, eventID string) (bool, error) {
+	if eventID == "" {
+		return true, nil
+	}
+	claimed, err := s.store.ClaimChannelEvent(ctx, interfaceID, eventID)
+	if err != nil {
+		return false, fmt.Errorf("failed to record event %s: %w", eventID, err)
+	}
+	if !claimed {
+		log.Printf("[ChatService] Event %s of interface %s was already received; ignoring it", eventID, interfaceID)
+	}
+	return claimed, nil
+}
+
+// releaseChannelEvent releases the claim of an inbound event that was not processed, so that a
+// redelivery of it is. ctx may already be done, e.g. at shutdown.
+func (s *ChatService) releaseChannelEvent(ctx context.Context, interfaceID uuid.UUID, eventID string) {
+	if eventID == "" {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), channelEventReleaseTimeout)
+	defer cancel()
+	if err := s.store.ReleaseChannelEvent(ctx, interfaceID, eventID); err != nil {
+		log.Printf("WARN [ChatService] Failed to release event %s of interface %s: %v", eventID, interfaceID, err)
+		return
+	}
+	log.Printf("[ChatService] Released event %s of interface %s to be processed when delivered again", eventID, interfaceID)
+}
+
+// pruneChannelEvents deletes the channel events received more than channelEventRetention ago, at most
+// once per channelEventPruneInterval.
+func (s *ChatService) pruneChannelEvents(ctx context.Context) {
+	s.pruneMu.Lock()
+	if time.Since(s.channelsPrunedAt) < channelEventPruneInterval {
+		s.pruneMu.Unlock()
+		return
+	}
+	s.channelsPrunedAt = time.Now()
+	s.pruneMu.Unlock()
+
+	deleted, err := s.store.DeleteChannelEventsBefore(ctx, time.Now().Add(-channelEventRetention))
+	if err != nil {
+		log.Printf("WARN [ChatService] pruneChannelEvents: %v", err)
+		return
+	}
+	if deleted > 0 {
+		log.Printf("[ChatService] pruneChannelEvents: Deleted %d old channel events", deleted)
+	}
+}
+
+// chatbotInterface returns the interface of a service type mapped to a chatbot. Inactive chatbots and
+// interfaces are not found.
+func (s *ChatService) chatbotInterface(ctx context.Context, chatbot models.Chatbot, serviceType models.ServiceType) (*models.Interface, error) {
+	if !chatbot.IsActive {
+		return nil, fmt.Errorf("%w: chatbot %s is inactive", ErrChannelNotFound, chatbot.ID)
+	}
+	mappings, err := s.store.GetChatbotMappings(ctx, chatbot.ID, chatbot.OrganizationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get interfaces of chatbot %s: %w", chatbot.ID, err)
+	}
+	for _, mapped := range mappings.Interfaces {
+		if mapped.ServiceType != serviceType {
+			continue
+		}
+		iface, err := s.store.GetInterfaceByID(ctx, mapped.ID, chatbot.OrganizationID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get interface %s: %w", mapped.ID, err)
+		}
+		if !iface.IsActive {
+			return nil, fmt.Errorf("%w: %s interface %s of chatbot %s is inactive", ErrChannelNotFound, serviceType, iface.ID, chatbot.ID)
+		}
+		return iface, nil
+	}
+	return nil, fmt.Errorf("%w: no %s interface is mapped to chatbot %s", ErrChannelNotFound, serviceType, chatbot.ID)
+}
+
+// workspaceInterface returns the active interface of a service type configured for the workspace and
+// app of a webhook request. Interfaces configured without an app receive the requests of any app the
+// workspace has no interface for.
+func (s *ChatService) workspaceInterface(ctx context.Context, serviceType models.ServiceType, route integrations.WebhookRoute) (*models.Interface, error) {
+	iface, err := s.store.GetActiveInterfaceByWorkspace(ctx, serviceType, route.WorkspaceID, route.AppID)
+	if errors.Is(err, store.ErrNotFound) && route.AppID != "" {
+		iface, err = s.store.GetActiveInterfaceByWorkspace(ctx, serviceType, route.WorkspaceID, "")
+	}
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, fmt.Errorf("%w: no %s interface for workspace %s", ErrChannelNotFound, serviceType, route.WorkspaceID)
+		}
+		return nil, fmt.Errorf("failed to get interface for workspace %s: %w", route.WorkspaceID, err)
+	}
+	return iface, nil
+}
+
+// interfaceChatbot returns the chatbot an interface is mapped to. An interface should be mapped to
+// one chatbot; if it is mapped to several, the oldest answers. An inactive chatbot is not found.
+func (s *ChatService) interfaceChatbot(ctx context.Context, iface *models.Interface) (models.Chatbot, error) {
+	chatbots, err := s.store.ListChatbotsByInterface(ctx, iface.ID, iface.OrganizationID)
+	if err != nil {
+		return models.Chatbot{}, fmt.Errorf("failed to get chatbots of interface %s: %w", iface.ID, err)
+	}
+	if len(chatbots) == 0 {
+		return models.Chatbot{}, fmt.Errorf("%w: interface %s is not mapped to a chatbot", ErrChannelNotFound, iface.ID)
+	}
+	if len(chatbots) > 1 {
+		log.Printf("WARN [ChatService] Interface %s is mapped to %d chatbots; chatbot %s answers", iface.ID, len(chatbots), chatbots[0].ID)
+	}
+	if !chatbots[0].IsActive {
+		return models.Chatbot{}, fmt.Errorf("%w: chatbot %s of interface %s is inactive", ErrChannelNotFound, chatbots[0].ID, iface.ID)
+	}
+	return chatbots[0], nil
+}
+
+// channelInterface loads what a channel adapter needs for an interface, including its decrypted credential.
+func (s *ChatService) channelInterface(ctx context.Context, iface *models.Interface) (integrations.ChannelInterface, error) {
+	channelIface := integrations.ChannelInterface{ID: iface.ID, Configuration: iface.Configuration}
+	if iface.CredentialID != uuid.Nil {
+		cred, err := s.credentialService.GetDecryptedCredential(ctx, iface.CredentialID, iface.OrganizationID)
+		if err != nil {
+			return channelIface, fmt.Errorf("failed to load credential of interface %s: %w", iface.ID, err)
+		}
+		channelIface.Credentials = cred.DecryptedCredentials
+	}
+	return channelIface, nil
+}
@@ -0,0 +1,508 @@
+package services
+
+import (
+	"buildmychat-backend/internal/integrations"
+	"buildmychat-backend/internal/llm"
+	"buildmychat-backend/internal/models"
+	integration_models "buildmychat-backend/internal/models/integrations"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// fakeServiceType is the service type fakeChannel is registered for.
+const fakeServiceType models.ServiceType = "FAKE"
+
+// fakeChannel is a channel adapter whose webhooks are JSON encoded integrations.WebhookEvent values,
+// verified by a fixed signature header. It records what it delivers.
+type fakeChannel struct {
+	mu         sync.Mutex
+	delivered  []fakeDelivery
+	deliverErr error // Returned by Deliver, which then records nothing
+}
+
+type fakeDelivery struct {
+	Conversation integrations.Conversation
+	Message      integrations.OutboundMessage
+}
+
+func (f *fakeChannel) ValidateConfig(configJSON json.RawMessage) error { return nil }
+
+func (f *fakeChannel) TestConnection(ctx context.Context, decryptedCreds integration_models.DecryptedCredentials) (*integration_models.TestConnectionResult, error) {
+	return &integration_models.TestConnectionResult{Success: true}, nil
+}
+
+func (f *fakeChannel) GetCredentialSchema() interface{} { return nil }
+
+func (f *fakeChannel) VerifyWebhook(header http.Header, body []byte, iface integrations.ChannelInterface) error {
+	if header.Get("X-Fake-Signature") != "valid" {
+		return errors.New("invalid signature")
+	}
+	return nil
+}
+
+func (f *fakeChannel) ParseWebhook(body []byte, iface integrations.ChannelInterface) (*integrations.WebhookEvent, error) {
+	var event integrations.WebhookEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+// Conversation keys messages by channel and thread; a message outside a thread starts one.
+func (f *fakeChannel) Conversation(msg *integrations.InboundMessage, iface integrations.ChannelInterface) integrations.Conversation {
+	thread := msg.ThreadID
+	if thread == "" {
+		thread = msg.MessageID
+	}
+	configuration, _ := json.Marshal(map[string]string{"channel": msg.ChannelID, "thread": thread})
+	return integrations.Conversation{Key: msg.ChannelID + "/" + thread, Configuration: configuration}
+}
+
+func (f *fakeChannel) Deliver(ctx context.Context, iface integrations.ChannelInterface, conv integrations.Conversation, msg integrations.OutboundMessage) (*models.ChatMessageDelivery, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.deliverErr != nil {
+		return nil, f.deliverErr
+	}
+	f.delivered = append(f.delivered, fakeDelivery{Conversation: conv, Message: msg})
+	return &models.ChatMessageDelivery{ServiceType: fakeServiceType, ChannelID: conv.Key, MessageIDs: []string{fmt.Sprint(len(f.delivered))}}, nil
+}
+
+// RouteWebhook routes a message to its workspace, and the app named by the request's AppID field.
+func (f *fakeChannel) RouteWebhook(body []byte) (integrations.WebhookRoute, error) {
+	var routed struct {
+		Message *integrations.InboundMessage
+		AppID   string
+	}
+	if err := json.Unmarshal(body, &routed); err != nil {
+		return integrations.WebhookRoute{}, err
+	}
+	if routed.Message == nil {
+		return integrations.WebhookRoute{}, nil
+	}
+	return integrations.WebhookRoute{WorkspaceID: routed.Message.WorkspaceID, AppID: routed.AppID}, nil
+}
+
+func (f *fakeChannel) VerifyAppWebhook(header http.Header, body []byte) error {
+	return f.VerifyWebhook(header, body, integrations.ChannelInterface{})
+}
+
+func (f *fakeChannel) deliveries() []fakeDelivery {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]fakeDelivery(nil), f.delivered...)
+}
+
+// newTestChannelService creates a ChatService with fakeChannel registered, and a chatbot with an
+// interface of it.
+func newTestChannelService(t *testing.T, st *fakeStore) (*ChatService, *fakeChannel, models.Chatbot, *models.Interface) {
+	t.Helper()
+	s := newTestChatService(t, st, llm.NewFakeProvider(), nil)
+	channel := &fakeChannel{}
+	s.integrations.Register(string(fakeServiceType), channel)
+	chatbot := st.addChatbot(uuid.New())
+	return s, channel, chatbot, st.addInterface(chatbot, fakeServiceType, "{}")
+}
+
+// signedWebhook returns the header and body of a verified webhook request of event.
+func signedWebhook(t *testing.T, event integrations.WebhookEvent) (http.Header, []byte) {
+	t.Helper()
+	body, err := json.Marshal(event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	header := http.Header{}
+	header.Set("X-Fake-Signature", "valid")
+	return header, body
+}
+
+// postWebhook sends a signed webhook of event to the chatbot's URL, and waits for the work it queued.
+func postWebhook(t *testing.T, s *ChatService, chatbot models.Chatbot, event integrations.WebhookEvent) (*integrations.WebhookResponse, error) {
+	t.Helper()
+	header, body := signedWebhook(t, event)
+	resp, err := s.HandleChannelWebhook(context.Background(), fakeServiceType, chatbot.ID, header, body)
+	s.wg.Wait()
+	return resp, err
+}
+
+func TestHandleChannelWebhookRepliesInConversation(t *testing.T) {
+	st := newFakeStore()
+	s, channel, chatbot, iface := newTestChannelService(t, st)
+
+	resp, err := postWebhook(t, s, chatbot, integrations.WebhookEvent{
+		Response: &integrations.WebhookResponse{ContentType: "text/plain", Body: []byte("ok")},
+		Message:  &integrations.InboundMessage{EventID: "E1", ChannelID: "C1", MessageID: "M1", Text: "Hello there"},
+	})
+	if err != nil {
+		t.Fatalf("HandleChannelWebhook: %v", err)
+	}
+	if resp == nil || string(resp.Body) != "ok" {
+		t.Errorf("response = %+v, want the adapter's response", resp)
+	}
+
+	chats := st.chatsOfInterface(iface.ID)
+	if len(chats) != 1 {
+		t.Fatalf("%d chats of the interface, want 1", len(chats))
+	}
+	chat := chats[0]
+	if chat.ExternalChatID != "C1/M1" || chat.ChatbotID != chatbot.ID {
+		t.Errorf("chat = %+v, want the conversation C1/M1 of the chatbot", chat)
+	}
+	messages := st.chatMessages(t, chat.ID)
+	if len(messages) != 2 || messages[0].Content != "Hello there" || messages[1].Content != "[fake:echo] You said: Hello there" {
+		t.Errorf("messages = %+v, want the inbound message and the reply", messages)
+	}
+
+	delivered := channel.deliveries()
+	if len(delivered) != 1 {
+		t.Fatalf("%d deliveries, want 1", len(delivered))
+	}
+	if d := delivered[0]; d.Conversation.Key != "C1/M1" || d.Message.ChatID != chat.ID || d.Message.Text != messages[1].Content {
+		t.Errorf("delivery = %+v, want the reply in conversation C1/M1", d)
+	}
+
+	// The reply is stored with where it was posted
+	var metadata replyMetadata
+	if messages[1].Metadata == nil || json.Unmarshal(*messages[1].Metadata, &metadata) != nil {
+		t.Fatalf("metadata of the reply = %v", messages[1].Metadata)
+	}
+	if d := metadata.Delivery; d == nil || d.ServiceType != fakeServiceType || d.ChannelID != "C1/M1" || len(d.MessageIDs) != 1 || d.MessageIDs[0] != "1" {
+		t.Errorf("delivery in the metadata of the reply = %+v, want the posted message", d)
+	}
+}
+
+func TestHandleChannelWebhookStoresUndeliveredReply(t *testing.T) {
+	st := newFakeStore()
+	s, channel, chatbot, iface := newTestChannelService(t, st)
+
+	channel.deliverErr = errors.New("channel_not_found")
+	if _, err := postWebhook(t, s, chatbot, integrations.WebhookEvent{Message: &integrations.InboundMessage{EventID: "E1", ChannelID: "C1", MessageID: "M1", Text: "Hello there"}}); err != nil {
+		t.Fatalf("HandleChannelWebhook: %v", err)
+	}
+
+	chat := st.chatsOfInterface(iface.ID)[0]
+	messages := st.chatMessages(t, chat.ID)
+	if len(messages) != 2 {
+		t.Fatalf("messages = %+v, want the inbound message and the reply", messages)
+	}
+	var metadata replyMetadata
+	if messages[1].Metadata == nil || json.Unmarshal(*messages[1].Metadata, &metadata) != nil || metadata.Delivery != nil {
+		t.Errorf("metadata of the reply = %v, want no delivery", messages[1].Metadata)
+	}
+	if got := st.chatStatuses(chat.ID); len(got) == 0 || got[len(got)-1] != chatStatusActive {
+		t.Errorf("statuses = %v, want the chat active again", got)
+	}
+}
+
+func TestHandleChannelWebhookRejectsUnverified(t *testing.T) {
+	st := newFakeStore()
+	s, channel, chatbot, iface := newTestChannelService(t, st)
+
+	body, _ := json.Marshal(integrations.WebhookEvent{Message: &integrations.InboundMessage{ChannelID: "C1", MessageID: "M1", Text: "Hello"}})
+	_, err := s.HandleChannelWebhook(context.Background(), fakeServiceType, chatbot.ID, http.Header{}, body)
+	s.wg.Wait()
+	if !errors.Is(err, ErrChannelUnauthorized) {
+		t.Fatalf("error = %v, want ErrChannelUnauthorized", err)
+	}
+	if len(st.chatsOfInterface(iface.ID)) != 0 || len(channel.deliveries()) != 0 {
+		t.Error("an unverified webhook was processed")
+	}
+}
+
+func TestHandleChannelWebhookNotFound(t *testing.T) {
+	st := newFakeStore()
+	s, _, _, _ := newTestChannelService(t, st)
+	unmapped := st.addChatbot(uuid.New())
+
+	tests := []struct {
+		name        string
+		serviceType models.ServiceType
+		chatbotID   uuid.UUID
+		want        error
+	}{
+		{"unsupported service type", models.ServiceTypeSlack, unmapped.ID, ErrChannelUnsupported},
+		{"unknown chatbot", fakeServiceType, uuid.New(), ErrChannelNotFound},
+		{"chatbot without interface", fakeServiceType, unmapped.ID, ErrChannelNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.HandleChannelWebhook(context.Background(), tt.serviceType, tt.chatbotID, http.Header{}, []byte("{}"))
+			if !errors.Is(err, tt.want) {
+				t.Errorf("error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandleChannelWebhookInvalidPayload(t *testing.T) {
+	st := newFakeStore()
+	s, _, chatbot, _ := newTestChannelService(t, st)
+
+	header, _ := signedWebhook(t, integrations.WebhookEvent{})
+	_, err := s.HandleChannelWebhook(context.Background(), fakeServiceType, chatbot.ID, header, []byte("not json"))
+	if !errors.Is(err, ErrChannelInvalidPayload) {
+		t.Errorf("error = %v, want ErrChannelInvalidPayload", err)
+	}
+}
+
+func TestHandleChannelWebhookProcessesEventOnce(t *testing.T) {
+	st := newFakeStore()
+	s, channel, chatbot, iface := newTestChannelService(t, st)
+
+	event := integrations.WebhookEvent{Message: &integrations.InboundMessage{EventID: "E1", ChannelID: "C1", MessageID: "M1", Text: "Hello there"}}
+	for i := 0; i < 3; i++ {
+		if _, err := postWebhook(t, s, chatbot, event); err != nil {
+			t.Fatalf("delivery %d: %v", i+1, err)
+		}
+	}
+
+	chats := st.chatsOfInterface(iface.ID)
+	if len(chats) != 1 || len(st.chatMessages(t, chats[0].ID)) != 2 {
+		t.Errorf("chats = %+v, want one with the message and its reply", chats)
+	}
+	if n := len(channel.deliveries()); n != 1 {
+		t.Errorf("%d deliveries of an event delivered 3 times, want 1", n)
+	}
+}
+
+func TestHandleChannelWebhookReleasesUnstoredEvent(t *testing.T) {
+	st := newFakeStore()
+	s, channel, chatbot, iface := newTestChannelService(t, st)
+	event := integrations.WebhookEvent{Message: &integrations.InboundMessage{EventID: "E1", ChannelID: "C1", MessageID: "M1", Text: "Hello there"}}
+
+	st.createChatErr = errors.New("connection refused")
+	if _, err := postWebhook(t, s, chatbot, event); err != nil {
+		t.Fatalf("HandleChannelWebhook: %v", err)
+	}
+	if n := st.claimedEvents(); n != 0 {
+		t.Fatalf("%d claimed events after the message could not be stored, want none", n)
+	}
+
+	// The redelivery is processed
+	st.createChatErr = nil
+	if _, err := postWebhook(t, s, chatbot, event); err != nil {
+		t.Fatalf("HandleChannelWebhook: %v", err)
+	}
+	if len(st.chatsOfInterface(iface.ID)) != 1 || len(channel.deliveries()) != 1 {
+		t.Error("the redelivered event was not processed")
+	}
+}
+
+func TestHandleChannelWebhookReleasesDroppedEvent(t *testing.T) {
+	st := newFakeStore()
+	s, channel, chatbot, iface := newTestChannelService(t, st)
+
+	// Occupy every reply slot, so that the message is still queued at shutdown
+	for i := 0; i < cap(s.inboundSlots); i++ {
+		s.inboundSlots <- struct{}{}
+	}
+	header, body := signedWebhook(t, integrations.WebhookEvent{Message: &integrations.InboundMessage{EventID: "E1", ChannelID: "C1", MessageID: "M1", Text: "Hello there"}})
+	if _, err := s.HandleChannelWebhook(context.Background(), fakeServiceType, chatbot.ID, header, body); err != nil {
+		t.Fatalf("HandleChannelWebhook: %v", err)
+	}
+	s.Close()
+
+	if n := st.claimedEvents(); n != 0 {
+		t.Errorf("%d claimed events after the message was dropped, want none", n)
+	}
+	if len(st.chatsOfInterface(iface.ID)) != 0 || len(channel.deliveries()) != 0 {
+		t.Error("the dropped message was processed")
+	}
+}
+
+func TestHandleChannelWebhookChatPerThread(t *testing.T) {
+	st := newFakeStore()
+	s, channel, chatbot, iface := newTestChannelService(t, st)
+
+	messages := []*integrations.InboundMessage{
+		{EventID: "E1", ChannelID: "C1", MessageID: "M1", Text: "First thread"},
+		{EventID: "E2", ChannelID: "C1", MessageID: "M2", Text: "Second thread"},
+		{EventID: "E3", ChannelID: "C1", MessageID: "M3", ThreadID: "M1", Text: "Reply in the first thread"},
+	}
+	for _, msg := range messages {
+		if _, err := postWebhook(t, s, chatbot, integrations.WebhookEvent{Message: msg}); err != nil {
+			t.Fatalf("message %s: %v", msg.EventID, err)
+		}
+	}
+
+	chats := map[string]models.Chat{}
+	for _, chat := range st.chatsOfInterface(iface.ID) {
+		chats[chat.ExternalChatID] = chat
+	}
+	if len(chats) != 2 {
+		t.Fatalf("chats of the interface = %+v, want one per thread", chats)
+	}
+	first := chats["C1/M1"]
+	if got := st.chatMessages(t, first.ID); len(got) != 4 || got[2].Content != "Reply in the first thread" {
+		t.Errorf("messages of the first thread = %+v, want both messages and their replies", got)
+	}
+	if string(first.Configuration) != `{"channel":"C1","thread":"M1"}` {
+		t.Errorf("configuration of the first thread = %s, want its root", first.Configuration)
+	}
+	if got := st.chatMessages(t, chats["C1/M2"].ID); len(got) != 2 || got[0].Content != "Second thread" {
+		t.Errorf("messages of the second thread = %+v, want its message and reply", got)
+	}
+
+	delivered := channel.deliveries()
+	if len(delivered) != 3 || delivered[2].Conversation.Key != "C1/M1" || delivered[2].Message.ChatID != first.ID {
+		t.Errorf("deliveries = %+v, want the last reply in the first thread", delivered)
+	}
+}
+
+func TestHandleChannelWebhookInactive(t *testing.T) {
+	st := newFakeStore()
+	s, channel, chatbot, iface := newTestChannelService(t, st)
+	event := integrations.WebhookEvent{Message: &integrations.InboundMessage{ChannelID: "C1", MessageID: "M1", Text: "Hello there"}}
+
+	st.interfaces[iface.ID].IsActive = false
+	if _, err := postWebhook(t, s, chatbot, event); !errors.Is(err, ErrChannelNotFound) {
+		t.Errorf("inactive interface: error = %v, want ErrChannelNotFound", err)
+	}
+
+	st.interfaces[iface.ID].IsActive = true
+	chatbot.IsActive = false
+	st.chatbots[chatbot.ID] = chatbot
+	if _, err := postWebhook(t, s, chatbot, event); !errors.Is(err, ErrChannelNotFound) {
+		t.Errorf("inactive chatbot: error = %v, want ErrChannelNotFound", err)
+	}
+	if len(channel.deliveries()) != 0 {
+		t.Error("a message to an inactive chatbot or interface was answered")
+	}
+}
+
+func TestHandleChannelWebhookRecordsActionOnce(t *testing.T) {
+	st := newFakeStore()
+	s, channel, chatbot, iface := newTestChannelService(t, st)
+	if _, err := postWebhook(t, s, chatbot, integrations.WebhookEvent{Message: &integrations.InboundMessage{EventID: "E1", ChannelID: "C1", MessageID: "M1", Text: "Hello there"}}); err != nil {
+		t.Fatalf("message: %v", err)
+	}
+	chat := st.chatsOfInterface(iface.ID)[0]
+
+	action := integrations.WebhookEvent{Action: &integrations.InboundAction{
+		EventID:            "T1",
+		Action:             integrations.ActionEscalate,
+		ChatID:             chat.ID,
+		UserID:             "U1",
+		Notice:             "A teammate will follow up",
+		NoticeConversation: integrations.Conversation{Key: "C1/M1"},
+	}}
+	for i := 0; i < 2; i++ {
+		if _, err := postWebhook(t, s, chatbot, action); err != nil {
+			t.Fatalf("action delivery %d: %v", i+1, err)
+		}
+	}
+
+	if got := st.chatsOfInterface(iface.ID)[0].Feedback; got == nil || *got != -1 {
+		t.Errorf("feedback = %v, want -1", got)
+	}
+	notices := 0
+	for _, d := range channel.deliveries() {
+		if d.Message.Text == "A teammate will follow up" {
+			notices++
+		}
+	}
+	if notices != 1 {
+		t.Errorf("%d notices for an action delivered twice, want 1", notices)
+	}
+}
+
+func TestHandleChannelWebhookIgnoresActionOnOtherInterface(t *testing.T) {
+	st := newFakeStore()
+	s, channel, chatbot, iface := newTestChannelService(t, st)
+	otherChatbot := st.addChatbot(chatbot.OrganizationID)
+	st.addInterface(otherChatbot, fakeServiceType, `{"workspace_id":"W2"}`)
+	if _, err := postWebhook(t, s, chatbot, integrations.WebhookEvent{Message: &integrations.InboundMessage{EventID: "E1", ChannelID: "C1", MessageID: "M1", Text: "Hello there"}}); err != nil {
+		t.Fatalf("message: %v", err)
+	}
+	chat := st.chatsOfInterface(iface.ID)[0]
+
+	// The chat's ID, sent to the webhook of another interface of the organization
+	if _, err := postWebhook(t, s, otherChatbot, integrations.WebhookEvent{Action: &integrations.InboundAction{
+		EventID: "T1",
+		Action:  integrations.ActionFeedbackNegative,
+		ChatID:  chat.ID,
+		Notice:  "Thanks for the feedback",
+	}}); err != nil {
+		t.Fatalf("action: %v", err)
+	}
+
+	if got := st.chatsOfInterface(iface.ID)[0].Feedback; got != nil {
+		t.Errorf("feedback = %d, want none", *got)
+	}
+	if n := len(channel.deliveries()); n != 1 {
+		t.Errorf("%d deliveries, want only the reply", n)
+	}
+}
+
+func TestHandleSharedChannelWebhookRoutesByWorkspace(t *testing.T) {
+	st := newFakeStore()
+	s, channel, _, _ := newTestChannelService(t, st)
+	appBot := st.addChatbot(uuid.New())
+	appIface := st.addInterface(appBot, fakeServiceType, `{"workspace_id":"W1","app_id":"A1"}`)
+	anyAppBot := st.addChatbot(uuid.New())
+	anyAppIface := st.addInterface(anyAppBot, fakeServiceType, `{"workspace_id":"W1"}`)
+	inactiveBot := st.addChatbot(uuid.New())
+	inactiveBot.IsActive = false
+	st.chatbots[inactiveBot.ID] = inactiveBot
+	st.addInterface(inactiveBot, fakeServiceType, `{"workspace_id":"W2"}`)
+
+	post := func(workspaceID, appID string) error {
+		header, _ := signedWebhook(t, integrations.WebhookEvent{})
+		body, _ := json.Marshal(map[string]interface{}{
+			"Message": integrations.InboundMessage{WorkspaceID: workspaceID, ChannelID: "C1", MessageID: workspaceID + appID, Text: "Hello"},
+			"AppID":   appID,
+		})
+		_, err := s.HandleSharedChannelWebhook(context.Background(), fakeServiceType, header, body)
+		s.wg.Wait()
+		return err
+	}
+
+	if err := post("W1", "A1"); err != nil {
+		t.Fatalf("event of the app: %v", err)
+	}
+	if len(st.chatsOfInterface(appIface.ID)) != 1 {
+		t.Error("the event of the app did not go to the app's interface")
+	}
+	if err := post("W1", "A2"); err != nil {
+		t.Fatalf("event of another app: %v", err)
+	}
+	if len(st.chatsOfInterface(anyAppIface.ID)) != 1 {
+		t.Error("the event of another app did not go to the interface without an app")
+	}
+	if err := post("W2", "A1"); !errors.Is(err, ErrChannelNotFound) {
+		t.Errorf("workspace of an inactive chatbot: error = %v, want ErrChannelNotFound", err)
+	}
+	if err := post("W3", "A1"); !errors.Is(err, ErrChannelNotFound) {
+		t.Errorf("unknown workspace: error = %v, want ErrChannelNotFound", err)
+	}
+	if n := len(channel.deliveries()); n != 2 {
+		t.Errorf("%d deliveries, want 2", n)
+	}
+}
+
+func TestHandleSharedChannelWebhookHidesWorkspacesFromUnsigned(t *testing.T) {
+	st := newFakeStore()
+	s, _, _, _ := newTestChannelService(t, st)
+	st.addInterface(st.addChatbot(uuid.New()), fakeServiceType, `{"workspace_id":"W1"}`)
+	inactiveBot := st.addChatbot(uuid.New())
+	inactiveBot.IsActive = false
+	st.chatbots[inactiveBot.ID] = inactiveBot
+	st.addInterface(inactiveBot, fakeServiceType, `{"workspace_id":"W2"}`)
+
+	// Configured, unanswered and unknown workspaces are rejected alike without a signature
+	for _, workspaceID := range []string{"W1", "W2", "W3"} {
+		body, _ := json.Marshal(integrations.WebhookEvent{Message: &integrations.InboundMessage{WorkspaceID: workspaceID, ChannelID: "C1", MessageID: "M1", Text: "Hello"}})
+		_, err := s.HandleSharedChannelWebhook(context.Background(), fakeServiceType, http.Header{}, body)
+		s.wg.Wait()
+		if !errors.Is(err, ErrChannelUnauthorized) {
+			t.Errorf("unsigned event of workspace %s: error = %v, want ErrChannelUnauthorized", workspaceID, err)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package services
+
+import (
+	"buildmychat-backend/internal/models"
+	"buildmychat-backend/internal/realtime"
+	"buildmychat-backend/internal/store"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/google/uuid"
+)
+
+// ErrRealtimeNotConfigured is returned when subscribing without a real-time broker.
+var ErrRealtimeNotConfigured = errors.New("no real-time broker is configured")
+
+// chatStatusEventData is the payload of a realtime.EventStatus event.
+type chatStatusEventData struct {
+	Status string `json:"status"`
+}
+
+// chatTypingEventData is the payload of a realtime.EventTyping event.
+type chatTypingEventData struct {
+	Sender string `json:"sender"` // "user", "assistant", ...
+}
+
+// publishEvent sends a real-time event for a chat. Failures are only logged:
+// live updates are best effort and must never fail the write that triggered them.
+func (s *ChatService) publishEvent(ctx context.Context, eventType string, chatID uuid.UUID, data interface{}) {
+	if s.broker == nil {
+		return
+	}
+	event, err := realtime.NewEvent(eventType, chatID, data)
+	if err != nil {
+		log.Printf("ERROR [ChatService] publishEvent: Failed to build %s event for chat %s: %v", eventType, chatID, err)
+		return
+	}
+	if err := s.broker.Publish(ctx, event); err != nil {
+		log.Printf("WARN [ChatService] publishEvent: Failed to publish %s event for chat %s: %v", eventType, chatID, err)
+	}
+}
+
+// publishMessage announces a message added to chat_data. Hidden messages are not sent to live clients.
+func (s *ChatService) publishMessage(ctx context.Context, chatID uuid.UUID, message models.ChatMessage) {
+	if message.Hide != 0 {
+		return
+	}
+	s.publishEvent(ctx, realtime.EventMessage, chatID, message)
+}
+
+// setChatStatus updates the chat status and announces the change to live clients.
+func (s *ChatService) setChatStatus(ctx context.Context, orgID, chatID uuid.UUID, status string) error {
+	if err := s.store.UpdateChatStatus(ctx, chatID, status, orgID); err != nil {
+		return err
+	}
+	s.publishEvent(ctx, realtime.EventStatus, chatID, chatStatusEventData{Status: status})
+	return nil
+}
+
+// SubscribeToChat checks the chat belongs to the organization and subscribes to its live events.
+// The returned function must be called to release the subscription.
+func (s *ChatService) SubscribeToChat(ctx context.Context, orgID, chatID uuid.UUID) (<-chan realtime.Event, func(), error) {
+	if s.broker == nil {
+		return nil, nil, ErrRealtimeNotConfigured
+	}
+	if _, err := s.store.GetChatByID(ctx, chatID, orgID); err != nil {
+		if err == store.ErrNotFound {
+			return nil, nil, err // Propagate not found error
+		}
+		return nil, nil, fmt.Errorf("failed to get chat: %w", err)
+	}
+
+	events, unsubscribe := s.broker.Subscribe(chatID)
+	return events, unsubscribe, nil
+}
+
+// PublishTyping announces that a participant of the chat is typing.
+// sourceID identifies the originating connection so it can ignore its own echo.
+func (s *ChatService) PublishTyping(ctx context.Context, chatID uuid.UUID, sender, sourceID string) {
+	if s.broker == nil {
+		return
+	}
+	event, err := realtime.NewEvent(realtime.EventTyping, chatID, chatTypingEventData{Sender: sender})
+	if err != nil {
+		log.Printf("ERROR [ChatService] PublishTyping: Failed to build typing event for chat %s: %v", chatID, err)
+		return
+	}
+	event.SourceID = sourceID
+	if err := s.broker.Publish(ctx, event); err != nil {
+		log.Printf("WARN [ChatService] PublishTyping: Failed to publish typing event for chat %s: %v", chatID, err)
+	}
+}
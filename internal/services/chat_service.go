@@ -1,14 +1,17 @@
 package services
 
 import (
-	"buildmychat-backend/internal/integrations/slack"
+	"buildmychat-backend/internal/integrations"
+	"buildmychat-backend/internal/llm"
 	"buildmychat-backend/internal/models"
+	"buildmychat-backend/internal/realtime"
 	"buildmychat-backend/internal/store"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"strings"
+	"log"
+	"sync"
 	"time"
 
 	"bytes"
@@ -16,22 +19,51 @@ import (
 	"github.com/google/uuid"
 )
 
+// ErrInvalidFeedback is returned for chat feedback other than -1, 0 or 1.
+var ErrInvalidFeedback = errors.New("invalid chat feedback")
+
 // ChatService handles chat-related business logic.
 type ChatService struct {
 	store             store.Store
 	chatbotService    *ChatbotService
 	credentialService CredentialsService
+	llmRegistry       *llm.Registry
+	integrations      *integrations.Registry // Channel adapters for delivering to interfaces
+	broker            realtime.Broker        // Live updates for WebSocket clients; may be nil
+	retriever         *KBRetriever           // Knowledge base search for replies; may be nil
+
+	baseCtx      context.Context // Parent of background replies to inbound channel messages, cancelled by Close
+	cancel       context.CancelFunc
+	wg           sync.WaitGroup
+	inboundSlots chan struct{} // Bounds the inbound messages replied to at once
+
+	pruneMu          sync.Mutex
+	channelsPrunedAt time.Time // When old channel events were last deleted
 }
 
 // NewChatService creates a new ChatService.
-func NewChatService(store store.Store, chatbotService *ChatbotService, credentialService CredentialsService) *ChatService {
+func NewChatService(store store.Store, chatbotService *ChatbotService, credentialService CredentialsService, llmRegistry *llm.Registry, integrationRegistry *integrations.Registry, broker realtime.Broker, retriever *KBRetriever) *ChatService {
+	ctx, cancel := context.WithCancel(context.Background())
 	return &ChatService{
 		store:             store,
 		chatbotService:    chatbotService,
 		credentialService: credentialService,
+		llmRegistry:       llmRegistry,
+		integrations:      integrationRegistry,
+		broker:            broker,
+		retriever:         retriever,
+		baseCtx:           ctx,
+		cancel:            cancel,
+		inboundSlots:      make(chan struct{}, maxConcurrentInboundReplies),
 	}
 }
 
+// Close cancels the replies to inbound channel messages still running and waits for them to stop.
+func (s *ChatService) Close() {
+	s.cancel()
+	s.wg.Wait()
+}
+
 // mapChatToResponse converts a DB chat model to an API response DTO.
 func (s *ChatService) mapChatToResponse(ctx context.Context, dbChat *models.Chat, includeChatbot bool) (*models.ChatResponse, error) {
 	// Parse chat data as messages
@@ -201,6 +233,24 @@ func (s *ChatService) CreateChat(ctx context.Context, orgID uuid.UUID, req model
 		return nil, fmt.Errorf("failed to create chat in store: %w", err)
 	}
 
+	// Answer the initial message right away, like a follow-up message would be
+	if req.InitialMessage != nil {
+		resp, err := s.GenerateAssistantReply(ctx, orgID, createdChat.ID)
+		if err == nil {
+			return resp, nil
+		}
+		if !errors.Is(err, ErrReplyGeneration) {
+			return nil, err
+		}
+		log.Printf("WARN [ChatService] CreateChat: Reply generation failed for chat %s: %v", createdChat.ID, err)
+
+		// Re-fetch so the response reflects the ERROR status and recorded reason
+		createdChat, err = s.store.GetChatByID(ctx, createdChat.ID, orgID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get created chat: %w", err)
+		}
+	}
+
 	// Convert to API response
 	resp, err := s.mapChatToResponse(ctx, createdChat, true)
 	if err != nil {
@@ -308,15 +358,8 @@ func (s *ChatService) ListChatsByChatbot(ctx context.Context, orgID, chatbotID u
 	return &models.ListChatsResponse{Chats: responseChats}, nil
 }
 
-// AddMessageToChat adds a user message to a chat and processes it using the chatbot.
-func (s *ChatService) AddMessageToChat(ctx context.Context, orgID, chatID uuid.UUID, message string) (*models.ChatResponse, error) {
-	// First get the chat to ensure it exists and belongs to the organization
-	_, err := s.store.GetChatByID(ctx, chatID, orgID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get chat: %w", err)
-	}
-
-	// Create a new user message
+// appendUserMessage stores a user message on the chat without triggering a reply.
+func (s *ChatService) appendUserMessage(ctx context.Context, orgID, chatID uuid.UUID, message string) error {
 	userMessage := models.ChatMessage{
 		Role:      "user",
 		Content:   message,
@@ -325,27 +368,44 @@ func (s *ChatService) AddMessageToChat(ctx context.Context, orgID, chatID uuid.U
 		Hide:      0,                 // Default to show
 	}
 
-	// Add the message to the chat
 	if err := s.store.AddMessageToChat(ctx, chatID, userMessage, orgID); err != nil {
-		return nil, fmt.Errorf("failed to add user message to chat: %w", err)
+		return fmt.Errorf("failed to add user message to chat: %w", err)
 	}
+	s.publishMessage(ctx, chatID, userMessage)
+	return nil
+}
 
-	// TODO: In a real implementation, this would trigger an async job to process the message
-	// and generate a response from the AI. For now, we'll just update the status and return.
+// AddMessageToChat adds a user message to a chat and generates the chatbot's reply.
+// A failed generation does not fail the call: the chat is returned in ERROR status with
+// the reason recorded in its history, so the user message is never lost.
+func (s *ChatService) AddMessageToChat(ctx context.Context, orgID, chatID uuid.UUID, message string) (*models.ChatResponse, error) {
+	// First get the chat to ensure it exists and belongs to the organization
+	_, err := s.store.GetChatByID(ctx, chatID, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chat: %w", err)
+	}
 
-	// Update the chat status to PROCESSING
-	if err := s.store.UpdateChatStatus(ctx, chatID, "PROCESSING", orgID); err != nil {
-		return nil, fmt.Errorf("failed to update chat status: %w", err)
+	if err := s.appendUserMessage(ctx, orgID, chatID, message); err != nil {
+		return nil, err
 	}
 
-	// Get the updated chat to return
+	resp, err := s.GenerateAssistantReply(ctx, orgID, chatID)
+	if err == nil {
+		return resp, nil
+	}
+	if !errors.Is(err, ErrReplyGeneration) {
+		return nil, err
+	}
+	log.Printf("WARN [ChatService] AddMessageToChat: Reply generation failed for chat %s: %v", chatID, err)
+
+	// Get the updated chat (now in ERROR status) to return
 	updatedChat, err := s.store.GetChatByID(ctx, chatID, orgID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get updated chat: %w", err)
 	}
 
 	// Create the response
-	resp, err := s.mapChatToResponse(ctx, updatedChat, true)
+	resp, err = s.mapChatToResponse(ctx, updatedChat, true)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create chat response: %w", err)
 	}
@@ -376,9 +436,10 @@ func (s *ChatService) AddAssistantMessageToChat(ctx context.Context, orgID, chat
 	if err := s.store.AddMessageToChat(ctx, chatID, assistantMessage, orgID); err != nil {
 		return nil, fmt.Errorf("failed to add assistant message to chat: %w", err)
 	}
+	s.publishMessage(ctx, chatID, assistantMessage)
 
 	// Update the chat status to ACTIVE (ready for next user input)
-	if err := s.store.UpdateChatStatus(ctx, chatID, "ACTIVE", orgID); err != nil {
+	if err := s.setChatStatus(ctx, orgID, chatID, chatStatusActive); err != nil {
 		return nil, fmt.Errorf("failed to update chat status: %w", err)
 	}
 
@@ -397,58 +458,32 @@ func (s *ChatService) AddAssistantMessageToChat(ctx context.Context, orgID, chat
 	return resp, nil
 }
 
-// sendMessageToInterface sends a message to the appropriate interface based on the interface type.
-// Currently supports Slack interfaces.
+// sendMessageToInterface delivers a message to the chat's conversation through the channel adapter
+// of its interface's service type.
 func (s *ChatService) sendMessageToInterface(ctx context.Context, chat *models.Chat, message string) error {
-	// Get the interface details to determine its type
 	iface, err := s.store.GetInterfaceByID(ctx, chat.InterfaceID, chat.OrganizationID)
 	if err != nil {
 		return fmt.Errorf("failed to get interface details: %w", err)
 	}
-
-	// Check the interface type and dispatch to the appropriate handler
-	switch iface.ServiceType {
-	case models.ServiceTypeSlack:
-		return s.sendMessageToSlack(ctx, chat, iface, message)
-	default:
-		return fmt.Errorf("unsupported interface type: %s", iface.ServiceType)
+	adapter, ok := s.integrations.Channel(string(iface.ServiceType))
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrChannelUnsupported, iface.ServiceType)
 	}
-}
-
-// sendMessageToSlack sends a message to a Slack channel.
-func (s *ChatService) sendMessageToSlack(ctx context.Context, chat *models.Chat, iface *models.Interface, message string) error {
-	// Extract channel ID from external_chat_id
-	if chat.ExternalChatID == "" {
-		return fmt.Errorf("chat has no external chat ID for Slack")
-	}
-
-	parts := strings.Split(chat.ExternalChatID, "_")
-	if len(parts) < 2 {
-		return fmt.Errorf("invalid external chat ID format: %s", chat.ExternalChatID)
-	}
-
-	channelID := parts[1] // channel_id is the second part
-
-	// Extract thread_ts from configuration if available
-	var threadTs string
-	if chat.Configuration != nil && len(chat.Configuration) > 0 {
-		var config map[string]string
-		if err := json.Unmarshal(chat.Configuration, &config); err == nil {
-			threadTs = config["thread_ts"]
-			if threadTs != "" {
-				fmt.Printf("INFO - ChatService.sendMessageToSlack - Using thread_ts '%s' from configuration\n", threadTs)
-			}
-		}
+	channelIface, err := s.channelInterface(ctx, iface)
+	if err != nil {
+		return err
 	}
 
-	// Use the Slack integration to send the message using the interface configuration directly
-	fmt.Printf("INFO - ChatService.sendMessageToSlack - Sending message to Slack channel %s\n", channelID)
-
-	return slack.SendMessageUsingInterfaceConfig(ctx, iface.Configuration, channelID, message, threadTs)
+	conv := integrations.Conversation{Key: chat.ExternalChatID, Configuration: chat.Configuration}
+	_, err = adapter.Deliver(ctx, channelIface, conv, integrations.OutboundMessage{ChatID: chat.ID, Text: message})
+	return err
 }
 
-// UpdateChatFeedback updates the feedback for a chat.
+// UpdateChatFeedback updates the feedback for a chat: -1 (negative), 0 (neutral) or 1 (positive).
 func (s *ChatService) UpdateChatFeedback(ctx context.Context, orgID, chatID uuid.UUID, feedback int8) error {
+	if feedback < -1 || feedback > 1 {
+		return fmt.Errorf("%w: %d, must be -1, 0 or 1", ErrInvalidFeedback, feedback)
+	}
 	if err := s.store.UpdateChatFeedback(ctx, chatID, feedback, orgID); err != nil {
 		return fmt.Errorf("failed to update chat feedback: %w", err)
 	}
@@ -480,21 +515,20 @@ func (s *ChatService) GetOrgIDForChatbot(ctx context.Context, chatbotID uuid.UUI
 	return chatbot.OrganizationID, nil
 }
 
-// FindOrCreateChatForExternalID finds a chat by its externalID and chatbotID,
-// or creates a new one if not found. The provided initialMessage is added to the
+// FindOrCreateChatForExternalID finds the chat of an interface by its externalID,
+// or creates a new one for the chatbot if not found. The provided initialMessage is added to the
 // chat session (either to the existing one or as the first message in a new one).
 func (s *ChatService) FindOrCreateChatForExternalID(
 	ctx context.Context,
 	orgID uuid.UUID, // Organization ID, assumed to be validated by the caller
 	chatbotID uuid.UUID,
+	interfaceID uuid.UUID, // Interface the conversation takes place on
 	externalChatID string,
 	initialMessage models.Message, // The user's message from the external event
 	configuration json.RawMessage, // Optional configuration for the chat
 ) (*models.Chat, error) {
 	// Attempt to find an existing chat
-	// Assuming GetChatByExternalID needs (ctx, externalID, interfaceID, orgID)
-	// Passing uuid.Nil for interfaceID as a placeholder. This needs proper handling.
-	existingChat, err := s.store.GetChatByExternalID(ctx, externalChatID, uuid.Nil /*TODO: interfaceID*/, orgID)
+	existingChat, err := s.store.GetChatByExternalID(ctx, externalChatID, interfaceID, orgID)
 
 	if err == nil && existingChat != nil {
 		fmt.Printf("DEBUG - ChatService.FindOrCreateChatForExternalID: Found existing chat ID %s for externalID %s. Adding message.\n", existingChat.ID, externalChatID)
@@ -512,13 +546,12 @@ func (s *ChatService) FindOrCreateChatForExternalID(
 			}
 		}
 
-		// AddMessageToChat expects string content and returns *models.ChatResponse.
-		// We pass initialMessage.Content and then re-fetch the *models.Chat.
-		_, addMsgErr := s.AddMessageToChat(ctx, existingChat.OrganizationID, existingChat.ID, initialMessage.Content)
+		// Only store the message here; the caller decides when to generate the reply.
+		addMsgErr := s.appendUserMessage(ctx, existingChat.OrganizationID, existingChat.ID, initialMessage.Content)
 		if addMsgErr != nil {
 			return nil, fmt.Errorf("failed to add message (content) to existing chat %s for externalID %s: %w", existingChat.ID, externalChatID, addMsgErr)
 		}
-		// Re-fetch to get *models.Chat object as AddMessageToChat returns ChatResponse
+		// Re-fetch to get the updated *models.Chat object
 		updatedDbChat, getErr := s.store.GetChatByID(ctx, existingChat.ID, existingChat.OrganizationID)
 		if getErr != nil {
 			return nil, fmt.Errorf("failed to re-fetch chat %s after adding message: %w", existingChat.ID, getErr)
@@ -562,13 +595,13 @@ func (s *ChatService) FindOrCreateChatForExternalID(
 		ID:             uuid.New(),
 		OrganizationID: orgID,
 		ChatbotID:      chatbotID,
+		InterfaceID:    interfaceID,
 		ExternalChatID: externalChatID, // Corrected: assign string directly
 		ChatData:       chatDataJSON,   // Use marshalled ChatMessage
 		Configuration:  configJSON,     // Use the provided configuration
 		CreatedAt:      time.Now().UTC(),
 		UpdatedAt:      time.Now().UTC(),
 		Status:         "ACTIVE", // Set a default status
-		// InterfaceID: uuid.Nil, // Or determine actual InterfaceID if possible
 	}
 
 	// Construct CreateChatParams for the store call
@@ -576,7 +609,7 @@ func (s *ChatService) FindOrCreateChatForExternalID(
 		ID:             newChat.ID,
 		OrganizationID: newChat.OrganizationID,
 		ChatbotID:      newChat.ChatbotID,
-		InterfaceID:    newChat.InterfaceID, // Will be uuid.Nil if not set on newChat
+		InterfaceID:    newChat.InterfaceID,
 		ExternalChatID: newChat.ExternalChatID,
 		ChatData:       newChat.ChatData,
 		Configuration:  newChat.Configuration,
@@ -32,6 +32,7 @@ var (
 // This is useful if other services need to interact with credentials.
 type CredentialsService interface {
 	CreateCredential(ctx context.Context, req api_models.CreateCredentialRequest, orgID uuid.UUID) (*api_models.CredentialResponse, error)
+	CreateInstalledCredential(ctx context.Context, orgID uuid.UUID, serviceType api_models.ServiceType, name string, credentials map[string]string) (*api_models.CredentialResponse, error)
 	GetCredential(ctx context.Context, id uuid.UUID, orgID uuid.UUID) (*api_models.CredentialResponse, error)
 	ListCredentials(ctx context.Context, orgID uuid.UUID, serviceType *string) ([]api_models.CredentialResponse, error)
 	DeleteCredential(ctx context.Context, id uuid.UUID, orgID uuid.UUID) error
@@ -83,68 +84,52 @@ func (s *credentialsService) CreateCredential(ctx context.Context, req api_model
 		finalCredentialName = *req.CredentialName
 	}
 
-	if req.ServiceType == api_models.ServiceTypeNotion {
-		log.Printf("[CredService] CreateCredential: Notion type detected, performing pre-save test for OrgID %s", orgID)
-		// Get Notion integration handler
-		integration, err := s.registry.Get(string(api_models.ServiceTypeNotion))
-		if err != nil {
-			// Should not happen if registry is initialized correctly
-			log.Printf("ERROR [CredService] CreateCredential: Failed to get Notion integration handler: %v", err)
-			return nil, fmt.Errorf("internal configuration error for Notion service")
-		}
-
-		// Test connection using the RAW, unencrypted credentials from the request
-		testResult, err := integration.TestConnection(ctx, req.Credentials)
-		if err != nil {
-			// System error during test
-			log.Printf("ERROR [CredService] CreateCredential: Notion TestConnection system error for OrgID %s: %v", orgID, err)
-			return nil, fmt.Errorf("failed to test Notion connection: %w", err)
-		}
-
-		if !testResult.Success {
-			// Test failed (e.g., invalid key)
-			log.Printf("WARN [CredService] CreateCredential: Notion pre-save test failed for OrgID %s: %s", orgID, testResult.Message)
-			return nil, fmt.Errorf("%w: %s", ErrCredentialTestFailed, testResult.Message)
-		}
+	// Every service type is tested with its registered integration before the credential is saved
+	integration, err := s.registry.Get(string(req.ServiceType))
+	if err != nil {
+		return nil, fmt.Errorf("%w: unsupported service type %q", ErrCredentialValidation, req.ServiceType)
+	}
+	if kb, ok := integration.(integrations.KnowledgeBaseIntegration); ok && kb.KnowledgeBaseCredentialPolicy() == integrations.CredentialNone {
+		return nil, fmt.Errorf("%w: %s knowledge bases do not use credentials", ErrCredentialValidation, req.ServiceType)
+	}
+	log.Printf("[CredService] CreateCredential: Performing %s pre-save test for OrgID %s", req.ServiceType, orgID)
 
-		// Test succeeded, get the bot name from details
-		if botName, ok := testResult.Details["bot_name"].(string); ok && botName != "" {
-			finalCredentialName = botName
-			log.Printf("[CredService] CreateCredential: Notion test successful. Using fetched bot name: '%s' for OrgID %s", finalCredentialName, orgID)
-		} else {
-			log.Printf("WARN [CredService] CreateCredential: Notion test successful but failed to extract bot name for OrgID %s. Using provided/default name: '%s'", orgID, finalCredentialName)
-		}
-	} else if req.ServiceType == api_models.ServiceTypeSlack { // Added block for Slack
-		log.Printf("[CredService] CreateCredential: Slack type detected, performing pre-save test for OrgID %s", orgID)
-		integration, err := s.registry.Get(string(api_models.ServiceTypeSlack))
-		if err != nil {
-			log.Printf("ERROR [CredService] CreateCredential: Failed to get Slack integration handler: %v", err)
-			return nil, fmt.Errorf("internal configuration error for Slack service")
-		}
+	// Test connection using the RAW, unencrypted credentials from the request
+	testResult, err := integration.TestConnection(ctx, req.Credentials)
+	if err != nil {
+		// System error during test
+		log.Printf("ERROR [CredService] CreateCredential: %s TestConnection system error for OrgID %s: %v", req.ServiceType, orgID, err)
+		return nil, fmt.Errorf("failed to test %s connection: %w", req.ServiceType, err)
+	}
+	if !testResult.Success {
+		// Test failed (e.g., invalid key)
+		log.Printf("WARN [CredService] CreateCredential: %s pre-save test failed for OrgID %s: %s", req.ServiceType, orgID, testResult.Message)
+		return nil, fmt.Errorf("%w: %s", ErrCredentialTestFailed, testResult.Message)
+	}
 
-		testResult, err := integration.TestConnection(ctx, req.Credentials)
-		if err != nil {
-			log.Printf("ERROR [CredService] CreateCredential: Slack TestConnection system error for OrgID %s: %v", orgID, err)
-			return nil, fmt.Errorf("failed to test Slack connection: %w", err)
-		}
+	// Use the bot name reported by the test, if any (Notion, Slack)
+	if botName, ok := testResult.Details["bot_name"].(string); ok && botName != "" {
+		finalCredentialName = botName
+		log.Printf("[CredService] CreateCredential: %s test successful. Using fetched bot name: '%s' for OrgID %s", req.ServiceType, finalCredentialName, orgID)
+	}
+	// --- End Pre-Save Test ---
 
-		if !testResult.Success {
-			log.Printf("WARN [CredService] CreateCredential: Slack pre-save test failed for OrgID %s: %s", orgID, testResult.Message)
-			return nil, fmt.Errorf("%w: %s", ErrCredentialTestFailed, testResult.Message)
-		}
+	return s.saveCredential(ctx, orgID, req.ServiceType, finalCredentialName, req.Credentials)
+}
 
-		// Use fetched bot name if available
-		if botName, ok := testResult.Details["bot_name"].(string); ok && botName != "" {
-			finalCredentialName = botName
-			log.Printf("[CredService] CreateCredential: Slack test successful. Using fetched bot name: '%s' for OrgID %s", finalCredentialName, orgID)
-		} else {
-			log.Printf("WARN [CredService] CreateCredential: Slack test successful but failed to extract bot name for OrgID %s. Using provided/default name: '%s'", orgID, finalCredentialName)
-		}
+// CreateInstalledCredential encrypts and stores credentials issued by the service itself, e.g. the
+// bot token of an OAuth installation. They are not tested before saving.
+func (s *credentialsService) CreateInstalledCredential(ctx context.Context, orgID uuid.UUID, serviceType api_models.ServiceType, name string, credentials map[string]string) (*api_models.CredentialResponse, error) {
+	if _, err := s.registry.Get(string(serviceType)); err != nil {
+		return nil, fmt.Errorf("%w: unsupported service type %q", ErrCredentialValidation, serviceType)
 	}
-	// --- End Pre-Save Test ---
+	return s.saveCredential(ctx, orgID, serviceType, name, credentials)
+}
 
-	// Marshal the raw credentials map to JSON bytes (using req.Credentials)
-	plaintextBytes, err := json.Marshal(req.Credentials)
+// saveCredential encrypts credentials and stores them as an active credential.
+func (s *credentialsService) saveCredential(ctx context.Context, orgID uuid.UUID, serviceType api_models.ServiceType, name string, credentials map[string]string) (*api_models.CredentialResponse, error) {
+	// Marshal the raw credentials map to JSON bytes
+	plaintextBytes, err := json.Marshal(credentials)
 	if err != nil {
 		log.Printf("ERROR [CredService] CreateCredential: Failed marshal credentials for OrgID %s: %v", orgID, err)
 		return nil, fmt.Errorf("failed to process credentials data: %w", err)
@@ -174,8 +159,8 @@ func (s *credentialsService) CreateCredential(ctx context.Context, req api_model
 	params := store.CreateIntegrationCredentialParams{
 		ID:                   uuid.New(),
 		OrganizationID:       orgID,
-		ServiceType:          string(req.ServiceType),
-		CredentialName:       finalCredentialName, // Use the final name (fetched or provided or empty)
+		ServiceType:          string(serviceType),
+		CredentialName:       name, // The final name (fetched or provided or empty)
 		EncryptedCredentials: wrappedJSONBytes,
 		Status:               "ACTIVE",
 	}
@@ -0,0 +1,501 @@
+package services
+
+import (
+	"buildmychat-backend/internal/integrations"
+	"buildmychat-backend/internal/llm"
+	"buildmychat-backend/internal/models"
+	"buildmychat-backend/internal/realtime"
+	"buildmychat-backend/internal/store"
+	"context"
+	"encoding/json"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// fakeStore keeps chatbots and chats in memory for service tests. It embeds store.Store, so calling
+// an operation the tests do not set up panics instead of silently succeeding.
+type fakeStore struct {
+	store.Store
+
+	mu       sync.Mutex
+	chatbots map[uuid.UUID]models.Chatbot
+	chats    map[uuid.UUID]*models.Chat
+	statuses map[uuid.UUID][]string // Every status a chat was moved to, in order
+
+	knowledgeBases map[uuid.UUID][]models.KnowledgeBase // Active knowledge bases mapped to each chatbot
+	storedKBs      map[uuid.UUID]*models.KnowledgeBase  // Knowledge bases by ID
+
+	credentials       map[uuid.UUID]*models.IntegrationCredential
+	interfaces        map[uuid.UUID]*models.Interface
+	interfaceChatbots map[uuid.UUID][]uuid.UUID // Chatbots each interface is mapped to, oldest first
+	channelEvents     map[string]time.Time      // Claimed events by interface and event ID
+
+	usedOAuthStates map[string]bool
+
+	createChatErr error // Returned by CreateChat if set
+}
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{
+		chatbots: map[uuid.UUID]models.Chatbot{},
+		chats:    map[uuid.UUID]*models.Chat{},
+		statuses: map[uuid.UUID][]string{},
+
+		knowledgeBases: map[uuid.UUID][]models.KnowledgeBase{},
+		storedKBs:      map[uuid.UUID]*models.KnowledgeBase{},
+
+		credentials:       map[uuid.UUID]*models.IntegrationCredential{},
+		interfaces:        map[uuid.UUID]*models.Interface{},
+		interfaceChatbots: map[uuid.UUID][]uuid.UUID{},
+		channelEvents:     map[string]time.Time{},
+
+		usedOAuthStates: map[string]bool{},
+	}
+}
+
+// addChatbot stores an active chatbot of the organization using the fake LLM provider.
+func (s *fakeStore) addChatbot(orgID uuid.UUID) models.Chatbot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	model := "fake:echo"
+	chatbot := models.Chatbot{ID: uuid.New(), OrganizationID: orgID, Name: "Test bot", IsActive: true, LLMModel: &model}
+	s.chatbots[chatbot.ID] = chatbot
+	return chatbot
+}
+
+// addInterface stores an active interface of the chatbot's organization, mapped to the chatbot.
+func (s *fakeStore) addInterface(chatbot models.Chatbot, serviceType models.ServiceType, configuration string) *models.Interface {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	iface := &models.Interface{
+		ID:             uuid.New(),
+		OrganizationID: chatbot.OrganizationID,
+		ServiceType:    serviceType,
+		Name:           "Test interface",
+		Configuration:  json.RawMessage(configuration),
+		IsActive:       true,
+	}
+	s.interfaces[iface.ID] = iface
+	s.interfaceChatbots[iface.ID] = append(s.interfaceChatbots[iface.ID], chatbot.ID)
+	return iface
+}
+
+// chatsOfInterface returns the chats of an interface.
+func (s *fakeStore) chatsOfInterface(interfaceID uuid.UUID) []models.Chat {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var chats []models.Chat
+	for _, chat := range s.chats {
+		if chat.InterfaceID == interfaceID {
+			chats = append(chats, *chat)
+		}
+	}
+	return chats
+}
+
+// claimedEvents returns the number of claimed channel events.
+func (s *fakeStore) claimedEvents() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.channelEvents)
+}
+
+// chatMessages returns the messages stored on a chat.
+func (s *fakeStore) chatMessages(t *testing.T, chatID uuid.UUID) []models.ChatMessage {
+	t.Helper()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var messages []models.ChatMessage
+	if err := json.Unmarshal(s.chats[chatID].ChatData, &messages); err != nil {
+		t.Fatalf("chat data of %s: %v", chatID, err)
+	}
+	return messages
+}
+
+// chatStatuses returns the statuses a chat was moved to, in order.
+func (s *fakeStore) chatStatuses(chatID uuid.UUID) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.statuses[chatID]...)
+}
+
+func (s *fakeStore) GetChatbotByID(ctx context.Context, chatbotID, orgID uuid.UUID) (models.Chatbot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	chatbot, ok := s.chatbots[chatbotID]
+	if !ok || chatbot.OrganizationID != orgID {
+		return models.Chatbot{}, store.ErrNotFound
+	}
+	return chatbot, nil
+}
+
+func (s *fakeStore) GetChatbotByIDOnly(ctx context.Context, chatbotID uuid.UUID) (models.Chatbot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	chatbot, ok := s.chatbots[chatbotID]
+	if !ok {
+		return models.Chatbot{}, store.ErrNotFound
+	}
+	return chatbot, nil
+}
+
+func (s *fakeStore) GetChatbotMappings(ctx context.Context, chatbotID, orgID uuid.UUID) (*models.ChatbotMappingsResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	mappings := &models.ChatbotMappingsResponse{}
+	for id, chatbotIDs := range s.interfaceChatbots {
+		iface := s.interfaces[id]
+		if iface.OrganizationID != orgID || !slices.Contains(chatbotIDs, chatbotID) {
+			continue
+		}
+		mappings.Interfaces = append(mappings.Interfaces, models.InterfaceResponse{ID: iface.ID, OrganizationID: iface.OrganizationID, ServiceType: iface.ServiceType, Name: iface.Name, IsActive: iface.IsActive})
+	}
+	return mappings, nil
+}
+
+func (s *fakeStore) ListChatbotsByInterface(ctx context.Context, interfaceID, orgID uuid.UUID) ([]models.Chatbot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var chatbots []models.Chatbot
+	for _, id := range s.interfaceChatbots[interfaceID] {
+		if chatbot := s.chatbots[id]; chatbot.OrganizationID == orgID {
+			chatbots = append(chatbots, chatbot)
+		}
+	}
+	return chatbots, nil
+}
+
+func (s *fakeStore) GetInterfaceByID(ctx context.Context, id uuid.UUID, orgID uuid.UUID) (*models.Interface, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	iface, ok := s.interfaces[id]
+	if !ok || iface.OrganizationID != orgID {
+		return nil, store.ErrNotFound
+	}
+	copied := *iface
+	return &copied, nil
+}
+
+// fakeWorkspaceKeys are the configuration keys of the workspace and app of interfaces, by service type.
+var fakeWorkspaceKeys = map[models.ServiceType][2]string{
+	models.ServiceTypeSlack: {"slack_team_id", "slack_app_id"},
+	fakeServiceType:         {"workspace_id", "app_id"},
+}
+
+func (s *fakeStore) GetActiveInterfaceByWorkspace(ctx context.Context, serviceType models.ServiceType, workspaceID string, appID string) (*models.Interface, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	keys := fakeWorkspaceKeys[serviceType]
+	var found *models.Interface
+	for _, iface := range s.interfaces {
+		var config map[string]string
+		if iface.ServiceType != serviceType || !iface.IsActive || json.Unmarshal(iface.Configuration, &config) != nil {
+			continue
+		}
+		if config[keys[0]] == workspaceID && config[keys[1]] == appID {
+			if found != nil {
+				panic("two active interfaces for workspace " + workspaceID)
+			}
+			copied := *iface
+			found = &copied
+		}
+	}
+	if found == nil {
+		return nil, store.ErrNotFound
+	}
+	return found, nil
+}
+
+func (s *fakeStore) GetIntegrationCredentialByID(ctx context.Context, id uuid.UUID, orgID uuid.UUID) (*models.IntegrationCredential, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cred, ok := s.credentials[id]
+	if !ok || cred.OrganizationID != orgID {
+		return nil, store.ErrNotFound
+	}
+	copied := *cred
+	return &copied, nil
+}
+
+func (s *fakeStore) CreateInterface(ctx context.Context, arg store.CreateInterfaceParams) (*models.Interface, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	iface := &models.Interface{
+		ID:             arg.ID,
+		OrganizationID: arg.OrganizationID,
+		CredentialID:   arg.CredentialID,
+		ServiceType:    models.ServiceType(arg.ServiceType),
+		Name:           arg.Name,
+		Configuration:  arg.Configuration,
+		IsActive:       arg.IsActive,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	s.interfaces[iface.ID] = iface
+	copied := *iface
+	return &copied, nil
+}
+
+func (s *fakeStore) UpdateInterface(ctx context.Context, arg store.UpdateInterfaceParams) (*models.Interface, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	iface, ok := s.interfaces[arg.ID]
+	if !ok || iface.OrganizationID != arg.OrganizationID {
+		return nil, store.ErrNotFound
+	}
+	if arg.CredentialID != nil {
+		iface.CredentialID = *arg.CredentialID
+	}
+	if arg.Name != nil {
+		iface.Name = *arg.Name
+	}
+	if arg.Configuration != nil {
+		iface.Configuration = arg.Configuration
+	}
+	if arg.IsActive != nil {
+		iface.IsActive = *arg.IsActive
+	}
+	iface.UpdatedAt = time.Now()
+	copied := *iface
+	return &copied, nil
+}
+
+func (s *fakeStore) ClaimChannelEvent(ctx context.Context, interfaceID uuid.UUID, eventID string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := interfaceID.String() + "/" + eventID
+	if _, claimed := s.channelEvents[key]; claimed {
+		return false, nil
+	}
+	s.channelEvents[key] = time.Now()
+	return true, nil
+}
+
+func (s *fakeStore) ReleaseChannelEvent(ctx context.Context, interfaceID uuid.UUID, eventID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.channelEvents, interfaceID.String()+"/"+eventID)
+	return nil
+}
+
+func (s *fakeStore) DeleteChannelEventsBefore(ctx context.Context, before time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var deleted int64
+	for key, receivedAt := range s.channelEvents {
+		if receivedAt.Before(before) {
+			delete(s.channelEvents, key)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+func (s *fakeStore) ListActiveChatbotKnowledgeBases(ctx context.Context, chatbotID, orgID uuid.UUID) ([]models.KnowledgeBase, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var kbs []models.KnowledgeBase
+	for _, kb := range s.knowledgeBases[chatbotID] {
+		if kb.OrganizationID == orgID {
+			kbs = append(kbs, kb)
+		}
+	}
+	return kbs, nil
+}
+
+// addKnowledgeBase stores an active knowledge base of the organization.
+func (s *fakeStore) addKnowledgeBase(orgID uuid.UUID, serviceType models.ServiceType, configuration string) *models.KnowledgeBase {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	kb := &models.KnowledgeBase{
+		ID:             uuid.New(),
+		OrganizationID: orgID,
+		ServiceType:    serviceType,
+		Name:           "Test knowledge base",
+		Configuration:  json.RawMessage(configuration),
+		IsActive:       true,
+	}
+	s.storedKBs[kb.ID] = kb
+	return kb
+}
+
+func (s *fakeStore) GetKnowledgeBaseByID(ctx context.Context, id uuid.UUID, orgID uuid.UUID) (*models.KnowledgeBase, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	kb, ok := s.storedKBs[id]
+	if !ok || kb.OrganizationID != orgID {
+		return nil, store.ErrNotFound
+	}
+	copied := *kb
+	return &copied, nil
+}
+
+// UpdateKnowledgeBase merges the configuration into the stored one, like the Postgres store.
+func (s *fakeStore) UpdateKnowledgeBase(ctx context.Context, arg store.UpdateKnowledgeBaseParams) (*models.KnowledgeBase, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	kb, ok := s.storedKBs[arg.ID]
+	if !ok || kb.OrganizationID != arg.OrganizationID {
+		return nil, store.ErrNotFound
+	}
+	if arg.Name != nil {
+		kb.Name = *arg.Name
+	}
+	if arg.Configuration != nil {
+		config := map[string]json.RawMessage{}
+		if len(kb.Configuration) > 0 {
+			if err := json.Unmarshal(kb.Configuration, &config); err != nil {
+				return nil, err
+			}
+		}
+		var patch map[string]json.RawMessage
+		if err := json.Unmarshal(arg.Configuration, &patch); err != nil {
+			return nil, err
+		}
+		for key, value := range patch {
+			config[key] = value
+		}
+		kb.Configuration, _ = json.Marshal(config)
+	}
+	copied := *kb
+	return &copied, nil
+}
+
+func (s *fakeStore) CreateChat(ctx context.Context, arg store.CreateChatParams) (*models.Chat, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.createChatErr != nil {
+		return nil, s.createChatErr
+	}
+	id := arg.ID
+	if id == uuid.Nil {
+		id = uuid.New()
+	}
+	chatData := arg.ChatData
+	if chatData == nil {
+		chatData = []byte("[]")
+	}
+	now := time.Now()
+	chat := &models.Chat{
+		ID:             id,
+		ChatbotID:      arg.ChatbotID,
+		OrganizationID: arg.OrganizationID,
+		InterfaceID:    arg.InterfaceID,
+		ExternalChatID: arg.ExternalChatID,
+		ChatData:       chatData,
+		Status:         chatStatusActive,
+		Configuration:  arg.Configuration,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	s.chats[id] = chat
+	copied := *chat
+	return &copied, nil
+}
+
+func (s *fakeStore) GetChatByID(ctx context.Context, id uuid.UUID, orgID uuid.UUID) (*models.Chat, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	chat, ok := s.chats[id]
+	if !ok || chat.OrganizationID != orgID {
+		return nil, store.ErrNotFound
+	}
+	copied := *chat
+	return &copied, nil
+}
+
+func (s *fakeStore) GetChatByExternalID(ctx context.Context, externalID string, interfaceID uuid.UUID, orgID uuid.UUID) (*models.Chat, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, chat := range s.chats {
+		if chat.ExternalChatID == externalID && chat.InterfaceID == interfaceID && chat.OrganizationID == orgID {
+			copied := *chat
+			return &copied, nil
+		}
+	}
+	return nil, store.ErrNotFound
+}
+
+func (s *fakeStore) AddMessageToChat(ctx context.Context, chatID uuid.UUID, message models.ChatMessage, orgID uuid.UUID) error {
+	if err := ctx.Err(); err != nil {
+		return err // Like a database call on a cancelled context
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	chat, ok := s.chats[chatID]
+	if !ok || chat.OrganizationID != orgID {
+		return store.ErrNotFound
+	}
+	var messages []models.ChatMessage
+	if err := json.Unmarshal(chat.ChatData, &messages); err != nil {
+		return err
+	}
+	data, err := json.Marshal(append(messages, message))
+	if err != nil {
+		return err
+	}
+	chat.ChatData = data
+	return nil
+}
+
+func (s *fakeStore) UpdateChatStatus(ctx context.Context, chatID uuid.UUID, status string, orgID uuid.UUID) error {
+	if err := ctx.Err(); err != nil {
+		return err // Like a database call on a cancelled context
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	chat, ok := s.chats[chatID]
+	if !ok || chat.OrganizationID != orgID {
+		return store.ErrNotFound
+	}
+	chat.Status = status
+	s.statuses[chatID] = append(s.statuses[chatID], status)
+	return nil
+}
+
+func (s *fakeStore) UpdateChatFeedback(ctx context.Context, chatID uuid.UUID, feedback int8, orgID uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	chat, ok := s.chats[chatID]
+	if !ok || chat.OrganizationID != orgID {
+		return store.ErrNotFound
+	}
+	chat.Feedback = &feedback
+	return nil
+}
+
+func (s *fakeStore) UpdateChatConfiguration(ctx context.Context, chatID uuid.UUID, configuration []byte, orgID uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	chat, ok := s.chats[chatID]
+	if !ok || chat.OrganizationID != orgID {
+		return store.ErrNotFound
+	}
+	chat.Configuration = configuration
+	return nil
+}
+
+func (s *fakeStore) UseOAuthState(ctx context.Context, stateID string, expiresAt time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.usedOAuthStates[stateID] {
+		return false, nil
+	}
+	s.usedOAuthStates[stateID] = true
+	return true, nil
+}
+
+// newTestChatService creates a ChatService on st whose chatbots are answered by provider.
+func newTestChatService(t *testing.T, st store.Store, provider *llm.FakeProvider, retriever *KBRetriever) *ChatService {
+	t.Helper()
+	registry := llm.NewRegistry(provider.Name(), "echo")
+	registry.Register(provider)
+	s := NewChatService(st, NewChatbotService(st), nil, registry, integrations.NewRegistry(), realtime.NewMemoryBroker(), retriever)
+	t.Cleanup(s.Close)
+	return s
+}
@@ -1,8 +1,10 @@
 package services
 
 import (
+	"buildmychat-backend/internal/integrations"
 	api_models "buildmychat-backend/internal/models"
 	db_models "buildmychat-backend/internal/models"
+	integration_models "buildmychat-backend/internal/models/integrations"
 	"buildmychat-backend/internal/store"
 	"context"
 	"encoding/json"
@@ -18,6 +20,7 @@ var (
 	ErrInterfaceNotFound           = errors.New("interface not found")
 	ErrInterfaceValidation         = errors.New("interface validation failed")
 	ErrInterfaceCredentialMismatch = errors.New("provided credential is not valid for this interface type (expected SLACK)")
+	ErrInterfaceWorkspaceTaken     = errors.New("another active interface is configured for this Slack workspace and app")
 )
 
 // InterfaceService defines the interface for Interface operations.
@@ -30,16 +33,90 @@ type InterfaceService interface {
 }
 
 type interfaceService struct {
-	store store.Store
+	store             store.Store
+	credentialService CredentialsService
+	registry          *integrations.Registry
 }
 
-// NewInterfaceService creates a new InterfaceService.
-func NewInterfaceService(s store.Store) InterfaceService {
+// NewInterfaceService creates a new InterfaceService. Interface configurations are validated by the
+// integration registered for their service type, and the workspaces they name are confirmed with the
+// interface's credential, which credentialService decrypts.
+func NewInterfaceService(s store.Store, credentialService CredentialsService, registry *integrations.Registry) InterfaceService {
 	return &interfaceService{
-		store: s,
+		store:             s,
+		credentialService: credentialService,
+		registry:          registry,
 	}
 }
 
+// validateConfig checks a Slack interface configuration with the Slack integration.
+func (s *interfaceService) validateConfig(configJSON json.RawMessage) error {
+	integration, err := s.registry.Get(string(api_models.ServiceTypeSlack))
+	if err != nil {
+		return fmt.Errorf("failed to get integration for interface validation: %w", err)
+	}
+	if err := integration.ValidateConfig(configJSON); err != nil {
+		return fmt.Errorf("%w: %v", ErrInterfaceValidation, err)
+	}
+	return nil
+}
+
+// verifyWorkspace confirms with the bot token of credentialID that the Slack team and app of configJSON
+// are the ones the bot is installed for. Interfaces installed through "Add to Slack" get their team
+// from Slack, but IDs typed in by users must not claim another organization's workspace.
+func (s *interfaceService) verifyWorkspace(ctx context.Context, orgID, interfaceID, credentialID uuid.UUID, configJSON json.RawMessage) error {
+	integration, err := s.registry.Get(string(api_models.ServiceTypeSlack))
+	if err != nil {
+		return fmt.Errorf("failed to get integration for interface validation: %w", err)
+	}
+	verifier, ok := integration.(integrations.WorkspaceVerifier)
+	if !ok {
+		return nil
+	}
+	cred, err := s.credentialService.GetDecryptedCredential(ctx, credentialID, orgID)
+	if err != nil {
+		return fmt.Errorf("failed to load credential %s: %w", credentialID, err)
+	}
+	iface := integrations.ChannelInterface{ID: interfaceID, Configuration: configJSON, Credentials: cred.DecryptedCredentials}
+	if err := verifier.VerifyWorkspace(ctx, iface); err != nil {
+		if errors.Is(err, integrations.ErrWorkspaceUnproven) {
+			return fmt.Errorf("%w: %v", ErrInterfaceValidation, err)
+		}
+		return fmt.Errorf("failed to verify Slack workspace: %w", err)
+	}
+	return nil
+}
+
+// slackWorkspace returns the Slack team and app a configuration names; "" if it names none.
+func slackWorkspace(configJSON json.RawMessage) (teamID, appID string) {
+	var config integration_models.SlackInterfaceConfig
+	if err := json.Unmarshal(configJSON, &config); err != nil {
+		return "", ""
+	}
+	return config.SlackTeamID, config.SlackAppID
+}
+
+// checkSlackWorkspace returns ErrInterfaceWorkspaceTaken if an active Slack interface other than
+// interfaceID, of any organization, is configured for the workspace and app of configJSON. Events of a
+// workspace and app must be routed to one interface.
+func checkSlackWorkspace(ctx context.Context, st store.Store, interfaceID uuid.UUID, configJSON json.RawMessage) error {
+	var config integration_models.SlackInterfaceConfig
+	if err := json.Unmarshal(configJSON, &config); err != nil || config.SlackTeamID == "" {
+		return nil // Interfaces without a workspace receive no shared events
+	}
+	taken, err := st.GetActiveInterfaceByWorkspace(ctx, api_models.ServiceTypeSlack, config.SlackTeamID, config.SlackAppID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil
+		}
+		return fmt.Errorf("failed to check interfaces of Slack team %s: %w", config.SlackTeamID, err)
+	}
+	if taken.ID != interfaceID {
+		return fmt.Errorf("%w: team %s", ErrInterfaceWorkspaceTaken, config.SlackTeamID)
+	}
+	return nil
+}
+
 // --- Helper Function ---
 func mapDbInterfaceToResponse(dbIntf *db_models.Interface) *api_models.InterfaceResponse {
 	return &api_models.InterfaceResponse{
@@ -67,7 +144,9 @@ func (s *interfaceService) CreateInterface(ctx context.Context, req api_models.C
 	if req.Configuration != nil && !json.Valid(req.Configuration) {
 		return nil, fmt.Errorf("%w: configuration is not valid JSON", ErrInterfaceValidation)
 	}
-	// TODO: Validate Slack configuration specifics (e.g., team_id format?)
+	if err := s.validateConfig(req.Configuration); err != nil {
+		return nil, err
+	}
 
 	// Verify Credential exists, belongs to org, and is for SLACK
 	cred, err := s.store.GetIntegrationCredentialByID(ctx, req.CredentialID, orgID)
@@ -81,19 +160,38 @@ func (s *interfaceService) CreateInterface(ctx context.Context, req api_models.C
 	if cred.ServiceType != api_models.ServiceTypeSlack {
 		return nil, ErrInterfaceCredentialMismatch
 	}
+	interfaceID := uuid.New()
+	if teamID, _ := slackWorkspace(req.Configuration); teamID != "" {
+		if err := s.verifyWorkspace(ctx, orgID, interfaceID, req.CredentialID, req.Configuration); err != nil {
+			return nil, err
+		}
+	}
+
+	isActive := false // Default to inactive initially
+	if req.IsActive != nil {
+		isActive = *req.IsActive
+	}
+	if isActive {
+		if err := checkSlackWorkspace(ctx, s.store, uuid.Nil, req.Configuration); err != nil {
+			return nil, err
+		}
+	}
 
 	params := store.CreateInterfaceParams{
-		ID:             uuid.New(),
+		ID:             interfaceID,
 		OrganizationID: orgID,
 		CredentialID:   req.CredentialID,
 		ServiceType:    string(api_models.ServiceTypeSlack), // Hardcode for now
 		Name:           req.Name,
 		Configuration:  req.Configuration,
-		IsActive:       false, // Default to inactive initially
+		IsActive:       isActive,
 	}
 
 	dbIntf, err := s.store.CreateInterface(ctx, params)
 	if err != nil {
+		if errors.Is(err, store.ErrWorkspaceTaken) {
+			return nil, fmt.Errorf("%w: %v", ErrInterfaceWorkspaceTaken, err)
+		}
 		log.Printf("ERROR [InterfaceService] CreateInterface: Store call failed for OrgID %s: %v", orgID, err)
 		return nil, fmt.Errorf("failed to save interface: %w", err)
 	}
@@ -140,6 +238,11 @@ func (s *interfaceService) UpdateInterface(ctx context.Context, id uuid.UUID, or
 	if req.Configuration != nil && !json.Valid(req.Configuration) {
 		return nil, fmt.Errorf("%w: configuration is not valid JSON", ErrInterfaceValidation)
 	}
+	if req.Configuration != nil {
+		if err := s.validateConfig(req.Configuration); err != nil {
+			return nil, err
+		}
+	}
 
 	if req.CredentialID != uuid.Nil {
 		cred, err := s.store.GetIntegrationCredentialByID(ctx, req.CredentialID, orgID)
@@ -156,6 +259,36 @@ func (s *interfaceService) UpdateInterface(ctx context.Context, id uuid.UUID, or
 		log.Printf("WARN [InterfaceService] UpdateInterface: Attempted to update CredentialID for Interface %s - This is not supported via this endpoint.", id)
 	}
 
+	// A new workspace must be confirmed, and the interface must stay the only active one of its workspace
+	if req.Configuration != nil || (req.IsActive != nil && *req.IsActive) {
+		existing, err := s.store.GetInterfaceByID(ctx, id, orgID)
+		if err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				return nil, ErrInterfaceNotFound
+			}
+			return nil, fmt.Errorf("failed to retrieve interface: %w", err)
+		}
+		config, isActive := existing.Configuration, existing.IsActive
+		if req.Configuration != nil {
+			config = req.Configuration
+			teamID, appID := slackWorkspace(config)
+			storedTeamID, storedAppID := slackWorkspace(existing.Configuration)
+			if teamID != "" && (teamID != storedTeamID || appID != storedAppID) {
+				if err := s.verifyWorkspace(ctx, orgID, id, existing.CredentialID, config); err != nil {
+					return nil, err
+				}
+			}
+		}
+		if req.IsActive != nil {
+			isActive = *req.IsActive
+		}
+		if isActive {
+			if err := checkSlackWorkspace(ctx, s.store, id, config); err != nil {
+				return nil, err
+			}
+		}
+	}
+
 	params := store.UpdateInterfaceParams{
 		ID:             id,
 		OrganizationID: orgID,
@@ -166,13 +299,16 @@ func (s *interfaceService) UpdateInterface(ctx context.Context, id uuid.UUID, or
 	if req.Configuration != nil {
 		params.Configuration = req.Configuration
 	}
-	// TODO: Add IsActive to request?
+	params.IsActive = req.IsActive
 
 	dbIntf, err := s.store.UpdateInterface(ctx, params)
 	if err != nil {
 		if errors.Is(err, store.ErrNotFound) {
 			return nil, ErrInterfaceNotFound
 		}
+		if errors.Is(err, store.ErrWorkspaceTaken) {
+			return nil, fmt.Errorf("%w: %v", ErrInterfaceWorkspaceTaken, err)
+		}
 		log.Printf("ERROR [InterfaceService] UpdateInterface: Store call failed for ID %s, OrgID %s: %v", id, orgID, err)
 		return nil, fmt.Errorf("failed to update interface: %w", err)
 	}
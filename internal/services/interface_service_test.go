@@ -0,0 +1,140 @@
+package services
+
+import (
+	"buildmychat-backend/internal/integrations"
+	"buildmychat-backend/internal/models"
+	integration_models "buildmychat-backend/internal/models/integrations"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// fakeSlackIntegration confirms workspaces from bot tokens of the form "xoxb-<team>-<app>" instead of
+// asking Slack.
+type fakeSlackIntegration struct {
+	*integrations.SlackIntegration
+}
+
+func (f fakeSlackIntegration) VerifyWorkspace(ctx context.Context, iface integrations.ChannelInterface) error {
+	var config integration_models.SlackInterfaceConfig
+	var creds integration_models.SlackCredentials
+	if err := json.Unmarshal(iface.Configuration, &config); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(iface.Credentials, &creds); err != nil {
+		return err
+	}
+	teamID, appID, _ := strings.Cut(strings.TrimPrefix(creds.BotToken, "xoxb-"), "-")
+	if config.SlackTeamID != "" && (config.SlackTeamID != teamID || (config.SlackAppID != "" && config.SlackAppID != appID)) {
+		return fmt.Errorf("%w: the bot token is for team %s and app %s", integrations.ErrWorkspaceUnproven, teamID, appID)
+	}
+	return nil
+}
+
+// fakeCredentials decrypts the Slack credentials added by addSlackCredential.
+type fakeCredentials struct {
+	CredentialsService
+	botTokens map[uuid.UUID]string
+}
+
+func (f *fakeCredentials) GetDecryptedCredential(ctx context.Context, id uuid.UUID, orgID uuid.UUID) (*integration_models.DecryptedCredential, error) {
+	botToken, ok := f.botTokens[id]
+	if !ok {
+		return nil, ErrCredentialNotFound
+	}
+	return &integration_models.DecryptedCredential{
+		ID:                   id,
+		OrganizationID:       orgID,
+		ServiceType:          integration_models.ServiceType(models.ServiceTypeSlack),
+		DecryptedCredentials: json.RawMessage(`{"bot_token":"` + botToken + `"}`),
+	}, nil
+}
+
+// newTestInterfaceService creates an InterfaceService on st, with a fake Slack that confirms
+// workspaces by the bot tokens of creds.
+func newTestInterfaceService(t *testing.T, st *fakeStore, creds *fakeCredentials) InterfaceService {
+	t.Helper()
+	registry := integrations.NewRegistry()
+	registry.Register(string(models.ServiceTypeSlack), fakeSlackIntegration{integrations.NewSlackIntegration("")})
+	return NewInterfaceService(st, creds, registry)
+}
+
+// addSlackCredential adds a Slack credential of orgID with botToken.
+func addSlackCredential(st *fakeStore, creds *fakeCredentials, orgID uuid.UUID, botToken string) uuid.UUID {
+	cred := &models.IntegrationCredential{ID: uuid.New(), OrganizationID: orgID, ServiceType: models.ServiceTypeSlack}
+	st.credentials[cred.ID] = cred
+	creds.botTokens[cred.ID] = botToken
+	return cred.ID
+}
+
+func TestInterfaceServiceOneActiveInterfacePerWorkspace(t *testing.T) {
+	st := newFakeStore()
+	creds := &fakeCredentials{botTokens: map[uuid.UUID]string{}}
+	s := newTestInterfaceService(t, st, creds)
+	ctx := context.Background()
+	active := true
+	create := func(orgID, credID uuid.UUID, config string, isActive bool) (*models.InterfaceResponse, error) {
+		return s.CreateInterface(ctx, models.CreateInterfaceRequest{Name: "Slack", CredentialID: credID, Configuration: json.RawMessage(config), IsActive: &isActive}, orgID)
+	}
+
+	orgID := uuid.New()
+	credID := addSlackCredential(st, creds, orgID, "xoxb-T1-A1")
+	first, err := create(orgID, credID, `{"slack_team_id":"T1","slack_app_id":"A1"}`, true)
+	if err != nil {
+		t.Fatalf("CreateInterface: %v", err)
+	}
+
+	// Another organization cannot take the workspace and app, while it can configure another app
+	otherOrgID := uuid.New()
+	if _, err := create(otherOrgID, addSlackCredential(st, creds, otherOrgID, "xoxb-T1-A1"), `{"slack_team_id":"T1","slack_app_id":"A1"}`, true); !errors.Is(err, ErrInterfaceWorkspaceTaken) {
+		t.Errorf("second active interface: error = %v, want ErrInterfaceWorkspaceTaken", err)
+	}
+	if _, err := create(otherOrgID, addSlackCredential(st, creds, otherOrgID, "xoxb-T1-A2"), `{"slack_team_id":"T1","slack_app_id":"A2"}`, true); err != nil {
+		t.Errorf("interface of another app: %v", err)
+	}
+
+	// An inactive duplicate is accepted, but cannot be activated
+	inactive, err := create(orgID, credID, `{"slack_team_id":"T1","slack_app_id":"A1"}`, false)
+	if err != nil {
+		t.Fatalf("inactive interface: %v", err)
+	}
+	if _, err := s.UpdateInterface(ctx, inactive.ID, orgID, models.CreateInterfaceRequest{IsActive: &active}); !errors.Is(err, ErrInterfaceWorkspaceTaken) {
+		t.Errorf("activating a duplicate: error = %v, want ErrInterfaceWorkspaceTaken", err)
+	}
+
+	// The active interface keeps its workspace when updated
+	if _, err := s.UpdateInterface(ctx, first.ID, orgID, models.CreateInterfaceRequest{Configuration: json.RawMessage(`{"slack_team_id":"T1","slack_app_id":"A1","reply_policy":"all"}`), IsActive: &active}); err != nil {
+		t.Errorf("updating the active interface: %v", err)
+	}
+}
+
+func TestInterfaceServiceVerifiesWorkspace(t *testing.T) {
+	st := newFakeStore()
+	creds := &fakeCredentials{botTokens: map[uuid.UUID]string{}}
+	s := newTestInterfaceService(t, st, creds)
+	ctx := context.Background()
+	inactive := false
+
+	// A workspace the bot token is not installed in cannot be claimed, even by an inactive interface
+	orgID := uuid.New()
+	credID := addSlackCredential(st, creds, orgID, "xoxb-T2-A2")
+	for _, config := range []string{`{"slack_team_id":"T1"}`, `{"slack_team_id":"T2","slack_app_id":"A1"}`} {
+		req := models.CreateInterfaceRequest{Name: "Slack", CredentialID: credID, Configuration: json.RawMessage(config), IsActive: &inactive}
+		if _, err := s.CreateInterface(ctx, req, orgID); !errors.Is(err, ErrInterfaceValidation) {
+			t.Errorf("interface %s with the token of T2 and A2: error = %v, want ErrInterfaceValidation", config, err)
+		}
+	}
+
+	intf, err := s.CreateInterface(ctx, models.CreateInterfaceRequest{Name: "Slack", CredentialID: credID, Configuration: json.RawMessage(`{"slack_team_id":"T2","slack_app_id":"A2"}`), IsActive: &inactive}, orgID)
+	if err != nil {
+		t.Fatalf("interface of the token's workspace: %v", err)
+	}
+	if _, err := s.UpdateInterface(ctx, intf.ID, orgID, models.CreateInterfaceRequest{Configuration: json.RawMessage(`{"slack_team_id":"T1","slack_app_id":"A2"}`)}); !errors.Is(err, ErrInterfaceValidation) {
+		t.Errorf("moving the interface to team T1: error = %v, want ErrInterfaceValidation", err)
+	}
+}
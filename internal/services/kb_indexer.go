@@ -0,0 +1,95 @@
+package services
+
+import (
+	"buildmychat-backend/internal/chunking"
+	"buildmychat-backend/internal/embedding"
+	db_models "buildmychat-backend/internal/models"
+	integration_models "buildmychat-backend/internal/models/integrations"
+	"buildmychat-backend/internal/store"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// KBIndexer splits knowledge base documents into chunks, embeds them and stores the result.
+type KBIndexer struct {
+	vectors  store.VectorStore
+	embedder embedding.Embedder
+}
+
+// NewKBIndexer creates a KBIndexer writing to the given vector store with the given embedding backend.
+func NewKBIndexer(vectors store.VectorStore, embedder embedding.Embedder) *KBIndexer {
+	return &KBIndexer{
+		vectors:  vectors,
+		embedder: embedder,
+	}
+}
+
+// IndexDocument replaces the chunks of a stored document and returns how many were written.
+func (x *KBIndexer) IndexDocument(ctx context.Context, doc *db_models.KBDocument, cfg integration_models.ChunkingConfig) (int, error) {
+	chunks := chunking.Split(doc.Content, chunking.Options{
+		ChunkSize:    cfg.ChunkSize,
+		ChunkOverlap: cfg.ChunkOverlap,
+	})
+
+	texts := make([]string, len(chunks))
+	for i, c := range chunks {
+		texts[i] = embeddingText(doc.Title, c)
+	}
+
+	var vectors [][]float32
+	if len(texts) > 0 {
+		var err error
+		if vectors, err = x.embedder.Embed(ctx, texts); err != nil {
+			return 0, fmt.Errorf("failed to embed document %s: %w", doc.SourceID, err)
+		}
+	}
+
+	metadata, err := json.Marshal(map[string]string{
+		"source_id": doc.SourceID,
+		"title":     doc.Title,
+		"url":       doc.URL,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal chunk metadata: %w", err)
+	}
+
+	rows := make([]db_models.KBChunk, len(chunks))
+	for i, c := range chunks {
+		rows[i] = db_models.KBChunk{
+			KnowledgeBaseID: doc.KnowledgeBaseID,
+			ChunkIndex:      c.Index,
+			Heading:         c.Heading,
+			Content:         c.Content,
+			TokenCount:      c.Tokens,
+			Embedding:       vectors[i],
+			EmbeddingModel:  x.embedder.Model(),
+			Metadata:        metadata,
+		}
+	}
+	if err := x.vectors.UpsertDocumentChunks(ctx, doc.OrganizationID, doc.ID, rows); err != nil {
+		return 0, err
+	}
+
+	log.Printf("[KBIndexer] IndexDocument: Stored %d chunks for document %s (KB %s)", len(chunks), doc.SourceID, doc.KnowledgeBaseID)
+	return len(chunks), nil
+}
+
+// embeddingText prefixes a chunk with its document title and section, so chunks that only make
+// sense in context ("It takes 5 days.") are still found for questions about their topic.
+func embeddingText(title string, c chunking.Chunk) string {
+	var b strings.Builder
+	if title != "" {
+		b.WriteString(title + "\n")
+	}
+	if c.Heading != "" {
+		b.WriteString(c.Heading + "\n")
+	}
+	if b.Len() > 0 {
+		b.WriteString("\n")
+	}
+	b.WriteString(c.Content)
+	return b.String()
+}
@@ -0,0 +1,254 @@
+package services
+
+import (
+	"buildmychat-backend/internal/embedding"
+	"buildmychat-backend/internal/models"
+	db_models "buildmychat-backend/internal/models"
+	integration_models "buildmychat-backend/internal/models/integrations"
+	"buildmychat-backend/internal/rerank"
+	"buildmychat-backend/internal/store"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// Retrieval defaults, used when a chatbot's configuration leaves a field unset.
+const (
+	defaultRetrievalTopK             = 5
+	maxRetrievalTopK                 = 50
+	defaultRetrievalMaxContextTokens = 1500
+)
+
+// Candidate pool for hybrid search and re-ranking: each search returns candidatesPerResult * TopK
+// chunks (at least minRetrievalCandidates) before fusion and re-ranking cut the list down to TopK.
+const (
+	candidatesPerResult    = 4
+	minRetrievalCandidates = 20
+	maxRetrievalCandidates = 100
+)
+
+// rrfK dampens the weight of top ranks in reciprocal rank fusion; 60 is the value from the original paper.
+const rrfK = 60
+
+// RetrievalOptions controls a knowledge base search.
+type RetrievalOptions struct {
+	TopK           int
+	ScoreThreshold float64 // Applies to vector matches only
+
+	// HybridKnowledgeBaseIDs lists the searched knowledge bases that are also searched by full text.
+	// When non-empty, vector and full-text results are merged with reciprocal rank fusion.
+	HybridKnowledgeBaseIDs []uuid.UUID
+	Rerank                 bool // Re-order the candidates with the retriever's reranker, if it has one
+}
+
+// retrievalOptionsFromConfig applies the defaults and bounds to a chatbot's retrieval configuration.
+// It returns the search options and the token budget for the excerpts added to the prompt.
+func retrievalOptionsFromConfig(cfg *models.RetrievalConfig) (RetrievalOptions, int) {
+	opts := RetrievalOptions{TopK: defaultRetrievalTopK}
+	maxContextTokens := defaultRetrievalMaxContextTokens
+	if cfg == nil {
+		return opts, maxContextTokens
+	}
+	if cfg.TopK != nil && *cfg.TopK > 0 {
+		opts.TopK = min(*cfg.TopK, maxRetrievalTopK)
+	}
+	if cfg.ScoreThreshold != nil {
+		opts.ScoreThreshold = *cfg.ScoreThreshold
+	}
+	if cfg.MaxContextTokens != nil && *cfg.MaxContextTokens > 0 {
+		maxContextTokens = *cfg.MaxContextTokens
+	}
+	if cfg.Rerank != nil {
+		opts.Rerank = *cfg.Rerank
+	}
+	return opts, maxContextTokens
+}
+
+// hybridKnowledgeBaseIDs returns the knowledge bases to search by full text as well as by vector.
+// A chatbot mode ("vector" or "hybrid") applies to every knowledge base; without one, each knowledge
+// base's retrieval_mode decides.
+func hybridKnowledgeBaseIDs(chatbotMode *string, kbs []db_models.KnowledgeBase) []uuid.UUID {
+	var ids []uuid.UUID
+	for _, kb := range kbs {
+		mode := kbRetrievalMode(kb.Configuration)
+		if chatbotMode != nil && *chatbotMode != "" {
+			mode = *chatbotMode
+		}
+		if mode == integration_models.RetrievalModeHybrid {
+			ids = append(ids, kb.ID)
+		}
+	}
+	return ids
+}
+
+// kbRetrievalMode reads retrieval_mode from a knowledge base configuration. Invalid configurations yield "".
+func kbRetrievalMode(configuration json.RawMessage) string {
+	var cfg integration_models.KBRetrievalConfig
+	if len(configuration) > 0 {
+		_ = json.Unmarshal(configuration, &cfg)
+	}
+	return cfg.RetrievalMode
+}
+
+// validateRetrievalMode checks the optional retrieval_mode of a knowledge base configuration.
+func validateRetrievalMode(configuration json.RawMessage) error {
+	if len(configuration) == 0 {
+		return nil
+	}
+	var cfg integration_models.KBRetrievalConfig
+	if err := json.Unmarshal(configuration, &cfg); err != nil {
+		return fmt.Errorf("%w: invalid configuration: %v", ErrKBValidation, err)
+	}
+	switch cfg.RetrievalMode {
+	case "", integration_models.RetrievalModeVector, integration_models.RetrievalModeHybrid:
+		return nil
+	default:
+		return fmt.Errorf("%w: retrieval_mode must be %q or %q", ErrKBValidation, integration_models.RetrievalModeVector, integration_models.RetrievalModeHybrid)
+	}
+}
+
+// KBRetriever finds the knowledge base chunks most relevant to a query.
+type KBRetriever struct {
+	vectors  store.VectorStore
+	embedder embedding.Embedder
+	reranker rerank.Reranker // May be nil
+}
+
+// NewKBRetriever creates a KBRetriever. The embedder must be the one used to index the chunks.
+// reranker may be nil, in which case RetrievalOptions.Rerank has no effect.
+func NewKBRetriever(vectors store.VectorStore, embedder embedding.Embedder, reranker rerank.Reranker) *KBRetriever {
+	return &KBRetriever{
+		vectors:  vectors,
+		embedder: embedder,
+		reranker: reranker,
+	}
+}
+
+// Retrieve returns the best matching chunks of the organization's knowledge bases, best first.
+// Plain vector searches score matches by cosine similarity; hybrid searches by their fused rank, and
+// re-ranked searches by the reranker's score.
+func (r *KBRetriever) Retrieve(ctx context.Context, orgID uuid.UUID, kbIDs []uuid.UUID, query string, opts RetrievalOptions) ([]store.VectorMatch, error) {
+	if len(kbIDs) == 0 || strings.TrimSpace(query) == "" {
+		return []store.VectorMatch{}, nil
+	}
+
+	rerankEnabled := opts.Rerank && r.reranker != nil
+	limit := opts.TopK
+	if rerankEnabled || len(opts.HybridKnowledgeBaseIDs) > 0 {
+		limit = min(max(opts.TopK*candidatesPerResult, minRetrievalCandidates), maxRetrievalCandidates)
+	}
+
+	vectors, err := r.embedder.Embed(ctx, []string{query})
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed retrieval query: %w", err)
+	}
+
+	matches, err := r.vectors.SearchSimilar(ctx, store.VectorSearchParams{
+		OrganizationID:   orgID,
+		KnowledgeBaseIDs: kbIDs,
+		Embedding:        vectors[0],
+		EmbeddingModel:   r.embedder.Model(),
+		TopK:             limit,
+		MinScore:         opts.ScoreThreshold,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(opts.HybridKnowledgeBaseIDs) > 0 {
+		lexical, err := r.vectors.SearchLexical(ctx, store.LexicalSearchParams{
+			OrganizationID:   orgID,
+			KnowledgeBaseIDs: opts.HybridKnowledgeBaseIDs,
+			Query:            query,
+			TopK:             limit,
+		})
+		if err != nil {
+			return nil, err
+		}
+		matches = fuseRankings(matches, lexical)
+	}
+
+	if rerankEnabled && len(matches) > 0 {
+		reranked, err := r.rerank(ctx, query, matches)
+		if err != nil {
+			// The fused order is still a reasonable answer; do not fail the search.
+			log.Printf("WARN [KBRetriever] Retrieve: Reranker %s failed, keeping retrieval order: %v", r.reranker.Name(), err)
+		} else {
+			matches = reranked
+		}
+	}
+
+	if len(matches) > opts.TopK {
+		matches = matches[:opts.TopK]
+	}
+	return matches, nil
+}
+
+// rerank scores the matches with the reranker and sorts them by that score.
+func (r *KBRetriever) rerank(ctx context.Context, query string, matches []store.VectorMatch) ([]store.VectorMatch, error) {
+	docs := make([]string, len(matches))
+	for i, m := range matches {
+		docs[i] = m.Chunk.Content
+		if m.Chunk.Heading != "" {
+			docs[i] = m.Chunk.Heading + "\n" + m.Chunk.Content
+		}
+	}
+
+	scores, err := r.reranker.Score(ctx, query, docs)
+	if err != nil {
+		return nil, err
+	}
+	if len(scores) != len(matches) {
+		return nil, rerank.ErrScoreCount
+	}
+
+	reranked := make([]store.VectorMatch, len(matches))
+	for i, m := range matches {
+		m.Score = scores[i]
+		reranked[i] = m
+	}
+	sort.SliceStable(reranked, func(i, j int) bool { return reranked[i].Score > reranked[j].Score })
+	return reranked, nil
+}
+
+// fuseRankings merges ranked result lists with reciprocal rank fusion: a chunk scores the sum of
+// 1/(rrfK + rank) over the lists it appears in, so chunks found by both searches rise to the top.
+func fuseRankings(rankings ...[]store.VectorMatch) []store.VectorMatch {
+	fused := []store.VectorMatch{}
+	index := map[uuid.UUID]int{} // Chunk ID -> position in fused
+	for _, ranking := range rankings {
+		for rank, m := range ranking {
+			score := 1 / float64(rrfK+rank+1)
+			if i, ok := index[m.Chunk.ID]; ok {
+				fused[i].Score += score
+				continue
+			}
+			index[m.Chunk.ID] = len(fused)
+			m.Score = score
+			fused = append(fused, m)
+		}
+	}
+	sort.SliceStable(fused, func(i, j int) bool { return fused[i].Score > fused[j].Score })
+	return fused
+}
+
+// chunkSourceMetadata is the document information KBIndexer stores in each chunk's metadata.
+type chunkSourceMetadata struct {
+	SourceID string `json:"source_id"`
+	Title    string `json:"title"`
+	URL      string `json:"url"`
+}
+
+// parseChunkSource reads a chunk's source document metadata. Missing or invalid metadata yields empty fields.
+func parseChunkSource(chunk models.KBChunk) chunkSourceMetadata {
+	var meta chunkSourceMetadata
+	if len(chunk.Metadata) > 0 {
+		_ = json.Unmarshal(chunk.Metadata, &meta)
+	}
+	return meta
+}
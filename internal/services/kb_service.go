@@ -1,14 +1,18 @@
 package services
 
 import (
+	"buildmychat-backend/internal/integrations"
 	api_models "buildmychat-backend/internal/models"
 	db_models "buildmychat-backend/internal/models"
+	integration_models "buildmychat-backend/internal/models/integrations"
 	"buildmychat-backend/internal/store"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
+	"reflect"
+	"strings"
 
 	"github.com/google/uuid"
 )
@@ -17,7 +21,10 @@ import (
 var (
 	ErrKBNotFound           = errors.New("knowledge base not found")
 	ErrKBValidation         = errors.New("knowledge base validation failed")
-	ErrKBCredentialMismatch = errors.New("provided credential is not valid for this knowledge base type (expected NOTION)")
+	ErrKBCredentialMismatch = errors.New("provided credential is not valid for this knowledge base type")
+	ErrKBChatbotNotFound    = errors.New("chatbot not found")
+	ErrKBDocumentNotFound   = errors.New("knowledge base document not found")
+	ErrKBUploadUnsupported  = errors.New("documents can only be uploaded to UPLOAD knowledge bases")
 )
 
 // KBService defines the interface for Knowledge Base operations.
@@ -27,20 +34,179 @@ type KBService interface {
 	ListKnowledgeBases(ctx context.Context, orgID uuid.UUID) ([]api_models.KnowledgeBaseResponse, error)
 	UpdateKnowledgeBase(ctx context.Context, id uuid.UUID, orgID uuid.UUID, req api_models.CreateKnowledgeBaseRequest) (*api_models.KnowledgeBaseResponse, error) // Reuse Create req for update simplicity
 	DeleteKnowledgeBase(ctx context.Context, id uuid.UUID, orgID uuid.UUID) error
+	ListKnowledgeBaseDocuments(ctx context.Context, id uuid.UUID, orgID uuid.UUID) ([]api_models.KBDocumentResponse, error)
+	// SearchKnowledgeBase returns the chunks of one knowledge base most similar to query; topK <= 0 uses the default.
+	SearchKnowledgeBase(ctx context.Context, id uuid.UUID, orgID uuid.UUID, query string, topK int) (*api_models.KBSearchResponse, error)
+	// SearchChatbotKnowledgeBases searches the active knowledge bases of a chatbot the way its replies do,
+	// using the chatbot's retrieval configuration; topK > 0 overrides the configured top_k.
+	SearchChatbotKnowledgeBases(ctx context.Context, chatbotID uuid.UUID, orgID uuid.UUID, query string, topK int) (*api_models.KBSearchResponse, error)
+	// UploadDocuments extracts and indexes files into an UPLOAD KB. A file named like an existing
+	// document replaces it.
+	UploadDocuments(ctx context.Context, id uuid.UUID, orgID uuid.UUID, files []KBUploadFile) ([]api_models.KBDocumentResponse, error)
+	// ReplaceDocument replaces the content of an uploaded document with a new file.
+	ReplaceDocument(ctx context.Context, id uuid.UUID, documentID uuid.UUID, orgID uuid.UUID, file KBUploadFile) (*api_models.KBDocumentResponse, error)
+	DeleteDocument(ctx context.Context, id uuid.UUID, documentID uuid.UUID, orgID uuid.UUID) error
 }
 
 type kbService struct {
-	store store.Store
+	store     store.Store
+	registry  *integrations.Registry
+	retriever *KBRetriever
+	indexer   *KBIndexer
 }
 
-// NewKBService creates a new KBService.
-func NewKBService(s store.Store) KBService {
+// NewKBService creates a new KBService. The registry provides the integration of each knowledge base
+// service type, which validates its configuration and credential policy; the indexer chunks and embeds uploaded documents.
+func NewKBService(s store.Store, registry *integrations.Registry, retriever *KBRetriever, indexer *KBIndexer) KBService {
 	return &kbService{
-		store: s,
+		store:     s,
+		registry:  registry,
+		retriever: retriever,
+		indexer:   indexer,
 	}
 }
 
-// --- Helper Function ---
+// --- Helper Functions ---
+
+// kbSyncStateKeys are the configuration keys of integration_models.KBSyncState that the sync engine
+// maintains. Requests cannot set them.
+var kbSyncStateKeys = []string{"sync_status", "sync_error", "last_sync_started_at", "last_synced_at", "last_sync_stats", "sync_cursor"}
+
+// kbSourceKeys are the configuration keys, per service type, that select the documents of a knowledge
+// base. Changing any of them clears the sync cursor, so that the next sync lists the new selection in
+// full instead of only the changes since the cursor of the old one.
+var kbSourceKeys = map[api_models.ServiceType][]string{
+	api_models.ServiceTypeNotion:  {"notion_object_ids"},
+	api_models.ServiceTypeWebsite: {"seed_urls", "allowed_domains", "allowed_path_prefixes", "max_depth", "max_pages", "use_sitemap"},
+	api_models.ServiceTypeGit:     {"repository_url", "branch", "tag", "include", "exclude"},
+}
+
+// parseConfigurationObject parses a configuration as a JSON object; an empty or null configuration
+// has no keys.
+func parseConfigurationObject(configuration json.RawMessage) (map[string]json.RawMessage, error) {
+	keys := map[string]json.RawMessage{}
+	if len(configuration) == 0 || string(configuration) == "null" {
+		return keys, nil
+	}
+	if err := json.Unmarshal(configuration, &keys); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// validateNoSyncState rejects configurations of requests that set the sync state.
+func validateNoSyncState(configuration json.RawMessage) error {
+	keys, err := parseConfigurationObject(configuration)
+	if err != nil {
+		return fmt.Errorf("%w: configuration must be a JSON object", ErrKBValidation)
+	}
+	for _, key := range kbSyncStateKeys {
+		if _, ok := keys[key]; ok {
+			return fmt.Errorf("%w: %s is maintained by the sync engine and cannot be set", ErrKBValidation, key)
+		}
+	}
+	return nil
+}
+
+// mergeKBConfiguration shallow-merges a configuration update over a knowledge base's stored
+// configuration, the way the store applies it. It returns the merged configuration, and the update to
+// store: the given one, with the sync cursor cleared if it changes a source key of the service type.
+func mergeKBConfiguration(serviceType api_models.ServiceType, stored, update json.RawMessage) (merged, patch json.RawMessage, err error) {
+	storedKeys, err := parseConfigurationObject(stored)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid stored configuration: %w", err)
+	}
+	updateKeys, err := parseConfigurationObject(update)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: configuration must be a JSON object", ErrKBValidation)
+	}
+
+	mergedKeys := make(map[string]json.RawMessage, len(storedKeys)+len(updateKeys))
+	for key, value := range storedKeys {
+		mergedKeys[key] = value
+	}
+	for key, value := range updateKeys {
+		mergedKeys[key] = value
+	}
+	for _, key := range kbSourceKeys[serviceType] {
+		if !sameJSON(storedKeys[key], mergedKeys[key]) {
+			updateKeys["sync_cursor"] = json.RawMessage(`""`)
+			break
+		}
+	}
+
+	if merged, err = json.Marshal(mergedKeys); err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal configuration: %w", err)
+	}
+	if patch, err = json.Marshal(updateKeys); err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal configuration: %w", err)
+	}
+	return merged, patch, nil
+}
+
+// sameJSON reports whether two JSON values are equal; a missing value equals null.
+func sameJSON(a, b json.RawMessage) bool {
+	var av, bv interface{}
+	if len(a) > 0 && json.Unmarshal(a, &av) != nil {
+		return false
+	}
+	if len(b) > 0 && json.Unmarshal(b, &bv) != nil {
+		return false
+	}
+	return reflect.DeepEqual(av, bv)
+}
+
+// validateSyncSchedule checks the optional periodic sync interval of a configuration.
+func validateSyncSchedule(configuration json.RawMessage) error {
+	if len(configuration) == 0 {
+		return nil
+	}
+	var schedule struct {
+		SyncIntervalMinutes *int `json:"sync_interval_minutes"`
+	}
+	if err := json.Unmarshal(configuration, &schedule); err != nil {
+		return fmt.Errorf("%w: invalid configuration: %v", ErrKBValidation, err)
+	}
+	if m := schedule.SyncIntervalMinutes; m != nil && *m != 0 && *m < integration_models.MinSyncIntervalMinutes {
+		return fmt.Errorf("%w: sync_interval_minutes must be 0 (disabled) or at least %d", ErrKBValidation, integration_models.MinSyncIntervalMinutes)
+	}
+	return nil
+}
+
+// knowledgeBaseIntegration returns the integration registered for a knowledge base service type.
+func (s *kbService) knowledgeBaseIntegration(serviceType api_models.ServiceType) (integrations.KnowledgeBaseIntegration, error) {
+	integration, err := s.registry.KnowledgeBase(string(serviceType))
+	if err != nil {
+		return nil, fmt.Errorf("%w: unsupported service_type %q", ErrKBValidation, serviceType)
+	}
+	return integration, nil
+}
+
+// validateIntegrationConfig validates a configuration with the integration of its knowledge base.
+func validateIntegrationConfig(integration integrations.KnowledgeBaseIntegration, configuration json.RawMessage) error {
+	if err := integration.ValidateConfig(configuration); err != nil {
+		return fmt.Errorf("%w: %v", ErrKBValidation, err)
+	}
+	return nil
+}
+
+// validateCredential checks that a credential exists in the organization and is of the knowledge
+// base's service type.
+func (s *kbService) validateCredential(ctx context.Context, credentialID uuid.UUID, orgID uuid.UUID, serviceType api_models.ServiceType) error {
+	cred, err := s.store.GetIntegrationCredentialByID(ctx, credentialID, orgID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return fmt.Errorf("%w: credential_id %s not found", ErrKBValidation, credentialID)
+		}
+		log.Printf("ERROR [KBService] Failed GetIntegrationCredentialByID for CredID %s, OrgID %s: %v", credentialID, orgID, err)
+		return fmt.Errorf("failed to verify credential: %w", err)
+	}
+	if cred.ServiceType != serviceType {
+		return ErrKBCredentialMismatch
+	}
+	return nil
+}
+
 func mapDbKBToResponse(dbKB *db_models.KnowledgeBase) *api_models.KnowledgeBaseResponse {
 	return &api_models.KnowledgeBaseResponse{
 		ID:             dbKB.ID,
@@ -61,32 +227,53 @@ func (s *kbService) CreateKnowledgeBase(ctx context.Context, req api_models.Crea
 	if req.Name == "" {
 		return nil, fmt.Errorf("%w: name cannot be empty", ErrKBValidation)
 	}
-	if req.CredentialID == uuid.Nil {
-		return nil, fmt.Errorf("%w: credential_id cannot be empty", ErrKBValidation)
+	serviceType := req.ServiceType
+	if serviceType == "" {
+		serviceType = api_models.ServiceTypeNotion
+	}
+	integration, err := s.knowledgeBaseIntegration(serviceType)
+	if err != nil {
+		return nil, err
+	}
+	switch integration.KnowledgeBaseCredentialPolicy() {
+	case integrations.CredentialRequired:
+		if req.CredentialID == uuid.Nil {
+			return nil, fmt.Errorf("%w: credential_id cannot be empty", ErrKBValidation)
+		}
+	case integrations.CredentialNone:
+		if req.CredentialID != uuid.Nil {
+			return nil, fmt.Errorf("%w: credential_id must be empty for %s knowledge bases", ErrKBValidation, serviceType)
+		}
 	}
 	// Validate configuration JSON if provided
 	if req.Configuration != nil && !json.Valid(req.Configuration) {
 		return nil, fmt.Errorf("%w: configuration is not valid JSON", ErrKBValidation)
 	}
+	if err := validateNoSyncState(req.Configuration); err != nil {
+		return nil, err
+	}
+	if err := validateSyncSchedule(req.Configuration); err != nil {
+		return nil, err
+	}
+	if err := validateRetrievalMode(req.Configuration); err != nil {
+		return nil, err
+	}
+	if err := validateIntegrationConfig(integration, req.Configuration); err != nil {
+		return nil, err
+	}
 
-	// Verify Credential exists, belongs to org, and is for NOTION
-	cred, err := s.store.GetIntegrationCredentialByID(ctx, req.CredentialID, orgID)
-	if err != nil {
-		if errors.Is(err, store.ErrNotFound) {
-			return nil, fmt.Errorf("%w: %v", ErrKBValidation, err)
+	// Verify Credential exists, belongs to org, and is for the KB's service type
+	if req.CredentialID != uuid.Nil {
+		if err := s.validateCredential(ctx, req.CredentialID, orgID, serviceType); err != nil {
+			return nil, err
 		}
-		log.Printf("ERROR [KBService] CreateKB: Failed GetIntegrationCredentialByID for CredID %s, OrgID %s: %v", req.CredentialID, orgID, err)
-		return nil, fmt.Errorf("failed to verify credential: %w", err)
-	}
-	if cred.ServiceType != api_models.ServiceTypeNotion {
-		return nil, ErrKBCredentialMismatch
 	}
 
 	params := store.CreateKnowledgeBaseParams{
 		ID:             uuid.New(),
 		OrganizationID: orgID,
-		CredentialID:   req.CredentialID,
-		ServiceType:    string(api_models.ServiceTypeNotion), // Hardcode for now
+		CredentialID:   req.CredentialID, // uuid.Nil for knowledge bases without a credential
+		ServiceType:    string(serviceType),
 		Name:           req.Name,
 		Configuration:  req.Configuration,
 		IsActive:       false, // Default to inactive initially
@@ -132,7 +319,9 @@ func (s *kbService) ListKnowledgeBases(ctx context.Context, orgID uuid.UUID) ([]
 	return resp, nil
 }
 
-// UpdateKnowledgeBase updates an existing KB.
+// UpdateKnowledgeBase updates an existing KB. The configuration given is merged over the stored one,
+// so keys it leaves out, including the sync state, are kept; changing the documents it selects clears
+// the sync cursor.
 func (s *kbService) UpdateKnowledgeBase(ctx context.Context, id uuid.UUID, orgID uuid.UUID, req api_models.CreateKnowledgeBaseRequest) (*api_models.KnowledgeBaseResponse, error) {
 	// Validate input that might be updated
 	if req.Name != "" && len(req.Name) == 0 { // Check if Name is present but empty
@@ -141,19 +330,48 @@ func (s *kbService) UpdateKnowledgeBase(ctx context.Context, id uuid.UUID, orgID
 	if req.Configuration != nil && !json.Valid(req.Configuration) {
 		return nil, fmt.Errorf("%w: configuration is not valid JSON", ErrKBValidation)
 	}
+	if err := validateNoSyncState(req.Configuration); err != nil {
+		return nil, err
+	}
+	if err := validateSyncSchedule(req.Configuration); err != nil {
+		return nil, err
+	}
+	if err := validateRetrievalMode(req.Configuration); err != nil {
+		return nil, err
+	}
 
-	// Check if credential is being updated, if so, validate it
-	if req.CredentialID != uuid.Nil {
-		cred, err := s.store.GetIntegrationCredentialByID(ctx, req.CredentialID, orgID)
+	var existing *db_models.KnowledgeBase
+	if req.Configuration != nil || req.CredentialID != uuid.Nil {
+		var err error
+		existing, err = s.store.GetKnowledgeBaseByID(ctx, id, orgID)
 		if err != nil {
 			if errors.Is(err, store.ErrNotFound) {
-				return nil, fmt.Errorf("%w: credential_id %s not found", ErrKBValidation, req.CredentialID)
+				return nil, ErrKBNotFound
 			}
-			log.Printf("ERROR [KBService] UpdateKB: Failed GetIntegrationCredentialByID for CredID %s, OrgID %s: %v", req.CredentialID, orgID, err)
-			return nil, fmt.Errorf("failed to verify credential: %w", err)
+			log.Printf("ERROR [KBService] UpdateKB: Failed GetKnowledgeBaseByID for ID %s, OrgID %s: %v", id, orgID, err)
+			return nil, fmt.Errorf("failed to retrieve knowledge base: %w", err)
+		}
+	}
+	var configurationPatch json.RawMessage
+	if req.Configuration != nil {
+		integration, err := s.knowledgeBaseIntegration(existing.ServiceType)
+		if err != nil {
+			return nil, err
 		}
-		if cred.ServiceType != api_models.ServiceTypeNotion {
-			return nil, ErrKBCredentialMismatch
+		merged, patch, err := mergeKBConfiguration(existing.ServiceType, existing.Configuration, req.Configuration)
+		if err != nil {
+			return nil, err
+		}
+		if err := validateIntegrationConfig(integration, merged); err != nil {
+			return nil, err
+		}
+		configurationPatch = patch
+	}
+
+	// Check if credential is being updated, if so, validate it
+	if req.CredentialID != uuid.Nil {
+		if err := s.validateCredential(ctx, req.CredentialID, orgID, existing.ServiceType); err != nil {
+			return nil, err
 		}
 		// Note: We are disallowing updating CredentialID via this method for simplicity.
 		// If needed, a separate method or careful handling in UpdateKnowledgeBase store method is required.
@@ -169,8 +387,8 @@ func (s *kbService) UpdateKnowledgeBase(ctx context.Context, id uuid.UUID, orgID
 	if req.Name != "" {
 		params.Name = &req.Name
 	}
-	if req.Configuration != nil {
-		params.Configuration = req.Configuration
+	if configurationPatch != nil {
+		params.Configuration = configurationPatch
 	}
 	// TODO: Add IsActive to the request model if updatable?
 
@@ -203,3 +421,109 @@ func (s *kbService) DeleteKnowledgeBase(ctx context.Context, id uuid.UUID, orgID
 	log.Printf("[KBService] DeleteKnowledgeBase: Successfully deleted KB ID %s for OrgID %s", id, orgID)
 	return nil
 }
+
+// ListKnowledgeBaseDocuments lists the documents ingested into a KB, without their content.
+func (s *kbService) ListKnowledgeBaseDocuments(ctx context.Context, id uuid.UUID, orgID uuid.UUID) ([]api_models.KBDocumentResponse, error) {
+	if _, err := s.GetKnowledgeBase(ctx, id, orgID); err != nil {
+		return nil, err
+	}
+
+	docs, err := s.store.ListKBDocuments(ctx, id, orgID)
+	if err != nil {
+		log.Printf("ERROR [KBService] ListKBDocuments: Store call failed for KB %s, OrgID %s: %v", id, orgID, err)
+		return nil, fmt.Errorf("failed to list knowledge base documents: %w", err)
+	}
+
+	resp := make([]api_models.KBDocumentResponse, len(docs))
+	for i, d := range docs {
+		resp[i] = api_models.KBDocumentResponse{
+			ID:              d.ID,
+			KnowledgeBaseID: d.KnowledgeBaseID,
+			SourceID:        d.SourceID,
+			Title:           d.Title,
+			URL:             d.URL,
+			SourceUpdatedAt: d.SourceUpdatedAt,
+			ChunkCount:      d.ChunkCount,
+			CreatedAt:       d.CreatedAt,
+			SyncedAt:        d.UpdatedAt,
+		}
+	}
+	return resp, nil
+}
+
+// SearchKnowledgeBase runs a similarity search over a single KB, whether or not it is active.
+func (s *kbService) SearchKnowledgeBase(ctx context.Context, id uuid.UUID, orgID uuid.UUID, query string, topK int) (*api_models.KBSearchResponse, error) {
+	kb, err := s.GetKnowledgeBase(ctx, id, orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	opts, _ := retrievalOptionsFromConfig(nil)
+	if kbRetrievalMode(kb.Configuration) == integration_models.RetrievalModeHybrid {
+		opts.HybridKnowledgeBaseIDs = []uuid.UUID{id}
+	}
+	if topK > 0 {
+		opts.TopK = min(topK, maxRetrievalTopK)
+	}
+	return s.search(ctx, orgID, []uuid.UUID{id}, query, opts)
+}
+
+// SearchChatbotKnowledgeBases runs a similarity search over a chatbot's active KBs.
+func (s *kbService) SearchChatbotKnowledgeBases(ctx context.Context, chatbotID uuid.UUID, orgID uuid.UUID, query string, topK int) (*api_models.KBSearchResponse, error) {
+	chatbot, err := s.store.GetChatbotByID(ctx, chatbotID, orgID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, ErrKBChatbotNotFound
+		}
+		log.Printf("ERROR [KBService] SearchChatbotKBs: Failed GetChatbotByID for ChatbotID %s, OrgID %s: %v", chatbotID, orgID, err)
+		return nil, fmt.Errorf("failed to retrieve chatbot: %w", err)
+	}
+
+	kbs, err := s.store.ListActiveChatbotKnowledgeBases(ctx, chatbot.ID, orgID)
+	if err != nil {
+		log.Printf("ERROR [KBService] SearchChatbotKBs: Failed listing knowledge bases of ChatbotID %s: %v", chatbotID, err)
+		return nil, fmt.Errorf("failed to list chatbot knowledge bases: %w", err)
+	}
+
+	opts, _, kbIDs := chatbotRetrievalPlan(chatbot, kbs)
+	if topK > 0 {
+		opts.TopK = min(topK, maxRetrievalTopK)
+	}
+	return s.search(ctx, orgID, kbIDs, query, opts)
+}
+
+// search validates the query and maps the retrieved chunks to search results.
+func (s *kbService) search(ctx context.Context, orgID uuid.UUID, kbIDs []uuid.UUID, query string, opts RetrievalOptions) (*api_models.KBSearchResponse, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, fmt.Errorf("%w: query cannot be empty", ErrKBValidation)
+	}
+
+	matches, err := s.retriever.Retrieve(ctx, orgID, kbIDs, query, opts)
+	if err != nil {
+		log.Printf("ERROR [KBService] Search: Retrieval failed for OrgID %s: %v", orgID, err)
+		return nil, fmt.Errorf("failed to search knowledge bases: %w", err)
+	}
+
+	resp := &api_models.KBSearchResponse{
+		Query:            query,
+		KnowledgeBaseIDs: kbIDs,
+		Results:          make([]api_models.KBSearchResult, len(matches)),
+	}
+	for i, m := range matches {
+		meta := parseChunkSource(m.Chunk)
+		resp.Results[i] = api_models.KBSearchResult{
+			Score:           m.Score,
+			KnowledgeBaseID: m.Chunk.KnowledgeBaseID,
+			DocumentID:      m.Chunk.DocumentID,
+			ChunkID:         m.Chunk.ID,
+			ChunkIndex:      m.Chunk.ChunkIndex,
+			Heading:         m.Chunk.Heading,
+			Content:         m.Chunk.Content,
+			SourceID:        meta.SourceID,
+			Title:           meta.Title,
+			URL:             meta.URL,
+		}
+	}
+	return resp, nil
+}
@@ -0,0 +1,167 @@
+package services
+
+import (
+	"buildmychat-backend/internal/integrations"
+	"buildmychat-backend/internal/models"
+	integration_models "buildmychat-backend/internal/models/integrations"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// newTestKBService creates a KBService for Notion, website and Git knowledge bases.
+func newTestKBService(st *fakeStore) KBService {
+	registry := integrations.NewRegistry()
+	registry.Register(string(models.ServiceTypeNotion), integrations.NewNotionIntegration())
+	registry.Register(string(models.ServiceTypeWebsite), integrations.NewWebsiteIntegration(nil))
+	registry.Register(string(models.ServiceTypeGit), integrations.NewGitIntegration(nil))
+	return NewKBService(st, registry, nil, nil)
+}
+
+// syncedNotionConfig is the configuration of a Notion knowledge base after a sync.
+const syncedNotionConfig = `{"notion_object_ids":["page-1"],"format":"plain","sync_interval_minutes":60,` +
+	`"sync_status":"COMPLETED","last_synced_at":"2025-01-15T10:00:42Z","sync_cursor":"cursor-1"}`
+
+// kbSyncState returns the sync state stored in a knowledge base's configuration.
+func kbSyncState(t *testing.T, st *fakeStore, kb *models.KnowledgeBase) integration_models.KBSyncState {
+	t.Helper()
+	stored, err := st.GetKnowledgeBaseByID(context.Background(), kb.ID, kb.OrganizationID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var state integration_models.KBSyncState
+	if err := json.Unmarshal(stored.Configuration, &state); err != nil {
+		t.Fatal(err)
+	}
+	return state
+}
+
+func TestUpdateKnowledgeBaseKeepsSyncState(t *testing.T) {
+	st := newFakeStore()
+	s := newTestKBService(st)
+	kb := st.addKnowledgeBase(uuid.New(), models.ServiceTypeNotion, syncedNotionConfig)
+
+	resp, err := s.UpdateKnowledgeBase(context.Background(), kb.ID, kb.OrganizationID, models.CreateKnowledgeBaseRequest{
+		Configuration: json.RawMessage(`{"format":"markdown","notion_object_ids":["page-1"]}`),
+	})
+	if err != nil {
+		t.Fatalf("UpdateKnowledgeBase: %v", err)
+	}
+
+	var config integration_models.NotionKBConfig
+	if err := json.Unmarshal(resp.Configuration, &config); err != nil {
+		t.Fatal(err)
+	}
+	if config.Format != "markdown" || config.SyncIntervalMinutes != 60 {
+		t.Errorf("configuration = %s, want the new format merged over the stored settings", resp.Configuration)
+	}
+	if state := kbSyncState(t, st, kb); state.SyncStatus != "COMPLETED" || state.LastSyncedAt == nil || state.SyncCursor != "cursor-1" {
+		t.Errorf("sync state = %+v, want it kept", state)
+	}
+}
+
+func TestUpdateKnowledgeBaseSourceChangeClearsCursor(t *testing.T) {
+	st := newFakeStore()
+	s := newTestKBService(st)
+	kb := st.addKnowledgeBase(uuid.New(), models.ServiceTypeNotion, syncedNotionConfig)
+
+	_, err := s.UpdateKnowledgeBase(context.Background(), kb.ID, kb.OrganizationID, models.CreateKnowledgeBaseRequest{
+		Configuration: json.RawMessage(`{"notion_object_ids":["page-1","page-2"]}`),
+	})
+	if err != nil {
+		t.Fatalf("UpdateKnowledgeBase: %v", err)
+	}
+	if state := kbSyncState(t, st, kb); state.SyncCursor != "" || state.LastSyncedAt == nil {
+		t.Errorf("sync state = %+v, want only the cursor cleared", state)
+	}
+}
+
+func TestUpdateWebsiteKnowledgeBaseClearsCursor(t *testing.T) {
+	st := newFakeStore()
+	s := newTestKBService(st)
+	kb := st.addKnowledgeBase(uuid.New(), models.ServiceTypeWebsite, `{"seed_urls":["https://example.com/docs/"],"max_pages":50,"sync_cursor":"cursor-1"}`)
+
+	tests := []struct {
+		name       string
+		config     string
+		wantCursor string
+	}{
+		{"crawl settings kept", `{"chunk_size":300}`, "cursor-1"},
+		{"page limit", `{"max_pages":100}`, ""},
+		{"seed URLs", `{"seed_urls":["https://example.com/blog/"]}`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st.storedKBs[kb.ID].Configuration = json.RawMessage(`{"seed_urls":["https://example.com/docs/"],"max_pages":50,"sync_cursor":"cursor-1"}`)
+			_, err := s.UpdateKnowledgeBase(context.Background(), kb.ID, kb.OrganizationID, models.CreateKnowledgeBaseRequest{Configuration: json.RawMessage(tt.config)})
+			if err != nil {
+				t.Fatalf("UpdateKnowledgeBase: %v", err)
+			}
+			if state := kbSyncState(t, st, kb); state.SyncCursor != tt.wantCursor {
+				t.Errorf("sync cursor = %q, want %q", state.SyncCursor, tt.wantCursor)
+			}
+		})
+	}
+}
+
+func TestUpdateGitKnowledgeBaseClearsCursor(t *testing.T) {
+	st := newFakeStore()
+	s := newTestKBService(st)
+	const synced = `{"repository_url":"https://github.com/acme/docs.git","branch":"main","sync_cursor":"0123abcd:fingerprint"}`
+	kb := st.addKnowledgeBase(uuid.New(), models.ServiceTypeGit, synced)
+
+	tests := []struct {
+		name       string
+		config     string
+		wantCursor string
+	}{
+		{"same branch", `{"branch":"main","sync_interval_minutes":30}`, "0123abcd:fingerprint"},
+		{"other branch", `{"branch":"release"}`, ""},
+		{"tag instead of the branch", `{"branch":null,"tag":"v1.0.0"}`, ""},
+		{"other repository", `{"repository_url":"https://github.com/acme/handbook.git"}`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st.storedKBs[kb.ID].Configuration = json.RawMessage(synced)
+			_, err := s.UpdateKnowledgeBase(context.Background(), kb.ID, kb.OrganizationID, models.CreateKnowledgeBaseRequest{Configuration: json.RawMessage(tt.config)})
+			if err != nil {
+				t.Fatalf("UpdateKnowledgeBase: %v", err)
+			}
+			if state := kbSyncState(t, st, kb); state.SyncCursor != tt.wantCursor {
+				t.Errorf("sync cursor = %q, want %q", state.SyncCursor, tt.wantCursor)
+			}
+		})
+	}
+}
+
+func TestKnowledgeBaseRejectsSyncState(t *testing.T) {
+	st := newFakeStore()
+	s := newTestKBService(st)
+	kb := st.addKnowledgeBase(uuid.New(), models.ServiceTypeNotion, syncedNotionConfig)
+
+	for _, config := range []string{
+		`{"sync_cursor":"forged"}`,
+		`{"last_synced_at":"2030-01-01T00:00:00Z"}`,
+		`{"notion_object_ids":[],"sync_status":"COMPLETED"}`,
+	} {
+		_, err := s.UpdateKnowledgeBase(context.Background(), kb.ID, kb.OrganizationID, models.CreateKnowledgeBaseRequest{Configuration: json.RawMessage(config)})
+		if !errors.Is(err, ErrKBValidation) {
+			t.Errorf("update with %s: error = %v, want ErrKBValidation", config, err)
+		}
+		_, err = s.CreateKnowledgeBase(context.Background(), models.CreateKnowledgeBaseRequest{
+			Name:          "Docs",
+			ServiceType:   models.ServiceTypeNotion,
+			CredentialID:  uuid.New(),
+			Configuration: json.RawMessage(config),
+		}, kb.OrganizationID)
+		if !errors.Is(err, ErrKBValidation) {
+			t.Errorf("create with %s: error = %v, want ErrKBValidation", config, err)
+		}
+	}
+	if state := kbSyncState(t, st, kb); state.SyncCursor != "cursor-1" {
+		t.Errorf("sync cursor = %q, want it unchanged", state.SyncCursor)
+	}
+}
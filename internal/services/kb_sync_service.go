@@ -0,0 +1,499 @@
+package services
+
+import (
+	"buildmychat-backend/internal/integrations"
+	api_models "buildmychat-backend/internal/models"
+	db_models "buildmychat-backend/internal/models"
+	integration_models "buildmychat-backend/internal/models/integrations"
+	"buildmychat-backend/internal/store"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Custom errors for KB sync service
+var (
+	ErrKBSyncInProgress  = errors.New("a sync is already running for this knowledge base")
+	ErrKBSyncUnsupported = errors.New("sync is not supported for this knowledge base type")
+)
+
+// KBSyncService defines the interface for syncing knowledge base content from its source.
+type KBSyncService interface {
+	// TriggerSync starts a background sync of the knowledge base and returns its PENDING status.
+	// Syncs are incremental; full re-fetches and re-indexes every page.
+	TriggerSync(ctx context.Context, kbID uuid.UUID, orgID uuid.UUID, full bool) (*api_models.KBSyncStatusResponse, error)
+	GetSyncStatus(ctx context.Context, kbID uuid.UUID, orgID uuid.UUID) (*api_models.KBSyncStatusResponse, error)
+	// Close stops the scheduler, cancels running syncs and waits for them to stop.
+	Close()
+}
+
+type kbSyncService struct {
+	store             store.Store
+	credentialService CredentialsService
+	indexer           *KBIndexer
+	registry          *integrations.Registry
+
+	baseCtx context.Context // Parent of all sync runs, cancelled by Close
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+
+	mu      sync.Mutex
+	running map[uuid.UUID]bool // KB IDs with a sync in progress in this process
+}
+
+// NewKBSyncService creates a new KBSyncService. Knowledge bases are synced from the KnowledgeSource
+// registered for their service type. When pollInterval is positive, a scheduler checks that often
+// for knowledge bases whose sync_interval_minutes has elapsed and syncs them.
+func NewKBSyncService(s store.Store, credentialService CredentialsService, registry *integrations.Registry, indexer *KBIndexer, pollInterval time.Duration) KBSyncService {
+	ctx, cancel := context.WithCancel(context.Background())
+	svc := &kbSyncService{
+		store:             s,
+		credentialService: credentialService,
+		indexer:           indexer,
+		registry:          registry,
+		baseCtx:           ctx,
+		cancel:            cancel,
+		running:           make(map[uuid.UUID]bool),
+	}
+
+	if pollInterval > 0 {
+		svc.wg.Add(1)
+		go func() {
+			defer svc.wg.Done()
+			svc.runScheduler(pollInterval)
+		}()
+	}
+	return svc
+}
+
+// TriggerSync validates the knowledge base, marks it PENDING and runs the sync in the background.
+func (s *kbSyncService) TriggerSync(ctx context.Context, kbID uuid.UUID, orgID uuid.UUID, full bool) (*api_models.KBSyncStatusResponse, error) {
+	kb, err := s.store.GetKnowledgeBaseByID(ctx, kbID, orgID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, ErrKBNotFound
+		}
+		log.Printf("ERROR [KBSyncService] TriggerSync: Failed to get KB %s for OrgID %s: %v", kbID, orgID, err)
+		return nil, fmt.Errorf("failed to retrieve knowledge base: %w", err)
+	}
+	if !s.syncSupported(kb.ServiceType) {
+		return nil, fmt.Errorf("%w: %s", ErrKBSyncUnsupported, kb.ServiceType)
+	}
+
+	if !s.acquire(kbID) {
+		return nil, ErrKBSyncInProgress
+	}
+
+	if err := s.updateSyncState(ctx, kbID, orgID, map[string]interface{}{
+		"sync_status": integration_models.SyncStatusPending,
+		"sync_error":  "",
+	}); err != nil {
+		s.release(kbID)
+		return nil, err
+	}
+
+	s.launch(kbID, orgID, full)
+	log.Printf("[KBSyncService] TriggerSync: Sync queued for KB %s, OrgID %s (full=%t)", kbID, orgID, full)
+	return s.GetSyncStatus(ctx, kbID, orgID)
+}
+
+// GetSyncStatus returns the sync state stored in the knowledge base configuration.
+func (s *kbSyncService) GetSyncStatus(ctx context.Context, kbID uuid.UUID, orgID uuid.UUID) (*api_models.KBSyncStatusResponse, error) {
+	kb, err := s.store.GetKnowledgeBaseByID(ctx, kbID, orgID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, ErrKBNotFound
+		}
+		log.Printf("ERROR [KBSyncService] GetSyncStatus: Failed to get KB %s for OrgID %s: %v", kbID, orgID, err)
+		return nil, fmt.Errorf("failed to retrieve knowledge base: %w", err)
+	}
+
+	state, err := parseSyncState(kb.Configuration)
+	if err != nil {
+		return nil, err
+	}
+
+	count, err := s.store.CountKBDocuments(ctx, kbID, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count knowledge base documents: %w", err)
+	}
+
+	return &api_models.KBSyncStatusResponse{
+		KnowledgeBaseID:     kbID,
+		SyncStatus:          state.SyncStatus,
+		SyncError:           state.SyncError,
+		LastSyncStartedAt:   state.LastSyncStartedAt,
+		LastSyncedAt:        state.LastSyncedAt,
+		LastSyncStats:       state.LastSyncStats,
+		SyncIntervalMinutes: state.SyncIntervalMinutes,
+		DocumentCount:       count,
+	}, nil
+}
+
+// Close stops the scheduler, cancels running syncs and waits for them to record their final status.
+func (s *kbSyncService) Close() {
+	s.cancel()
+	s.wg.Wait()
+}
+
+// acquire marks a knowledge base as syncing in this process. It returns false if it already is.
+func (s *kbSyncService) acquire(kbID uuid.UUID) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running[kbID] {
+		return false
+	}
+	s.running[kbID] = true
+	return true
+}
+
+func (s *kbSyncService) release(kbID uuid.UUID) {
+	s.mu.Lock()
+	delete(s.running, kbID)
+	s.mu.Unlock()
+}
+
+// launch runs an acquired sync in the background.
+func (s *kbSyncService) launch(kbID uuid.UUID, orgID uuid.UUID, full bool) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer s.release(kbID)
+		s.runSync(kbID, orgID, full)
+	}()
+}
+
+// runScheduler periodically starts the scheduled syncs that are due, until Close is called.
+func (s *kbSyncService) runScheduler(pollInterval time.Duration) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	log.Printf("[KBSyncService] Scheduler started (poll interval %s)", pollInterval)
+	for {
+		select {
+		case <-s.baseCtx.Done():
+			return
+		case <-ticker.C:
+			s.startDueSyncs(s.baseCtx)
+		}
+	}
+}
+
+// startDueSyncs starts an incremental sync for every knowledge base whose sync interval has elapsed
+// since its last sync started. The claim in the store keeps replicas from syncing the same knowledge base twice.
+func (s *kbSyncService) startDueSyncs(ctx context.Context) {
+	kbs, err := s.store.ListKnowledgeBasesWithSyncSchedule(ctx)
+	if err != nil {
+		log.Printf("ERROR [KBSyncService] Scheduler: Failed to list scheduled knowledge bases: %v", err)
+		return
+	}
+
+	now := time.Now().UTC()
+	for _, kb := range kbs {
+		if !s.syncSupported(kb.ServiceType) {
+			continue
+		}
+		state, err := parseSyncState(kb.Configuration)
+		if err != nil {
+			log.Printf("WARN [KBSyncService] Scheduler: Skipping KB %s: %v", kb.ID, err)
+			continue
+		}
+
+		interval := syncInterval(state)
+		if state.LastSyncStartedAt != nil && now.Sub(*state.LastSyncStartedAt) < interval {
+			continue
+		}
+		if !s.acquire(kb.ID) {
+			continue
+		}
+
+		patch, err := json.Marshal(map[string]interface{}{
+			"sync_status":          integration_models.SyncStatusPending,
+			"sync_error":           "",
+			"last_sync_started_at": now,
+		})
+		if err != nil {
+			s.release(kb.ID)
+			log.Printf("ERROR [KBSyncService] Scheduler: Failed to marshal claim for KB %s: %v", kb.ID, err)
+			continue
+		}
+		claimed, err := s.store.ClaimKnowledgeBaseSync(ctx, kb.ID, kb.OrganizationID, patch, now.Add(-interval))
+		if err != nil || !claimed {
+			s.release(kb.ID)
+			if err != nil {
+				log.Printf("ERROR [KBSyncService] Scheduler: Failed to claim sync for KB %s: %v", kb.ID, err)
+			}
+			continue // Another instance got there first
+		}
+
+		log.Printf("[KBSyncService] Scheduler: Starting scheduled sync for KB %s, OrgID %s", kb.ID, kb.OrganizationID)
+		s.launch(kb.ID, kb.OrganizationID, false)
+	}
+}
+
+// syncSupported reports whether knowledge bases of the service type are synced from a source.
+func (s *kbSyncService) syncSupported(serviceType api_models.ServiceType) bool {
+	_, ok := s.registry.KnowledgeSource(string(serviceType))
+	return ok
+}
+
+// syncInterval returns the configured periodic sync interval, raised to the minimum.
+func syncInterval(state *integration_models.KBSyncState) time.Duration {
+	minutes := state.SyncIntervalMinutes
+	if minutes < integration_models.MinSyncIntervalMinutes {
+		minutes = integration_models.MinSyncIntervalMinutes
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// runSync performs a sync and records its outcome. Failures are stored in sync_error.
+func (s *kbSyncService) runSync(kbID uuid.UUID, orgID uuid.UUID, full bool) {
+	ctx := s.baseCtx
+	startedAt := time.Now().UTC()
+	if err := s.updateSyncState(ctx, kbID, orgID, map[string]interface{}{
+		"sync_status":          integration_models.SyncStatusSyncing,
+		"last_sync_started_at": startedAt,
+	}); err != nil {
+		log.Printf("ERROR [KBSyncService] runSync: Failed to mark KB %s as syncing: %v", kbID, err)
+		return
+	}
+
+	stats, cursor, err := s.syncSource(ctx, kbID, orgID, full)
+
+	// Record the outcome even when the sync was cancelled by shutdown
+	recordCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+	defer cancel()
+	if err != nil {
+		log.Printf("ERROR [KBSyncService] runSync: Sync failed for KB %s, OrgID %s: %v", kbID, orgID, err)
+		if updateErr := s.updateSyncState(recordCtx, kbID, orgID, map[string]interface{}{
+			"sync_status": integration_models.SyncStatusFailed,
+			"sync_error":  err.Error(),
+		}); updateErr != nil {
+			log.Printf("ERROR [KBSyncService] runSync: Failed to record failure for KB %s: %v", kbID, updateErr)
+		}
+		return
+	}
+
+	if err := s.updateSyncState(recordCtx, kbID, orgID, map[string]interface{}{
+		"sync_status":     integration_models.SyncStatusCompleted,
+		"sync_error":      "",
+		"last_synced_at":  time.Now().UTC(),
+		"last_sync_stats": stats,
+		"sync_cursor":     cursor,
+	}); err != nil {
+		log.Printf("ERROR [KBSyncService] runSync: Failed to record completion for KB %s: %v", kbID, err)
+		return
+	}
+	log.Printf("[KBSyncService] runSync: Synced KB %s in %s (updated %d, unchanged %d, skipped %d, deleted %d)",
+		kbID, time.Since(startedAt).Round(time.Millisecond), stats.Updated, stats.Unchanged, stats.Skipped, stats.Deleted)
+}
+
+// syncSource syncs a knowledge base from the KnowledgeSource registered for its service type, and
+// returns the cursor to resume from. Incremental syncs ask the source for the changes since the last
+// cursor; sources that cannot report them are listed in full. Listed documents whose edit time is
+// unchanged are not fetched, and fetched documents whose content hash is unchanged are not re-indexed,
+// unless full is set. Documents no longer listed are deleted, except those the source failed to read.
+func (s *kbSyncService) syncSource(ctx context.Context, kbID uuid.UUID, orgID uuid.UUID, full bool) (*integration_models.SyncStats, string, error) {
+	kb, err := s.store.GetKnowledgeBaseByID(ctx, kbID, orgID)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to retrieve knowledge base: %w", err)
+	}
+	source, ok := s.registry.KnowledgeSource(string(kb.ServiceType))
+	if !ok {
+		return nil, "", fmt.Errorf("%w: %s", ErrKBSyncUnsupported, kb.ServiceType)
+	}
+	state, err := parseSyncState(kb.Configuration)
+	if err != nil {
+		return nil, "", err
+	}
+	var chunking integration_models.ChunkingConfig
+	if len(kb.Configuration) > 0 {
+		if err := json.Unmarshal(kb.Configuration, &chunking); err != nil {
+			return nil, "", fmt.Errorf("invalid knowledge base configuration: %w", err)
+		}
+	}
+
+	sourceKB := integrations.SourceKnowledgeBase{ID: kbID, Configuration: kb.Configuration}
+	if kb.CredentialID != uuid.Nil {
+		cred, err := s.credentialService.GetDecryptedCredential(ctx, kb.CredentialID, orgID)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to load %s credential: %w", kb.ServiceType, err)
+		}
+		sourceKB.Credentials = cred.DecryptedCredentials
+	}
+
+	storedVersions, err := s.store.ListKBDocumentVersions(ctx, kbID, orgID)
+	if err != nil {
+		return nil, "", err
+	}
+	versions := make(map[string]db_models.KBDocumentVersion, len(storedVersions))
+	for _, v := range storedVersions {
+		versions[v.SourceID] = v
+	}
+
+	stats := &integration_models.SyncStats{}
+	var (
+		refs   []integrations.DocumentRef
+		keep   []string
+		cursor string
+	)
+	incremental := !full && state.SyncCursor != ""
+	if incremental {
+		changes, err := source.ChangesSince(ctx, sourceKB, state.SyncCursor)
+		switch {
+		case errors.Is(err, integrations.ErrFullSyncRequired):
+			incremental = false
+		case err != nil:
+			return nil, "", err
+		default:
+			// Keep every stored document except the removed and changed ones; changed documents
+			// are kept again below once they are stored.
+			dropped := make(map[string]bool, len(changes.Deleted)+len(changes.Updated))
+			for _, id := range changes.Deleted {
+				dropped[id] = true
+			}
+			for _, ref := range changes.Updated {
+				dropped[ref.SourceID] = true
+			}
+			for id := range versions {
+				if !dropped[id] {
+					keep = append(keep, id)
+				}
+			}
+			refs, cursor = changes.Updated, changes.Cursor
+			stats.Skipped = changes.Skipped
+		}
+	}
+	if !incremental {
+		list, err := source.ListDocuments(ctx, sourceKB)
+		if err != nil {
+			return nil, "", err
+		}
+		refs, cursor = list.Documents, list.Cursor
+		keep = append(keep, list.Failed...)
+		stats.Skipped, stats.Failed = list.Skipped, len(list.Failed)
+	}
+	log.Printf("[KBSyncService] syncSource: %d documents to sync for %s KB %s (incremental=%t, %d stored documents)",
+		len(refs), kb.ServiceType, kbID, incremental, len(versions))
+
+	for _, ref := range refs {
+		keep = append(keep, ref.SourceID)
+
+		version, stored := versions[ref.SourceID]
+		if ref.Document == nil && ref.UpdatedAt != nil && !full && stored && !sourceChanged(*ref.UpdatedAt, version) {
+			stats.Skipped++
+			continue
+		}
+
+		doc := ref.Document
+		if doc == nil {
+			if doc, err = source.FetchDocument(ctx, sourceKB, ref); err != nil {
+				return nil, "", err
+			}
+		}
+		updatedAt := time.Now().UTC()
+		if doc.UpdatedAt != nil {
+			updatedAt = *doc.UpdatedAt
+		}
+
+		hash := contentHash(doc.Title, doc.URL, doc.Content)
+		if !full && stored && version.ContentHash == hash {
+			if err := s.store.TouchKBDocument(ctx, kbID, orgID, ref.SourceID, updatedAt); err != nil {
+				return nil, "", err
+			}
+			stats.Unchanged++
+			continue
+		}
+
+		fields := make(map[string]interface{}, len(doc.Metadata)+1)
+		for k, v := range doc.Metadata {
+			fields[k] = v
+		}
+		fields["source"] = string(kb.ServiceType)
+		metadata, err := json.Marshal(fields)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to marshal document metadata: %w", err)
+		}
+
+		// The content hash is only recorded once the document is indexed, so a document whose
+		// indexing failed is picked up again by the next incremental sync.
+		storedDoc, err := s.store.UpsertKBDocument(ctx, store.UpsertKBDocumentParams{
+			KnowledgeBaseID: kbID,
+			OrganizationID:  orgID,
+			SourceID:        ref.SourceID,
+			Title:           doc.Title,
+			URL:             doc.URL,
+			Content:         doc.Content,
+			Metadata:        metadata,
+			SourceUpdatedAt: &updatedAt,
+		})
+		if err != nil {
+			return nil, "", err
+		}
+		if _, err := s.indexer.IndexDocument(ctx, storedDoc, chunking); err != nil {
+			return nil, "", err
+		}
+		if err := s.store.SetKBDocumentContentHash(ctx, storedDoc.ID, orgID, hash); err != nil {
+			return nil, "", err
+		}
+		stats.Updated++
+	}
+
+	deleted, err := s.store.DeleteKBDocumentsNotIn(ctx, kbID, orgID, keep)
+	if err != nil {
+		return nil, "", err
+	}
+	stats.Deleted = int(deleted)
+	return stats, cursor, nil
+}
+
+// sourceChanged reports whether a listed document must be re-fetched. Edit times may be coarse
+// (Notion rounds last_edited_time down to the minute), so a document edited in the same minute it was
+// fetched is re-fetched until a later sync sees a timestamp older than the fetch.
+func sourceChanged(updatedAt time.Time, stored db_models.KBDocumentVersion) bool {
+	if stored.SourceUpdatedAt == nil || stored.ContentHash == "" || !updatedAt.Equal(*stored.SourceUpdatedAt) {
+		return true
+	}
+	return !updatedAt.Before(stored.UpdatedAt.Truncate(time.Minute))
+}
+
+// contentHash fingerprints everything stored for a document, so re-rendering changes are detected too.
+func contentHash(title, url, content string) string {
+	sum := sha256.Sum256([]byte(title + "\x00" + url + "\x00" + content))
+	return hex.EncodeToString(sum[:])
+}
+
+// updateSyncState merges sync fields into the knowledge base configuration.
+func (s *kbSyncService) updateSyncState(ctx context.Context, kbID uuid.UUID, orgID uuid.UUID, fields map[string]interface{}) error {
+	patch, err := json.Marshal(fields)
+	if err != nil {
+		return fmt.Errorf("failed to marshal sync state: %w", err)
+	}
+	if err := s.store.MergeKnowledgeBaseConfiguration(ctx, kbID, orgID, patch); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return ErrKBNotFound
+		}
+		return fmt.Errorf("failed to update sync state: %w", err)
+	}
+	return nil
+}
+
+// parseSyncState reads the sync schedule and state from any synced knowledge base configuration.
+func parseSyncState(raw json.RawMessage) (*integration_models.KBSyncState, error) {
+	var state integration_models.KBSyncState
+	if len(raw) == 0 || string(raw) == "null" {
+		return &state, nil
+	}
+	if err := json.Unmarshal(raw, &state); err != nil {
+		return nil, fmt.Errorf("invalid knowledge base configuration: %w", err)
+	}
+	return &state, nil
+}
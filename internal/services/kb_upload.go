@@ -0,0 +1,205 @@
+package services
+
+import (
+	"buildmychat-backend/internal/extract"
+	api_models "buildmychat-backend/internal/models"
+	db_models "buildmychat-backend/internal/models"
+	integration_models "buildmychat-backend/internal/models/integrations"
+	"buildmychat-backend/internal/store"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// KBUploadFile is a file uploaded into an UPLOAD knowledge base.
+type KBUploadFile struct {
+	Filename string
+	Data     []byte
+}
+
+// extractedUpload is an uploaded file after text extraction.
+type extractedUpload struct {
+	filename string
+	size     int
+	doc      *extract.Document
+}
+
+// UploadDocuments extracts every file before storing any, so a batch with an unreadable file
+// is rejected as a whole. Documents are keyed by file name.
+func (s *kbService) UploadDocuments(ctx context.Context, id uuid.UUID, orgID uuid.UUID, files []KBUploadFile) ([]api_models.KBDocumentResponse, error) {
+	kb, config, err := s.uploadKnowledgeBase(ctx, id, orgID)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("%w: no files uploaded", ErrKBValidation)
+	}
+
+	uploads := make([]extractedUpload, 0, len(files))
+	seen := make(map[string]bool, len(files))
+	for _, f := range files {
+		upload, err := extractUpload(f)
+		if err != nil {
+			return nil, err
+		}
+		if seen[upload.filename] {
+			return nil, fmt.Errorf("%w: file %q uploaded more than once", ErrKBValidation, upload.filename)
+		}
+		seen[upload.filename] = true
+		uploads = append(uploads, upload)
+	}
+
+	resp := make([]api_models.KBDocumentResponse, 0, len(uploads))
+	for _, upload := range uploads {
+		doc, err := s.storeUpload(ctx, kb, config, upload.filename, upload)
+		if err != nil {
+			return nil, err
+		}
+		resp = append(resp, *doc)
+	}
+	log.Printf("[KBService] UploadDocuments: Indexed %d documents into KB %s for OrgID %s", len(resp), id, orgID)
+	return resp, nil
+}
+
+// ReplaceDocument re-extracts and re-indexes a document from a new file, keeping its ID and source ID.
+func (s *kbService) ReplaceDocument(ctx context.Context, id uuid.UUID, documentID uuid.UUID, orgID uuid.UUID, file KBUploadFile) (*api_models.KBDocumentResponse, error) {
+	kb, config, err := s.uploadKnowledgeBase(ctx, id, orgID)
+	if err != nil {
+		return nil, err
+	}
+	existing, err := s.store.GetKBDocumentByID(ctx, documentID, id, orgID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, ErrKBDocumentNotFound
+		}
+		log.Printf("ERROR [KBService] ReplaceDocument: Failed GetKBDocumentByID for DocumentID %s, KB %s: %v", documentID, id, err)
+		return nil, fmt.Errorf("failed to retrieve knowledge base document: %w", err)
+	}
+
+	upload, err := extractUpload(file)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := s.storeUpload(ctx, kb, config, existing.SourceID, upload)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("[KBService] ReplaceDocument: Replaced document %s in KB %s for OrgID %s", documentID, id, orgID)
+	return doc, nil
+}
+
+// DeleteDocument removes a document and its chunks from an UPLOAD knowledge base.
+func (s *kbService) DeleteDocument(ctx context.Context, id uuid.UUID, documentID uuid.UUID, orgID uuid.UUID) error {
+	if _, _, err := s.uploadKnowledgeBase(ctx, id, orgID); err != nil {
+		return err
+	}
+	if err := s.store.DeleteKBDocument(ctx, documentID, id, orgID); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return ErrKBDocumentNotFound
+		}
+		log.Printf("ERROR [KBService] DeleteDocument: Store call failed for DocumentID %s, KB %s: %v", documentID, id, err)
+		return fmt.Errorf("failed to delete knowledge base document: %w", err)
+	}
+	log.Printf("[KBService] DeleteDocument: Deleted document %s from KB %s for OrgID %s", documentID, id, orgID)
+	return nil
+}
+
+// uploadKnowledgeBase loads a knowledge base that accepts uploads, along with its configuration.
+func (s *kbService) uploadKnowledgeBase(ctx context.Context, id uuid.UUID, orgID uuid.UUID) (*db_models.KnowledgeBase, *integration_models.UploadKBConfig, error) {
+	kb, err := s.store.GetKnowledgeBaseByID(ctx, id, orgID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, nil, ErrKBNotFound
+		}
+		log.Printf("ERROR [KBService] Upload: Failed GetKnowledgeBaseByID for ID %s, OrgID %s: %v", id, orgID, err)
+		return nil, nil, fmt.Errorf("failed to retrieve knowledge base: %w", err)
+	}
+	if kb.ServiceType != api_models.ServiceTypeUpload {
+		return nil, nil, ErrKBUploadUnsupported
+	}
+
+	var config integration_models.UploadKBConfig
+	if len(kb.Configuration) > 0 && string(kb.Configuration) != "null" {
+		if err := json.Unmarshal(kb.Configuration, &config); err != nil {
+			return nil, nil, fmt.Errorf("invalid knowledge base configuration: %w", err)
+		}
+	}
+	return kb, &config, nil
+}
+
+// extractUpload validates a file name and extracts the file's text.
+func extractUpload(f KBUploadFile) (extractedUpload, error) {
+	filename := filepath.Base(strings.ReplaceAll(strings.TrimSpace(f.Filename), "\\", "/"))
+	if filename == "" || filename == "." || filename == "/" {
+		return extractedUpload{}, fmt.Errorf("%w: file name cannot be empty", ErrKBValidation)
+	}
+
+	doc, err := extract.FromFile(filename, f.Data)
+	if err != nil {
+		if errors.Is(err, extract.ErrUnsupportedFormat) || errors.Is(err, extract.ErrNoText) {
+			return extractedUpload{}, fmt.Errorf("%w: %s: %v", ErrKBValidation, filename, err)
+		}
+		return extractedUpload{}, fmt.Errorf("%w: %s: could not be read: %v", ErrKBValidation, filename, err)
+	}
+	if doc.Title == "" {
+		doc.Title = strings.TrimSuffix(filename, filepath.Ext(filename))
+	}
+	return extractedUpload{filename: filename, size: len(f.Data), doc: doc}, nil
+}
+
+// storeUpload upserts an extracted file as the document with the given source ID and indexes it.
+// As with syncs, the content hash is recorded only once indexing succeeded.
+func (s *kbService) storeUpload(ctx context.Context, kb *db_models.KnowledgeBase, config *integration_models.UploadKBConfig, sourceID string, upload extractedUpload) (*api_models.KBDocumentResponse, error) {
+	metadata, err := json.Marshal(map[string]interface{}{
+		"source":     string(api_models.ServiceTypeUpload),
+		"filename":   upload.filename,
+		"format":     upload.doc.Format,
+		"size_bytes": upload.size,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal document metadata: %w", err)
+	}
+
+	uploadedAt := time.Now().UTC()
+	storedDoc, err := s.store.UpsertKBDocument(ctx, store.UpsertKBDocumentParams{
+		KnowledgeBaseID: kb.ID,
+		OrganizationID:  kb.OrganizationID,
+		SourceID:        sourceID,
+		Title:           upload.doc.Title,
+		Content:         upload.doc.Content,
+		Metadata:        metadata,
+		SourceUpdatedAt: &uploadedAt,
+	})
+	if err != nil {
+		log.Printf("ERROR [KBService] Upload: Failed to store document %q in KB %s: %v", sourceID, kb.ID, err)
+		return nil, fmt.Errorf("failed to save knowledge base document: %w", err)
+	}
+
+	chunkCount, err := s.indexer.IndexDocument(ctx, storedDoc, config.ChunkingConfig)
+	if err != nil {
+		log.Printf("ERROR [KBService] Upload: Failed to index document %q in KB %s: %v", sourceID, kb.ID, err)
+		return nil, fmt.Errorf("failed to index knowledge base document: %w", err)
+	}
+	if err := s.store.SetKBDocumentContentHash(ctx, storedDoc.ID, kb.OrganizationID, contentHash(storedDoc.Title, storedDoc.URL, storedDoc.Content)); err != nil {
+		return nil, fmt.Errorf("failed to save knowledge base document: %w", err)
+	}
+
+	return &api_models.KBDocumentResponse{
+		ID:              storedDoc.ID,
+		KnowledgeBaseID: storedDoc.KnowledgeBaseID,
+		SourceID:        storedDoc.SourceID,
+		Title:           storedDoc.Title,
+		URL:             storedDoc.URL,
+		SourceUpdatedAt: storedDoc.SourceUpdatedAt,
+		ChunkCount:      chunkCount,
+		CreatedAt:       storedDoc.CreatedAt,
+		SyncedAt:        storedDoc.UpdatedAt,
+	}, nil
+}
@@ -0,0 +1,303 @@
+package services
+
+import (
+	"buildmychat-backend/internal/llm"
+	"buildmychat-backend/internal/models"
+	"buildmychat-backend/internal/realtime"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Reply generation errors.
+var (
+	ErrLLMNotConfigured = errors.New("no LLM provider is configured")
+	ErrReplyGeneration  = errors.New("assistant reply generation failed")
+)
+
+// Chat statuses used while generating a reply.
+const (
+	chatStatusActive     = "ACTIVE"
+	chatStatusProcessing = "PROCESSING"
+	chatStatusError      = "ERROR"
+)
+
+// replyMetadata is stored in ChatMessage.Metadata for generated assistant messages.
+type replyMetadata struct {
+	Provider     string    `json:"provider"`
+	Model        string    `json:"model"`
+	FinishReason string    `json:"finish_reason,omitempty"`
+	Usage        llm.Usage `json:"usage"`
+
+	Sources  []models.ChatMessageSource  `json:"sources,omitempty"`  // Knowledge base documents given to the model
+	Delivery *models.ChatMessageDelivery `json:"delivery,omitempty"` // The channel messages showing the reply
+}
+
+// replyErrorMetadata is stored on the hidden system message that records a failed generation.
+type replyErrorMetadata struct {
+	Type  string `json:"type"` // Always "reply_error"
+	Error string `json:"error"`
+}
+
+// parseChatbotConfiguration reads the known keys from a chatbot's configuration JSON.
+// Invalid or empty JSON yields the zero configuration so a bad config never blocks replies.
+func parseChatbotConfiguration(raw json.RawMessage) models.ChatbotConfiguration {
+	var cfg models.ChatbotConfiguration
+	if len(raw) == 0 || string(raw) == "null" {
+		return cfg
+	}
+	if err := json.Unmarshal(raw, &cfg); err != nil {
+		log.Printf("WARN [ChatService] Ignoring invalid chatbot configuration: %v", err)
+	}
+	return cfg
+}
+
+// buildPromptMessages converts the stored chat history into LLM messages.
+// Only user and assistant turns are sent; system entries in chat_data are bookkeeping
+// (conversation start markers, error records) and the real system prompt comes from the chatbot.
+func buildPromptMessages(history []models.ChatMessage) []llm.Message {
+	messages := make([]llm.Message, 0, len(history))
+	for _, msg := range history {
+		if msg.Role != llm.RoleUser && msg.Role != llm.RoleAssistant {
+			continue
+		}
+		if strings.TrimSpace(msg.Content) == "" {
+			continue
+		}
+		messages = append(messages, llm.Message{Role: msg.Role, Content: msg.Content})
+	}
+	return messages
+}
+
+// buildChatRequest assembles the completion request for a chatbot and a conversation history.
+func (s *ChatService) buildChatRequest(chatbot models.Chatbot, history []models.ChatMessage) (llm.Provider, llm.ChatRequest, error) {
+	if s.llmRegistry == nil {
+		return nil, llm.ChatRequest{}, ErrLLMNotConfigured
+	}
+
+	provider, model, err := s.llmRegistry.Resolve(chatbot.LLMModel)
+	if err != nil {
+		return nil, llm.ChatRequest{}, fmt.Errorf("failed to resolve LLM for chatbot %s: %w", chatbot.ID, err)
+	}
+
+	cfg := parseChatbotConfiguration(chatbot.Configuration)
+	req := llm.ChatRequest{
+		Model:       model,
+		Messages:    buildPromptMessages(history),
+		Temperature: cfg.Temperature,
+	}
+	if chatbot.SystemPrompt != nil {
+		req.SystemPrompt = *chatbot.SystemPrompt
+	}
+	if cfg.MaxTokens != nil {
+		req.MaxTokens = *cfg.MaxTokens
+	}
+	return provider, req, nil
+}
+
+// prepareReply loads the chat and its chatbot and builds the completion request for the current history.
+// Excerpts of the chatbot's knowledge bases are added to the system prompt; the cited documents are returned.
+func (s *ChatService) prepareReply(ctx context.Context, orgID, chatID uuid.UUID) (llm.Provider, llm.ChatRequest, []models.ChatMessageSource, error) {
+	chat, err := s.store.GetChatByID(ctx, chatID, orgID)
+	if err != nil {
+		return nil, llm.ChatRequest{}, nil, fmt.Errorf("failed to get chat: %w", err)
+	}
+
+	chatbot, err := s.store.GetChatbotByID(ctx, chat.ChatbotID, orgID)
+	if err != nil {
+		return nil, llm.ChatRequest{}, nil, fmt.Errorf("failed to get chatbot %s: %w", chat.ChatbotID, err)
+	}
+
+	var history []models.ChatMessage
+	if err := json.Unmarshal(chat.ChatData, &history); err != nil {
+		return nil, llm.ChatRequest{}, nil, fmt.Errorf("failed to parse chat data: %w", err)
+	}
+
+	provider, req, err := s.buildChatRequest(chatbot, history)
+	if err != nil {
+		return nil, llm.ChatRequest{}, nil, err
+	}
+
+	knowledge := s.retrieveKnowledge(ctx, chatbot, req.Messages)
+	if knowledge.Prompt != "" {
+		if req.SystemPrompt != "" {
+			req.SystemPrompt += "\n\n"
+		}
+		req.SystemPrompt += knowledge.Prompt
+	}
+	return provider, req, knowledge.Sources, nil
+}
+
+// GenerateReply asks the chatbot's model for a reply to the chat's current history.
+// It does not persist anything; callers decide what to do with the returned completion.
+func (s *ChatService) GenerateReply(ctx context.Context, orgID, chatID uuid.UUID) (*llm.ChatResponse, error) {
+	completion, _, err := s.generateReply(ctx, orgID, chatID)
+	return completion, err
+}
+
+// generateReply is GenerateReply, also returning the knowledge base documents given to the model.
+func (s *ChatService) generateReply(ctx context.Context, orgID, chatID uuid.UUID) (*llm.ChatResponse, []models.ChatMessageSource, error) {
+	provider, req, sources, err := s.prepareReply(ctx, orgID, chatID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	log.Printf("[ChatService] GenerateReply: Calling provider %s (model %s) for chat %s with %d messages", provider.Name(), req.Model, chatID, len(req.Messages))
+	completion, err := provider.ChatCompletion(ctx, req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("LLM completion failed for chat %s: %w", chatID, err)
+	}
+	if completion.Model == "" {
+		completion.Model = req.Model
+	}
+	return completion, sources, nil
+}
+
+// GenerateAssistantReply runs the full reply cycle for a chat: the chat is moved to PROCESSING,
+// the chatbot's model is called with the stored history, and the reply is appended through
+// AddAssistantMessageToChat (which moves the chat back to ACTIVE).
+// On failure the chat is moved to ERROR, the reason is recorded as a hidden system message,
+// and an error wrapping ErrReplyGeneration is returned.
+// Status and message writes are detached from ctx cancellation, so a caller that goes away (e.g. a
+// client hanging up or a request timeout) never leaves the chat in PROCESSING.
+func (s *ChatService) GenerateAssistantReply(ctx context.Context, orgID, chatID uuid.UUID) (*models.ChatResponse, error) {
+	persistCtx := context.WithoutCancel(ctx)
+
+	if err := s.setChatStatus(persistCtx, orgID, chatID, chatStatusProcessing); err != nil {
+		return nil, fmt.Errorf("failed to update chat status: %w", err)
+	}
+
+	completion, sources, err := s.generateReply(ctx, orgID, chatID)
+	if err != nil {
+		s.recordReplyError(persistCtx, orgID, chatID, err)
+		return nil, fmt.Errorf("%w: %v", ErrReplyGeneration, err)
+	}
+
+	metadata, err := marshalReplyMetadata(completion, sources, nil)
+	if err != nil {
+		s.recordReplyError(persistCtx, orgID, chatID, err)
+		return nil, fmt.Errorf("%w: %v", ErrReplyGeneration, err)
+	}
+
+	return s.AddAssistantMessageToChat(persistCtx, orgID, chatID, completion.Content, metadata)
+}
+
+// StreamAssistantReply adds a user message to a chat and streams the chatbot's reply.
+// onDelta is called for every chunk of the reply as the model produces it, and each chunk is also
+// published to live subscribers of the chat. Once the model is done the full reply is persisted
+// through AddAssistantMessageToChat and the updated chat is returned.
+// Failures of the model are recorded on the chat like in GenerateAssistantReply and wrap ErrReplyGeneration;
+// an error returned by onDelta is not one of them, and the reply is still stored.
+// Persisting is detached from ctx cancellation so a client hanging up never leaves the chat in PROCESSING.
+func (s *ChatService) StreamAssistantReply(ctx context.Context, orgID, chatID uuid.UUID, message string, onDelta llm.DeltaFunc) (*models.ChatResponse, error) {
+	if _, err := s.store.GetChatByID(ctx, chatID, orgID); err != nil {
+		return nil, fmt.Errorf("failed to get chat: %w", err)
+	}
+
+	if err := s.appendUserMessage(ctx, orgID, chatID, message); err != nil {
+		return nil, err
+	}
+
+	completion, sources, err := s.streamReply(ctx, orgID, chatID, onDelta)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata, err := marshalReplyMetadata(completion, sources, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.AddAssistantMessageToChat(context.WithoutCancel(ctx), orgID, chatID, completion.Content, metadata)
+}
+
+// streamReply moves the chat to PROCESSING and streams the chatbot's reply to its current history,
+// publishing each chunk to live subscribers of the chat before passing it to onDelta. It does not
+// persist the reply. An error from onDelta (e.g. a client that went away) only stops the chunks
+// passed to it; provider failures are recorded on the chat and wrap ErrReplyGeneration.
+func (s *ChatService) streamReply(ctx context.Context, orgID, chatID uuid.UUID, onDelta llm.DeltaFunc) (*llm.ChatResponse, []models.ChatMessageSource, error) {
+	if err := s.setChatStatus(ctx, orgID, chatID, chatStatusProcessing); err != nil {
+		return nil, nil, fmt.Errorf("failed to update chat status: %w", err)
+	}
+
+	persistCtx := context.WithoutCancel(ctx)
+
+	provider, req, sources, err := s.prepareReply(ctx, orgID, chatID)
+	if err != nil {
+		s.recordReplyError(persistCtx, orgID, chatID, err)
+		return nil, nil, fmt.Errorf("%w: %v", ErrReplyGeneration, err)
+	}
+
+	log.Printf("[ChatService] streamReply: Streaming from provider %s (model %s) for chat %s with %d messages", provider.Name(), req.Model, chatID, len(req.Messages))
+	var deliveryErr error // Set once onDelta fails; the reply is still generated and stored
+	completion, err := provider.StreamChatCompletion(ctx, req, func(delta string) error {
+		s.publishEvent(ctx, realtime.EventDelta, chatID, models.ChatStreamDeltaEvent{Content: delta})
+		if deliveryErr == nil {
+			if deliveryErr = onDelta(delta); deliveryErr != nil {
+				log.Printf("WARN [ChatService] streamReply: Stopped forwarding the reply of chat %s to its client: %v", chatID, deliveryErr)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		err = fmt.Errorf("LLM streaming completion failed for chat %s: %w", chatID, err)
+		s.recordReplyError(persistCtx, orgID, chatID, err)
+		return nil, nil, fmt.Errorf("%w: %v", ErrReplyGeneration, err)
+	}
+	if completion.Model == "" {
+		completion.Model = req.Model
+	}
+	return completion, sources, nil
+}
+
+// marshalReplyMetadata builds the metadata stored alongside a generated assistant message. delivery is
+// where the message was posted in its channel, if known.
+func marshalReplyMetadata(completion *llm.ChatResponse, sources []models.ChatMessageSource, delivery *models.ChatMessageDelivery) (*json.RawMessage, error) {
+	raw, err := json.Marshal(replyMetadata{
+		Provider:     completion.Provider,
+		Model:        completion.Model,
+		FinishReason: completion.FinishReason,
+		Usage:        completion.Usage,
+		Sources:      sources,
+		Delivery:     delivery,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal reply metadata: %w", err)
+	}
+	metadata := json.RawMessage(raw)
+	return &metadata, nil
+}
+
+// recordReplyError moves the chat to ERROR and stores the failure reason as a hidden system message.
+// Failures here are only logged: the original generation error is what the caller reports.
+func (s *ChatService) recordReplyError(ctx context.Context, orgID, chatID uuid.UUID, cause error) {
+	log.Printf("ERROR [ChatService] Reply generation failed for chat %s: %v", chatID, cause)
+
+	raw, err := json.Marshal(replyErrorMetadata{Type: "reply_error", Error: cause.Error()})
+	if err != nil {
+		log.Printf("ERROR [ChatService] recordReplyError: Failed to marshal error metadata for chat %s: %v", chatID, err)
+		return
+	}
+	metadata := json.RawMessage(raw)
+
+	errorMessage := models.ChatMessage{
+		Role:      "system",
+		Content:   "The assistant could not generate a reply: " + cause.Error(),
+		Timestamp: time.Now().Unix(),
+		SentBy:    "system",
+		Hide:      1, // Visible to operators through the API, hidden from end users
+		Metadata:  &metadata,
+	}
+	if err := s.store.AddMessageToChat(ctx, chatID, errorMessage, orgID); err != nil {
+		log.Printf("ERROR [ChatService] recordReplyError: Failed to store error message for chat %s: %v", chatID, err)
+	}
+	if err := s.setChatStatus(ctx, orgID, chatID, chatStatusError); err != nil {
+		log.Printf("ERROR [ChatService] recordReplyError: Failed to set ERROR status for chat %s: %v", chatID, err)
+	}
+}
@@ -0,0 +1,182 @@
+package services
+
+import (
+	"buildmychat-backend/internal/llm"
+	"buildmychat-backend/internal/models"
+	"buildmychat-backend/internal/store"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// newTestChat creates a chat of a new chatbot holding one user message.
+func newTestChat(t *testing.T, st *fakeStore, message string) (orgID, chatID uuid.UUID) {
+	t.Helper()
+	orgID = uuid.New()
+	chatbot := st.addChatbot(orgID)
+	data, _ := json.Marshal([]models.ChatMessage{{Role: "user", Content: message, SentBy: "user"}})
+	chat, err := st.CreateChat(context.Background(), store.CreateChatParams{OrganizationID: orgID, ChatbotID: chatbot.ID, ChatData: data})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return orgID, chat.ID
+}
+
+func TestGenerateAssistantReply(t *testing.T) {
+	st := newFakeStore()
+	provider := llm.NewFakeProvider()
+	s := newTestChatService(t, st, provider, nil)
+	orgID, chatID := newTestChat(t, st, "Hello there")
+
+	resp, err := s.GenerateAssistantReply(context.Background(), orgID, chatID)
+	if err != nil {
+		t.Fatalf("GenerateAssistantReply: %v", err)
+	}
+	if resp.Status != chatStatusActive {
+		t.Errorf("status = %s, want %s", resp.Status, chatStatusActive)
+	}
+	if got := st.chatStatuses(chatID); strings.Join(got, ",") != "PROCESSING,ACTIVE" {
+		t.Errorf("statuses = %v, want PROCESSING then ACTIVE", got)
+	}
+
+	messages := st.chatMessages(t, chatID)
+	reply := messages[len(messages)-1]
+	if reply.Role != "assistant" || reply.Content != "[fake:echo] You said: Hello there" || reply.Hide != 0 {
+		t.Errorf("reply = %+v, want the visible fake reply", reply)
+	}
+	var metadata replyMetadata
+	if reply.Metadata == nil || json.Unmarshal(*reply.Metadata, &metadata) != nil {
+		t.Fatalf("reply metadata = %v", reply.Metadata)
+	}
+	if metadata.Provider != "fake" || metadata.Model != "echo" || metadata.FinishReason != "stop" {
+		t.Errorf("metadata = %+v, want the fake provider and model", metadata)
+	}
+	if requests := provider.Requests(); len(requests) != 1 || len(requests[0].Messages) != 1 {
+		t.Errorf("requests = %+v, want one with the user message", requests)
+	}
+}
+
+func TestGenerateAssistantReplyFailure(t *testing.T) {
+	st := newFakeStore()
+	provider := llm.NewFakeProvider()
+	provider.Reply = func(req llm.ChatRequest) (string, error) {
+		return "", errors.New("model overloaded")
+	}
+	s := newTestChatService(t, st, provider, nil)
+	orgID, chatID := newTestChat(t, st, "Hello there")
+
+	_, err := s.GenerateAssistantReply(context.Background(), orgID, chatID)
+	if !errors.Is(err, ErrReplyGeneration) {
+		t.Fatalf("error = %v, want ErrReplyGeneration", err)
+	}
+	if got := st.chatStatuses(chatID); strings.Join(got, ",") != "PROCESSING,ERROR" {
+		t.Errorf("statuses = %v, want PROCESSING then ERROR", got)
+	}
+
+	messages := st.chatMessages(t, chatID)
+	if len(messages) != 2 {
+		t.Fatalf("%d messages, want the user message and the error record", len(messages))
+	}
+	record := messages[1]
+	if record.Role != "system" || record.Hide != 1 || !strings.Contains(record.Content, "model overloaded") {
+		t.Errorf("error record = %+v, want a hidden system message with the reason", record)
+	}
+	var metadata replyErrorMetadata
+	if record.Metadata == nil || json.Unmarshal(*record.Metadata, &metadata) != nil || metadata.Type != "reply_error" {
+		t.Errorf("error record metadata = %v, want type reply_error", record.Metadata)
+	}
+}
+
+func TestGenerateAssistantReplyCallerGone(t *testing.T) {
+	st := newFakeStore()
+	provider := llm.NewFakeProvider()
+	ctx, cancel := context.WithCancel(context.Background())
+	provider.Reply = func(req llm.ChatRequest) (string, error) {
+		cancel() // The client hangs up while the model is called
+		return "", context.Canceled
+	}
+	s := newTestChatService(t, st, provider, nil)
+	orgID, chatID := newTestChat(t, st, "Hello there")
+
+	if _, err := s.GenerateAssistantReply(ctx, orgID, chatID); !errors.Is(err, ErrReplyGeneration) {
+		t.Fatalf("error = %v, want ErrReplyGeneration", err)
+	}
+	if got := st.chatStatuses(chatID); strings.Join(got, ",") != "PROCESSING,ERROR" {
+		t.Errorf("statuses = %v, want PROCESSING then ERROR", got)
+	}
+	if messages := st.chatMessages(t, chatID); len(messages) != 2 || messages[1].Role != "system" {
+		t.Errorf("messages = %+v, want the failure recorded", messages)
+	}
+}
+
+func TestAddMessageToChatKeepsMessageOnFailure(t *testing.T) {
+	st := newFakeStore()
+	provider := llm.NewFakeProvider()
+	provider.Reply = func(req llm.ChatRequest) (string, error) {
+		return "", errors.New("model overloaded")
+	}
+	s := newTestChatService(t, st, provider, nil)
+	orgID, chatID := newTestChat(t, st, "Hello there")
+
+	resp, err := s.AddMessageToChat(context.Background(), orgID, chatID, "Anyone?")
+	if err != nil {
+		t.Fatalf("AddMessageToChat: %v", err)
+	}
+	if resp.Status != chatStatusError {
+		t.Errorf("status = %s, want %s", resp.Status, chatStatusError)
+	}
+	if len(resp.Chat) != 3 || resp.Chat[1].Content != "Anyone?" {
+		t.Errorf("chat = %+v, want both user messages and the error record", resp.Chat)
+	}
+}
+
+func TestStreamAssistantReplyOutlivesClient(t *testing.T) {
+	st := newFakeStore()
+	s := newTestChatService(t, st, llm.NewFakeProvider(), nil)
+	orgID, chatID := newTestChat(t, st, "Hello there")
+
+	var received []string
+	resp, err := s.StreamAssistantReply(context.Background(), orgID, chatID, "Are you there?", func(delta string) error {
+		received = append(received, delta)
+		return errors.New("write: broken pipe") // The client went away after the first chunk
+	})
+	if err != nil {
+		t.Fatalf("StreamAssistantReply: %v", err)
+	}
+	if len(received) != 1 {
+		t.Errorf("%d chunks passed on after the client failed, want only the first", len(received))
+	}
+	if resp.Status != chatStatusActive {
+		t.Errorf("status = %s, want %s", resp.Status, chatStatusActive)
+	}
+	reply := resp.Chat[len(resp.Chat)-1]
+	if reply.Role != "assistant" || reply.Content != "[fake:echo] You said: Are you there?" {
+		t.Errorf("reply = %+v, want the full reply stored", reply)
+	}
+}
+
+func TestStreamAssistantReplyProviderFailure(t *testing.T) {
+	st := newFakeStore()
+	provider := llm.NewFakeProvider()
+	provider.Reply = func(req llm.ChatRequest) (string, error) {
+		return "", errors.New("connection reset")
+	}
+	s := newTestChatService(t, st, provider, nil)
+	orgID, chatID := newTestChat(t, st, "Hello there")
+
+	_, err := s.StreamAssistantReply(context.Background(), orgID, chatID, "Are you there?", func(string) error { return nil })
+	if !errors.Is(err, ErrReplyGeneration) {
+		t.Fatalf("error = %v, want ErrReplyGeneration", err)
+	}
+	if got := st.chatStatuses(chatID); strings.Join(got, ",") != "PROCESSING,ERROR" {
+		t.Errorf("statuses = %v, want PROCESSING then ERROR", got)
+	}
+	messages := st.chatMessages(t, chatID)
+	if last := messages[len(messages)-1]; last.Role != "system" || last.Hide != 1 || messages[len(messages)-2].Content != "Are you there?" {
+		t.Errorf("messages = %+v, want the user message followed by the hidden error record", messages)
+	}
+}
@@ -0,0 +1,173 @@
+package services
+
+import (
+	"buildmychat-backend/internal/chunking"
+	"buildmychat-backend/internal/llm"
+	"buildmychat-backend/internal/models"
+	"buildmychat-backend/internal/store"
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// Earlier turns added to the latest user message when searching the knowledge bases,
+// so follow-ups like "and for teams?" keep the topic of the conversation.
+const (
+	retrievalContextMessages     = 2
+	retrievalContextMessageRunes = 300
+)
+
+// knowledgeInstructions introduces the retrieved excerpts in the system prompt.
+const knowledgeInstructions = `Answer using the knowledge base excerpts below when they are relevant. ` +
+	`Cite the excerpts you rely on with their source markers, e.g. [1]. ` +
+	`If the excerpts do not contain the answer, say so rather than guessing.`
+
+// knowledgeContext is the knowledge base material retrieved for one reply.
+type knowledgeContext struct {
+	Prompt   string // Appended to the system prompt; empty when nothing was retrieved
+	Sources  []models.ChatMessageSource
+	Excerpts int // Number of chunks in Prompt
+}
+
+// retrieveKnowledge searches the chatbot's active knowledge bases for the conversation's latest user message.
+// Retrieval problems are logged and yield an empty context: the chatbot then answers without its knowledge
+// bases instead of failing.
+func (s *ChatService) retrieveKnowledge(ctx context.Context, chatbot models.Chatbot, messages []llm.Message) knowledgeContext {
+	if s.retriever == nil {
+		return knowledgeContext{}
+	}
+	query := retrievalQuery(messages)
+	if query == "" {
+		return knowledgeContext{}
+	}
+
+	kbs, err := s.store.ListActiveChatbotKnowledgeBases(ctx, chatbot.ID, chatbot.OrganizationID)
+	if err != nil {
+		log.Printf("WARN [ChatService] retrieveKnowledge: Failed to list knowledge bases of chatbot %s: %v", chatbot.ID, err)
+		return knowledgeContext{}
+	}
+	if len(kbs) == 0 {
+		return knowledgeContext{}
+	}
+
+	opts, maxContextTokens, kbIDs := chatbotRetrievalPlan(chatbot, kbs)
+	matches, err := s.retriever.Retrieve(ctx, chatbot.OrganizationID, kbIDs, query, opts)
+	if err != nil {
+		log.Printf("WARN [ChatService] retrieveKnowledge: Retrieval failed for chatbot %s: %v", chatbot.ID, err)
+		return knowledgeContext{}
+	}
+
+	knowledge := buildKnowledgeContext(matches, maxContextTokens)
+	log.Printf("[ChatService] retrieveKnowledge: %d of %d retrieved chunks from %d documents used for chatbot %s", knowledge.Excerpts, len(matches), len(knowledge.Sources), chatbot.ID)
+	return knowledge
+}
+
+// chatbotRetrievalPlan returns the search options and context token budget for a chatbot's knowledge bases,
+// and the IDs of those knowledge bases.
+func chatbotRetrievalPlan(chatbot models.Chatbot, kbs []models.KnowledgeBase) (RetrievalOptions, int, []uuid.UUID) {
+	cfg := parseChatbotConfiguration(chatbot.Configuration)
+	opts, maxContextTokens := retrievalOptionsFromConfig(cfg.Retrieval)
+	var chatbotMode *string
+	if cfg.Retrieval != nil {
+		chatbotMode = cfg.Retrieval.Mode
+	}
+	opts.HybridKnowledgeBaseIDs = hybridKnowledgeBaseIDs(chatbotMode, kbs)
+
+	kbIDs := make([]uuid.UUID, len(kbs))
+	for i, kb := range kbs {
+		kbIDs[i] = kb.ID
+	}
+	return opts, maxContextTokens, kbIDs
+}
+
+// retrievalQuery builds the knowledge base search text: the latest user message, preceded by
+// a shortened copy of the turns just before it. It is empty if the conversation has no user message.
+func retrievalQuery(messages []llm.Message) string {
+	last := -1
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == llm.RoleUser {
+			last = i
+			break
+		}
+	}
+	if last < 0 {
+		return ""
+	}
+
+	var parts []string
+	for _, msg := range messages[max(0, last-retrievalContextMessages):last] {
+		parts = append(parts, truncateRunes(msg.Content, retrievalContextMessageRunes))
+	}
+	parts = append(parts, messages[last].Content)
+	return strings.TrimSpace(strings.Join(parts, "\n"))
+}
+
+// buildKnowledgeContext formats the retrieved chunks for the system prompt, best match first, until
+// maxTokens is used up. Chunks of the same document share a source marker.
+func buildKnowledgeContext(matches []store.VectorMatch, maxTokens int) knowledgeContext {
+	var (
+		knowledge knowledgeContext
+		excerpts  strings.Builder
+		used      int
+		markers   = map[uuid.UUID]int{} // Document ID -> index in knowledge.Sources
+	)
+
+	for _, m := range matches {
+		tokens := m.Chunk.TokenCount
+		if tokens <= 0 {
+			tokens = chunking.EstimateTokens(m.Chunk.Content)
+		}
+		if used+tokens > maxTokens {
+			continue // A smaller chunk further down may still fit
+		}
+		used += tokens
+
+		meta := parseChunkSource(m.Chunk)
+		idx, ok := markers[m.Chunk.DocumentID]
+		if !ok {
+			idx = len(knowledge.Sources)
+			markers[m.Chunk.DocumentID] = idx
+			knowledge.Sources = append(knowledge.Sources, models.ChatMessageSource{
+				Marker:          idx + 1,
+				KnowledgeBaseID: m.Chunk.KnowledgeBaseID,
+				DocumentID:      m.Chunk.DocumentID,
+				SourceID:        meta.SourceID,
+				Title:           meta.Title,
+				URL:             meta.URL,
+				Score:           m.Score,
+			})
+		}
+		source := knowledge.Sources[idx]
+
+		title := source.Title
+		if title == "" {
+			title = "Untitled"
+		}
+		if m.Chunk.Heading != "" {
+			title += " > " + m.Chunk.Heading
+		}
+		fmt.Fprintf(&excerpts, "\n\n[%d] %s\n", source.Marker, title)
+		if source.URL != "" {
+			fmt.Fprintf(&excerpts, "Source: %s\n", source.URL)
+		}
+		excerpts.WriteString(strings.TrimSpace(m.Chunk.Content))
+		knowledge.Excerpts++
+	}
+
+	if excerpts.Len() > 0 {
+		knowledge.Prompt = knowledgeInstructions + excerpts.String()
+	}
+	return knowledge
+}
+
+// truncateRunes shortens s to at most n runes.
+func truncateRunes(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n])
+}
@@ -0,0 +1,112 @@
+package services
+
+import (
+	"buildmychat-backend/internal/embedding"
+	"buildmychat-backend/internal/llm"
+	"buildmychat-backend/internal/models"
+	"buildmychat-backend/internal/store"
+	"buildmychat-backend/internal/store/memory"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// indexTestDocument stores a document of one chunk in the vector store, embedded by embedder.
+func indexTestDocument(t *testing.T, vectors *memory.VectorStore, embedder embedding.Embedder, kb models.KnowledgeBase, title, url, content string) {
+	t.Helper()
+	ctx := context.Background()
+	vecs, err := embedder.Embed(ctx, []string{content})
+	if err != nil {
+		t.Fatal(err)
+	}
+	meta, _ := json.Marshal(chunkSourceMetadata{SourceID: strings.ToLower(title), Title: title, URL: url})
+	documentID := uuid.New()
+	err = vectors.UpsertDocumentChunks(ctx, kb.OrganizationID, documentID, []models.KBChunk{{
+		ID:              uuid.New(),
+		DocumentID:      documentID,
+		KnowledgeBaseID: kb.ID,
+		OrganizationID:  kb.OrganizationID,
+		Content:         content,
+		Embedding:       vecs[0],
+		EmbeddingModel:  embedder.Model(),
+		Metadata:        meta,
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGenerateAssistantReplyUsesKnowledgeBases(t *testing.T) {
+	st := newFakeStore()
+	vectors := memory.NewVectorStore()
+	embedder := embedding.NewHashingEmbedder(256)
+	provider := llm.NewFakeProvider()
+	s := newTestChatService(t, st, provider, NewKBRetriever(vectors, embedder, nil))
+
+	orgID := uuid.New()
+	chatbot := st.addChatbot(orgID)
+	mapped := models.KnowledgeBase{ID: uuid.New(), OrganizationID: orgID, IsActive: true}
+	unmapped := models.KnowledgeBase{ID: uuid.New(), OrganizationID: orgID, IsActive: true}
+	st.knowledgeBases[chatbot.ID] = []models.KnowledgeBase{mapped}
+	indexTestDocument(t, vectors, embedder, mapped, "Refund policy", "https://example.com/refunds", "Refunds are accepted within 30 days of purchase.")
+	indexTestDocument(t, vectors, embedder, unmapped, "Internal notes", "", "Refunds for the internal team are handled by finance.")
+
+	data, _ := json.Marshal([]models.ChatMessage{{Role: "user", Content: "How many days do I have for refunds?", SentBy: "user"}})
+	chat, err := st.CreateChat(context.Background(), store.CreateChatParams{OrganizationID: orgID, ChatbotID: chatbot.ID, ChatData: data})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.GenerateAssistantReply(context.Background(), orgID, chat.ID); err != nil {
+		t.Fatalf("GenerateAssistantReply: %v", err)
+	}
+
+	requests := provider.Requests()
+	if len(requests) != 1 {
+		t.Fatalf("%d requests, want 1", len(requests))
+	}
+	prompt := requests[0].SystemPrompt
+	if !strings.HasPrefix(prompt, knowledgeInstructions) {
+		t.Errorf("system prompt does not start with the knowledge instructions:\n%s", prompt)
+	}
+	if !strings.Contains(prompt, "[1] Refund policy\nSource: https://example.com/refunds\nRefunds are accepted within 30 days") {
+		t.Errorf("system prompt lacks the excerpt of the mapped knowledge base:\n%s", prompt)
+	}
+	if strings.Contains(prompt, "Internal notes") {
+		t.Errorf("system prompt has an excerpt of a knowledge base the chatbot is not mapped to:\n%s", prompt)
+	}
+
+	messages := st.chatMessages(t, chat.ID)
+	reply := messages[len(messages)-1]
+	var metadata replyMetadata
+	if reply.Metadata == nil || json.Unmarshal(*reply.Metadata, &metadata) != nil {
+		t.Fatalf("reply metadata = %v", reply.Metadata)
+	}
+	if len(metadata.Sources) != 1 {
+		t.Fatalf("sources = %+v, want the refund policy", metadata.Sources)
+	}
+	source := metadata.Sources[0]
+	if source.Marker != 1 || source.Title != "Refund policy" || source.URL != "https://example.com/refunds" || source.KnowledgeBaseID != mapped.ID {
+		t.Errorf("source = %+v, want marker 1 of the refund policy", source)
+	}
+}
+
+func TestGenerateAssistantReplyWithoutKnowledgeBases(t *testing.T) {
+	st := newFakeStore()
+	provider := llm.NewFakeProvider()
+	s := newTestChatService(t, st, provider, NewKBRetriever(memory.NewVectorStore(), embedding.NewHashingEmbedder(256), nil))
+	orgID, chatID := newTestChat(t, st, "Hello there")
+
+	if _, err := s.GenerateAssistantReply(context.Background(), orgID, chatID); err != nil {
+		t.Fatalf("GenerateAssistantReply: %v", err)
+	}
+	if prompt := provider.Requests()[0].SystemPrompt; prompt != "" {
+		t.Errorf("system prompt = %q, want none for a chatbot without knowledge bases", prompt)
+	}
+	messages := st.chatMessages(t, chatID)
+	if reply := messages[len(messages)-1]; strings.Contains(string(*reply.Metadata), `"sources"`) {
+		t.Errorf("reply metadata = %s, want no sources", *reply.Metadata)
+	}
+}
@@ -0,0 +1,245 @@
+package services
+
+import (
+	"buildmychat-backend/internal/auth"
+	slack_sender "buildmychat-backend/internal/integrations/slack"
+	api_models "buildmychat-backend/internal/models"
+	db_models "buildmychat-backend/internal/models"
+	integration_models "buildmychat-backend/internal/models/integrations"
+	"buildmychat-backend/internal/store"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Errors of the "Add to Slack" flow
+var (
+	ErrSlackInstallState           = errors.New("invalid or expired Slack installation request")
+	ErrSlackInstallDenied          = errors.New("slack installation was not approved")
+	ErrSlackInstallChatbotNotFound = errors.New("chatbot not found")
+)
+
+// SlackInstallStateTTL is how long the user has to approve the installation in Slack.
+const SlackInstallStateTTL = 15 * time.Minute
+
+// SlackInstallConfig configures the server's Slack app, which organizations install through OAuth.
+type SlackInstallConfig struct {
+	RedirectURL   string   // The OAuth callback URL, as registered with the app
+	SigningSecret string   // The app's signing secret; stored with each installation to verify its events
+	StateSecret   string   // Signs the OAuth state (the JWT secret)
+	Scopes        []string // Bot scopes to request; slack.DefaultBotScopes if empty
+}
+
+// SlackInstallService installs the server's Slack app in workspaces ("Add to Slack"). An installation
+// creates the Slack credential and interface, so they do not have to be set up by hand.
+type SlackInstallService struct {
+	store             store.Store
+	credentialService CredentialsService
+	oauth             *slack_sender.OAuthClient
+	cfg               SlackInstallConfig
+}
+
+// NewSlackInstallService creates a new SlackInstallService.
+func NewSlackInstallService(s store.Store, credentialService CredentialsService, oauth *slack_sender.OAuthClient, cfg SlackInstallConfig) *SlackInstallService {
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = slack_sender.DefaultBotScopes
+	}
+	return &SlackInstallService{
+		store:             s,
+		credentialService: credentialService,
+		oauth:             oauth,
+		cfg:               cfg,
+	}
+}
+
+// AuthorizeURL returns the Slack page on which the user installs the app for their organization, and
+// the nonce its state is bound to. The nonce must be kept by the user's browser and passed back to
+// CompleteInstall. If chatbotID is set, the installed interface is connected to that chatbot.
+func (s *SlackInstallService) AuthorizeURL(ctx context.Context, userID, orgID uuid.UUID, chatbotID *uuid.UUID) (authorizeURL string, nonce string, err error) {
+	if chatbotID != nil {
+		if _, err := s.store.GetChatbotByID(ctx, *chatbotID, orgID); err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				return "", "", ErrSlackInstallChatbotNotFound
+			}
+			return "", "", fmt.Errorf("failed to verify chatbot: %w", err)
+		}
+	}
+	nonce, err = auth.NewOAuthNonce()
+	if err != nil {
+		return "", "", err
+	}
+	state, err := auth.NewOAuthState("slack", userID, orgID, chatbotID, nonce, s.cfg.StateSecret, SlackInstallStateTTL)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to sign OAuth state: %w", err)
+	}
+	return s.oauth.AuthorizeURL(state, s.cfg.RedirectURL, s.cfg.Scopes), nonce, nil
+}
+
+// CompleteInstall handles the redirect back from Slack: it exchanges the code for a bot token, stores
+// the token as an encrypted credential and creates the workspace's Slack interface. Reinstalling the
+// app in a workspace updates the organization's existing interface for it. The installation is
+// rejected with ErrInterfaceWorkspaceTaken if another active interface, of any organization, receives
+// the workspace's events for the app. The state must have been created by AuthorizeURL with nonce, and
+// is accepted only once.
+func (s *SlackInstallService) CompleteInstall(ctx context.Context, code, state, nonce string) (*api_models.SlackInstallResponse, error) {
+	claims, err := auth.ParseOAuthState(state, "slack", nonce, s.cfg.StateSecret)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrSlackInstallState, err)
+	}
+	firstUse, err := s.store.UseOAuthState(ctx, claims.ID, claims.ExpiresAt.Time)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record OAuth state: %w", err)
+	}
+	if !firstUse {
+		return nil, fmt.Errorf("%w: state %s was already used", ErrSlackInstallState, claims.ID)
+	}
+	orgID := claims.OrgID
+
+	access, err := s.oauth.Exchange(ctx, code, s.cfg.RedirectURL)
+	if err != nil {
+		if errors.Is(err, slack_sender.ErrAccessDenied) {
+			return nil, fmt.Errorf("%w: %v", ErrSlackInstallDenied, err)
+		}
+		return nil, fmt.Errorf("failed to exchange Slack authorization code: %w", err)
+	}
+	log.Printf("[SlackInstallService] CompleteInstall: App %s installed in team %s for OrgID %s by user %s", access.AppID, access.Team.ID, orgID, claims.UserID)
+
+	existing, err := s.findTeamInterface(ctx, orgID, access.Team.ID)
+	if err != nil {
+		return nil, err
+	}
+	existingID := uuid.Nil
+	if existing != nil {
+		existingID = existing.ID
+	}
+	workspace, err := json.Marshal(integration_models.SlackInterfaceConfig{SlackTeamID: access.Team.ID, SlackAppID: access.AppID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal interface configuration: %w", err)
+	}
+	if err := checkSlackWorkspace(ctx, s.store, existingID, workspace); err != nil {
+		return nil, err
+	}
+
+	// Keys of integration_models.SlackCredentials
+	cred, err := s.credentialService.CreateInstalledCredential(ctx, orgID, api_models.ServiceTypeSlack, access.Team.Name, map[string]string{
+		"bot_token":      access.AccessToken,
+		"signing_secret": s.cfg.SigningSecret,
+		"client_id":      s.oauth.ClientID(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to save Slack credential: %w", err)
+	}
+
+	intf, err := s.saveInterface(ctx, orgID, cred.ID, access, existing)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.ChatbotID != nil {
+		if err := s.store.AddInterfaceMapping(ctx, *claims.ChatbotID, intf.ID, orgID); err != nil {
+			return nil, fmt.Errorf("failed to connect interface %s to chatbot %s: %w", intf.ID, *claims.ChatbotID, err)
+		}
+	}
+
+	return &api_models.SlackInstallResponse{
+		Credential: *cred,
+		Interface:  *mapDbInterfaceToResponse(intf),
+		ChatbotID:  claims.ChatbotID,
+	}, nil
+}
+
+// saveInterface creates the active Slack interface of an installation, or points the organization's
+// existing interface for the workspace at the new credential. The replaced credential is deleted if no
+// other interface uses it.
+func (s *SlackInstallService) saveInterface(ctx context.Context, orgID, credentialID uuid.UUID, access *slack_sender.OAuthAccess, existing *db_models.Interface) (*db_models.Interface, error) {
+	active := true
+	if existing == nil {
+		configJSON, err := json.Marshal(integration_models.SlackInterfaceConfig{
+			SlackTeamID:    access.Team.ID,
+			SlackAppID:     access.AppID,
+			SlackBotUserID: access.BotUserID,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal interface configuration: %w", err)
+		}
+		intf, err := s.store.CreateInterface(ctx, store.CreateInterfaceParams{
+			ID:             uuid.New(),
+			OrganizationID: orgID,
+			CredentialID:   credentialID,
+			ServiceType:    string(api_models.ServiceTypeSlack),
+			Name:           "Slack: " + access.Team.Name,
+			Configuration:  configJSON,
+			IsActive:       active,
+		})
+		if err != nil {
+			if errors.Is(err, store.ErrWorkspaceTaken) {
+				return nil, fmt.Errorf("%w: %v", ErrInterfaceWorkspaceTaken, err)
+			}
+			return nil, fmt.Errorf("failed to save interface: %w", err)
+		}
+		return intf, nil
+	}
+
+	// Keep the settings of the existing interface, such as its reply policy
+	var config map[string]interface{}
+	if len(existing.Configuration) > 0 {
+		if err := json.Unmarshal(existing.Configuration, &config); err != nil {
+			return nil, fmt.Errorf("failed to parse configuration of interface %s: %w", existing.ID, err)
+		}
+	}
+	if config == nil {
+		config = map[string]interface{}{}
+	}
+	config["slack_team_id"] = access.Team.ID
+	config["slack_app_id"] = access.AppID
+	config["slack_bot_user_id"] = access.BotUserID
+	configJSON, err := json.Marshal(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal interface configuration: %w", err)
+	}
+	intf, err := s.store.UpdateInterface(ctx, store.UpdateInterfaceParams{
+		ID:             existing.ID,
+		OrganizationID: orgID,
+		CredentialID:   &credentialID,
+		Configuration:  configJSON,
+		IsActive:       &active,
+	})
+	if err != nil {
+		if errors.Is(err, store.ErrWorkspaceTaken) {
+			return nil, fmt.Errorf("%w: %v", ErrInterfaceWorkspaceTaken, err)
+		}
+		return nil, fmt.Errorf("failed to update interface %s: %w", existing.ID, err)
+	}
+	if existing.CredentialID != credentialID {
+		if err := s.credentialService.DeleteCredential(ctx, existing.CredentialID, orgID); err != nil && !errors.Is(err, ErrCredentialNotFound) {
+			log.Printf("WARN [SlackInstallService] saveInterface: Kept replaced credential %s of interface %s: %v", existing.CredentialID, existing.ID, err)
+		}
+	}
+	return intf, nil
+}
+
+// findTeamInterface returns the organization's Slack interface for a workspace, or nil if it has none.
+func (s *SlackInstallService) findTeamInterface(ctx context.Context, orgID uuid.UUID, teamID string) (*db_models.Interface, error) {
+	interfaces, err := s.store.ListInterfacesByOrg(ctx, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list interfaces: %w", err)
+	}
+	for i := range interfaces {
+		if interfaces[i].ServiceType != api_models.ServiceTypeSlack {
+			continue
+		}
+		var config integration_models.SlackInterfaceConfig
+		if err := json.Unmarshal(interfaces[i].Configuration, &config); err != nil {
+			continue
+		}
+		if config.SlackTeamID == teamID {
+			return &interfaces[i], nil
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,38 @@
+package services
+
+import (
+	slack_sender "buildmychat-backend/internal/integrations/slack"
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestCompleteInstallChecksState(t *testing.T) {
+	st := newFakeStore()
+	oauth := slack_sender.NewOAuthClient("https://slack.invalid", "client", "secret", nil)
+	s := NewSlackInstallService(st, nil, oauth, SlackInstallConfig{RedirectURL: "https://api.example.com/slack/oauth/callback", StateSecret: "secret"})
+
+	authorizeURL, nonce, err := s.AuthorizeURL(context.Background(), uuid.New(), uuid.New(), nil)
+	if err != nil {
+		t.Fatalf("AuthorizeURL: %v", err)
+	}
+	page, err := url.Parse(authorizeURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state := page.Query().Get("state")
+
+	if _, err := s.CompleteInstall(context.Background(), "", state, "other browser"); !errors.Is(err, ErrSlackInstallState) {
+		t.Errorf("state with another nonce: error = %v, want ErrSlackInstallState", err)
+	}
+	// Without a code, the exchange fails as if the user cancelled, after the state was used
+	if _, err := s.CompleteInstall(context.Background(), "", state, nonce); !errors.Is(err, ErrSlackInstallDenied) {
+		t.Errorf("first use: error = %v, want ErrSlackInstallDenied", err)
+	}
+	if _, err := s.CompleteInstall(context.Background(), "", state, nonce); !errors.Is(err, ErrSlackInstallState) {
+		t.Errorf("second use: error = %v, want ErrSlackInstallState", err)
+	}
+}
@@ -0,0 +1,175 @@
+// Package memory provides in-memory store implementations for tests and local development.
+package memory
+
+import (
+	db_models "buildmychat-backend/internal/models"
+	"buildmychat-backend/internal/store"
+	"context"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/google/uuid"
+)
+
+// Ensure VectorStore implements store.VectorStore.
+var _ store.VectorStore = (*VectorStore)(nil)
+
+// VectorStore is a brute-force store.VectorStore: every search computes the cosine similarity
+// with each chunk of the requested knowledge bases.
+type VectorStore struct {
+	mu     sync.RWMutex
+	chunks map[uuid.UUID][]db_models.KBChunk // Document ID -> chunks
+}
+
+// NewVectorStore creates an empty in-memory vector store.
+func NewVectorStore() *VectorStore {
+	return &VectorStore{chunks: make(map[uuid.UUID][]db_models.KBChunk)}
+}
+
+// UpsertDocumentChunks replaces all chunks of a document.
+func (s *VectorStore) UpsertDocumentChunks(ctx context.Context, orgID uuid.UUID, documentID uuid.UUID, chunks []db_models.KBChunk) error {
+	stored := make([]db_models.KBChunk, len(chunks))
+	for i, c := range chunks {
+		if c.ID == uuid.Nil {
+			c.ID = uuid.New()
+		}
+		c.OrganizationID = orgID
+		c.DocumentID = documentID
+		c.Embedding = append([]float32(nil), c.Embedding...)
+		stored[i] = c
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.removeDocument(orgID, documentID)
+	if len(stored) > 0 {
+		s.chunks[documentID] = stored
+	}
+	return nil
+}
+
+// DeleteDocumentChunks removes all chunks of a document.
+func (s *VectorStore) DeleteDocumentChunks(ctx context.Context, orgID uuid.UUID, documentID uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.removeDocument(orgID, documentID)
+	return nil
+}
+
+// removeDocument deletes a document's chunks if they belong to orgID. Callers hold s.mu.
+func (s *VectorStore) removeDocument(orgID uuid.UUID, documentID uuid.UUID) {
+	if existing := s.chunks[documentID]; len(existing) > 0 && existing[0].OrganizationID == orgID {
+		delete(s.chunks, documentID)
+	}
+}
+
+// SearchSimilar returns the TopK most similar chunks of the organization's requested knowledge bases.
+func (s *VectorStore) SearchSimilar(ctx context.Context, arg store.VectorSearchParams) ([]store.VectorMatch, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	kbs := make(map[uuid.UUID]bool, len(arg.KnowledgeBaseIDs))
+	for _, id := range arg.KnowledgeBaseIDs {
+		kbs[id] = true
+	}
+
+	s.mu.RLock()
+	matches := []store.VectorMatch{}
+	for _, chunks := range s.chunks {
+		for _, c := range chunks {
+			if c.OrganizationID != arg.OrganizationID || !kbs[c.KnowledgeBaseID] || c.EmbeddingModel != arg.EmbeddingModel {
+				continue
+			}
+			score := cosine(arg.Embedding, c.Embedding)
+			if score < arg.MinScore {
+				continue
+			}
+			c.Embedding = nil
+			matches = append(matches, store.VectorMatch{Chunk: c, Score: score})
+		}
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(matches, func(i, j int) bool { return matches[i].Score > matches[j].Score })
+	if arg.TopK >= 0 && len(matches) > arg.TopK {
+		matches = matches[:arg.TopK]
+	}
+	return matches, nil
+}
+
+// SearchLexical returns the TopK chunks of the organization's requested knowledge bases that contain
+// the most query terms. Terms are compared case-insensitively; Score counts term occurrences, with
+// each distinct matched term weighing more than repetitions.
+func (s *VectorStore) SearchLexical(ctx context.Context, arg store.LexicalSearchParams) ([]store.VectorMatch, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	queryTerms := map[string]bool{}
+	for _, t := range terms(arg.Query) {
+		queryTerms[t] = true
+	}
+	kbs := make(map[uuid.UUID]bool, len(arg.KnowledgeBaseIDs))
+	for _, id := range arg.KnowledgeBaseIDs {
+		kbs[id] = true
+	}
+
+	s.mu.RLock()
+	matches := []store.VectorMatch{}
+	for _, chunks := range s.chunks {
+		for _, c := range chunks {
+			if c.OrganizationID != arg.OrganizationID || !kbs[c.KnowledgeBaseID] {
+				continue
+			}
+			counts := map[string]int{}
+			for _, t := range terms(c.Heading + " " + c.Content) {
+				if queryTerms[t] {
+					counts[t]++
+				}
+			}
+			if len(counts) == 0 {
+				continue
+			}
+			score := float64(len(counts))
+			for _, n := range counts {
+				score += float64(n-1) * 0.1
+			}
+			c.Embedding = nil
+			matches = append(matches, store.VectorMatch{Chunk: c, Score: score})
+		}
+	}
+	s.mu.RUnlock()
+
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].Score > matches[j].Score })
+	if arg.TopK >= 0 && len(matches) > arg.TopK {
+		matches = matches[:arg.TopK]
+	}
+	return matches, nil
+}
+
+// terms splits text into lower-case words of letters and digits, keeping hyphenated and
+// underscored codes such as "ERR_TIMEOUT" or "SKU-1234" together.
+func terms(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-' && r != '_'
+	})
+}
+
+// cosine returns the cosine similarity of two vectors, or 0 if their sizes differ or either is zero.
+func cosine(a, b []float32) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
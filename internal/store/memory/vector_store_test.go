@@ -0,0 +1,105 @@
+package memory
+
+import (
+	db_models "buildmychat-backend/internal/models"
+	"buildmychat-backend/internal/store"
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func chunk(kbID uuid.UUID, content string, embedding ...float32) db_models.KBChunk {
+	return db_models.KBChunk{KnowledgeBaseID: kbID, Content: content, Embedding: embedding, EmbeddingModel: "test"}
+}
+
+func TestVectorStoreSearchIsTenantScoped(t *testing.T) {
+	ctx := context.Background()
+	s := NewVectorStore()
+	orgA, orgB := uuid.New(), uuid.New()
+	kbA, kbA2, kbB := uuid.New(), uuid.New(), uuid.New()
+
+	s.UpsertDocumentChunks(ctx, orgA, uuid.New(), []db_models.KBChunk{
+		chunk(kbA, "exact", 1, 0),
+		chunk(kbA, "close", 0.9, 0.1),
+		chunk(kbA, "far", 0, 1),
+	})
+	s.UpsertDocumentChunks(ctx, orgA, uuid.New(), []db_models.KBChunk{chunk(kbA2, "other kb", 1, 0)})
+	s.UpsertDocumentChunks(ctx, orgB, uuid.New(), []db_models.KBChunk{chunk(kbB, "other org", 1, 0)})
+
+	matches, err := s.SearchSimilar(ctx, store.VectorSearchParams{
+		OrganizationID:   orgA,
+		KnowledgeBaseIDs: []uuid.UUID{kbA, kbB}, // kbB belongs to another organization
+		Embedding:        []float32{1, 0},
+		EmbeddingModel:   "test",
+		TopK:             10,
+		MinScore:         0.5,
+	})
+	if err != nil {
+		t.Fatalf("SearchSimilar returned error: %v", err)
+	}
+	if len(matches) != 2 || matches[0].Chunk.Content != "exact" || matches[1].Chunk.Content != "close" {
+		t.Fatalf("unexpected matches: %+v", matches)
+	}
+	if matches[0].Score < 0.999 || matches[0].Chunk.Embedding != nil {
+		t.Errorf("unexpected first match: %+v", matches[0])
+	}
+
+	matches, _ = s.SearchSimilar(ctx, store.VectorSearchParams{OrganizationID: orgA, Embedding: []float32{1, 0}, EmbeddingModel: "test", TopK: 10})
+	if len(matches) != 0 {
+		t.Errorf("search without knowledge bases should match nothing, got %+v", matches)
+	}
+}
+
+func TestVectorStoreUpsertReplacesAndDeletes(t *testing.T) {
+	ctx := context.Background()
+	s := NewVectorStore()
+	org, kb, doc := uuid.New(), uuid.New(), uuid.New()
+	search := func() []store.VectorMatch {
+		matches, _ := s.SearchSimilar(ctx, store.VectorSearchParams{
+			OrganizationID: org, KnowledgeBaseIDs: []uuid.UUID{kb}, Embedding: []float32{1, 0}, EmbeddingModel: "test", TopK: 10,
+		})
+		return matches
+	}
+
+	s.UpsertDocumentChunks(ctx, org, doc, []db_models.KBChunk{chunk(kb, "v1", 1, 0), chunk(kb, "v1 bis", 1, 0)})
+	s.UpsertDocumentChunks(ctx, org, doc, []db_models.KBChunk{chunk(kb, "v2", 1, 0)})
+	if matches := search(); len(matches) != 1 || matches[0].Chunk.Content != "v2" {
+		t.Fatalf("upsert did not replace the document's chunks: %+v", matches)
+	}
+
+	s.DeleteDocumentChunks(ctx, uuid.New(), doc) // Wrong organization: no effect
+	if len(search()) != 1 {
+		t.Fatal("another organization deleted the document's chunks")
+	}
+	s.DeleteDocumentChunks(ctx, org, doc)
+	if matches := search(); len(matches) != 0 {
+		t.Fatalf("chunks remain after delete: %+v", matches)
+	}
+}
+
+func TestVectorStoreSearchLexicalMatchesTerms(t *testing.T) {
+	ctx := context.Background()
+	s := NewVectorStore()
+	org, kb := uuid.New(), uuid.New()
+
+	s.UpsertDocumentChunks(ctx, org, uuid.New(), []db_models.KBChunk{
+		chunk(kb, "Error SKU-1234 means the item is out of stock.", 1, 0),
+		chunk(kb, "Items out of stock can be backordered.", 1, 0),
+		chunk(kb, "Shipping takes three days.", 1, 0),
+	})
+	s.UpsertDocumentChunks(ctx, uuid.New(), uuid.New(), []db_models.KBChunk{chunk(kb, "sku-1234 in another org", 1, 0)})
+
+	matches, err := s.SearchLexical(ctx, store.LexicalSearchParams{
+		OrganizationID: org, KnowledgeBaseIDs: []uuid.UUID{kb}, Query: "what is sku-1234 stock", TopK: 10,
+	})
+	if err != nil {
+		t.Fatalf("SearchLexical returned error: %v", err)
+	}
+	if len(matches) != 2 || matches[0].Chunk.Content != "Error SKU-1234 means the item is out of stock." {
+		t.Fatalf("unexpected matches: %+v", matches)
+	}
+	if matches[0].Score <= matches[1].Score {
+		t.Errorf("chunk with more matching terms should rank first: %+v", matches)
+	}
+}
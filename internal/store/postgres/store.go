@@ -415,6 +415,35 @@ func (s *PostgresStore) RemoveKnowledgeBaseMapping(ctx context.Context, chatbotI
 	return nil
 }
 
+const listActiveChatbotKnowledgeBases = `-- name: ListActiveChatbotKnowledgeBases :many
+SELECT kb.id, kb.organization_id, kb.credential_id, kb.service_type, kb.name, kb.configuration, kb.is_active, kb.created_at, kb.updated_at
+FROM knowledge_bases kb
+JOIN chatbot_kb_mappings map ON kb.id = map.kb_id
+WHERE map.chatbot_id = $1 AND kb.organization_id = $2 AND kb.is_active = TRUE;
+`
+
+// ListActiveChatbotKnowledgeBases returns the active knowledge bases mapped to a chatbot.
+func (s *PostgresStore) ListActiveChatbotKnowledgeBases(ctx context.Context, chatbotID, orgID uuid.UUID) ([]models.KnowledgeBase, error) {
+	rows, err := s.db.Query(ctx, listActiveChatbotKnowledgeBases, chatbotID, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch knowledge base mappings: %w", err)
+	}
+	defer rows.Close()
+
+	kbs := []models.KnowledgeBase{}
+	for rows.Next() {
+		var kb models.KnowledgeBase
+		if err := rows.Scan(&kb.ID, &kb.OrganizationID, &kb.CredentialID, &kb.ServiceType, &kb.Name, &kb.Configuration, &kb.IsActive, &kb.CreatedAt, &kb.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan knowledge base: %w", err)
+		}
+		kbs = append(kbs, kb)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over knowledge base rows: %w", err)
+	}
+	return kbs, nil
+}
+
 const addInterfaceMapping = `-- name: AddInterfaceMapping :exec
 INSERT INTO chatbot_interface_mappings (
     chatbot_id, interface_id
@@ -469,6 +498,47 @@ func (s *PostgresStore) RemoveInterfaceMapping(ctx context.Context, chatbotID, i
 	return nil
 }
 
+const listChatbotsByInterface = `-- name: ListChatbotsByInterface :many
+SELECT c.id, c.organization_id, c.name, c.system_prompt, c.is_active, c.chat_count, c.llm_model, c.configuration, c.created_at, c.updated_at
+FROM chatbots c
+JOIN chatbot_interface_mappings map ON c.id = map.chatbot_id
+WHERE map.interface_id = $1 AND c.organization_id = $2
+ORDER BY c.created_at, c.id;
+`
+
+// ListChatbotsByInterface returns the chatbots an interface is mapped to, oldest first.
+func (s *PostgresStore) ListChatbotsByInterface(ctx context.Context, interfaceID, orgID uuid.UUID) ([]models.Chatbot, error) {
+	rows, err := s.db.Query(ctx, listChatbotsByInterface, interfaceID, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("error querying chatbots of interface: %w", err)
+	}
+	defer rows.Close()
+
+	var items []models.Chatbot
+	for rows.Next() {
+		var i models.Chatbot
+		if err := rows.Scan(
+			&i.ID,
+			&i.OrganizationID,
+			&i.Name,
+			&i.SystemPrompt,
+			&i.IsActive,
+			&i.ChatCount,
+			&i.LLMModel,
+			&i.Configuration,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("error scanning chatbot row: %w", err)
+		}
+		items = append(items, i)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating chatbot rows: %w", err)
+	}
+	return items, nil
+}
+
 func (s *PostgresStore) GetChatbotMappings(ctx context.Context, chatbotID, orgID uuid.UUID) (*models.ChatbotMappingsResponse, error) {
 	// First verify the chatbot exists and belongs to the organization
 	_, err := s.GetChatbotByID(ctx, chatbotID, orgID)
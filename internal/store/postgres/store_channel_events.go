@@ -0,0 +1,49 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// --- Channel Event Methods ---
+
+// ClaimChannelEvent records an inbound event of an interface. It reports whether the event was new,
+// so that redelivered or concurrently delivered events are processed only once.
+func (s *PostgresStore) ClaimChannelEvent(ctx context.Context, interfaceID uuid.UUID, eventID string) (bool, error) {
+	query := `
+        INSERT INTO channel_events (interface_id, event_id)
+        VALUES ($1, $2)
+        ON CONFLICT (interface_id, event_id) DO NOTHING`
+
+	cmdTag, err := s.db.Exec(ctx, query, interfaceID, eventID)
+	if err != nil {
+		log.Printf("ERROR [PostgresStore] ClaimChannelEvent: Failed exec for InterfaceID %s, EventID %s: %v", interfaceID, eventID, err)
+		return false, fmt.Errorf("database error claiming channel event: %w", err)
+	}
+	return cmdTag.RowsAffected() > 0, nil
+}
+
+// ReleaseChannelEvent removes the claim of an event that was not processed, so that a redelivery of
+// it is processed.
+func (s *PostgresStore) ReleaseChannelEvent(ctx context.Context, interfaceID uuid.UUID, eventID string) error {
+	_, err := s.db.Exec(ctx, `DELETE FROM channel_events WHERE interface_id = $1 AND event_id = $2`, interfaceID, eventID)
+	if err != nil {
+		log.Printf("ERROR [PostgresStore] ReleaseChannelEvent: Failed exec for InterfaceID %s, EventID %s: %v", interfaceID, eventID, err)
+		return fmt.Errorf("database error releasing channel event: %w", err)
+	}
+	return nil
+}
+
+// DeleteChannelEventsBefore removes the events of all interfaces received before the given time.
+func (s *PostgresStore) DeleteChannelEventsBefore(ctx context.Context, before time.Time) (int64, error) {
+	cmdTag, err := s.db.Exec(ctx, `DELETE FROM channel_events WHERE received_at < $1`, before)
+	if err != nil {
+		log.Printf("ERROR [PostgresStore] DeleteChannelEventsBefore: Failed exec: %v", err)
+		return 0, fmt.Errorf("database error deleting channel events: %w", err)
+	}
+	return cmdTag.RowsAffected(), nil
+}
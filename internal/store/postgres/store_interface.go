@@ -17,6 +17,9 @@ import (
 
 // --- Interface Methods ---
 
+// workspaceIndex is the unique index allowing one active interface per workspace and app.
+const workspaceIndex = "idx_interfaces_workspace_active"
+
 // CreateInterface inserts a new interface record.
 func (s *PostgresStore) CreateInterface(ctx context.Context, arg store.CreateInterfaceParams) (*db_models.Interface, error) {
 	log.Printf("[PostgresStore] CreateInterface called for OrgID: %s, Name: %s", arg.OrganizationID, arg.Name)
@@ -60,6 +63,10 @@ func (s *PostgresStore) CreateInterface(ctx context.Context, arg store.CreateInt
 				log.Printf("WARN [PostgresStore] CreateInterface: Foreign key violation for OrgID %s, CredID %s: %v", arg.OrganizationID, arg.CredentialID, err)
 				return nil, fmt.Errorf("invalid credential ID provided")
 			}
+			if pgErr.Code == "23505" && pgErr.ConstraintName == workspaceIndex {
+				log.Printf("WARN [PostgresStore] CreateInterface: Workspace already taken for OrgID %s: %v", arg.OrganizationID, err)
+				return nil, store.ErrWorkspaceTaken
+			}
 		}
 		log.Printf("ERROR [PostgresStore] CreateInterface: Failed exec/scan for OrgID %s: %v", arg.OrganizationID, err)
 		return nil, fmt.Errorf("database error creating interface: %w", err)
@@ -156,6 +163,11 @@ func (s *PostgresStore) UpdateInterface(ctx context.Context, arg store.UpdateInt
 	args := []interface{}{arg.ID, arg.OrganizationID}
 	argCounter := 3
 
+	if arg.CredentialID != nil {
+		setClauses = append(setClauses, fmt.Sprintf("credential_id = $%d", argCounter))
+		args = append(args, *arg.CredentialID)
+		argCounter++
+	}
 	if arg.Name != nil {
 		setClauses = append(setClauses, fmt.Sprintf("name = $%d", argCounter))
 		args = append(args, *arg.Name)
@@ -207,6 +219,10 @@ func (s *PostgresStore) UpdateInterface(ctx context.Context, arg store.UpdateInt
 			return nil, store.ErrNotFound
 		}
 		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == workspaceIndex {
+			log.Printf("WARN [PostgresStore] UpdateInterface: Workspace already taken for OrgID %s, ID %s: %v", arg.OrganizationID, arg.ID, err)
+			return nil, store.ErrWorkspaceTaken
+		}
 		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
 			log.Printf("WARN [PostgresStore] UpdateInterface: Unique constraint violation for OrgID %s, ID %s: %v", arg.OrganizationID, arg.ID, err)
 			return nil, fmt.Errorf("interface name conflicts with an existing one in this organization")
@@ -243,3 +259,49 @@ func (s *PostgresStore) DeleteInterface(ctx context.Context, id uuid.UUID, orgID
 	log.Printf("[PostgresStore] DeleteInterface: Successfully deleted Interface ID %s for OrgID %s", id, orgID)
 	return nil
 }
+
+// workspaceConfigKeys are the configuration keys holding the workspace and app of the interfaces of a
+// service type whose webhooks are routed by workspace. They match the unique index of migration
+// 0010_interfaces_workspace_unique.sql.
+var workspaceConfigKeys = map[db_models.ServiceType][2]string{
+	db_models.ServiceTypeSlack: {"slack_team_id", "slack_app_id"},
+}
+
+// GetActiveInterfaceByWorkspace finds the active interface of a service type configured for a workspace
+// and app, in any organization, for webhooks sent to the URL the service type shares between interfaces.
+// appID "" finds the interface configured without an app. There is at most one such interface.
+func (s *PostgresStore) GetActiveInterfaceByWorkspace(ctx context.Context, serviceType db_models.ServiceType, workspaceID string, appID string) (*db_models.Interface, error) {
+	keys, ok := workspaceConfigKeys[serviceType]
+	if !ok {
+		return nil, fmt.Errorf("interfaces of service type %s are not configured for workspaces", serviceType)
+	}
+	// The keys are constants; they are part of the query so that the index can be used
+	query := fmt.Sprintf(`
+        SELECT id, organization_id, credential_id, service_type, name, configuration, is_active, created_at, updated_at
+        FROM interfaces
+        WHERE service_type = $1
+          AND is_active
+          AND configuration->>'%s' = $2
+          AND COALESCE(configuration->>'%s', '') = $3`, keys[0], keys[1])
+
+	intf := &db_models.Interface{}
+	err := s.db.QueryRow(ctx, query, serviceType, workspaceID, appID).Scan(
+		&intf.ID,
+		&intf.OrganizationID,
+		&intf.CredentialID,
+		&intf.ServiceType,
+		&intf.Name,
+		&intf.Configuration,
+		&intf.IsActive,
+		&intf.CreatedAt,
+		&intf.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, store.ErrNotFound
+		}
+		log.Printf("ERROR [PostgresStore] GetActiveInterfaceByWorkspace: Failed query/scan for %s workspace %s, app %s: %v", serviceType, workspaceID, appID, err)
+		return nil, fmt.Errorf("database error fetching interface for workspace: %w", err)
+	}
+	return intf, nil
+}
@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"log"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
@@ -34,10 +35,15 @@ func (s *PostgresStore) CreateKnowledgeBase(ctx context.Context, arg store.Creat
 		configBytes = []byte("{}") // Storing empty JSON object by default
 	}
 
+	var credentialID *uuid.UUID // NULL for knowledge bases without a credential (UPLOAD)
+	if arg.CredentialID != uuid.Nil {
+		credentialID = &arg.CredentialID
+	}
+
 	err := s.db.QueryRow(ctx, query,
 		arg.ID,
 		arg.OrganizationID,
-		arg.CredentialID,
+		credentialID,
 		arg.ServiceType,
 		arg.Name,
 		configBytes,
@@ -169,7 +175,8 @@ func (s *PostgresStore) UpdateKnowledgeBase(ctx context.Context, arg store.Updat
 		if !json.Valid(arg.Configuration) {
 			return nil, errors.New("invalid JSON format in configuration")
 		}
-		setClauses = append(setClauses, fmt.Sprintf("configuration = $%d", argCounter))
+		// Merged, so that sync state written by the sync engine in the meantime is kept
+		setClauses = append(setClauses, fmt.Sprintf("configuration = COALESCE(configuration, '{}'::jsonb) || $%d::jsonb", argCounter))
 		args = append(args, arg.Configuration)
 		argCounter++
 	}
@@ -250,3 +257,90 @@ func (s *PostgresStore) DeleteKnowledgeBase(ctx context.Context, id uuid.UUID, o
 	log.Printf("[PostgresStore] DeleteKnowledgeBase: Successfully deleted KB ID %s for OrgID %s", id, orgID)
 	return nil
 }
+
+// MergeKnowledgeBaseConfiguration shallow-merges a JSON object into a knowledge base's configuration.
+// Used by the sync engine to update sync state without overwriting user settings.
+func (s *PostgresStore) MergeKnowledgeBaseConfiguration(ctx context.Context, id uuid.UUID, orgID uuid.UUID, patch []byte) error {
+	if !json.Valid(patch) {
+		return errors.New("invalid JSON format in configuration patch")
+	}
+	query := `
+        UPDATE knowledge_bases
+        SET configuration = COALESCE(configuration, '{}'::jsonb) || $3::jsonb, updated_at = now()
+        WHERE id = $1 AND organization_id = $2`
+
+	cmdTag, err := s.db.Exec(ctx, query, id, orgID, patch)
+	if err != nil {
+		log.Printf("ERROR [PostgresStore] MergeKnowledgeBaseConfiguration: Failed exec for ID %s, OrgID %s: %v", id, orgID, err)
+		return fmt.Errorf("database error updating knowledge base configuration: %w", err)
+	}
+	if cmdTag.RowsAffected() == 0 {
+		return store.ErrNotFound
+	}
+	return nil
+}
+
+// ClaimKnowledgeBaseSync merges patch into the configuration only if no sync started at or after startedBefore
+// (per the configuration's last_sync_started_at). It reports whether the claim succeeded, so that only one
+// server instance starts a scheduled sync.
+func (s *PostgresStore) ClaimKnowledgeBaseSync(ctx context.Context, id uuid.UUID, orgID uuid.UUID, patch []byte, startedBefore time.Time) (bool, error) {
+	if !json.Valid(patch) {
+		return false, errors.New("invalid JSON format in configuration patch")
+	}
+	query := `
+        UPDATE knowledge_bases
+        SET configuration = COALESCE(configuration, '{}'::jsonb) || $3::jsonb, updated_at = now()
+        WHERE id = $1 AND organization_id = $2
+          AND (configuration->>'last_sync_started_at' IS NULL
+               OR (configuration->>'last_sync_started_at')::timestamptz < $4)`
+
+	cmdTag, err := s.db.Exec(ctx, query, id, orgID, patch, startedBefore)
+	if err != nil {
+		log.Printf("ERROR [PostgresStore] ClaimKnowledgeBaseSync: Failed exec for ID %s, OrgID %s: %v", id, orgID, err)
+		return false, fmt.Errorf("database error claiming knowledge base sync: %w", err)
+	}
+	return cmdTag.RowsAffected() > 0, nil
+}
+
+// ListKnowledgeBasesWithSyncSchedule retrieves the knowledge bases of all organizations
+// that have a periodic sync configured (configuration.sync_interval_minutes > 0).
+func (s *PostgresStore) ListKnowledgeBasesWithSyncSchedule(ctx context.Context) ([]db_models.KnowledgeBase, error) {
+	query := `
+        SELECT id, organization_id, credential_id, service_type, name, configuration, is_active, created_at, updated_at
+        FROM knowledge_bases
+        WHERE jsonb_typeof(configuration->'sync_interval_minutes') = 'number'
+          AND (configuration->>'sync_interval_minutes')::numeric > 0`
+
+	rows, err := s.db.Query(ctx, query)
+	if err != nil {
+		log.Printf("ERROR [PostgresStore] ListKnowledgeBasesWithSyncSchedule: Failed query: %v", err)
+		return nil, fmt.Errorf("database error listing scheduled knowledge bases: %w", err)
+	}
+	defer rows.Close()
+
+	kbs := []db_models.KnowledgeBase{}
+	for rows.Next() {
+		kb := db_models.KnowledgeBase{}
+		if err := rows.Scan(
+			&kb.ID,
+			&kb.OrganizationID,
+			&kb.CredentialID,
+			&kb.ServiceType,
+			&kb.Name,
+			&kb.Configuration,
+			&kb.IsActive,
+			&kb.CreatedAt,
+			&kb.UpdatedAt,
+		); err != nil {
+			log.Printf("ERROR [PostgresStore] ListKnowledgeBasesWithSyncSchedule: Failed scanning row: %v", err)
+			return nil, fmt.Errorf("database error scanning knowledge base: %w", err)
+		}
+		kbs = append(kbs, kb)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Printf("ERROR [PostgresStore] ListKnowledgeBasesWithSyncSchedule: Error after iterating rows: %v", err)
+		return nil, fmt.Errorf("database error after listing scheduled knowledge bases: %w", err)
+	}
+	return kbs, nil
+}
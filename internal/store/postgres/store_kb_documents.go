@@ -0,0 +1,244 @@
+package postgres
+
+import (
+	db_models "buildmychat-backend/internal/models"
+	"buildmychat-backend/internal/store"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// --- Knowledge Base Document Methods ---
+
+// UpsertKBDocument inserts a document or replaces the existing one with the same (knowledge_base_id, source_id).
+func (s *PostgresStore) UpsertKBDocument(ctx context.Context, arg store.UpsertKBDocumentParams) (*db_models.KBDocument, error) {
+	query := `
+        INSERT INTO kb_documents (id, knowledge_base_id, organization_id, source_id, title, url, content, metadata, source_updated_at, content_hash)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+        ON CONFLICT (knowledge_base_id, source_id) DO UPDATE
+        SET title = EXCLUDED.title,
+            url = EXCLUDED.url,
+            content = EXCLUDED.content,
+            metadata = EXCLUDED.metadata,
+            source_updated_at = EXCLUDED.source_updated_at,
+            content_hash = EXCLUDED.content_hash,
+            updated_at = now()
+        RETURNING id, knowledge_base_id, organization_id, source_id, title, url, content, metadata, source_updated_at, content_hash, created_at, updated_at`
+
+	metadata := arg.Metadata
+	if metadata == nil {
+		metadata = []byte("{}")
+	}
+
+	doc := &db_models.KBDocument{}
+	err := s.db.QueryRow(ctx, query,
+		uuid.New(),
+		arg.KnowledgeBaseID,
+		arg.OrganizationID,
+		arg.SourceID,
+		arg.Title,
+		arg.URL,
+		arg.Content,
+		metadata,
+		arg.SourceUpdatedAt,
+		arg.ContentHash,
+	).Scan(
+		&doc.ID,
+		&doc.KnowledgeBaseID,
+		&doc.OrganizationID,
+		&doc.SourceID,
+		&doc.Title,
+		&doc.URL,
+		&doc.Content,
+		&doc.Metadata,
+		&doc.SourceUpdatedAt,
+		&doc.ContentHash,
+		&doc.CreatedAt,
+		&doc.UpdatedAt,
+	)
+	if err != nil {
+		log.Printf("ERROR [PostgresStore] UpsertKBDocument: Failed for KB %s, SourceID %s: %v", arg.KnowledgeBaseID, arg.SourceID, err)
+		return nil, fmt.Errorf("database error saving knowledge base document: %w", err)
+	}
+	return doc, nil
+}
+
+// CountKBDocuments returns the number of documents stored for a knowledge base.
+func (s *PostgresStore) CountKBDocuments(ctx context.Context, kbID uuid.UUID, orgID uuid.UUID) (int, error) {
+	query := `SELECT COUNT(*) FROM kb_documents WHERE knowledge_base_id = $1 AND organization_id = $2`
+
+	var count int
+	if err := s.db.QueryRow(ctx, query, kbID, orgID).Scan(&count); err != nil {
+		log.Printf("ERROR [PostgresStore] CountKBDocuments: Failed for KB %s, OrgID %s: %v", kbID, orgID, err)
+		return 0, fmt.Errorf("database error counting knowledge base documents: %w", err)
+	}
+	return count, nil
+}
+
+// ListKBDocuments returns every document of a knowledge base with its chunk count, ordered by title.
+func (s *PostgresStore) ListKBDocuments(ctx context.Context, kbID uuid.UUID, orgID uuid.UUID) ([]db_models.KBDocumentSummary, error) {
+	query := `
+        SELECT d.id, d.knowledge_base_id, d.source_id, d.title, d.url, d.source_updated_at,
+               (SELECT COUNT(*) FROM kb_chunks c WHERE c.document_id = d.id) AS chunk_count,
+               d.created_at, d.updated_at
+        FROM kb_documents d
+        WHERE d.knowledge_base_id = $1 AND d.organization_id = $2
+        ORDER BY d.title, d.source_id`
+
+	rows, err := s.db.Query(ctx, query, kbID, orgID)
+	if err != nil {
+		log.Printf("ERROR [PostgresStore] ListKBDocuments: Failed query for KB %s, OrgID %s: %v", kbID, orgID, err)
+		return nil, fmt.Errorf("database error listing knowledge base documents: %w", err)
+	}
+	defer rows.Close()
+
+	docs := []db_models.KBDocumentSummary{}
+	for rows.Next() {
+		d := db_models.KBDocumentSummary{}
+		if err := rows.Scan(&d.ID, &d.KnowledgeBaseID, &d.SourceID, &d.Title, &d.URL, &d.SourceUpdatedAt, &d.ChunkCount, &d.CreatedAt, &d.UpdatedAt); err != nil {
+			log.Printf("ERROR [PostgresStore] ListKBDocuments: Failed scanning row for KB %s: %v", kbID, err)
+			return nil, fmt.Errorf("database error scanning knowledge base document: %w", err)
+		}
+		docs = append(docs, d)
+	}
+	if err = rows.Err(); err != nil {
+		log.Printf("ERROR [PostgresStore] ListKBDocuments: Error after iterating rows for KB %s: %v", kbID, err)
+		return nil, fmt.Errorf("database error after listing knowledge base documents: %w", err)
+	}
+	return docs, nil
+}
+
+// ListKBDocumentVersions returns the change-tracking state of every document in a knowledge base.
+func (s *PostgresStore) ListKBDocumentVersions(ctx context.Context, kbID uuid.UUID, orgID uuid.UUID) ([]db_models.KBDocumentVersion, error) {
+	query := `
+        SELECT source_id, source_updated_at, content_hash, updated_at
+        FROM kb_documents
+        WHERE knowledge_base_id = $1 AND organization_id = $2`
+
+	rows, err := s.db.Query(ctx, query, kbID, orgID)
+	if err != nil {
+		log.Printf("ERROR [PostgresStore] ListKBDocumentVersions: Failed query for KB %s, OrgID %s: %v", kbID, orgID, err)
+		return nil, fmt.Errorf("database error listing knowledge base documents: %w", err)
+	}
+	defer rows.Close()
+
+	versions := []db_models.KBDocumentVersion{}
+	for rows.Next() {
+		v := db_models.KBDocumentVersion{}
+		if err := rows.Scan(&v.SourceID, &v.SourceUpdatedAt, &v.ContentHash, &v.UpdatedAt); err != nil {
+			log.Printf("ERROR [PostgresStore] ListKBDocumentVersions: Failed scanning row for KB %s: %v", kbID, err)
+			return nil, fmt.Errorf("database error scanning knowledge base document: %w", err)
+		}
+		versions = append(versions, v)
+	}
+	if err = rows.Err(); err != nil {
+		log.Printf("ERROR [PostgresStore] ListKBDocumentVersions: Error after iterating rows for KB %s: %v", kbID, err)
+		return nil, fmt.Errorf("database error after listing knowledge base documents: %w", err)
+	}
+	return versions, nil
+}
+
+// TouchKBDocument records a new source edit time for a document whose content did not change.
+func (s *PostgresStore) TouchKBDocument(ctx context.Context, kbID uuid.UUID, orgID uuid.UUID, sourceID string, sourceUpdatedAt time.Time) error {
+	query := `
+        UPDATE kb_documents
+        SET source_updated_at = $4, updated_at = now()
+        WHERE knowledge_base_id = $1 AND organization_id = $2 AND source_id = $3`
+
+	cmdTag, err := s.db.Exec(ctx, query, kbID, orgID, sourceID, sourceUpdatedAt)
+	if err != nil {
+		log.Printf("ERROR [PostgresStore] TouchKBDocument: Failed for KB %s, SourceID %s: %v", kbID, sourceID, err)
+		return fmt.Errorf("database error updating knowledge base document: %w", err)
+	}
+	if cmdTag.RowsAffected() == 0 {
+		return store.ErrNotFound
+	}
+	return nil
+}
+
+// SetKBDocumentContentHash records the content hash of a document once it has been indexed.
+func (s *PostgresStore) SetKBDocumentContentHash(ctx context.Context, documentID uuid.UUID, orgID uuid.UUID, contentHash string) error {
+	query := `UPDATE kb_documents SET content_hash = $3 WHERE id = $1 AND organization_id = $2`
+
+	cmdTag, err := s.db.Exec(ctx, query, documentID, orgID, contentHash)
+	if err != nil {
+		log.Printf("ERROR [PostgresStore] SetKBDocumentContentHash: Failed for DocumentID %s: %v", documentID, err)
+		return fmt.Errorf("database error updating knowledge base document: %w", err)
+	}
+	if cmdTag.RowsAffected() == 0 {
+		return store.ErrNotFound
+	}
+	return nil
+}
+
+// DeleteKBDocumentsNotIn removes every document of a knowledge base whose source ID is not in keepSourceIDs.
+// Used after a full sync to drop documents for pages that no longer exist or are no longer shared.
+func (s *PostgresStore) DeleteKBDocumentsNotIn(ctx context.Context, kbID uuid.UUID, orgID uuid.UUID, keepSourceIDs []string) (int64, error) {
+	query := `
+        DELETE FROM kb_documents
+        WHERE knowledge_base_id = $1 AND organization_id = $2 AND NOT (source_id = ANY($3))`
+
+	if keepSourceIDs == nil {
+		keepSourceIDs = []string{} // ANY(NULL) would match nothing and delete nothing
+	}
+
+	cmdTag, err := s.db.Exec(ctx, query, kbID, orgID, keepSourceIDs)
+	if err != nil {
+		log.Printf("ERROR [PostgresStore] DeleteKBDocumentsNotIn: Failed for KB %s, OrgID %s: %v", kbID, orgID, err)
+		return 0, fmt.Errorf("database error deleting stale knowledge base documents: %w", err)
+	}
+	log.Printf("[PostgresStore] DeleteKBDocumentsNotIn: Deleted %d stale documents for KB %s", cmdTag.RowsAffected(), kbID)
+	return cmdTag.RowsAffected(), nil
+}
+
+// GetKBDocumentByID retrieves a document of a knowledge base.
+func (s *PostgresStore) GetKBDocumentByID(ctx context.Context, documentID uuid.UUID, kbID uuid.UUID, orgID uuid.UUID) (*db_models.KBDocument, error) {
+	query := `
+        SELECT id, knowledge_base_id, organization_id, source_id, title, url, content, metadata, source_updated_at, content_hash, created_at, updated_at
+        FROM kb_documents
+        WHERE id = $1 AND knowledge_base_id = $2 AND organization_id = $3`
+
+	doc := &db_models.KBDocument{}
+	err := s.db.QueryRow(ctx, query, documentID, kbID, orgID).Scan(
+		&doc.ID,
+		&doc.KnowledgeBaseID,
+		&doc.OrganizationID,
+		&doc.SourceID,
+		&doc.Title,
+		&doc.URL,
+		&doc.Content,
+		&doc.Metadata,
+		&doc.SourceUpdatedAt,
+		&doc.ContentHash,
+		&doc.CreatedAt,
+		&doc.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, store.ErrNotFound
+		}
+		log.Printf("ERROR [PostgresStore] GetKBDocumentByID: Failed for DocumentID %s, KB %s: %v", documentID, kbID, err)
+		return nil, fmt.Errorf("database error fetching knowledge base document: %w", err)
+	}
+	return doc, nil
+}
+
+// DeleteKBDocument removes a document of a knowledge base; its chunks are deleted by ON DELETE CASCADE.
+func (s *PostgresStore) DeleteKBDocument(ctx context.Context, documentID uuid.UUID, kbID uuid.UUID, orgID uuid.UUID) error {
+	query := `DELETE FROM kb_documents WHERE id = $1 AND knowledge_base_id = $2 AND organization_id = $3`
+
+	cmdTag, err := s.db.Exec(ctx, query, documentID, kbID, orgID)
+	if err != nil {
+		log.Printf("ERROR [PostgresStore] DeleteKBDocument: Failed for DocumentID %s, KB %s: %v", documentID, kbID, err)
+		return fmt.Errorf("database error deleting knowledge base document: %w", err)
+	}
+	if cmdTag.RowsAffected() == 0 {
+		return store.ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+// --- OAuth State Methods ---
+
+// UseOAuthState records that an OAuth state was used. It reports whether this was its first use, so
+// that a state is accepted only once. States that expired are deleted.
+func (s *PostgresStore) UseOAuthState(ctx context.Context, stateID string, expiresAt time.Time) (bool, error) {
+	if _, err := s.db.Exec(ctx, `DELETE FROM used_oauth_states WHERE expires_at < now()`); err != nil {
+		log.Printf("WARN [PostgresStore] UseOAuthState: Failed to delete expired states: %v", err)
+	}
+
+	query := `
+        INSERT INTO used_oauth_states (state_id, expires_at)
+        VALUES ($1, $2)
+        ON CONFLICT (state_id) DO NOTHING`
+
+	cmdTag, err := s.db.Exec(ctx, query, stateID, expiresAt)
+	if err != nil {
+		log.Printf("ERROR [PostgresStore] UseOAuthState: Failed exec for StateID %s: %v", stateID, err)
+		return false, fmt.Errorf("database error recording OAuth state: %w", err)
+	}
+	return cmdTag.RowsAffected() > 0, nil
+}
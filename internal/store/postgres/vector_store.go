@@ -0,0 +1,198 @@
+package postgres
+
+import (
+	db_models "buildmychat-backend/internal/models"
+	"buildmychat-backend/internal/store"
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Compile-time check to ensure PGVectorStore implements store.VectorStore
+var _ store.VectorStore = (*PGVectorStore)(nil)
+
+// PGVectorStore keeps chunk embeddings in the kb_chunks table using the pgvector extension.
+// Chunks are also removed when their kb_documents row is deleted (ON DELETE CASCADE).
+type PGVectorStore struct {
+	db *pgxpool.Pool
+}
+
+func NewPGVectorStore(db *pgxpool.Pool) *PGVectorStore {
+	return &PGVectorStore{db: db}
+}
+
+// UpsertDocumentChunks atomically replaces all chunks of a document.
+func (s *PGVectorStore) UpsertDocumentChunks(ctx context.Context, orgID uuid.UUID, documentID uuid.UUID, chunks []db_models.KBChunk) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("database error starting transaction: %w", err)
+	}
+	defer tx.Rollback(ctx) // No-op after Commit
+
+	if _, err := tx.Exec(ctx, `DELETE FROM kb_chunks WHERE document_id = $1 AND organization_id = $2`, documentID, orgID); err != nil {
+		log.Printf("ERROR [PGVectorStore] UpsertDocumentChunks: Failed deleting chunks of DocumentID %s: %v", documentID, err)
+		return fmt.Errorf("database error deleting knowledge base chunks: %w", err)
+	}
+
+	// pgx has no codec for the vector type, so embeddings are sent as text and cast.
+	query := `
+        INSERT INTO kb_chunks (id, document_id, knowledge_base_id, organization_id, chunk_index, heading, content, token_count, embedding, embedding_model, metadata)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9::vector, $10, $11)`
+
+	batch := &pgx.Batch{}
+	for _, c := range chunks {
+		id := c.ID
+		if id == uuid.Nil {
+			id = uuid.New()
+		}
+		metadata := []byte(c.Metadata)
+		if metadata == nil {
+			metadata = []byte("{}")
+		}
+		batch.Queue(query, id, documentID, c.KnowledgeBaseID, orgID, c.ChunkIndex, c.Heading, c.Content, c.TokenCount,
+			vectorLiteral(c.Embedding), c.EmbeddingModel, metadata)
+	}
+	if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+		log.Printf("ERROR [PGVectorStore] UpsertDocumentChunks: Failed inserting %d chunks for DocumentID %s: %v", len(chunks), documentID, err)
+		return fmt.Errorf("database error inserting knowledge base chunks: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("database error committing knowledge base chunks: %w", err)
+	}
+	return nil
+}
+
+// DeleteDocumentChunks removes all chunks of a document.
+func (s *PGVectorStore) DeleteDocumentChunks(ctx context.Context, orgID uuid.UUID, documentID uuid.UUID) error {
+	if _, err := s.db.Exec(ctx, `DELETE FROM kb_chunks WHERE document_id = $1 AND organization_id = $2`, documentID, orgID); err != nil {
+		log.Printf("ERROR [PGVectorStore] DeleteDocumentChunks: Failed for DocumentID %s, OrgID %s: %v", documentID, orgID, err)
+		return fmt.Errorf("database error deleting knowledge base chunks: %w", err)
+	}
+	return nil
+}
+
+// SearchSimilar ranks chunks by cosine distance (the <=> operator) to the query embedding.
+func (s *PGVectorStore) SearchSimilar(ctx context.Context, arg store.VectorSearchParams) ([]store.VectorMatch, error) {
+	if len(arg.KnowledgeBaseIDs) == 0 || arg.TopK <= 0 {
+		return []store.VectorMatch{}, nil
+	}
+
+	query := `
+        SELECT id, document_id, knowledge_base_id, organization_id, chunk_index, heading, content, token_count,
+               embedding_model, metadata, created_at, 1 - (embedding <=> $1::vector) AS score
+        FROM kb_chunks
+        WHERE organization_id = $2 AND knowledge_base_id = ANY($3) AND embedding_model = $4
+        ORDER BY embedding <=> $1::vector
+        LIMIT $5`
+
+	rows, err := s.db.Query(ctx, query, vectorLiteral(arg.Embedding), arg.OrganizationID, arg.KnowledgeBaseIDs, arg.EmbeddingModel, arg.TopK)
+	if err != nil {
+		log.Printf("ERROR [PGVectorStore] SearchSimilar: Failed query for OrgID %s: %v", arg.OrganizationID, err)
+		return nil, fmt.Errorf("database error searching knowledge base chunks: %w", err)
+	}
+	defer rows.Close()
+
+	matches := []store.VectorMatch{}
+	for rows.Next() {
+		var m store.VectorMatch
+		if err := rows.Scan(
+			&m.Chunk.ID,
+			&m.Chunk.DocumentID,
+			&m.Chunk.KnowledgeBaseID,
+			&m.Chunk.OrganizationID,
+			&m.Chunk.ChunkIndex,
+			&m.Chunk.Heading,
+			&m.Chunk.Content,
+			&m.Chunk.TokenCount,
+			&m.Chunk.EmbeddingModel,
+			&m.Chunk.Metadata,
+			&m.Chunk.CreatedAt,
+			&m.Score,
+		); err != nil {
+			log.Printf("ERROR [PGVectorStore] SearchSimilar: Failed scanning row for OrgID %s: %v", arg.OrganizationID, err)
+			return nil, fmt.Errorf("database error scanning knowledge base chunk: %w", err)
+		}
+		if m.Score < arg.MinScore {
+			break // Rows are ordered by score
+		}
+		matches = append(matches, m)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("database error after searching knowledge base chunks: %w", err)
+	}
+	return matches, nil
+}
+
+// SearchLexical ranks chunks matching any of the query's terms with ts_rank_cd over the content_tsv column.
+// plainto_tsquery ANDs the terms; they are ORed instead so a question containing a product code still
+// matches chunks that only mention the code.
+func (s *PGVectorStore) SearchLexical(ctx context.Context, arg store.LexicalSearchParams) ([]store.VectorMatch, error) {
+	if len(arg.KnowledgeBaseIDs) == 0 || arg.TopK <= 0 || strings.TrimSpace(arg.Query) == "" {
+		return []store.VectorMatch{}, nil
+	}
+
+	query := `
+        WITH q AS (SELECT replace(plainto_tsquery('simple', $1)::text, ' & ', ' | ')::tsquery AS query)
+        SELECT id, document_id, knowledge_base_id, organization_id, chunk_index, heading, content, token_count,
+               embedding_model, metadata, created_at, ts_rank_cd(content_tsv, q.query) AS score
+        FROM kb_chunks, q
+        WHERE organization_id = $2 AND knowledge_base_id = ANY($3) AND content_tsv @@ q.query
+        ORDER BY score DESC
+        LIMIT $4`
+
+	rows, err := s.db.Query(ctx, query, arg.Query, arg.OrganizationID, arg.KnowledgeBaseIDs, arg.TopK)
+	if err != nil {
+		log.Printf("ERROR [PGVectorStore] SearchLexical: Failed query for OrgID %s: %v", arg.OrganizationID, err)
+		return nil, fmt.Errorf("database error searching knowledge base chunks: %w", err)
+	}
+	defer rows.Close()
+
+	matches := []store.VectorMatch{}
+	for rows.Next() {
+		var m store.VectorMatch
+		if err := rows.Scan(
+			&m.Chunk.ID,
+			&m.Chunk.DocumentID,
+			&m.Chunk.KnowledgeBaseID,
+			&m.Chunk.OrganizationID,
+			&m.Chunk.ChunkIndex,
+			&m.Chunk.Heading,
+			&m.Chunk.Content,
+			&m.Chunk.TokenCount,
+			&m.Chunk.EmbeddingModel,
+			&m.Chunk.Metadata,
+			&m.Chunk.CreatedAt,
+			&m.Score,
+		); err != nil {
+			log.Printf("ERROR [PGVectorStore] SearchLexical: Failed scanning row for OrgID %s: %v", arg.OrganizationID, err)
+			return nil, fmt.Errorf("database error scanning knowledge base chunk: %w", err)
+		}
+		matches = append(matches, m)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("database error after searching knowledge base chunks: %w", err)
+	}
+	return matches, nil
+}
+
+// vectorLiteral formats an embedding in pgvector's text format, e.g. "[0.1,0.2]".
+func vectorLiteral(v []float32) string {
+	var b strings.Builder
+	b.Grow(len(v) * 10)
+	b.WriteByte('[')
+	for i, x := range v {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(strconv.FormatFloat(float64(x), 'g', -1, 32))
+	}
+	b.WriteByte(']')
+	return b.String()
+}
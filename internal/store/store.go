@@ -7,6 +7,7 @@ import (
 	"errors"
 
 	"encoding/json"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -14,6 +15,9 @@ import (
 // ErrNotFound is returned when a specific record is not found.
 var ErrNotFound = errors.New("record not found")
 
+// ErrWorkspaceTaken is returned when an interface would be the second active one of its workspace and app.
+var ErrWorkspaceTaken = errors.New("another active interface is configured for the workspace and app")
+
 // CreateIntegrationCredentialParams contains parameters for creating a credential.
 // We pass encrypted bytes directly, assuming JSONB handling (base64 wrapping) happens in the implementation.
 type CreateIntegrationCredentialParams struct {
@@ -29,8 +33,8 @@ type CreateIntegrationCredentialParams struct {
 type CreateKnowledgeBaseParams struct {
 	ID             uuid.UUID
 	OrganizationID uuid.UUID
-	CredentialID   uuid.UUID
-	ServiceType    string // Expect models.ServiceTypeNotion
+	CredentialID   uuid.UUID // uuid.Nil stores NULL (UPLOAD knowledge bases)
+	ServiceType    string    // models.ServiceTypeNotion or models.ServiceTypeUpload
 	Name           string
 	Configuration  []byte // JSON marshaled bytes
 	IsActive       bool
@@ -40,9 +44,24 @@ type CreateKnowledgeBaseParams struct {
 type UpdateKnowledgeBaseParams struct {
 	ID             uuid.UUID
 	OrganizationID uuid.UUID
-	Name           *string // Pointer to allow optional update
-	Configuration  []byte  // JSON marshaled bytes, optional update
-	IsActive       *bool   // Pointer to allow optional update
+	CredentialID   *uuid.UUID // Pointer to allow optional update
+	Name           *string    // Pointer to allow optional update
+	Configuration  []byte     // JSON object shallow-merged into the stored configuration, optional update
+	IsActive       *bool      // Pointer to allow optional update
+}
+
+// UpsertKBDocumentParams contains parameters for creating or replacing a knowledge base document.
+// Documents are keyed by (KnowledgeBaseID, SourceID).
+type UpsertKBDocumentParams struct {
+	KnowledgeBaseID uuid.UUID
+	OrganizationID  uuid.UUID
+	SourceID        string
+	Title           string
+	URL             string
+	Content         string
+	Metadata        []byte // JSON marshaled bytes, optional
+	SourceUpdatedAt *time.Time
+	ContentHash     string
 }
 
 // CreateInterfaceParams contains parameters for creating an interface.
@@ -60,9 +79,10 @@ type CreateInterfaceParams struct {
 type UpdateInterfaceParams struct {
 	ID             uuid.UUID
 	OrganizationID uuid.UUID
-	Name           *string // Pointer to allow optional update
-	Configuration  []byte  // JSON marshaled bytes, optional update
-	IsActive       *bool   // Pointer to allow optional update
+	CredentialID   *uuid.UUID // Pointer to allow optional update
+	Name           *string    // Pointer to allow optional update
+	Configuration  []byte     // JSON marshaled bytes, optional update
+	IsActive       *bool      // Pointer to allow optional update
 }
 
 // Store defines the interface for database operations.
@@ -93,6 +113,20 @@ type Store interface {
 	ListKnowledgeBasesByOrg(ctx context.Context, orgID uuid.UUID) ([]db_models.KnowledgeBase, error)
 	UpdateKnowledgeBase(ctx context.Context, arg UpdateKnowledgeBaseParams) (*db_models.KnowledgeBase, error) // For config/status updates
 	DeleteKnowledgeBase(ctx context.Context, id uuid.UUID, orgID uuid.UUID) error
+	MergeKnowledgeBaseConfiguration(ctx context.Context, id uuid.UUID, orgID uuid.UUID, patch []byte) error // Shallow-merges patch into configuration
+	ClaimKnowledgeBaseSync(ctx context.Context, id uuid.UUID, orgID uuid.UUID, patch []byte, startedBefore time.Time) (bool, error)
+	ListKnowledgeBasesWithSyncSchedule(ctx context.Context) ([]db_models.KnowledgeBase, error) // All organizations
+
+	// Knowledge Base document operations
+	UpsertKBDocument(ctx context.Context, arg UpsertKBDocumentParams) (*db_models.KBDocument, error)
+	CountKBDocuments(ctx context.Context, kbID uuid.UUID, orgID uuid.UUID) (int, error)
+	ListKBDocuments(ctx context.Context, kbID uuid.UUID, orgID uuid.UUID) ([]db_models.KBDocumentSummary, error) // Ordered by title
+	ListKBDocumentVersions(ctx context.Context, kbID uuid.UUID, orgID uuid.UUID) ([]db_models.KBDocumentVersion, error)
+	TouchKBDocument(ctx context.Context, kbID uuid.UUID, orgID uuid.UUID, sourceID string, sourceUpdatedAt time.Time) error // Records a new source edit time for unchanged content
+	SetKBDocumentContentHash(ctx context.Context, documentID uuid.UUID, orgID uuid.UUID, contentHash string) error
+	DeleteKBDocumentsNotIn(ctx context.Context, kbID uuid.UUID, orgID uuid.UUID, keepSourceIDs []string) (int64, error)
+	GetKBDocumentByID(ctx context.Context, documentID uuid.UUID, kbID uuid.UUID, orgID uuid.UUID) (*db_models.KBDocument, error)
+	DeleteKBDocument(ctx context.Context, documentID uuid.UUID, kbID uuid.UUID, orgID uuid.UUID) error // Chunks are removed by cascade
 
 	// Interface operations
 	CreateInterface(ctx context.Context, arg CreateInterfaceParams) (*db_models.Interface, error)
@@ -100,6 +134,15 @@ type Store interface {
 	ListInterfacesByOrg(ctx context.Context, orgID uuid.UUID) ([]db_models.Interface, error)
 	UpdateInterface(ctx context.Context, arg UpdateInterfaceParams) (*db_models.Interface, error) // For config/status updates
 	DeleteInterface(ctx context.Context, id uuid.UUID, orgID uuid.UUID) error
+	GetActiveInterfaceByWorkspace(ctx context.Context, serviceType db_models.ServiceType, workspaceID string, appID string) (*db_models.Interface, error) // Any organization; appID "" for interfaces without one
+
+	// Channel event operations
+	ClaimChannelEvent(ctx context.Context, interfaceID uuid.UUID, eventID string) (bool, error) // False if the event was already claimed
+	ReleaseChannelEvent(ctx context.Context, interfaceID uuid.UUID, eventID string) error       // Lets the event be claimed again
+	DeleteChannelEventsBefore(ctx context.Context, before time.Time) (int64, error)             // All interfaces
+
+	// OAuth state operations
+	UseOAuthState(ctx context.Context, stateID string, expiresAt time.Time) (bool, error) // False if the state was already used
 
 	// Add other interfaces for Chatbots, Mappings, Chats, etc.
 	// ...
@@ -119,6 +162,8 @@ type Store interface {
 	AddInterfaceMapping(ctx context.Context, chatbotID, interfaceID, orgID uuid.UUID) error
 	RemoveInterfaceMapping(ctx context.Context, chatbotID, interfaceID, orgID uuid.UUID) error
 	GetChatbotMappings(ctx context.Context, chatbotID, orgID uuid.UUID) (*models.ChatbotMappingsResponse, error)
+	ListChatbotsByInterface(ctx context.Context, interfaceID, orgID uuid.UUID) ([]models.Chatbot, error)             // Oldest first
+	ListActiveChatbotKnowledgeBases(ctx context.Context, chatbotID, orgID uuid.UUID) ([]models.KnowledgeBase, error) // Mapped KBs with is_active
 
 	// Chat operations
 	CreateChat(ctx context.Context, arg CreateChatParams) (*models.Chat, error)
@@ -0,0 +1,50 @@
+package store
+
+import (
+	db_models "buildmychat-backend/internal/models"
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// VectorSearchParams describes a similarity search. Results are always restricted to
+// OrganizationID and KnowledgeBaseIDs; an empty KnowledgeBaseIDs matches nothing.
+type VectorSearchParams struct {
+	OrganizationID   uuid.UUID
+	KnowledgeBaseIDs []uuid.UUID
+	Embedding        []float32
+	EmbeddingModel   string  // Only chunks embedded with this model are compared
+	TopK             int     // Maximum number of matches
+	MinScore         float64 // Matches scoring below this are dropped; 0 keeps everything
+}
+
+// LexicalSearchParams describes a full-text search. Like VectorSearchParams, results are restricted to
+// OrganizationID and KnowledgeBaseIDs.
+type LexicalSearchParams struct {
+	OrganizationID   uuid.UUID
+	KnowledgeBaseIDs []uuid.UUID
+	Query            string // Free text; a chunk matches if it contains any of the query's terms
+	TopK             int    // Maximum number of matches
+}
+
+// VectorMatch is a chunk found by a search. For SearchSimilar, Score is the cosine similarity
+// (1 = same direction, 0 = unrelated); for SearchLexical it is a text rank that is only meaningful
+// relative to the other matches of the same search. Chunk.Embedding is not populated.
+type VectorMatch struct {
+	Chunk db_models.KBChunk
+	Score float64
+}
+
+// VectorStore stores knowledge base chunk embeddings and finds the chunks closest to a query vector,
+// or containing the query's terms.
+// Like Store, every method is scoped to an organization.
+type VectorStore interface {
+	// UpsertDocumentChunks replaces all chunks of a document with chunks. Chunks without an ID get one.
+	UpsertDocumentChunks(ctx context.Context, orgID uuid.UUID, documentID uuid.UUID, chunks []db_models.KBChunk) error
+	// DeleteDocumentChunks removes all chunks of a document.
+	DeleteDocumentChunks(ctx context.Context, orgID uuid.UUID, documentID uuid.UUID) error
+	// SearchSimilar returns the TopK chunks most similar to the query embedding, best first.
+	SearchSimilar(ctx context.Context, arg VectorSearchParams) ([]VectorMatch, error)
+	// SearchLexical returns the TopK chunks ranking highest for the query's terms, best first.
+	SearchLexical(ctx context.Context, arg LexicalSearchParams) ([]VectorMatch, error)
+}
@@ -0,0 +1,17 @@
+-- Documents ingested into knowledge bases by the sync engine (one row per source page).
+
+CREATE TABLE IF NOT EXISTS kb_documents (
+    id                UUID PRIMARY KEY,
+    knowledge_base_id UUID NOT NULL REFERENCES knowledge_bases(id) ON DELETE CASCADE,
+    organization_id   UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    source_id         TEXT NOT NULL,              -- ID in the source system, e.g. the Notion page ID
+    title             TEXT NOT NULL DEFAULT '',
+    url               TEXT NOT NULL DEFAULT '',
+    content           TEXT NOT NULL DEFAULT '',   -- Rendered Markdown or plain text
+    metadata          JSONB NOT NULL DEFAULT '{}',
+    created_at        TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at        TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (knowledge_base_id, source_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_kb_documents_org_kb ON kb_documents (organization_id, knowledge_base_id);
@@ -0,0 +1,5 @@
+-- Change tracking for incremental knowledge base syncs.
+
+ALTER TABLE kb_documents
+    ADD COLUMN IF NOT EXISTS source_updated_at TIMESTAMPTZ,                -- Last edit time reported by the source (Notion last_edited_time)
+    ADD COLUMN IF NOT EXISTS content_hash      TEXT NOT NULL DEFAULT '';  -- SHA-256 of title and content; '' forces a re-fetch
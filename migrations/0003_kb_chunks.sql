@@ -0,0 +1,19 @@
+-- Retrieval chunks of knowledge base documents, with their embeddings.
+
+CREATE TABLE IF NOT EXISTS kb_chunks (
+    id                UUID PRIMARY KEY,
+    document_id       UUID NOT NULL REFERENCES kb_documents(id) ON DELETE CASCADE,
+    knowledge_base_id UUID NOT NULL REFERENCES knowledge_bases(id) ON DELETE CASCADE,
+    organization_id   UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    chunk_index       INT NOT NULL,                -- Position within the document
+    heading           TEXT NOT NULL DEFAULT '',    -- Section heading path, e.g. 'Billing > Refunds'
+    content           TEXT NOT NULL,
+    token_count       INT NOT NULL DEFAULT 0,      -- Estimated
+    embedding         REAL[] NOT NULL,
+    embedding_model   TEXT NOT NULL,               -- Vectors of different models are not comparable
+    metadata          JSONB NOT NULL DEFAULT '{}', -- Source metadata: source_id, title, url
+    created_at        TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (document_id, chunk_index)
+);
+
+CREATE INDEX IF NOT EXISTS idx_kb_chunks_org_kb ON kb_chunks (organization_id, knowledge_base_id);
@@ -0,0 +1,15 @@
+-- Store chunk embeddings as pgvector vectors so similarity search runs in the database.
+-- Requires the pgvector extension (https://github.com/pgvector/pgvector) to be installed on the server.
+
+CREATE EXTENSION IF NOT EXISTS vector;
+
+ALTER TABLE kb_chunks ALTER COLUMN embedding TYPE vector USING embedding::vector;
+
+-- The column accepts any dimension so the embedding model can be changed. Approximate nearest-neighbour
+-- indexes need a fixed dimension; create one matching EMBEDDING_DIMENSIONS once the corpus is large, e.g.:
+--
+--   CREATE INDEX idx_kb_chunks_embedding_hnsw ON kb_chunks
+--       USING hnsw ((embedding::vector(1536)) vector_cosine_ops);
+--
+-- (queries must use the same cast to benefit from it). Without such an index, searches are exact scans of the
+-- organization's chunks, narrowed by idx_kb_chunks_org_kb.
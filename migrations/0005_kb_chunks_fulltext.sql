@@ -0,0 +1,9 @@
+-- Full-text index over chunk text for hybrid (lexical + vector) retrieval.
+-- The 'simple' configuration does no stemming or stop-word removal, so product codes and error strings
+-- are matched as written.
+
+ALTER TABLE kb_chunks
+    ADD COLUMN IF NOT EXISTS content_tsv tsvector
+        GENERATED ALWAYS AS (to_tsvector('simple', heading || ' ' || content)) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_kb_chunks_content_tsv ON kb_chunks USING gin (content_tsv);
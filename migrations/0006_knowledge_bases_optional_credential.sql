@@ -0,0 +1,3 @@
+-- Knowledge bases filled by file uploads have no integration credential.
+
+ALTER TABLE knowledge_bases ALTER COLUMN credential_id DROP NOT NULL;
@@ -0,0 +1,11 @@
+-- Inbound events of messaging channels (e.g. Slack Events API deliveries) that were accepted for processing.
+-- Services redeliver events that were not acknowledged in time; an event is only processed by whoever inserts it.
+
+CREATE TABLE IF NOT EXISTS channel_events (
+    interface_id UUID NOT NULL REFERENCES interfaces(id) ON DELETE CASCADE,
+    event_id     TEXT NOT NULL,                       -- ID of the event at the service, e.g. the Slack event_id
+    received_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (interface_id, event_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_channel_events_received_at ON channel_events (received_at);
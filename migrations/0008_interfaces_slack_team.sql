@@ -0,0 +1,5 @@
+-- Slack events sent to the shared events URL are routed to the interface of their team.
+
+CREATE INDEX IF NOT EXISTS idx_interfaces_slack_team_id
+    ON interfaces ((configuration->>'slack_team_id'))
+    WHERE service_type = 'SLACK';
@@ -0,0 +1,9 @@
+-- OAuth states (e.g. of the "Add to Slack" flow) that were used by a callback. A state is accepted only by
+-- whoever inserts it; rows are deleted once the state has expired.
+
+CREATE TABLE IF NOT EXISTS used_oauth_states (
+    state_id   TEXT PRIMARY KEY,                     -- The state's "jti" claim
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_used_oauth_states_expires_at ON used_oauth_states (expires_at);
@@ -0,0 +1,32 @@
+-- Only one active Slack interface may be configured for a workspace (team) and app, so that events sent to the
+-- shared events URL belong to exactly one interface. Interfaces without slack_app_id count as one app.
+
+-- Active duplicates may belong to different organizations, so which one keeps the workspace is not decided
+-- here: the migration fails and lists them, and must be run again once all but one of each are deactivated.
+DO $$
+DECLARE
+    conflicts text;
+BEGIN
+    SELECT string_agg(format('team %s, app %s: interfaces %s (organizations %s)', team_id, app_id, ids, org_ids), E'\n')
+    INTO conflicts
+    FROM (
+        SELECT configuration->>'slack_team_id' AS team_id,
+               COALESCE(NULLIF(configuration->>'slack_app_id', ''), '-') AS app_id,
+               string_agg(id::text, ', ' ORDER BY updated_at DESC) AS ids,
+               string_agg(organization_id::text, ', ' ORDER BY updated_at DESC) AS org_ids
+        FROM interfaces
+        WHERE service_type = 'SLACK' AND is_active AND configuration->>'slack_team_id' IS NOT NULL
+        GROUP BY 1, 2
+        HAVING count(*) > 1
+    ) duplicates;
+
+    IF conflicts IS NOT NULL THEN
+        RAISE EXCEPTION E'Several active Slack interfaces are configured for the same workspace and app:\n%', conflicts
+            USING HINT = 'Deactivate all but one interface of each workspace and app, then run this migration again.';
+    END IF;
+END
+$$;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_interfaces_workspace_active
+    ON interfaces ((configuration->>'slack_team_id'), (COALESCE(configuration->>'slack_app_id', '')))
+    WHERE service_type = 'SLACK' AND is_active;